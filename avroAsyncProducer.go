@@ -0,0 +1,138 @@
+package kafka
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Shopify/sarama"
+	"github.com/linkedin/goavro/v2"
+)
+
+// Delivery is the outcome of a message sent by the AvroAsyncProducer, it carries the original key and textual value
+type Delivery struct {
+	Topic     string
+	Key       []byte
+	Value     []byte
+	SchemaId  int
+	Partition int32
+	Offset    int64
+}
+
+// AsyncProducerCallbacks are called from the delivery goroutines of the AvroAsyncProducer
+type AsyncProducerCallbacks struct {
+	OnSuccess func(delivery *Delivery)
+	OnError   func(delivery *Delivery, err error)
+}
+
+// AvroAsyncProducer is like AvroProducer on top of a sarama.AsyncProducer, reporting deliveries through callbacks
+type AvroAsyncProducer struct {
+	producer             sarama.AsyncProducer
+	schemaRegistryClient SchemaRegistry
+	callbacks            AsyncProducerCallbacks
+	config               *Config
+	wg                   sync.WaitGroup
+	rateLimit            *rateLimiter
+	schemas              schemaSelection
+	claimCheck           *ClaimCheck
+}
+
+// NewAvroAsyncProducer creates an asynchronous producer to interact with schema registry, avro and kafka
+func NewAvroAsyncProducer(kafkaServers []string, schemaRegistryServers []string, callbacks AsyncProducerCallbacks,
+	opts ...Option) (*AvroAsyncProducer, error) {
+	o := applyOptions(defaultAvroProducerConfig(), opts)
+	// deliveries are reported from both channels
+	o.saramaConfig.Producer.Return.Successes = true
+	o.saramaConfig.Producer.Return.Errors = true
+	producer, err := sarama.NewAsyncProducer(kafkaServers, o.saramaConfig)
+	if err != nil {
+		return nil, err
+	}
+	ap := newAvroAsyncProducer(producer, o.schemaRegistryClient(schemaRegistryServers), callbacks)
+	ap.config = o.config
+	ap.rateLimit = o.rateLimit
+	ap.schemas = o.schemas
+	ap.claimCheck = o.claimCheck
+	return ap, nil
+}
+
+func newAvroAsyncProducer(producer sarama.AsyncProducer, schemaRegistryClient SchemaRegistry,
+	callbacks AsyncProducerCallbacks) *AvroAsyncProducer {
+	ap := &AvroAsyncProducer{
+		producer:             producer,
+		schemaRegistryClient: schemaRegistryClient,
+		callbacks:            callbacks,
+	}
+	ap.wg.Add(2)
+	go func() {
+		defer ap.wg.Done()
+		for msg := range producer.Successes() {
+			delivery := msg.Metadata.(*Delivery)
+			delivery.Partition, delivery.Offset = msg.Partition, msg.Offset
+			if ap.callbacks.OnSuccess != nil {
+				ap.callbacks.OnSuccess(delivery)
+			}
+		}
+	}()
+	go func() {
+		defer ap.wg.Done()
+		for producerErr := range producer.Errors() {
+			delivery := producerErr.Msg.Metadata.(*Delivery)
+			if ap.callbacks.OnError != nil {
+				ap.callbacks.OnError(delivery, producerErr.Err)
+			}
+		}
+	}()
+	return ap
+}
+
+// SetConfig sets the layered per-topic and per-subject configuration of the producer
+func (ap *AvroAsyncProducer) SetConfig(config Config) {
+	ap.config = &config
+}
+
+// GetSchemaId get schema id from schema-registry service
+func (ap *AvroAsyncProducer) GetSchemaId(topic string, avroCodec *goavro.Codec) (int, error) {
+	subject, err := ap.config.valueSubject(topic, avroCodec.Schema())
+	if err != nil {
+		return 0, err
+	}
+	return ap.schemas.schemaId(ap.schemaRegistryClient, subject, avroCodec)
+}
+
+// Add encodes the value and queues the message. Schema and encoding errors are returned,
+// the delivery is reported to the callbacks.
+func (ap *AvroAsyncProducer) Add(topic string, schema string, key []byte, value []byte) error {
+	avroCodec, err := goavro.NewCodec(schema)
+	if err != nil {
+		return err
+	}
+	schemaId, err := ap.GetSchemaId(topic, avroCodec)
+	if err != nil {
+		return err
+	}
+	binaryValue, err := encodeTextual(avroCodec, value)
+	if err != nil {
+		return err
+	}
+	encoded, headers := ap.config.ForTopic(topic).wireFormat().Encode(schemaId, binaryValue)
+	topic = ap.config.PhysicalTopic(topic)
+	msg := &sarama.ProducerMessage{
+		Topic:    topic,
+		Key:      sarama.StringEncoder(key),
+		Value:    encoded,
+		Headers:  headers,
+		Metadata: &Delivery{Topic: topic, Key: key, Value: value, SchemaId: schemaId},
+	}
+	if err := ap.claimCheck.store(context.Background(), msg); err != nil {
+		return err
+	}
+	ap.rateLimit.wait(context.Background(), len(key)+msg.Value.Length())
+	ap.producer.Input() <- msg
+	return nil
+}
+
+// Close flushes the queued messages and waits for their deliveries to be reported
+func (ap *AvroAsyncProducer) Close() {
+	ap.producer.AsyncClose()
+	ap.wg.Wait()
+}
@@ -0,0 +1,42 @@
+package kafka
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/mocks"
+)
+
+func TestAvroAsyncProducer_Add(t *testing.T) {
+	config := sarama.NewConfig()
+	config.Producer.Return.Successes = true
+	producerMock := mocks.NewAsyncProducer(t, config)
+	producerMock.ExpectInputAndSucceed()
+	producerMock.ExpectInputAndFail(errors.New("delivery failed"))
+	schemaRegistryTestObject := createSchemaRegistryTestObject(t, "test", 1)
+	defer schemaRegistryTestObject.MockServer.Close()
+
+	var succeeded, failed []*Delivery
+	avroProducer := newAvroAsyncProducer(producerMock, NewCachedSchemaRegistryClient([]string{schemaRegistryTestObject.MockServer.URL}),
+		AsyncProducerCallbacks{
+			OnSuccess: func(delivery *Delivery) { succeeded = append(succeeded, delivery) },
+			OnError:   func(delivery *Delivery, err error) { failed = append(failed, delivery) },
+		})
+	for _, key := range []string{"a", "b"} {
+		if err := avroProducer.Add("test", schemaRegistryTestObject.Codec.Schema(), []byte(key), []byte(`{"val":1}`)); err != nil {
+			t.Errorf("Error adding msg: %v", err)
+		}
+	}
+	if err := avroProducer.Add("test", schemaRegistryTestObject.Codec.Schema(), []byte("c"), []byte(`{"val":"x"}`)); err == nil {
+		t.Errorf("Expected encoding error")
+	}
+	avroProducer.Close()
+
+	if len(succeeded) != 1 || string(succeeded[0].Key) != "a" || succeeded[0].SchemaId != 1 {
+		t.Errorf("Expected delivery of a, got %v", succeeded)
+	}
+	if len(failed) != 1 || string(failed[0].Key) != "b" || string(failed[0].Value) != `{"val":1}` {
+		t.Errorf("Expected failed delivery of b, got %v", failed)
+	}
+}
@@ -1,24 +1,77 @@
 package kafka
 
 import (
-	"encoding/binary"
+	"context"
+	"fmt"
 	"github.com/Shopify/sarama"
-	"github.com/bsm/sarama-cluster"
 	"github.com/linkedin/goavro/v2"
-	"os"
-	"os/signal"
+	"github.com/rcrowley/go-metrics"
+	"sync"
+	"time"
 )
 
-type avroConsumer struct {
-	Consumer             *cluster.Consumer
-	SchemaRegistryClient *CachedSchemaRegistryClient
+type AvroConsumer struct {
+	Consumer             sarama.ConsumerGroup
+	SchemaRegistryClient SchemaRegistry
 	callbacks            ConsumerCallbacks
+	redaction            RedactionProfile
+	decodeCache          *decodeCache
+	bootstrap            *BootstrapConfig
+	config               *Config
+	kafkaServers         []string
+	groupId              string
+	topics               []string
+	saramaConfig         *sarama.Config
+	leader               *leaderStrategy
+	membership           GroupMembership
+	claims               map[string][]int32
+	membershipLock       sync.Mutex
+	session              sarama.ConsumerGroupSession
+	commitStrategy       OffsetCommitStrategy
+	cancel               context.CancelFunc
+	done                 chan struct{}
+	runLock              sync.Mutex
+	deadLetter           *DeadLetterConfig
+	retry                *RetryConfig
+	halted               int32
+	logicalTopics        map[string]string
+	metricRegistry       metrics.Registry
+	metricsOnce          sync.Once
+	strict               *strictValidator
+	metrics              Metrics
+	logger               Logger
+	readers              readerSchemas
+	jsonFormat           JSONFormat
+	converters           structConverters
+	unframed             UnframedFallback
+	batch                BatchConfig
+	concurrency          ConcurrencyConfig
+	rejoin               context.CancelFunc
+	seeks                map[string]map[int32]int64
+	rateLimit            *rateLimiter
+	interceptors         []ConsumerInterceptor
+	decodeMode           DecodeMode
+	claimCheck           *ClaimCheck
+	chunks               chunkAssembler
 }
 
 type ConsumerCallbacks struct {
 	OnDataReceived func(msg Message)
+	// OnProcess is called after OnDataReceived for decoded messages, a failed message is retried
+	// or dead-lettered when the consumer has retry topics or a dead-letter queue, otherwise passed to OnError
+	OnProcess      func(msg Message) error
 	OnError        func(err error)
-	OnNotification func(notification *cluster.Notification)
+	OnNotification func(notification *Notification)
+	// OnBatchReceived replaces OnDataReceived and OnProcess with batches of decoded messages of a partition, see
+	// SetBatchConfig. The offsets are marked once per batch, the messages of a failed batch are retried or
+	// dead-lettered one by one like a failed OnProcess.
+	OnBatchReceived func(msgs []Message) error
+	// OnPartitionsAssigned is called with the partitions by topic claimed in a new generation, before their
+	// messages are consumed
+	OnPartitionsAssigned func(partitions map[string][]int32)
+	// OnPartitionsRevoked is called with the partitions by topic released on a rebalance, once their messages are
+	// handled and before their last offsets are committed
+	OnPartitionsRevoked func(partitions map[string][]int32)
 }
 
 type Message struct {
@@ -28,104 +81,429 @@ type Message struct {
 	Offset    int64
 	Key       string
 	Value     string
+	// Timestamp is the timestamp of the message, the time it was produced or appended to the log depending on the
+	// timestamp type of the topic, which sarama does not report. It is zero for brokers older than 0.10.
+	Timestamp time.Time
+	// Headers are the record headers of the message, including the wire-format headers
+	Headers []*sarama.RecordHeader
+	// Tombstone is true for a message with a nil value, deleting its key from a compacted topic. Value is empty.
+	Tombstone bool
+	// Payload is the binary avro data of the value in DecodeRaw mode, it shares the memory of the consumed message
+	Payload []byte
+	// MessageIndexes is the path of the message type in the schema of a PROTOBUF topic
+	MessageIndexes []int
+	// native and codec are the decoded avro value and its writer schema, used by ConsumeInto
+	native interface{}
+	codec  *goavro.Codec
+	// ack is the delivery of the message, see Ack
+	ack *messageAck
 }
 
-// avroConsumer is a basic consumer to interact with schema registry, avro and kafka
+// NewAvroConsumer is a basic consumer to interact with schema registry, avro and kafka
 func NewAvroConsumer(kafkaServers []string, schemaRegistryServers []string,
-	topic string, groupId string, callbacks ConsumerCallbacks) (*avroConsumer, error) {
-	// init (custom) config, enable errors and notifications
-	config := cluster.NewConfig()
+	topic string, groupId string, callbacks ConsumerCallbacks, opts ...Option) (*AvroConsumer, error) {
+	return newAvroConsumer(kafkaServers, schemaRegistryServers, []string{topic}, groupId, callbacks, opts)
+}
+
+// NewAvroConsumerMulti is like NewAvroConsumer, subscribing the group to several topics.
+// The topic of every message is available in Message.Topic.
+func NewAvroConsumerMulti(kafkaServers []string, schemaRegistryServers []string,
+	topics []string, groupId string, callbacks ConsumerCallbacks, opts ...Option) (*AvroConsumer, error) {
+	if len(topics) == 0 {
+		return nil, fmt.Errorf("at least one topic is required")
+	}
+	return newAvroConsumer(kafkaServers, schemaRegistryServers, topics, groupId, callbacks, opts)
+}
+
+// NewAvroConsumerWithConfig is like NewAvroConsumer, subscribing to the physical topic resolved by the configuration
+// and applying the fetch sizes configured for the topic
+//
+// Deprecated: use NewAvroConsumer with WithConfig
+func NewAvroConsumerWithConfig(kafkaServers []string, schemaRegistryServers []string,
+	topic string, groupId string, callbacks ConsumerCallbacks, config Config) (*AvroConsumer, error) {
+	return NewAvroConsumer(kafkaServers, schemaRegistryServers, topic, groupId, callbacks, WithConfig(config))
+}
+
+func defaultAvroConsumerConfig() *sarama.Config {
+	// init (custom) config, enable errors, consumer groups need at least kafka 0.10.2
+	config := sarama.NewConfig()
+	config.Version = sarama.V2_0_1_0
 	config.Consumer.Return.Errors = true
-	config.Group.Return.Notifications = true
 	//read from beginning at the first time
 	config.Consumer.Offsets.Initial = sarama.OffsetOldest
-	topics := []string{topic}
-	consumer, err := cluster.NewConsumer(kafkaServers, groupId, topics, config)
+	return config
+}
+
+func newAvroConsumer(kafkaServers []string, schemaRegistryServers []string,
+	topics []string, groupId string, callbacks ConsumerCallbacks, opts []Option) (*AvroConsumer, error) {
+	o := applyOptions(defaultAvroConsumerConfig(), opts)
+	config := o.saramaConfig
+	var logicalTopics map[string]string
+	if o.config != nil {
+		// a single topic gets its own fetch sizes, several topics share the defaults
+		fetch := o.config.Defaults.Fetch
+		if len(topics) == 1 {
+			fetch = o.config.ForTopic(topics[0]).Fetch
+		}
+		fetch.apply(config)
+		logicalTopics = make(map[string]string, len(topics))
+		physicalTopics := make([]string, len(topics))
+		for i, topic := range topics {
+			physicalTopics[i] = o.config.PhysicalTopic(topic)
+			logicalTopics[physicalTopics[i]] = topic
+		}
+		topics = physicalTopics
+	}
+	leader := &leaderStrategy{BalanceStrategy: config.Consumer.Group.Rebalance.Strategy}
+	config.Consumer.Group.Rebalance.Strategy = leader
+	consumer, err := sarama.NewConsumerGroup(kafkaServers, groupId, config)
 	if err != nil {
 		return nil, err
 	}
 
-	schemaRegistryClient := NewCachedSchemaRegistryClient(schemaRegistryServers)
-	return &avroConsumer{
-		consumer,
-		schemaRegistryClient,
-		callbacks,
+	schemaRegistryClient := o.schemaRegistryClient(schemaRegistryServers)
+	return &AvroConsumer{
+		Consumer:             consumer,
+		SchemaRegistryClient: schemaRegistryClient,
+		callbacks:            callbacks,
+		kafkaServers:         kafkaServers,
+		groupId:              groupId,
+		topics:               topics,
+		saramaConfig:         config,
+		leader:               leader,
+		config:               o.config,
+		bootstrap:            o.bootstrap,
+		logicalTopics:        logicalTopics,
+		metrics:              o.metrics,
+		logger:               o.logger,
+		rateLimit:            o.rateLimit,
+		claimCheck:           o.claimCheck,
 	}, nil
 }
 
 //GetSchemaId get schema id from schema-registry service
-func (ac *avroConsumer) GetSchema(id int) (*goavro.Codec, error) {
-	codec, err := ac.SchemaRegistryClient.GetSchema(id)
+func (ac *AvroConsumer) GetSchema(id int) (*goavro.Codec, error) {
+	return ac.GetSchemaContext(context.Background(), id)
+}
+
+// GetSchemaContext is GetSchema using the context for the registry requests
+func (ac *AvroConsumer) GetSchemaContext(ctx context.Context, id int) (*goavro.Codec, error) {
+	codec, err := ac.SchemaRegistryClient.GetSchemaContext(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 	return codec, nil
 }
 
-func (ac *avroConsumer) Consume() {
-	// trap SIGINT to trigger a shutdown.
-	signals := make(chan os.Signal, 1)
-	signal.Notify(signals, os.Interrupt)
+// SetConfig sets the layered per-topic and per-subject configuration of the consumer
+func (ac *AvroConsumer) SetConfig(config Config) {
+	ac.config = &config
+}
+
+// SetSubjectNameStrategy sets the default subject name strategy of the consumer, topics may still override it
+func (ac *AvroConsumer) SetSubjectNameStrategy(strategy SubjectNameStrategy) {
+	config := Config{}
+	if ac.config != nil {
+		config = *ac.config
+	}
+	config.Defaults.SubjectNameStrategy = strategy
+	ac.config = &config
+}
+
+// ValueSubject returns the subject of the message value according to the subject name strategy of its topic
+func (ac *AvroConsumer) ValueSubject(msg Message) (string, error) {
+	codec, err := ac.GetSchema(msg.SchemaId)
+	if err != nil {
+		return "", err
+	}
+	return ac.config.ForTopic(msg.Topic).SubjectNameStrategy(msg.Topic, false, codec.Schema())
+}
+
+// SetRedactionProfile sets the redaction applied to every decoded message value of this consumer
+func (ac *AvroConsumer) SetRedactionProfile(profile RedactionProfile) {
+	ac.redaction = profile
+}
+
+// EnableDecodeCache keeps the last size decoded messages, so offsets re-delivered after a rebalance are not decoded again
+func (ac *AvroConsumer) EnableDecodeCache(size int) {
+	ac.decodeCache = newDecodeCache(size)
+}
+
+// GetSchemaMetadata returns the full schema object (version, type, references) for the given id
+func (ac *AvroConsumer) GetSchemaMetadata(id int) (*SchemaMetadata, error) {
+	return ac.SchemaRegistryClient.GetSchemaMetadataByID(id)
+}
+
+// Consume joins the group and handles messages until the context is cancelled or the consumer is closed
+func (ac *AvroConsumer) Consume(ctx context.Context) {
+	ac.run(ctx, &consumerGroupHandler{consumer: ac})
+}
+
+// run joins the group with the handler until the context is cancelled or the consumer is closed
+func (ac *AvroConsumer) run(ctx context.Context, handler sarama.ConsumerGroupHandler) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := make(chan struct{})
+	defer close(done)
+	ac.runLock.Lock()
+	ac.cancel, ac.done = cancel, done
+	ac.runLock.Unlock()
 
 	// consume errors
 	go func() {
 		for err := range ac.Consumer.Errors() {
+			orNop(ac.logger).Error("consumer group error", "group", ac.groupId, "error", err)
 			if ac.callbacks.OnError != nil {
 				ac.callbacks.OnError(err)
 			}
 		}
 	}()
 
-	// consume notifications
-	go func() {
-		for notification := range ac.Consumer.Notifications() {
-			if ac.callbacks.OnNotification != nil {
-				ac.callbacks.OnNotification(notification)
+	// join the group again after every rebalance until the consumer is stopped,
+	// leaving a session commits the offsets of the handled messages. Seek leaves the session with rejoin.
+	for {
+		sessionCtx, rejoin := context.WithCancel(ctx)
+		ac.runLock.Lock()
+		ac.rejoin = rejoin
+		ac.runLock.Unlock()
+		err := ac.Consumer.Consume(sessionCtx, ac.topics, handler)
+		rejoin()
+		if err == sarama.ErrClosedConsumerGroup || ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			orNop(ac.logger).Error("could not join the consumer group", "group", ac.groupId, "error", err)
+			ac.notify(&Notification{Type: RebalanceError, GroupMembership: ac.Membership()})
+			if ac.callbacks.OnError != nil {
+				ac.callbacks.OnError(err)
+			}
+			// wait before joining again, so unreachable brokers do not turn into a busy loop
+			select {
+			case <-time.After(ac.rejoinBackoff()):
+			case <-ctx.Done():
+				return
 			}
 		}
-	}()
+	}
+}
 
-	for {
-		select {
-		case m, ok := <-ac.Consumer.Messages():
-			if ok {
-				msg, err := ac.ProcessAvroMsg(m)
-				if err != nil {
-					ac.callbacks.OnError(err)
-				}
-				ac.Consumer.MarkOffset(m, "")
-				if ac.callbacks.OnDataReceived != nil {
-					ac.callbacks.OnDataReceived(msg)
-				}
-			}
-		case <-signals:
+// rejoinBackoff returns the time to wait before joining the group again after an error, the consumer retry backoff
+func (ac *AvroConsumer) rejoinBackoff() time.Duration {
+	if ac.saramaConfig == nil || ac.saramaConfig.Consumer.Retry.Backoff <= 0 {
+		return sarama.NewConfig().Consumer.Retry.Backoff
+	}
+	return ac.saramaConfig.Consumer.Retry.Backoff
+}
+
+// logicalTopic returns the configured name of a consumed topic
+func (ac *AvroConsumer) logicalTopic(topic string) string {
+	if logical, ok := ac.logicalTopics[topic]; ok {
+		return logical
+	}
+	return topic
+}
+
+func (ac *AvroConsumer) handle(session sarama.ConsumerGroupSession, m *sarama.ConsumerMessage) {
+	msg, handled, err := ac.receive(session, m)
+	if handled {
+		return
+	}
+	// the message of the last chunk of a chunked value has the reassembled value
+	m = msg.ack.msg
+	if ac.commitStrategy == CommitBeforeCallback {
+		session.MarkMessage(m, "")
+	}
+	if ac.callbacks.OnDataReceived != nil {
+		ac.callbacks.OnDataReceived(msg)
+	}
+	handledErr := err
+	if ac.callbacks.OnProcess != nil && err == nil {
+		handledErr = ac.callbacks.OnProcess(msg)
+	}
+	ac.interceptHandled(msg, handledErr)
+	if err == nil && handledErr != nil {
+		if err := ac.processFailed(m, handledErr); err != nil {
+			ac.haltWith(err)
 			return
 		}
 	}
+	if ac.commitStrategy == CommitAfterCallback {
+		session.MarkMessage(m, "")
+	}
+}
+
+// receive decodes the message and reports a decode error. It returns true when the consumer is halted, when the
+// message is a chunk of a value that is not complete yet, or when the message was already handled by the unframed
+// fallback or the dead-letter queue.
+func (ac *AvroConsumer) receive(session sarama.ConsumerGroupSession, m *sarama.ConsumerMessage) (Message, bool, error) {
+	if ac.isHalted() {
+		return Message{}, true, nil
+	}
+	// the session ended while waiting, the message is consumed again by the next session
+	if err := ac.rateLimit.wait(session.Context(), len(m.Key)+len(m.Value)); err != nil {
+		return Message{}, true, nil
+	}
+	assembled, chunks, err := ac.chunks.add(m)
+	ac.markEvicted(session, ac.evictChunks(m.Topic))
+	if err == nil && assembled == nil {
+		// the chunk is committed with the message of the last chunk
+		return Message{}, true, nil
+	}
+	if assembled != nil {
+		m = assembled
+	}
+	if tracked, ok := session.(*trackedSession); ok {
+		tracked.tracker.join(m.Offset, chunks)
+	}
+	ac.interceptReceive(m)
+	var msg Message
+	if err == nil {
+		msg, err = ac.ProcessAvroMsgContext(session.Context(), m)
+	}
+	msg = ac.interceptDecoded(msg, err)
+	if ac.metrics != nil {
+		ac.metrics.MessageConsumed(m.Topic, err)
+	}
+	if IsUnframedError(err) && ac.handleUnframed(session, m, err) {
+		return msg, true, err
+	}
+	if err != nil {
+		orNop(ac.logger).Error("could not decode message", "topic", m.Topic, "partition", m.Partition,
+			"offset", m.Offset, "error", err)
+	}
+	if err != nil && ac.deadLetter != nil {
+		if err := ac.deadLetterMsg(m, err); err != nil {
+			ac.haltWith(err)
+			return msg, true, err
+		}
+		ac.markHandled(session, m)
+		return msg, true, err
+	}
+	if err != nil && ac.callbacks.OnError != nil {
+		ac.callbacks.OnError(err)
+	}
+	msg.ack = &messageAck{consumer: ac, session: session, msg: m}
+	return msg, false, err
 }
 
-func (ac *avroConsumer) ProcessAvroMsg(m *sarama.ConsumerMessage) (Message, error) {
-	schemaId := binary.BigEndian.Uint32(m.Value[1:5])
-	codec, err := ac.GetSchema(int(schemaId))
+func (ac *AvroConsumer) ProcessAvroMsg(m *sarama.ConsumerMessage) (Message, error) {
+	return ac.ProcessAvroMsgContext(context.Background(), m)
+}
+
+// ProcessAvroMsgContext decodes the message, the schema lookup is cancelled with the context
+func (ac *AvroConsumer) ProcessAvroMsgContext(ctx context.Context, m *sarama.ConsumerMessage) (Message, error) {
+	if ac.decodeCache == nil {
+		return ac.decodeAvroMsg(ctx, m)
+	}
+	key := decodeCacheKey{m.Topic, m.Partition, m.Offset}
+	if msg, found := ac.decodeCache.get(key); found {
+		return msg, nil
+	}
+	msg, err := ac.decodeAvroMsg(ctx, m)
+	if err != nil {
+		return msg, err
+	}
+	ac.decodeCache.add(key, msg)
+	return msg, nil
+}
+
+func (ac *AvroConsumer) decodeAvroMsg(ctx context.Context, m *sarama.ConsumerMessage) (Message, error) {
+	topicHistogram(ac.MetricRegistry(), "avro-message-size", m.Topic).Update(int64(len(m.Value)))
+	if ac.metrics != nil {
+		start := time.Now()
+		defer func() {
+			ac.metrics.Decoded(m.Topic, time.Since(start))
+		}()
+	}
+	if m.Value == nil {
+		return Message{Topic: m.Topic, Partition: m.Partition, Offset: m.Offset, Key: string(m.Key),
+			Timestamp: m.Timestamp, Headers: m.Headers, Tombstone: true}, nil
+	}
+	m, err := ac.claimCheck.fetch(ctx, m)
 	if err != nil {
 		return Message{}, err
 	}
+	topicConfig := ac.config.ForTopic(ac.logicalTopic(m.Topic))
+	schemaId, payload, err := topicConfig.wireFormat().Decode(m)
+	if err != nil {
+		return Message{}, err
+	}
+	if topicConfig.SchemaType != SchemaTypeAvro {
+		return ac.decodeSchemaType(m, schemaId, payload, topicConfig.SchemaType)
+	}
+	if ac.decodeMode != DecodeTextual && len(ac.redaction) > 0 {
+		return Message{}, fmt.Errorf("cannot skip the textual conversion of message %s/%d@%d, "+
+			"the consumer has a redaction profile", m.Topic, m.Partition, m.Offset)
+	}
+	codec, err := ac.GetSchemaContext(ctx, schemaId)
+	if err != nil {
+		return Message{}, err
+	}
+	if ac.strict != nil {
+		if err := ac.strict.validateSchema(m.Topic, schemaId, codec); err != nil {
+			return Message{}, err
+		}
+	}
+	msg := Message{SchemaId: schemaId, Topic: m.Topic, Partition: m.Partition, Offset: m.Offset,
+		Key: string(m.Key), Timestamp: m.Timestamp, Headers: m.Headers}
+	if ac.decodeMode == DecodeRaw {
+		if ac.strict != nil || topicConfig.ReaderSchema != "" {
+			return Message{}, fmt.Errorf("cannot validate or resolve message %s/%d@%d without decoding it",
+				m.Topic, m.Partition, m.Offset)
+		}
+		msg.Payload, msg.codec = payload, codec
+		return msg, nil
+	}
 	// Convert binary Avro data back to native Go form
-	native, _, err := codec.NativeFromBinary(m.Value[5:])
+	native, _, err := codec.NativeFromBinary(payload)
 	if err != nil {
 		return Message{}, err
 	}
+	if ac.strict != nil {
+		if err := ac.strict.validateNative(m.Topic, m.Partition, m.Offset, native); err != nil {
+			return Message{}, err
+		}
+	}
+
+	if reader := topicConfig.ReaderSchema; reader != "" {
+		if native, codec, err = ac.readers.project(codec, reader, native); err != nil {
+			return Message{}, fmt.Errorf("could not resolve message %s/%d@%d to the reader schema: %v",
+				m.Topic, m.Partition, m.Offset, err)
+		}
+	}
+	msg.native, msg.codec = native, codec
+	if ac.decodeMode == DecodeNative {
+		return msg, nil
+	}
 
 	// Convert native Go form to textual Avro data
-	textual, err := codec.TextualFromNative(nil, native)
+	var textual []byte
+	if ac.jsonFormat == PlainJSON {
+		textual, err = ac.converters.plainJSON(codec, native)
+	} else {
+		textual, err = codec.TextualFromNative(nil, native)
+	}
 
 	if err != nil {
 		return Message{}, err
 	}
-	msg := Message{int(schemaId), m.Topic, m.Partition, m.Offset, string(m.Key), string(textual)}
+	textual, err = ac.redaction.Apply(textual)
+	if err != nil {
+		return Message{}, err
+	}
+	msg.Value = string(textual)
 	return msg, nil
 }
 
-func (ac *avroConsumer) Close() {
+// Close stops a running Consume, waiting for in-flight messages to be handled and their offsets to be committed,
+// then closes the consumer group
+func (ac *AvroConsumer) Close() {
+	ac.runLock.Lock()
+	cancel, done := ac.cancel, ac.done
+	ac.runLock.Unlock()
+	if cancel != nil {
+		cancel()
+		<-done
+	}
 	ac.Consumer.Close()
 }
@@ -1,10 +1,14 @@
 package kafka
 
 import (
+	"context"
 	"encoding/binary"
+	"errors"
 	"github.com/Shopify/sarama"
 	"github.com/linkedin/goavro/v2"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 var testData = `{"val":1}`
@@ -28,13 +32,15 @@ func TestAvroConsumer_ProcessAvroMsg(t *testing.T) {
 	schemaRegistryTestObject := createSchemaRegistryTestObject(t, "test", 1)
 	schemaRegistryMock := NewCachedSchemaRegistryClient([]string{schemaRegistryTestObject.MockServer.URL})
 	callbacks := &ConsumerCallbacks{}
-	avroConsumer := &avroConsumer{nil, schemaRegistryMock, *callbacks}
+	avroConsumer := &AvroConsumer{SchemaRegistryClient: schemaRegistryMock, callbacks: *callbacks}
 	consumerMsg := &sarama.ConsumerMessage{
 		Value:     getTestAvroMsg(t, schemaRegistryTestObject.Codec),
 		Key:       []byte("key"),
 		Topic:     "test",
 		Partition: 0,
 		Offset:    1,
+		Timestamp: time.Unix(1600000000, 0),
+		Headers:   []*sarama.RecordHeader{{Key: []byte("trace"), Value: []byte("1")}},
 	}
 	msg, err := avroConsumer.ProcessAvroMsg(consumerMsg)
 	if err != nil {
@@ -43,4 +49,122 @@ func TestAvroConsumer_ProcessAvroMsg(t *testing.T) {
 	if msg.Value != testData {
 		t.Errorf("Wrong data")
 	}
+	if !msg.Timestamp.Equal(consumerMsg.Timestamp) {
+		t.Errorf("Expected the timestamp of the message, got %v", msg.Timestamp)
+	}
+	if len(msg.Headers) != 1 || string(msg.Headers[0].Key) != "trace" {
+		t.Errorf("Expected the headers of the message, got %v", msg.Headers)
+	}
+}
+
+func TestAvroConsumer_ProcessAvroMsgRedacted(t *testing.T) {
+	schemaRegistryTestObject := createSchemaRegistryTestObject(t, "test", 1)
+	schemaRegistryMock := NewCachedSchemaRegistryClient([]string{schemaRegistryTestObject.MockServer.URL})
+	avroConsumer := &AvroConsumer{SchemaRegistryClient: schemaRegistryMock}
+	avroConsumer.SetRedactionProfile(RedactionProfile{"val": RedactMask})
+	consumerMsg := &sarama.ConsumerMessage{
+		Value: getTestAvroMsg(t, schemaRegistryTestObject.Codec),
+		Topic: "test",
+	}
+	msg, err := avroConsumer.ProcessAvroMsg(consumerMsg)
+	if err != nil {
+		t.Errorf("Error process avro msg: %v", err)
+	}
+	if msg.Value != `{"val":"****"}` {
+		t.Errorf("Expected masked value, got %s", msg.Value)
+	}
+}
+
+func TestAvroConsumer_ProcessAvroMsgCached(t *testing.T) {
+	schemaRegistryTestObject := createSchemaRegistryTestObject(t, "test", 1)
+	schemaRegistryMock := NewCachedSchemaRegistryClient([]string{schemaRegistryTestObject.MockServer.URL})
+	avroConsumer := &AvroConsumer{SchemaRegistryClient: schemaRegistryMock}
+	avroConsumer.EnableDecodeCache(10)
+	consumerMsg := &sarama.ConsumerMessage{
+		Value:  getTestAvroMsg(t, schemaRegistryTestObject.Codec),
+		Topic:  "test",
+		Offset: 1,
+	}
+	if _, err := avroConsumer.ProcessAvroMsg(consumerMsg); err != nil {
+		t.Errorf("Error process avro msg: %v", err)
+	}
+	// a re-delivered offset is served from the cache even though the payload can't be decoded anymore
+	consumerMsg.Value = []byte{0}
+	msg, err := avroConsumer.ProcessAvroMsg(consumerMsg)
+	if err != nil {
+		t.Errorf("Expected cached msg, got error: %v", err)
+	}
+	if msg.Value != testData {
+		t.Errorf("Wrong data")
+	}
+}
+
+type testConsumerGroup struct {
+	started chan struct{}
+	left    bool
+	closed  bool
+}
+
+func (g *testConsumerGroup) Consume(ctx context.Context, topics []string, handler sarama.ConsumerGroupHandler) error {
+	close(g.started)
+	<-ctx.Done()
+	g.left = true
+	return nil
+}
+func (g *testConsumerGroup) Errors() <-chan error { return make(chan error) }
+func (g *testConsumerGroup) Close() error         { g.closed = true; return nil }
+
+func TestAvroConsumer_ProcessAvroMsgTombstone(t *testing.T) {
+	avroConsumer := &AvroConsumer{SchemaRegistryClient: newMemorySchemaRegistry()}
+	msg, err := avroConsumer.ProcessAvroMsg(&sarama.ConsumerMessage{Topic: "test", Offset: 3, Key: []byte("key")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !msg.Tombstone || msg.Key != "key" || msg.Offset != 3 || msg.Value != "" {
+		t.Errorf("Expected a tombstone, got %+v", msg)
+	}
+}
+
+func TestAvroConsumer_Close(t *testing.T) {
+	group := &testConsumerGroup{started: make(chan struct{})}
+	consumer := &AvroConsumer{Consumer: group}
+	go consumer.Consume(context.Background())
+	<-group.started
+	consumer.Close()
+	if !group.left {
+		t.Errorf("Expected Close to wait for the session to be released")
+	}
+	if !group.closed {
+		t.Errorf("Expected consumer group to be closed")
+	}
+}
+
+func TestNewAvroConsumerMulti_NoTopics(t *testing.T) {
+	if _, err := NewAvroConsumerMulti([]string{"localhost:9092"}, []string{"http://localhost:8081"}, nil, "group", ConsumerCallbacks{}); err == nil {
+		t.Errorf("Expected error without topics")
+	}
+}
+
+type failingConsumerGroup struct {
+	joins int32
+}
+
+func (g *failingConsumerGroup) Consume(ctx context.Context, topics []string, handler sarama.ConsumerGroupHandler) error {
+	atomic.AddInt32(&g.joins, 1)
+	return errors.New("brokers unreachable")
+}
+func (g *failingConsumerGroup) Errors() <-chan error { return make(chan error) }
+func (g *failingConsumerGroup) Close() error         { return nil }
+
+func TestAvroConsumer_ConsumeBackoff(t *testing.T) {
+	group := &failingConsumerGroup{}
+	config := sarama.NewConfig()
+	config.Consumer.Retry.Backoff = 50 * time.Millisecond
+	consumer := &AvroConsumer{Consumer: group, saramaConfig: config}
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Millisecond)
+	defer cancel()
+	consumer.Consume(ctx)
+	if joins := atomic.LoadInt32(&group.joins); joins < 2 || joins > 4 {
+		t.Errorf("Expected the consumer to wait between joins, got %d joins", joins)
+	}
 }
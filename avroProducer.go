@@ -1,19 +1,70 @@
 package kafka
 
 import (
+	"context"
 	"encoding/binary"
+	"fmt"
 	"github.com/Shopify/sarama"
 	"github.com/linkedin/goavro/v2"
+	"github.com/rcrowley/go-metrics"
+	"sync"
 	"time"
 )
 
 type AvroProducer struct {
 	producer             sarama.SyncProducer
-	schemaRegistryClient *CachedSchemaRegistryClient
+	client               sarama.Client
+	schemaRegistryClient SchemaRegistry
+	partitionWatcher     *partitionWatcher
+	callbacks            ProducerCallbacks
+	schemaIds            map[string]schemaVersion
+	schemaIdsLock        sync.Mutex
+	topicSchemas         *registryCache
+	topicSchemasOnce     sync.Once
+	schemas              schemaSelection
+	config               *Config
+	metricRegistry       metrics.Registry
+	metricsOnce          sync.Once
+	metrics              Metrics
+	logger               Logger
+	rateLimit            *rateLimiter
+	claimCheck           *ClaimCheck
 }
 
 // NewAvroProducer is a basic producer to interact with schema registry, avro and kafka
-func NewAvroProducer(kafkaServers []string, schemaRegistryServers []string) (*AvroProducer, error) {
+func NewAvroProducer(kafkaServers []string, schemaRegistryServers []string, opts ...Option) (*AvroProducer, error) {
+	o := applyOptions(defaultAvroProducerConfig(), opts)
+	config := o.saramaConfig
+	// required by the sync producer
+	config.Producer.Return.Successes = true
+	if err := validateChunking(o.config, config.Producer.Partitioner); err != nil {
+		return nil, err
+	}
+	client, err := sarama.NewClient(kafkaServers, config)
+	if err != nil {
+		return nil, err
+	}
+	producer, err := sarama.NewSyncProducerFromClient(client)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	schemaRegistryClient := o.schemaRegistryClient(schemaRegistryServers)
+	return &AvroProducer{
+		producer:             producer,
+		client:               client,
+		schemaRegistryClient: schemaRegistryClient,
+		config:               o.config,
+		metricRegistry:       config.MetricRegistry,
+		metrics:              o.metrics,
+		logger:               o.logger,
+		rateLimit:            o.rateLimit,
+		schemas:              o.schemas,
+		claimCheck:           o.claimCheck,
+	}, nil
+}
+
+func defaultAvroProducerConfig() *sarama.Config {
 	config := sarama.NewConfig()
 	config.Version = sarama.V2_0_1_0
 	config.Producer.Partitioner = sarama.NewHashPartitioner
@@ -23,24 +74,237 @@ func NewAvroProducer(kafkaServers []string, schemaRegistryServers []string) (*Av
 	config.Producer.MaxMessageBytes = 10000000
 	config.Producer.Retry.Max = 10
 	config.Producer.Retry.Backoff = 1000 * time.Millisecond
-	producer, err := sarama.NewSyncProducer(kafkaServers, config)
-	if err != nil {
-		return nil, err
+	return config
+}
+
+// NewIdempotentAvroProducer is like NewAvroProducer with sarama's idempotent producer enabled,
+// so retries do not introduce duplicates. It requires kafka 0.11 or later.
+//
+// Deprecated: use NewAvroProducer with WithIdempotence
+func NewIdempotentAvroProducer(kafkaServers []string, schemaRegistryServers []string) (*AvroProducer, error) {
+	return NewAvroProducer(kafkaServers, schemaRegistryServers, WithIdempotence())
+}
+
+// enableIdempotence applies the settings sarama requires for the idempotent producer
+func enableIdempotence(config *sarama.Config) {
+	config.Producer.Idempotent = true
+	config.Producer.RequiredAcks = sarama.WaitForAll
+	config.Net.MaxOpenRequests = 1
+	if config.Producer.Retry.Max < 1 {
+		config.Producer.Retry.Max = 1
+	}
+}
+
+// SetConfig sets the layered per-topic and per-subject configuration of the producer
+func (ap *AvroProducer) SetConfig(config Config) {
+	ap.config = &config
+}
+
+// SetSubjectNameStrategy sets the default subject name strategy of the producer, topics may still override it
+func (ap *AvroProducer) SetSubjectNameStrategy(strategy SubjectNameStrategy) {
+	config := Config{}
+	if ap.config != nil {
+		config = *ap.config
+	}
+	config.Defaults.SubjectNameStrategy = strategy
+	ap.config = &config
+}
+
+// WatchPartitions refreshes the metadata of every produced topic at the given interval and reports partition count changes,
+// giving the application a chance to pause or log since hash partitioned keys silently move to other partitions
+func (ap *AvroProducer) WatchPartitions(interval time.Duration, callbacks PartitionWatchCallbacks) {
+	if ap.client == nil || ap.partitionWatcher != nil {
+		return
 	}
-	schemaRegistryClient := NewCachedSchemaRegistryClient(schemaRegistryServers)
-	return &AvroProducer{producer, schemaRegistryClient}, nil
+	ap.partitionWatcher = newPartitionWatcher(ap.client, callbacks)
+	go ap.partitionWatcher.run(interval)
 }
 
 //GetSchemaId get schema id from schema-registry service
 func (ap *AvroProducer) GetSchemaId(topic string, avroCodec *goavro.Codec) (int, error) {
-	schemaId, err := ap.schemaRegistryClient.CreateSubject(topic+"-value", avroCodec)
+	subject, err := ap.config.valueSubject(topic, avroCodec.Schema())
 	if err != nil {
 		return 0, err
 	}
+	schemaId, err := ap.GetSchemaIdForSubject(subject, avroCodec)
+	if err != nil {
+		return 0, err
+	}
+	ap.trackSchemaId(topic, subject, avroCodec.Schema(), nil, schemaId)
 	return schemaId, nil
 }
 
+// GetValueSchemaId get the schema id of the topic's values, same as GetSchemaId
+func (ap *AvroProducer) GetValueSchemaId(topic string, avroCodec *goavro.Codec) (int, error) {
+	return ap.GetSchemaId(topic, avroCodec)
+}
+
+// GetKeySchemaId get the schema id of the topic's keys, registering it to the key subject (by default "<topic>-key")
+func (ap *AvroProducer) GetKeySchemaId(topic string, avroCodec *goavro.Codec) (int, error) {
+	subject, err := ap.config.keySubject(topic, avroCodec.Schema())
+	if err != nil {
+		return 0, err
+	}
+	return ap.GetSchemaIdForSubject(subject, avroCodec)
+}
+
+// GetSchemaIdForSubject get the schema id for an explicit subject through the same cached path as GetSchemaId
+func (ap *AvroProducer) GetSchemaIdForSubject(subject string, avroCodec *goavro.Codec) (int, error) {
+	return ap.schemas.schemaId(ap.schemaRegistryClient, subject, avroCodec)
+}
+
+// GetSchemaIdWithImports registers the imported schemas in dependency order, then the topic schema referencing them.
+// It returns the schema id together with a codec compiled from the schema with all imports resolved.
+func (ap *AvroProducer) GetSchemaIdWithImports(topic string, schema string, imports []SchemaImport) (int, *goavro.Codec, error) {
+	ordered, byName, schemas, err := sortImports(imports)
+	if err != nil {
+		return 0, nil, err
+	}
+	registered := make(map[string]SchemaReference, len(ordered))
+	referencesOf := func(schema string) ([]SchemaReference, error) {
+		names, err := referencedNames(schema, schemas)
+		if err != nil {
+			return nil, err
+		}
+		var references []SchemaReference
+		for _, name := range names {
+			if reference, ok := registered[name]; ok {
+				references = append(references, reference)
+			}
+		}
+		return references, nil
+	}
+	for _, name := range ordered {
+		imp := byName[name]
+		references, err := referencesOf(imp.Schema)
+		if err != nil {
+			return 0, nil, err
+		}
+		if _, err := ap.schemaRegistryClient.CreateSubjectWithReferences(imp.Subject, imp.Schema, references); err != nil {
+			return 0, nil, err
+		}
+		metadata, err := ap.schemaRegistryClient.LookupSchema(imp.Subject, imp.Schema, references)
+		if err != nil {
+			return 0, nil, err
+		}
+		registered[name] = SchemaReference{Name: name, Subject: imp.Subject, Version: metadata.Version}
+	}
+	references, err := referencesOf(schema)
+	if err != nil {
+		return 0, nil, err
+	}
+	subject, err := ap.config.valueSubject(topic, schema)
+	if err != nil {
+		return 0, nil, err
+	}
+	schemaId, err := ap.schemaRegistryClient.CreateSubjectWithReferences(subject, schema, references)
+	if err != nil {
+		return 0, nil, err
+	}
+	ap.trackSchemaId(topic, subject, schema, references, schemaId)
+	resolved, err := inlineReferences(schema, schemas)
+	if err != nil {
+		return 0, nil, err
+	}
+	avroCodec, err := goavro.NewCodec(resolved)
+	if err != nil {
+		return 0, nil, err
+	}
+	return schemaId, avroCodec, nil
+}
+
+// Add sends the value in the schema type of the topic: textual Avro data for AVRO topics, JSON for JSON topics,
+// and a serialized message of the first message type for PROTOBUF topics
 func (ap *AvroProducer) Add(topic string, schema string, key []byte, value []byte) error {
+	if schemaType := ap.config.ForTopic(topic).SchemaType; schemaType != SchemaTypeAvro {
+		return ap.addSchemaType(topic, schemaType, schema, nil, key, value)
+	}
+	resolved, err := ap.topicSchema(topic, schema)
+	if err != nil {
+		return err
+	}
+	if err := ap.send(topic, resolved.codec, resolved.id, key, value); err != nil {
+		ap.invalidateTopicSchema(topic, schema)
+		return err
+	}
+	return nil
+}
+
+// AddWithImports is like Add for a schema that references the imported schemas
+func (ap *AvroProducer) AddWithImports(topic string, schema string, imports []SchemaImport, key []byte, value []byte) error {
+	schemaId, avroCodec, err := ap.GetSchemaIdWithImports(topic, schema, imports)
+	if err != nil {
+		return err
+	}
+	return ap.send(topic, avroCodec, schemaId, key, value)
+}
+
+// AddNative is like Add for a value already in native goavro form, it is encoded without a JSON round-trip
+func (ap *AvroProducer) AddNative(topic string, schema string, key []byte, native interface{}) error {
+	resolved, err := ap.topicSchema(topic, schema)
+	if err != nil {
+		return err
+	}
+	if err := ap.sendNative(topic, resolved.codec, resolved.id, key, native); err != nil {
+		ap.invalidateTopicSchema(topic, schema)
+		return err
+	}
+	return nil
+}
+
+// AddStruct is like AddNative for a Go struct (or map) matching the schema, see StructConverter
+func (ap *AvroProducer) AddStruct(topic string, schema string, key []byte, value interface{}) error {
+	converter, err := NewStructConverter(schema)
+	if err != nil {
+		return err
+	}
+	native, err := converter.Native(value)
+	if err != nil {
+		return err
+	}
+	schemaId, err := ap.GetSchemaId(topic, converter.Codec)
+	if err != nil {
+		return err
+	}
+	return ap.sendNative(topic, converter.Codec, schemaId, key, native)
+}
+
+func (ap *AvroProducer) sendNative(topic string, avroCodec *goavro.Codec, schemaId int, key []byte, native interface{}) error {
+	start := time.Now()
+	binaryValue, err := binaryFromNative(avroCodec, native)
+	if err != nil {
+		return err
+	}
+	ap.encoded(topic, start)
+	_, _, err = ap.sendBinary(topic, schemaId, sarama.StringEncoder(key), binaryValue)
+	return err
+}
+
+func (ap *AvroProducer) send(topic string, avroCodec *goavro.Codec, schemaId int, key []byte, value []byte) error {
+	start := time.Now()
+	binaryValue, err := encodeTextual(avroCodec, value)
+	if err != nil {
+		return err
+	}
+	ap.encoded(topic, start)
+	_, _, err = ap.sendBinary(topic, schemaId, sarama.StringEncoder(key), binaryValue)
+	return err
+}
+
+// AddWithAvroKey is like Add, encoding the key with keySchema registered to the key subject of the topic
+func (ap *AvroProducer) AddWithAvroKey(topic string, keySchema string, schema string, key []byte, value []byte) error {
+	keyCodec, err := goavro.NewCodec(keySchema)
+	if err != nil {
+		return err
+	}
+	keySchemaId, err := ap.GetKeySchemaId(topic, keyCodec)
+	if err != nil {
+		return err
+	}
+	binaryKey, err := encodeTextual(keyCodec, key)
+	if err != nil {
+		return err
+	}
 	avroCodec, err := goavro.NewCodec(schema)
 	if err != nil {
 		return err
@@ -49,32 +313,157 @@ func (ap *AvroProducer) Add(topic string, schema string, key []byte, value []byt
 	if err != nil {
 		return err
 	}
+	start := time.Now()
+	binaryValue, err := encodeTextual(avroCodec, value)
+	if err != nil {
+		return err
+	}
+	ap.encoded(topic, start)
+	_, _, err = ap.sendBinary(topic, schemaId, &AvroEncoder{SchemaID: keySchemaId, Content: binaryKey}, binaryValue)
+	return err
+}
+
+// encoded reports the time spent encoding a message of the topic since start
+func (ap *AvroProducer) encoded(topic string, start time.Time) {
+	if ap.metrics != nil {
+		ap.metrics.Encoded(topic, time.Since(start))
+	}
+}
 
+// encodeTextual converts textual Avro data to binary Avro data
+func encodeTextual(avroCodec *goavro.Codec, value []byte) ([]byte, error) {
 	native, _, err := avroCodec.NativeFromTextual(value)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	// Convert native Go form to binary Avro data
-	binaryValue, err := avroCodec.BinaryFromNative(nil, native)
+	return binaryFromNative(avroCodec, native)
+}
+
+// PrepareMessage encodes the textual Avro value like Add and returns the message without sending it,
+// e.g. to send it in a batch with a Batcher
+func (ap *AvroProducer) PrepareMessage(topic string, schema string, key []byte, value []byte) (*sarama.ProducerMessage, error) {
+	if schemaType := ap.config.ForTopic(topic).SchemaType; schemaType != SchemaTypeAvro {
+		return nil, fmt.Errorf("cannot prepare a message of %s topic %s", schemaType, topic)
+	}
+	resolved, err := ap.topicSchema(topic, schema)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	start := time.Now()
+	binaryValue, err := encodeTextual(resolved.codec, value)
+	if err != nil {
+		return nil, err
+	}
+	ap.encoded(topic, start)
+	return ap.prepare(topic, resolved.id, sarama.StringEncoder(key), binaryValue), nil
+}
+
+// PrepareMessageToPartition is like PrepareMessage for an explicit partition, the producer honours it when it was
+// created with WithManualPartitioner
+func (ap *AvroProducer) PrepareMessageToPartition(topic string, partition int32, schema string, key []byte, value []byte) (*sarama.ProducerMessage, error) {
+	msg, err := ap.PrepareMessage(topic, schema, key, value)
+	if err != nil {
+		return nil, err
+	}
+	msg.Partition = partition
+	return msg, nil
+}
+
+// PrepareMessageWithTimestamp is like PrepareMessage, setting the timestamp of the message, e.g. to the event time.
+// Brokers replace it when the timestamp type of the topic is LogAppendTime.
+func (ap *AvroProducer) PrepareMessageWithTimestamp(topic string, timestamp time.Time, schema string, key []byte, value []byte) (*sarama.ProducerMessage, error) {
+	msg, err := ap.PrepareMessage(topic, schema, key, value)
+	if err != nil {
+		return nil, err
 	}
+	msg.Timestamp = timestamp
+	return msg, nil
+}
 
-	binaryMsg := &AvroEncoder{
-		SchemaID: schemaId,
-		Content:  binaryValue,
+// AddWithTimestamp is like Add for AVRO topics, setting the timestamp of the message
+func (ap *AvroProducer) AddWithTimestamp(topic string, timestamp time.Time, schema string, key []byte, value []byte) error {
+	msg, err := ap.PrepareMessageWithTimestamp(topic, timestamp, schema, key, value)
+	if err != nil {
+		return err
 	}
-	msg := &sarama.ProducerMessage{
-		Topic: topic,
-		Key:   sarama.StringEncoder(key),
-		Value: binaryMsg,
+	if _, _, err := ap.sendPrepared(msg); err != nil {
+		ap.invalidateTopicSchema(topic, schema)
+		return err
+	}
+	return nil
+}
+
+// ProduceTombstone sends a message with the key and a nil value, deleting the key from a compacted topic
+func (ap *AvroProducer) ProduceTombstone(topic string, key []byte) error {
+	topic = ap.config.PhysicalTopic(topic)
+	if ap.partitionWatcher != nil {
+		ap.partitionWatcher.track(topic)
 	}
-	_, _, err = ap.producer.SendMessage(msg)
+	_, _, err := ap.sendPrepared(&sarama.ProducerMessage{Topic: topic, Key: sarama.StringEncoder(key)})
 	return err
 }
 
+// prepare frames the binary value in the wire format of the topic
+func (ap *AvroProducer) prepare(topic string, schemaId int, key sarama.Encoder, binaryValue []byte) *sarama.ProducerMessage {
+	value, headers := ap.config.ForTopic(topic).wireFormat().Encode(schemaId, binaryValue)
+	topic = ap.config.PhysicalTopic(topic)
+	if ap.partitionWatcher != nil {
+		ap.partitionWatcher.track(topic)
+	}
+	topicHistogram(ap.MetricRegistry(), "avro-message-size", topic).Update(int64(value.Length()))
+	return &sarama.ProducerMessage{
+		Topic:   topic,
+		Key:     key,
+		Value:   value,
+		Headers: headers,
+	}
+}
+
+func (ap *AvroProducer) sendBinary(topic string, schemaId int, key sarama.Encoder, binaryValue []byte) (int32, int64, error) {
+	return ap.sendPrepared(ap.prepare(topic, schemaId, key, binaryValue))
+}
+
+// sendPrepared sends the message, or its chunks when the topic splits large values, returning the partition and
+// the offset of the last one
+func (ap *AvroProducer) sendPrepared(msg *sarama.ProducerMessage) (int32, int64, error) {
+	if err := ap.claimCheck.store(context.Background(), msg); err != nil {
+		return 0, 0, err
+	}
+	chunks, err := ap.split(msg)
+	if err != nil {
+		return 0, 0, err
+	}
+	var partition int32
+	var offset int64
+	for _, chunk := range chunks {
+		if partition, offset, err = ap.sendMessage(chunk); err != nil {
+			return partition, offset, err
+		}
+	}
+	return partition, offset, nil
+}
+
+func (ap *AvroProducer) sendMessage(msg *sarama.ProducerMessage) (int32, int64, error) {
+	ap.rateLimit.wait(context.Background(), encodedLength(msg.Key)+encodedLength(msg.Value))
+	partition, offset, err := ap.producer.SendMessage(msg)
+	if ap.metrics != nil {
+		ap.metrics.MessageProduced(msg.Topic, err)
+	}
+	if err != nil {
+		orNop(ap.logger).Error("could not send message", "topic", msg.Topic, "error", err)
+	}
+	return partition, offset, err
+}
+
 func (ac *AvroProducer) Close() {
+	if ac.partitionWatcher != nil {
+		ac.partitionWatcher.close()
+	}
 	ac.producer.Close()
+	if ac.client != nil {
+		ac.client.Close()
+	}
 }
 
 // AvroEncoder encodes schemaId and Avro message.
@@ -86,16 +475,15 @@ type AvroEncoder struct {
 // Notice: the Confluent schema registry has special requirements for the Avro serialization rules,
 // not only need to serialize the specific content, but also attach the Schema ID and Magic Byte.
 // Ref: https://docs.confluent.io/current/schema-registry/serializer-formatter.html#wire-format
+// The value is allocated at its exact size, sarama keeps it until the message is sent.
 func (a *AvroEncoder) Encode() ([]byte, error) {
-	var binaryMsg []byte
+	binaryMsg := make([]byte, a.Length())
 	// Confluent serialization format version number; currently always 0.
-	binaryMsg = append(binaryMsg, byte(0))
+	binaryMsg[0] = 0
 	// 4-byte schema ID as returned by Schema Registry
-	binarySchemaId := make([]byte, 4)
-	binary.BigEndian.PutUint32(binarySchemaId, uint32(a.SchemaID))
-	binaryMsg = append(binaryMsg, binarySchemaId...)
+	binary.BigEndian.PutUint32(binaryMsg[1:5], uint32(a.SchemaID))
 	// Avro serialized data in Avro's binary encoding
-	binaryMsg = append(binaryMsg, a.Content...)
+	copy(binaryMsg[5:], a.Content)
 	return binaryMsg, nil
 }
 
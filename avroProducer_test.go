@@ -1,8 +1,16 @@
 package kafka
 
 import (
+	"encoding/json"
+	"fmt"
 	"github.com/Shopify/sarama/mocks"
+	"github.com/linkedin/goavro/v2"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestAvroProducer_Add(t *testing.T) {
@@ -10,10 +18,177 @@ func TestAvroProducer_Add(t *testing.T) {
 	producerMock.ExpectSendMessageAndSucceed()
 	schemaRegistryTestObject := createSchemaRegistryTestObject(t, "test", 1)
 	schemaRegistryMock := NewCachedSchemaRegistryClient([]string{schemaRegistryTestObject.MockServer.URL})
-	avroProducer := &AvroProducer{producerMock, schemaRegistryMock}
+	avroProducer := &AvroProducer{producer: producerMock, schemaRegistryClient: schemaRegistryMock}
 	defer avroProducer.Close()
 	err := avroProducer.Add("test", schemaRegistryTestObject.Codec.Schema(), []byte("key"), []byte(`{"val":1}`))
 	if nil != err {
 		t.Errorf("Error adding msg: %v", err)
 	}
 }
+
+func TestAvroProducer_AddWithImports(t *testing.T) {
+	var registered []string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request schemaRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		if strings.HasSuffix(r.URL.Path, "/versions") {
+			registered = append(registered, r.URL.Path)
+			if r.URL.Path == "/subjects/test-value/versions" && len(request.References) != 1 {
+				t.Errorf("Expected 1 reference, got %v", request.References)
+			}
+			fmt.Fprintf(w, `{"id": %d}`, len(registered))
+			return
+		}
+		fmt.Fprintf(w, `{"id": %d, "version": 1}`, len(registered))
+	}))
+	defer mockServer.Close()
+	producerMock := mocks.NewSyncProducer(t, nil)
+	producerMock.ExpectSendMessageAndSucceed()
+	avroProducer := &AvroProducer{producer: producerMock, schemaRegistryClient: NewCachedSchemaRegistryClient([]string{mockServer.URL})}
+	defer avroProducer.Close()
+	value := `{"home": {"street": "a", "country": "NL"}, "work": null}`
+	err := avroProducer.AddWithImports("test", personSchema, testImports(), []byte("key"), []byte(value))
+	if nil != err {
+		t.Errorf("Error adding msg: %v", err)
+	}
+	expected := []string{"/subjects/country/versions", "/subjects/address/versions", "/subjects/test-value/versions"}
+	if !reflect.DeepEqual(registered, expected) {
+		t.Errorf("Expected registrations %v, got %v", expected, registered)
+	}
+}
+
+func TestAvroProducer_AddWithTopicResolver(t *testing.T) {
+	producerMock := mocks.NewSyncProducer(t, nil)
+	producerMock.ExpectSendMessageAndSucceed()
+	schemaRegistryTestObject := createSchemaRegistryTestObject(t, "prod.test", 1)
+	defer schemaRegistryTestObject.MockServer.Close()
+	avroProducer := &AvroProducer{producer: producerMock, schemaRegistryClient: NewCachedSchemaRegistryClient([]string{schemaRegistryTestObject.MockServer.URL})}
+	avroProducer.SetConfig(Config{TopicResolver: PrefixTopicResolver("prod.")})
+	defer avroProducer.Close()
+	err := avroProducer.Add("test", schemaRegistryTestObject.Codec.Schema(), []byte("key"), []byte(`{"val":1}`))
+	if nil != err {
+		t.Errorf("Error adding msg: %v", err)
+	}
+	if schemaRegistryTestObject.Count != 1 {
+		t.Errorf("Expected the prefixed subject to be registered")
+	}
+	if stats := avroProducer.PayloadStats("test"); stats.MessageSize.Count != 1 {
+		t.Errorf("Expected stats of the physical topic")
+	}
+}
+
+func TestAvroProducer_AddWithAvroKey(t *testing.T) {
+	var subjects []string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		subjects = append(subjects, r.URL.Path)
+		fmt.Fprintf(w, `{"id": %d}`, len(subjects))
+	}))
+	defer mockServer.Close()
+	producerMock := mocks.NewSyncProducer(t, nil)
+	producerMock.ExpectSendMessageAndSucceed()
+	avroProducer := &AvroProducer{producer: producerMock, schemaRegistryClient: NewCachedSchemaRegistryClient([]string{mockServer.URL})}
+	defer avroProducer.Close()
+	schema := `{"type": "record", "name": "test", "fields" : [{"name": "val", "type": "int", "default": 0}]}`
+	err := avroProducer.AddWithAvroKey("test", `"string"`, schema, []byte(`"key"`), []byte(`{"val":1}`))
+	if nil != err {
+		t.Errorf("Error adding msg: %v", err)
+	}
+	expected := []string{"/subjects/test-key/versions", "/subjects/test-value/versions"}
+	if !reflect.DeepEqual(subjects, expected) {
+		t.Errorf("Expected registrations %v, got %v", expected, subjects)
+	}
+	id, err := avroProducer.GetSchemaIdForSubject("test-key", mustCodec(t, `"string"`))
+	if err != nil || id != 1 {
+		t.Errorf("Expected cached key schema id 1, got %d, %v", id, err)
+	}
+}
+
+func mustCodec(t *testing.T, schema string) *goavro.Codec {
+	codec, err := goavro.NewCodec(schema)
+	if err != nil {
+		t.Fatalf("Could not create codec %v", err)
+	}
+	return codec
+}
+
+func TestAvroProducer_AddStruct(t *testing.T) {
+	producerMock := mocks.NewSyncProducer(t, nil)
+	producerMock.ExpectSendMessageAndSucceed()
+	producerMock.ExpectSendMessageAndSucceed()
+	schemaRegistryTestObject := createSchemaRegistryTestObject(t, "test", 1)
+	defer schemaRegistryTestObject.MockServer.Close()
+	avroProducer := &AvroProducer{producer: producerMock, schemaRegistryClient: NewCachedSchemaRegistryClient([]string{schemaRegistryTestObject.MockServer.URL})}
+	defer avroProducer.Close()
+	schema := schemaRegistryTestObject.Codec.Schema()
+	if err := avroProducer.AddStruct("test", schema, []byte("key"), struct {
+		Val int `avro:"val"`
+	}{1}); err != nil {
+		t.Errorf("Error adding struct: %v", err)
+	}
+	if err := avroProducer.AddNative("test", schema, []byte("key"), map[string]interface{}{"val": 1}); err != nil {
+		t.Errorf("Error adding native: %v", err)
+	}
+}
+
+func TestAvroProducer_SetSubjectNameStrategy(t *testing.T) {
+	producerMock := mocks.NewSyncProducer(t, nil)
+	producerMock.ExpectSendMessageAndSucceed()
+	schemaRegistryTestObject := createSchemaRegistryTestObject(t, "test", 1)
+	defer schemaRegistryTestObject.MockServer.Close()
+	avroProducer := &AvroProducer{producer: producerMock, schemaRegistryClient: NewCachedSchemaRegistryClient([]string{schemaRegistryTestObject.MockServer.URL})}
+	defer avroProducer.Close()
+	avroProducer.SetSubjectNameStrategy(func(topic string, isKey bool, schema string) (string, error) {
+		return "test-value", nil
+	})
+	err := avroProducer.Add("events", schemaRegistryTestObject.Codec.Schema(), []byte("key"), []byte(`{"val":1}`))
+	if nil != err {
+		t.Errorf("Error adding msg: %v", err)
+	}
+}
+
+func TestEnableIdempotence(t *testing.T) {
+	config := defaultAvroProducerConfig()
+	enableIdempotence(config)
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected valid idempotent config, got %v", err)
+	}
+	if !config.Producer.Idempotent || config.Net.MaxOpenRequests != 1 {
+		t.Errorf("Expected idempotent settings")
+	}
+}
+
+func TestAvroProducer_PrepareMessageToPartition(t *testing.T) {
+	schema := `{"type": "record", "name": "test", "fields" : [{"name": "val", "type": "int"}]}`
+	avroProducer := &AvroProducer{schemaRegistryClient: newMemorySchemaRegistry()}
+	msg, err := avroProducer.PrepareMessageToPartition("test", 3, schema, []byte("key"), []byte(`{"val":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Topic != "test" || msg.Partition != 3 {
+		t.Errorf("Expected a message to partition 3 of the topic, got %+v", msg)
+	}
+}
+
+func TestAvroProducer_ProduceTombstone(t *testing.T) {
+	producer := &testSyncProducer{}
+	avroProducer := &AvroProducer{producer: producer}
+	if err := avroProducer.ProduceTombstone("test", []byte("key")); err != nil {
+		t.Fatal(err)
+	}
+	if len(producer.sent) != 1 || producer.sent[0].Value != nil || producer.sent[0].Topic != "test" {
+		t.Errorf("Expected a message without value, got %+v", producer.sent)
+	}
+}
+
+func TestAvroProducer_AddWithTimestamp(t *testing.T) {
+	schema := `{"type": "record", "name": "test", "fields" : [{"name": "val", "type": "int"}]}`
+	producer := &testSyncProducer{}
+	avroProducer := &AvroProducer{producer: producer, schemaRegistryClient: newMemorySchemaRegistry()}
+	timestamp := time.Unix(1600000000, 0)
+	if err := avroProducer.AddWithTimestamp("test", timestamp, schema, nil, []byte(`{"val":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	if len(producer.sent) != 1 || !producer.sent[0].Timestamp.Equal(timestamp) {
+		t.Errorf("Expected the message to have the timestamp, got %+v", producer.sent)
+	}
+}
@@ -0,0 +1,134 @@
+package kafka
+
+import (
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+// BatchConfig controls the batches passed to OnBatchReceived
+type BatchConfig struct {
+	// MaxSize is the max number of messages of a batch, defaults to 100
+	MaxSize int
+	// MaxWait is the max time the first message of a batch waits before the batch is passed, defaults to 1s
+	MaxWait time.Duration
+}
+
+func (c BatchConfig) withDefaults() BatchConfig {
+	if c.MaxSize <= 0 {
+		c.MaxSize = 100
+	}
+	if c.MaxWait <= 0 {
+		c.MaxWait = time.Second
+	}
+	return c
+}
+
+// SetBatchConfig sets the max size and the max wait of the batches passed to OnBatchReceived
+func (ac *AvroConsumer) SetBatchConfig(config BatchConfig) {
+	ac.batch = config
+}
+
+// partitionBatch collects the decoded messages of a claim until the batch is full or its first message waited too long.
+// It is the session of the messages it receives, so the messages dead-lettered or skipped by the consumer are only
+// marked with the batch.
+type partitionBatch struct {
+	sarama.ConsumerGroupSession
+	consumer *AvroConsumer
+	msgs     []Message
+	raw      []*sarama.ConsumerMessage
+	// last is the last message received, including the messages left out of the batch
+	last *sarama.ConsumerMessage
+}
+
+// MarkMessage implements sarama.ConsumerGroupSession, the message is marked when the batch is passed
+func (b *partitionBatch) MarkMessage(msg *sarama.ConsumerMessage, metadata string) {
+	b.last = msg
+}
+
+// consumeBatches passes the messages of the claim to OnBatchReceived in batches, the pending batch is passed
+// when the claim is released so its offsets are committed with the session
+func (h *consumerGroupHandler) consumeBatches(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) {
+	config := h.consumer.batch.withDefaults()
+	batch := &partitionBatch{ConsumerGroupSession: session, consumer: h.consumer}
+	var timer *time.Timer
+	var wait <-chan time.Time
+	flush := func() {
+		if timer != nil {
+			timer.Stop()
+			timer, wait = nil, nil
+		}
+		h.lock.Lock()
+		defer h.lock.Unlock()
+		batch.flush()
+	}
+	defer flush()
+	for {
+		select {
+		case m, ok := <-claim.Messages():
+			if !ok {
+				return
+			}
+			if !waitForRetry(session, m) {
+				return
+			}
+			h.lock.Lock()
+			batch.add(m)
+			h.lock.Unlock()
+			if len(batch.msgs) >= config.MaxSize {
+				flush()
+			} else if batch.last != nil && timer == nil {
+				timer = time.NewTimer(config.MaxWait)
+				wait = timer.C
+			}
+		case <-wait:
+			timer, wait = nil, nil
+			flush()
+		}
+	}
+}
+
+func (b *partitionBatch) add(m *sarama.ConsumerMessage) {
+	msg, handled, err := b.consumer.receive(b, m)
+	if handled {
+		return
+	}
+	b.last = m
+	// acknowledging a message commits it at once, the batch does not wait for it
+	msg.ack.session = b.ConsumerGroupSession
+	if err == nil {
+		b.msgs = append(b.msgs, msg)
+		b.raw = append(b.raw, msg.ack.msg)
+	}
+}
+
+func (b *partitionBatch) flush() {
+	last := b.last
+	if last == nil || b.consumer.isHalted() {
+		return
+	}
+	msgs, raw := b.msgs, b.raw
+	b.msgs, b.raw, b.last = nil, nil, nil
+	if b.consumer.commitStrategy == CommitBeforeCallback {
+		b.ConsumerGroupSession.MarkMessage(last, "")
+	}
+	if len(msgs) > 0 {
+		err := b.consumer.callbacks.OnBatchReceived(msgs)
+		for _, msg := range msgs {
+			b.consumer.interceptHandled(msg, err)
+		}
+		if err != nil {
+			orNop(b.consumer.logger).Warn("could not process batch", "topic", last.Topic, "partition", last.Partition,
+				"size", len(msgs), "error", err)
+			for _, m := range raw {
+				if err := b.consumer.processFailed(m, err); err != nil {
+					b.consumer.haltWith(err)
+					return
+				}
+			}
+		}
+	}
+	if b.consumer.commitStrategy == CommitAfterCallback {
+		b.ConsumerGroupSession.MarkMessage(last, "")
+	}
+}
@@ -0,0 +1,154 @@
+package kafka
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/linkedin/goavro/v2"
+)
+
+type testClaim struct {
+	testPartitionMessages
+}
+
+func (c *testClaim) InitialOffset() int64 { return 0 }
+
+func newTestClaim(size int) *testClaim {
+	return &testClaim{testPartitionMessages{messages: make(chan *sarama.ConsumerMessage, size)}}
+}
+
+func TestAvroConsumer_ConsumeBatches(t *testing.T) {
+	registry := newMemorySchemaRegistry()
+	codec, err := goavro.NewCodec(`{"type": "record", "name": "test", "fields" : [{"name": "val", "type": "int"}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schemaId, err := registry.CreateSubject("test-value", codec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	value := []byte{0, 0, 0, 0, 0, 2}
+	binary.BigEndian.PutUint32(value[1:5], uint32(schemaId))
+
+	var batches [][]Message
+	var errs []error
+	consumer := &AvroConsumer{SchemaRegistryClient: registry, callbacks: ConsumerCallbacks{
+		OnBatchReceived: func(msgs []Message) error {
+			batches = append(batches, msgs)
+			if len(batches) == 2 {
+				return errors.New("failed")
+			}
+			return nil
+		},
+		OnError: func(err error) { errs = append(errs, err) },
+	}}
+	consumer.SetOffsetCommitStrategy(CommitAfterCallback)
+	consumer.SetBatchConfig(BatchConfig{MaxSize: 2, MaxWait: time.Hour})
+
+	claim := newTestClaim(4)
+	claim.messages <- &sarama.ConsumerMessage{Topic: "test", Offset: 0, Value: value}
+	claim.messages <- &sarama.ConsumerMessage{Topic: "test", Offset: 1, Value: []byte{1}}
+	claim.messages <- &sarama.ConsumerMessage{Topic: "test", Offset: 2, Value: value}
+	claim.messages <- &sarama.ConsumerMessage{Topic: "test", Offset: 3, Value: value}
+	close(claim.messages)
+	session := newTestSession(nil)
+	handler := &consumerGroupHandler{consumer: consumer}
+	if err := handler.ConsumeClaim(session, claim); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(batches) != 2 || len(batches[0]) != 2 || len(batches[1]) != 1 {
+		t.Fatalf("Expected a full batch and the pending batch, got %v", batches)
+	}
+	if batches[0][0].Offset != 0 || batches[0][1].Offset != 2 || batches[0][1].Value != `{"val":1}` {
+		t.Errorf("Unexpected first batch %+v", batches[0])
+	}
+	if len(errs) != 2 || session.offsets[0] != 4 {
+		t.Errorf("Expected the decode and batch errors to be reported and the offsets committed, got %v, %v", errs, session.offsets)
+	}
+}
+
+func TestAvroConsumer_ConsumeBatchesMaxWait(t *testing.T) {
+	registry := newMemorySchemaRegistry()
+	codec, err := goavro.NewCodec(`{"type": "record", "name": "test", "fields" : [{"name": "val", "type": "int"}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schemaId, err := registry.CreateSubject("test-value", codec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	value := []byte{0, 0, 0, 0, 0, 2}
+	binary.BigEndian.PutUint32(value[1:5], uint32(schemaId))
+
+	batches := make(chan []Message, 1)
+	consumer := &AvroConsumer{SchemaRegistryClient: registry, callbacks: ConsumerCallbacks{
+		OnBatchReceived: func(msgs []Message) error {
+			batches <- msgs
+			return nil
+		},
+	}}
+	consumer.SetBatchConfig(BatchConfig{MaxSize: 10, MaxWait: 10 * time.Millisecond})
+
+	claim := newTestClaim(1)
+	claim.messages <- &sarama.ConsumerMessage{Topic: "test", Offset: 7, Value: value}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		(&consumerGroupHandler{consumer: consumer}).ConsumeClaim(newTestSession(nil), claim)
+	}()
+	select {
+	case msgs := <-batches:
+		if len(msgs) != 1 || msgs[0].Offset != 7 {
+			t.Errorf("Unexpected batch %+v", msgs)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Expected the batch to be passed after the max wait")
+	}
+	close(claim.messages)
+	<-done
+}
+
+func TestAvroConsumer_ConsumeBatchesDeadLetter(t *testing.T) {
+	registry := newMemorySchemaRegistry()
+	codec, err := goavro.NewCodec(`{"type": "record", "name": "test", "fields" : [{"name": "val", "type": "int"}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schemaId, err := registry.CreateSubject("test-value", codec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	value := []byte{0, 0, 0, 0, 0, 2}
+	binary.BigEndian.PutUint32(value[1:5], uint32(schemaId))
+
+	session := newTestSession(nil)
+	var marked []int64
+	producer := &testSyncProducer{}
+	consumer := &AvroConsumer{SchemaRegistryClient: registry, callbacks: ConsumerCallbacks{
+		OnBatchReceived: func(msgs []Message) error {
+			marked = append(marked, session.offsets[0])
+			return nil
+		},
+	}}
+	consumer.SetOffsetCommitStrategy(CommitAfterCallback)
+	consumer.SetDeadLetterQueue(DeadLetterConfig{Producer: producer})
+	consumer.SetBatchConfig(BatchConfig{MaxSize: 2, MaxWait: time.Hour})
+
+	claim := newTestClaim(3)
+	claim.messages <- &sarama.ConsumerMessage{Topic: "test", Offset: 0, Value: value}
+	claim.messages <- &sarama.ConsumerMessage{Topic: "test", Offset: 1, Value: []byte("corrupt")}
+	claim.messages <- &sarama.ConsumerMessage{Topic: "test", Offset: 2, Value: value}
+	close(claim.messages)
+	(&consumerGroupHandler{consumer: consumer}).ConsumeClaim(session, claim)
+
+	if len(producer.sent) != 1 || len(marked) != 1 || marked[0] != 0 {
+		t.Fatalf("Expected the dead-lettered message not to be committed before the batch, got %v", marked)
+	}
+	if session.offsets[0] != 3 {
+		t.Errorf("Expected the offsets to be committed with the batch, got %v", session.offsets)
+	}
+}
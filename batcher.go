@@ -0,0 +1,177 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+// BatcherConfig controls when a Batcher sends its messages
+type BatcherConfig struct {
+	// MaxMessages is the max number of messages of a batch, defaults to 100
+	MaxMessages int
+	// MaxBytes is the max size of the keys and values of a batch, defaults to 1MB
+	MaxBytes int
+	// Linger is the max time the first message of a batch waits before the batch is sent, defaults to 100ms
+	Linger time.Duration
+}
+
+func (c BatcherConfig) withDefaults() BatcherConfig {
+	if c.MaxMessages <= 0 {
+		c.MaxMessages = 100
+	}
+	if c.MaxBytes <= 0 {
+		c.MaxBytes = 1 << 20
+	}
+	if c.Linger <= 0 {
+		c.Linger = 100 * time.Millisecond
+	}
+	return c
+}
+
+// Batcher accumulates prepared messages and sends them with one SendMessages call when the batch is full or lingered
+// long enough. The error of a batch sent after its linger is returned by the next Add, Flush or Close.
+type Batcher struct {
+	producer *AvroProducer
+	config   BatcherConfig
+	lock     sync.Mutex
+	msgs     []*sarama.ProducerMessage
+	bytes    int
+	timer    *time.Timer
+	err      error
+	closed   bool
+}
+
+// NewBatcher creates a batcher sending its messages with the producer
+func NewBatcher(producer *AvroProducer, config BatcherConfig) *Batcher {
+	return &Batcher{producer: producer, config: config.withDefaults()}
+}
+
+// Add prepares the textual Avro value like AvroProducer.Add and adds it to the batch
+func (b *Batcher) Add(topic string, schema string, key []byte, value []byte) error {
+	msg, err := b.producer.PrepareMessage(topic, schema, key, value)
+	if err != nil {
+		return err
+	}
+	return b.AddMessage(msg)
+}
+
+// AddMessage adds a message returned by PrepareMessage to the batch, the batch is sent when it is full
+func (b *Batcher) AddMessage(msg *sarama.ProducerMessage) error {
+	if err := b.producer.claimCheck.store(context.Background(), msg); err != nil {
+		return err
+	}
+	chunks, err := b.producer.split(msg)
+	if err != nil {
+		return err
+	}
+	for _, chunk := range chunks {
+		if err := b.add(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *Batcher) add(msg *sarama.ProducerMessage) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.closed {
+		return fmt.Errorf("batcher is closed")
+	}
+	if err := b.takeErr(); err != nil {
+		return err
+	}
+	size := encodedLength(msg.Key) + encodedLength(msg.Value)
+	if len(b.msgs) > 0 && b.bytes+size > b.config.MaxBytes {
+		if err := b.flush(); err != nil {
+			return err
+		}
+	}
+	b.msgs = append(b.msgs, msg)
+	b.bytes += size
+	if len(b.msgs) >= b.config.MaxMessages || b.bytes >= b.config.MaxBytes {
+		return b.flush()
+	}
+	if b.timer == nil {
+		b.timer = time.AfterFunc(b.config.Linger, b.linger)
+	}
+	return nil
+}
+
+// Flush sends the pending messages
+func (b *Batcher) Flush() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if err := b.takeErr(); err != nil {
+		return err
+	}
+	return b.flush()
+}
+
+// Close sends the pending messages, the batcher cannot be used afterwards. It does not close the producer.
+func (b *Batcher) Close() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.closed = true
+	err := b.takeErr()
+	if flushErr := b.flush(); err == nil {
+		err = flushErr
+	}
+	return err
+}
+
+func (b *Batcher) linger() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.timer = nil
+	if err := b.flush(); err != nil && b.err == nil {
+		b.err = err
+	}
+}
+
+func (b *Batcher) takeErr() error {
+	err := b.err
+	b.err = nil
+	return err
+}
+
+// flush sends the batch, the lock is held
+func (b *Batcher) flush() error {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	msgs := b.msgs
+	b.msgs, b.bytes = nil, 0
+	if len(msgs) == 0 {
+		return nil
+	}
+	ap := b.producer
+	for _, msg := range msgs {
+		ap.rateLimit.wait(context.Background(), encodedLength(msg.Key)+encodedLength(msg.Value))
+	}
+	err := ap.producer.SendMessages(msgs)
+	if ap.metrics != nil {
+		failed := make(map[*sarama.ProducerMessage]error)
+		if producerErrs, ok := err.(sarama.ProducerErrors); ok {
+			for _, producerErr := range producerErrs {
+				failed[producerErr.Msg] = producerErr.Err
+			}
+		}
+		for _, msg := range msgs {
+			msgErr, found := failed[msg]
+			if !found && len(failed) == 0 {
+				msgErr = err
+			}
+			ap.metrics.MessageProduced(msg.Topic, msgErr)
+		}
+	}
+	if err != nil {
+		orNop(ap.logger).Error("could not send batch", "size", len(msgs), "error", err)
+	}
+	return err
+}
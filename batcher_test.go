@@ -0,0 +1,74 @@
+package kafka
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+func TestBatcher(t *testing.T) {
+	schema := `{"type": "record", "name": "test", "fields" : [{"name": "val", "type": "int"}]}`
+	producer := &testSyncProducer{}
+	avroProducer := &AvroProducer{producer: producer, schemaRegistryClient: newMemorySchemaRegistry()}
+	batcher := NewBatcher(avroProducer, BatcherConfig{MaxMessages: 3, Linger: time.Hour})
+	for i := 0; i < 4; i++ {
+		if err := batcher.Add("test", schema, []byte("key"), []byte(`{"val":1}`)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(producer.sent) != 3 {
+		t.Fatalf("Expected a full batch to be sent, got %d messages", len(producer.sent))
+	}
+	if err := batcher.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(producer.sent) != 4 {
+		t.Errorf("Expected the pending message to be sent on close, got %d messages", len(producer.sent))
+	}
+	if err := batcher.Add("test", schema, nil, []byte(`{"val":1}`)); err == nil {
+		t.Errorf("Expected an error adding to a closed batcher")
+	}
+}
+
+func TestBatcher_MaxBytes(t *testing.T) {
+	producer := &testSyncProducer{}
+	batcher := NewBatcher(&AvroProducer{producer: producer}, BatcherConfig{MaxBytes: 10, Linger: time.Hour})
+	batcher.AddMessage(&sarama.ProducerMessage{Topic: "test", Value: sarama.ByteEncoder("123456")})
+	batcher.AddMessage(&sarama.ProducerMessage{Topic: "test", Value: sarama.ByteEncoder("123456")})
+	if len(producer.sent) != 1 {
+		t.Fatalf("Expected the batch to be sent before exceeding the max bytes, got %d messages", len(producer.sent))
+	}
+	batcher.AddMessage(&sarama.ProducerMessage{Topic: "test", Value: sarama.ByteEncoder("1234")})
+	if len(producer.sent) != 3 {
+		t.Errorf("Expected the batch to be sent once it reached the max bytes, got %d messages", len(producer.sent))
+	}
+}
+
+func TestBatcher_Linger(t *testing.T) {
+	producer := &testSyncProducer{err: errors.New("failed")}
+	batcher := NewBatcher(&AvroProducer{producer: producer}, BatcherConfig{Linger: time.Millisecond})
+	if err := batcher.AddMessage(&sarama.ProducerMessage{Topic: "test", Value: sarama.ByteEncoder("1")}); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		batcher.lock.Lock()
+		pending := len(batcher.msgs)
+		batcher.lock.Unlock()
+		if pending == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the batch to be sent after the linger")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := batcher.Flush(); err == nil {
+		t.Errorf("Expected the error of the lingered batch to be returned")
+	}
+	if err := batcher.Flush(); err != nil {
+		t.Errorf("Expected the error to be returned once, got %v", err)
+	}
+}
@@ -0,0 +1,236 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BlobStore keeps the values moved out of the messages by a ClaimCheck
+type BlobStore interface {
+	// Put stores the data under the key, replacing the data stored under the same key
+	Put(ctx context.Context, key string, data []byte) error
+	// Get returns the data stored under the key
+	Get(ctx context.Context, key string) ([]byte, error)
+}
+
+// FileBlobStore is a BlobStore keeping the blobs in files of a directory, e.g. on a shared volume
+type FileBlobStore struct {
+	Dir string
+}
+
+// Put implements BlobStore, the file is replaced atomically
+func (s *FileBlobStore) Put(ctx context.Context, key string, data []byte) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Get implements BlobStore
+func (s *FileBlobStore) Get(ctx context.Context, key string) ([]byte, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadFile(path)
+}
+
+func (s *FileBlobStore) path(key string) (string, error) {
+	path := filepath.Join(s.Dir, filepath.FromSlash(key))
+	relative, err := filepath.Rel(s.Dir, path)
+	if err != nil || relative == "." || strings.HasPrefix(relative, "..") {
+		return "", fmt.Errorf("blob key %s is outside of %s", key, s.Dir)
+	}
+	return path, nil
+}
+
+// S3BlobStore is a BlobStore keeping the blobs in an S3 bucket, or a bucket of an S3 compatible store like MinIO.
+// Requests are signed with AWS signature version 4 and use path-style URLs.
+type S3BlobStore struct {
+	Bucket string
+	Region string
+	// Endpoint is the URL of the store, https://s3.<Region>.amazonaws.com when empty
+	Endpoint        string
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken is the token of temporary credentials, if any
+	SessionToken string
+	// HTTPClient sends the requests, http.DefaultClient when nil
+	HTTPClient *http.Client
+}
+
+// Put implements BlobStore
+func (s *S3BlobStore) Put(ctx context.Context, key string, data []byte) error {
+	_, err := s.do(ctx, http.MethodPut, key, data)
+	return err
+}
+
+// Get implements BlobStore
+func (s *S3BlobStore) Get(ctx context.Context, key string) ([]byte, error) {
+	return s.do(ctx, http.MethodGet, key, nil)
+}
+
+func (s *S3BlobStore) do(ctx context.Context, method string, key string, data []byte) ([]byte, error) {
+	endpoint := s.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", s.Region)
+	}
+	req, err := http.NewRequest(method, strings.TrimSuffix(endpoint, "/")+"/"+s3Escape(s.Bucket)+"/"+s3Escape(key),
+		bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	s.sign(req, data, time.Now().UTC())
+	return doBlobRequest(s.HTTPClient, req.WithContext(ctx), key)
+}
+
+// sign adds the AWS signature version 4 of the request to its headers
+func (s *S3BlobStore) sign(req *http.Request, payload []byte, now time.Time) {
+	payloadHash := sha256.Sum256(payload)
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+	if s.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.SessionToken)
+	}
+	headers := map[string]string{"host": req.URL.Host}
+	for name := range req.Header {
+		if lower := strings.ToLower(name); strings.HasPrefix(lower, "x-amz-") {
+			headers[lower] = strings.TrimSpace(req.Header.Get(name))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+	canonicalRequest := strings.Join([]string{req.Method, req.URL.EscapedPath(), req.URL.RawQuery,
+		canonicalHeaders.String(), signedHeaders, hex.EncodeToString(payloadHash[:])}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	scope := date + "/" + s.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+	signingKey := []byte("AWS4" + s.SecretAccessKey)
+	for _, part := range []string{date, s.Region, "s3", "aws4_request"} {
+		signingKey = hmacSHA256(signingKey, part)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKeyID, scope, signedHeaders, hex.EncodeToString(hmacSHA256(signingKey, stringToSign))))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3Escape escapes a path like AWS does in canonical requests, every byte but the unreserved ones and '/'
+func s3Escape(path string) string {
+	var escaped strings.Builder
+	for _, b := range []byte(path) {
+		if 'A' <= b && b <= 'Z' || 'a' <= b && b <= 'z' || '0' <= b && b <= '9' || strings.IndexByte("-._~/", b) >= 0 {
+			escaped.WriteByte(b)
+		} else {
+			fmt.Fprintf(&escaped, "%%%02X", b)
+		}
+	}
+	return escaped.String()
+}
+
+// GCSBlobStore is a BlobStore keeping the blobs in a Google Cloud Storage bucket, using its JSON API
+type GCSBlobStore struct {
+	Bucket string
+	// TokenProvider returns the OAuth2 access tokens of the requests, e.g. of a service account
+	TokenProvider TokenProvider
+	// Endpoint is the URL of the API, https://storage.googleapis.com when empty
+	Endpoint string
+	// HTTPClient sends the requests, http.DefaultClient when nil
+	HTTPClient *http.Client
+}
+
+// Put implements BlobStore
+func (s *GCSBlobStore) Put(ctx context.Context, key string, data []byte) error {
+	target := fmt.Sprintf("%s/upload/storage/v1/b/%s/o?uploadType=media&name=%s", s.endpoint(),
+		url.PathEscape(s.Bucket), url.QueryEscape(key))
+	req, err := http.NewRequest(http.MethodPost, target, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	_, err = s.do(ctx, req, key)
+	return err
+}
+
+// Get implements BlobStore
+func (s *GCSBlobStore) Get(ctx context.Context, key string) ([]byte, error) {
+	target := fmt.Sprintf("%s/storage/v1/b/%s/o/%s?alt=media", s.endpoint(), url.PathEscape(s.Bucket),
+		url.PathEscape(key))
+	req, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	return s.do(ctx, req, key)
+}
+
+func (s *GCSBlobStore) endpoint() string {
+	if s.Endpoint == "" {
+		return "https://storage.googleapis.com"
+	}
+	return strings.TrimSuffix(s.Endpoint, "/")
+}
+
+func (s *GCSBlobStore) do(ctx context.Context, req *http.Request, key string) ([]byte, error) {
+	if s.TokenProvider != nil {
+		token, err := s.TokenProvider.Token(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("could not get a token for blob %s: %v", key, err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return doBlobRequest(s.HTTPClient, req.WithContext(ctx), key)
+}
+
+// doBlobRequest sends the request and returns the body of the response, an error if its status is not 2xx
+func doBlobRequest(client *http.Client, req *http.Request, key string) ([]byte, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("%s blob %s: %s %s", req.Method, key, resp.Status, bytes.TrimSpace(body))
+	}
+	return body, nil
+}
@@ -0,0 +1,61 @@
+package kafka
+
+import (
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+// BootstrapConfig enables parallel cold-start of a consumer with a large backlog.
+// Every partition is processed in its own goroutine until it is caught up, then its messages
+// are merged into the normal sequential processing. Callbacks must be safe for concurrent use.
+type BootstrapConfig struct {
+	// MaxLag is the number of messages behind the high water mark a partition may be to count as caught up
+	MaxLag int64
+	// MaxAge is the max age of a message timestamp for its partition to count as caught up, ignored when 0
+	MaxAge time.Duration
+	// OnCaughtUp is called once for every partition when it joins the sequential processing
+	OnCaughtUp func(topic string, partition int32)
+}
+
+// NewAvroConsumerWithBootstrap is like NewAvroConsumer, bootstrapping partitions in parallel until they are caught up
+//
+// Deprecated: use NewAvroConsumer with WithBootstrap
+func NewAvroConsumerWithBootstrap(kafkaServers []string, schemaRegistryServers []string,
+	topic string, groupId string, callbacks ConsumerCallbacks, bootstrap BootstrapConfig) (*AvroConsumer, error) {
+	return NewAvroConsumer(kafkaServers, schemaRegistryServers, topic, groupId, callbacks, WithBootstrap(bootstrap))
+}
+
+func (b *BootstrapConfig) caughtUp(m *sarama.ConsumerMessage, highWaterMark int64) bool {
+	if highWaterMark-m.Offset-1 > b.MaxLag {
+		return false
+	}
+	return b.MaxAge <= 0 || m.Timestamp.IsZero() || time.Since(m.Timestamp) <= b.MaxAge
+}
+
+type partitionMessages interface {
+	Messages() <-chan *sarama.ConsumerMessage
+	HighWaterMarkOffset() int64
+	Topic() string
+	Partition() int32
+}
+
+// bootstrapPartition handles messages in parallel to other partitions until it is caught up,
+// after that they are handed to the sequential handler
+func (ac *AvroConsumer) bootstrapPartition(session sarama.ConsumerGroupSession, pc partitionMessages,
+	sequential func(sarama.ConsumerGroupSession, *sarama.ConsumerMessage)) {
+	caughtUp := false
+	for m := range pc.Messages() {
+		if !caughtUp && ac.bootstrap.caughtUp(m, pc.HighWaterMarkOffset()) {
+			caughtUp = true
+			if ac.bootstrap.OnCaughtUp != nil {
+				ac.bootstrap.OnCaughtUp(pc.Topic(), pc.Partition())
+			}
+		}
+		if !caughtUp {
+			ac.handle(session, m)
+			continue
+		}
+		sequential(session, m)
+	}
+}
@@ -0,0 +1,49 @@
+package kafka
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+type testPartitionMessages struct {
+	messages      chan *sarama.ConsumerMessage
+	highWaterMark int64
+}
+
+func (p *testPartitionMessages) Messages() <-chan *sarama.ConsumerMessage { return p.messages }
+func (p *testPartitionMessages) HighWaterMarkOffset() int64               { return p.highWaterMark }
+func (p *testPartitionMessages) Topic() string                            { return "test" }
+func (p *testPartitionMessages) Partition() int32                         { return 0 }
+
+func TestBootstrapConfig_CaughtUp(t *testing.T) {
+	bootstrap := &BootstrapConfig{MaxLag: 10, MaxAge: time.Minute}
+	if bootstrap.caughtUp(&sarama.ConsumerMessage{Offset: 10, Timestamp: time.Now()}, 100) {
+		t.Errorf("Expected lagging partition not to be caught up")
+	}
+	if !bootstrap.caughtUp(&sarama.ConsumerMessage{Offset: 90, Timestamp: time.Now()}, 100) {
+		t.Errorf("Expected partition to be caught up")
+	}
+	if bootstrap.caughtUp(&sarama.ConsumerMessage{Offset: 99, Timestamp: time.Now().Add(-time.Hour)}, 100) {
+		t.Errorf("Expected old messages not to be caught up")
+	}
+}
+
+func TestAvroConsumer_BootstrapPartition(t *testing.T) {
+	var caughtUp []int32
+	consumer := &AvroConsumer{bootstrap: &BootstrapConfig{MaxLag: 1, OnCaughtUp: func(topic string, partition int32) {
+		caughtUp = append(caughtUp, partition)
+	}}}
+	pc := &testPartitionMessages{make(chan *sarama.ConsumerMessage, 2), 2}
+	pc.messages <- &sarama.ConsumerMessage{Offset: 0}
+	pc.messages <- &sarama.ConsumerMessage{Offset: 1}
+	close(pc.messages)
+	var sequential []*sarama.ConsumerMessage
+	consumer.bootstrapPartition(newTestSession(nil), pc, func(session sarama.ConsumerGroupSession, m *sarama.ConsumerMessage) {
+		sequential = append(sequential, m)
+	})
+	if len(sequential) != 2 || len(caughtUp) != 1 {
+		t.Errorf("Expected caught up partition to forward its messages, got %d messages", len(sequential))
+	}
+}
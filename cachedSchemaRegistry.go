@@ -1,97 +1,541 @@
 package kafka
 
 import (
+	"context"
 	"github.com/linkedin/goavro/v2"
-	"sync"
+	"golang.org/x/sync/singleflight"
+	"net/http"
+	"strconv"
+	"time"
 )
 
 // CachedSchemaRegistryClient is a schema registry client that will cache some data to improve performance
 type CachedSchemaRegistryClient struct {
 	SchemaRegistryClient *SchemaRegistryClient
-	schemaCache          map[int]*goavro.Codec
-	schemaCacheLock      sync.RWMutex
-	schemaIdCache        map[string]int
-	schemaIdCacheLock    sync.RWMutex
+	schemaCache          *registryCache
+	schemaIdCache        *registryCache
+	metadataCache        *registryCache
+	lookupCache          *registryCache
+	registrations        singleflight.Group
+	lookups              singleflight.Group
+	misses               *negativeCache
 }
 
-func NewCachedSchemaRegistryClient(connect []string) *CachedSchemaRegistryClient {
-	SchemaRegistryClient := NewSchemaRegistryClient(connect)
-	return &CachedSchemaRegistryClient{SchemaRegistryClient: SchemaRegistryClient, schemaCache: make(map[int]*goavro.Codec), schemaIdCache: make(map[string]int)}
+func NewCachedSchemaRegistryClient(connect []string, opts ...SchemaRegistryOption) *CachedSchemaRegistryClient {
+	SchemaRegistryClient := NewSchemaRegistryClient(connect, opts...)
+	return newCachedSchemaRegistryClient(SchemaRegistryClient)
 }
 
-func NewCachedSchemaRegistryClientWithRetries(connect []string, retries int) *CachedSchemaRegistryClient {
-	SchemaRegistryClient := NewSchemaRegistryClientWithRetries(connect, retries)
-	return &CachedSchemaRegistryClient{SchemaRegistryClient: SchemaRegistryClient, schemaCache: make(map[int]*goavro.Codec), schemaIdCache: make(map[string]int)}
+func NewCachedSchemaRegistryClientWithRetries(connect []string, retries int, opts ...SchemaRegistryOption) *CachedSchemaRegistryClient {
+	SchemaRegistryClient := NewSchemaRegistryClientWithRetries(connect, retries, opts...)
+	return newCachedSchemaRegistryClient(SchemaRegistryClient)
+}
+
+func newCachedSchemaRegistryClient(SchemaRegistryClient *SchemaRegistryClient) *CachedSchemaRegistryClient {
+	client := &CachedSchemaRegistryClient{
+		SchemaRegistryClient: SchemaRegistryClient,
+		schemaCache:          newRegistryCache(),
+		schemaIdCache:        newRegistryCache(),
+		metadataCache:        newRegistryCache(),
+		lookupCache:          newRegistryCache(),
+	}
+	for name, cache := range map[string]*registryCache{
+		"schema":   client.schemaCache,
+		"schemaId": client.schemaIdCache,
+		"metadata": client.metadataCache,
+		"lookup":   client.lookupCache,
+	} {
+		cache.name = name
+		cache.metrics = SchemaRegistryClient.metrics
+	}
+	return client
 }
 
 // GetSchema will return and cache the codec with the given id
 func (client *CachedSchemaRegistryClient) GetSchema(id int) (*goavro.Codec, error) {
-	client.schemaCacheLock.RLock()
-	cachedResult := client.schemaCache[id]
-	client.schemaCacheLock.RUnlock()
-	if nil != cachedResult {
-		return cachedResult, nil
+	return client.GetSchemaContext(context.Background(), id)
+}
+
+// GetSchemaContext is GetSchema using the context for the registry requests
+func (client *CachedSchemaRegistryClient) GetSchemaContext(ctx context.Context, id int) (*goavro.Codec, error) {
+	if cachedResult, found := client.schemaCache.get(id); found {
+		return cachedResult.(*goavro.Codec), nil
+	}
+	if err := client.misses.get(idMissKey(id)); err != nil {
+		return nil, err
 	}
-	codec, err := client.SchemaRegistryClient.GetSchema(id)
+	// concurrent misses for the same id share a single registry request
+	result, err := client.share(ctx, &client.lookups, "schema:"+strconv.Itoa(id), func(ctx context.Context) (interface{}, error) {
+		codec, err := client.SchemaRegistryClient.GetSchemaContext(ctx, id)
+		if err != nil {
+			client.misses.add(idMissKey(id), err)
+			return nil, err
+		}
+		client.schemaCache.add(id, codec)
+		return codec, nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	client.schemaCacheLock.Lock()
-	client.schemaCache[id] = codec
-	client.schemaCacheLock.Unlock()
-	return codec, nil
+	return result.(*goavro.Codec), nil
 }
 
 // GetSubjects returns a list of subjects
 func (client *CachedSchemaRegistryClient) GetSubjects() ([]string, error) {
-	return client.SchemaRegistryClient.GetSubjects()
+	return client.GetSubjectsContext(context.Background())
+}
+
+// GetSubjectsContext is GetSubjects using the context for the registry requests
+func (client *CachedSchemaRegistryClient) GetSubjectsContext(ctx context.Context) ([]string, error) {
+	return client.SchemaRegistryClient.GetSubjectsContext(ctx)
 }
 
 // GetVersions returns a list of all versions of a subject
 func (client *CachedSchemaRegistryClient) GetVersions(subject string) ([]int, error) {
-	return client.SchemaRegistryClient.GetVersions(subject)
+	return client.GetVersionsContext(context.Background(), subject)
+}
+
+// GetVersionsContext is GetVersions using the context for the registry requests
+func (client *CachedSchemaRegistryClient) GetVersionsContext(ctx context.Context, subject string) ([]int, error) {
+	key := subjectMissKey(subject, "versions")
+	if err := client.misses.get(key); err != nil {
+		return []int{}, err
+	}
+	versions, err := client.SchemaRegistryClient.GetVersionsContext(ctx, subject)
+	client.misses.add(key, err)
+	return versions, err
 }
 
 // GetSchemaByVersion returns the codec for a specific version of a subject
 func (client *CachedSchemaRegistryClient) GetSchemaByVersion(subject string, version int) (*goavro.Codec, error) {
-	return client.SchemaRegistryClient.GetSchemaByVersion(subject, version)
+	return client.GetSchemaByVersionContext(context.Background(), subject, version)
+}
+
+// GetSchemaByVersionContext is GetSchemaByVersion using the context for the registry requests
+func (client *CachedSchemaRegistryClient) GetSchemaByVersionContext(ctx context.Context, subject string, version int) (*goavro.Codec, error) {
+	return client.SchemaRegistryClient.GetSchemaByVersionContext(ctx, subject, version)
 }
 
 // GetLatestSchema returns the highest version schema for a subject
 func (client *CachedSchemaRegistryClient) GetLatestSchema(subject string) (*goavro.Codec, error) {
-	return client.SchemaRegistryClient.GetLatestSchema(subject)
+	return client.GetLatestSchemaContext(context.Background(), subject)
 }
 
-// CreateSubject will return and cache the id with the given codec
+// GetLatestSchemaContext is GetLatestSchema using the context for the registry requests
+func (client *CachedSchemaRegistryClient) GetLatestSchemaContext(ctx context.Context, subject string) (*goavro.Codec, error) {
+	key := subjectMissKey(subject, latestVersion)
+	if err := client.misses.get(key); err != nil {
+		return nil, err
+	}
+	codec, err := client.SchemaRegistryClient.GetLatestSchemaContext(ctx, subject)
+	client.misses.add(key, err)
+	return codec, err
+}
+
+// CreateSubject will return and cache the id with the given codec.
+// Concurrent registrations of the same schema to a subject result in a single registry request.
 func (client *CachedSchemaRegistryClient) CreateSubject(subject string, codec *goavro.Codec) (int, error) {
-	schemaJson := codec.Schema()
-	client.schemaIdCacheLock.RLock()
-	cachedResult, found := client.schemaIdCache[schemaJson]
-	client.schemaIdCacheLock.RUnlock()
-	if found {
-		return cachedResult, nil
-	}
-	id, err := client.SchemaRegistryClient.CreateSubject(subject, codec)
+	return client.CreateSubjectContext(context.Background(), subject, codec)
+}
+
+// CreateSubjectContext is CreateSubject using the context for the registry requests
+func (client *CachedSchemaRegistryClient) CreateSubjectContext(ctx context.Context, subject string, codec *goavro.Codec) (int, error) {
+	return client.register(ctx, subject, codec.Schema(), nil, func(ctx context.Context) (int, error) {
+		return client.SchemaRegistryClient.CreateSubjectContext(ctx, subject, codec)
+	})
+}
+
+func (client *CachedSchemaRegistryClient) register(ctx context.Context, subject string, schema string, references []SchemaReference, create func(ctx context.Context) (int, error)) (int, error) {
+	key := subject + ":" + schema
+	if cachedResult, found := client.schemaIdCache.get(key); found {
+		return cachedResult.(int), nil
+	}
+	result, err := client.share(ctx, &client.registrations, key, func(ctx context.Context) (interface{}, error) {
+		id, err := create(ctx)
+		if registryErr, ok := err.(*Error); ok && registryErr.ErrorCode == http.StatusConflict {
+			// another producer may have won the race, the conflict is harmless if the schema is registered now
+			metadata, lookupErr := client.SchemaRegistryClient.LookupSchemaContext(ctx, subject, schema, references)
+			if lookupErr != nil {
+				return 0, err
+			}
+			id, err = metadata.ID, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		client.schemaIdCache.add(key, id)
+		client.misses.forgetSubject(subject)
+		return id, nil
+	})
 	if err != nil {
 		return 0, err
 	}
-	client.schemaIdCacheLock.Lock()
-	client.schemaIdCache[schemaJson] = id
-	client.schemaIdCacheLock.Unlock()
-	return id, nil
+	return result.(int), nil
+}
+
+// share runs fn once for the concurrent calls with the same key. fn gets a context detached from the callers and
+// bounded by the timeout of the client, so a caller giving up does not fail the others, each waits on its own ctx.
+func (client *CachedSchemaRegistryClient) share(ctx context.Context, group *singleflight.Group, key string,
+	fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	results := group.DoChan(key, func() (interface{}, error) {
+		timeout := client.SchemaRegistryClient.httpClient.Timeout
+		if timeout <= 0 {
+			return fn(context.Background())
+		}
+		// the timeout is per request, retries may take retries+1 times as long
+		shared, cancel := context.WithTimeout(context.Background(),
+			timeout*time.Duration(client.SchemaRegistryClient.retries+1))
+		defer cancel()
+		return fn(shared)
+	})
+	select {
+	case result := <-results:
+		return result.Val, result.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
 }
 
 // IsSchemaRegistered checks if a specific codec is already registered to a subject
 func (client *CachedSchemaRegistryClient) IsSchemaRegistered(subject string, codec *goavro.Codec) (int, error) {
-	return client.SchemaRegistryClient.IsSchemaRegistered(subject, codec)
+	return client.IsSchemaRegisteredContext(context.Background(), subject, codec)
+}
+
+// IsSchemaRegisteredContext is IsSchemaRegistered using the context for the registry requests
+func (client *CachedSchemaRegistryClient) IsSchemaRegisteredContext(ctx context.Context, subject string, codec *goavro.Codec) (int, error) {
+	return client.SchemaRegistryClient.IsSchemaRegisteredContext(ctx, subject, codec)
 }
 
 // DeleteSubject deletes the subject, should only be used in development
 func (client *CachedSchemaRegistryClient) DeleteSubject(subject string) error {
-	return client.SchemaRegistryClient.DeleteSubject(subject)
+	return client.DeleteSubjectContext(context.Background(), subject)
+}
+
+// DeleteSubjectContext is DeleteSubject using the context for the registry requests
+func (client *CachedSchemaRegistryClient) DeleteSubjectContext(ctx context.Context, subject string) error {
+	err := client.SchemaRegistryClient.DeleteSubjectContext(ctx, subject)
+	if err == nil {
+		client.InvalidateSubject(subject)
+	}
+	return err
 }
 
 // DeleteVersion deletes the a specific version of a subject, should only be used in development.
 func (client *CachedSchemaRegistryClient) DeleteVersion(subject string, version int) error {
-	return client.SchemaRegistryClient.DeleteVersion(subject, version)
+	return client.DeleteVersionContext(context.Background(), subject, version)
+}
+
+// DeleteVersionContext is DeleteVersion using the context for the registry requests
+func (client *CachedSchemaRegistryClient) DeleteVersionContext(ctx context.Context, subject string, version int) error {
+	err := client.SchemaRegistryClient.DeleteVersionContext(ctx, subject, version)
+	if err == nil {
+		client.InvalidateSubject(subject)
+	}
+	return err
+}
+
+// DeleteSubjectPermanently hard deletes a soft deleted subject
+func (client *CachedSchemaRegistryClient) DeleteSubjectPermanently(subject string) error {
+	return client.DeleteSubjectPermanentlyContext(context.Background(), subject)
+}
+
+// DeleteSubjectPermanentlyContext is DeleteSubjectPermanently using the context for the registry requests
+func (client *CachedSchemaRegistryClient) DeleteSubjectPermanentlyContext(ctx context.Context, subject string) error {
+	err := client.SchemaRegistryClient.DeleteSubjectPermanentlyContext(ctx, subject)
+	if err == nil {
+		client.InvalidateSubject(subject)
+	}
+	return err
+}
+
+// DeleteVersionPermanently hard deletes a soft deleted version of a subject
+func (client *CachedSchemaRegistryClient) DeleteVersionPermanently(subject string, version int) error {
+	return client.DeleteVersionPermanentlyContext(context.Background(), subject, version)
+}
+
+// DeleteVersionPermanentlyContext is DeleteVersionPermanently using the context for the registry requests
+func (client *CachedSchemaRegistryClient) DeleteVersionPermanentlyContext(ctx context.Context, subject string, version int) error {
+	err := client.SchemaRegistryClient.DeleteVersionPermanentlyContext(ctx, subject, version)
+	if err == nil {
+		client.InvalidateSubject(subject)
+	}
+	return err
+}
+
+// ListDeletedSubjects returns all subjects, including the soft deleted ones
+func (client *CachedSchemaRegistryClient) ListDeletedSubjects() ([]string, error) {
+	return client.ListDeletedSubjectsContext(context.Background())
+}
+
+// ListDeletedSubjectsContext is ListDeletedSubjects using the context for the registry requests
+func (client *CachedSchemaRegistryClient) ListDeletedSubjectsContext(ctx context.Context) ([]string, error) {
+	return client.SchemaRegistryClient.ListDeletedSubjectsContext(ctx)
+}
+
+// GetSchemaMetadataByID will return and cache the full schema object with the given id
+func (client *CachedSchemaRegistryClient) GetSchemaMetadataByID(id int) (*SchemaMetadata, error) {
+	return client.GetSchemaMetadataByIDContext(context.Background(), id)
+}
+
+// GetSchemaMetadataByIDContext is GetSchemaMetadataByID using the context for the registry requests
+func (client *CachedSchemaRegistryClient) GetSchemaMetadataByIDContext(ctx context.Context, id int) (*SchemaMetadata, error) {
+	if cachedResult, found := client.metadataCache.get(id); found {
+		return cachedResult.(*SchemaMetadata), nil
+	}
+	if err := client.misses.get(idMissKey(id)); err != nil {
+		return nil, err
+	}
+	result, err := client.share(ctx, &client.lookups, "metadata:"+strconv.Itoa(id), func(ctx context.Context) (interface{}, error) {
+		schema, err := client.SchemaRegistryClient.GetSchemaMetadataByIDContext(ctx, id)
+		if err != nil {
+			client.misses.add(idMissKey(id), err)
+			return nil, err
+		}
+		client.metadataCache.add(id, schema)
+		return schema, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*SchemaMetadata), nil
+}
+
+// GetLatestSchemaMetadata returns the full schema object for the highest version of a subject
+func (client *CachedSchemaRegistryClient) GetLatestSchemaMetadata(subject string) (*SchemaMetadata, error) {
+	return client.GetLatestSchemaMetadataContext(context.Background(), subject)
+}
+
+// GetLatestSchemaMetadataContext is GetLatestSchemaMetadata using the context for the registry requests
+func (client *CachedSchemaRegistryClient) GetLatestSchemaMetadataContext(ctx context.Context, subject string) (*SchemaMetadata, error) {
+	key := subjectMissKey(subject, latestVersion)
+	if err := client.misses.get(key); err != nil {
+		return nil, err
+	}
+	metadata, err := client.SchemaRegistryClient.GetLatestSchemaMetadataContext(ctx, subject)
+	client.misses.add(key, err)
+	return metadata, err
+}
+
+// GetSchemaMetadata will return and cache the full schema object for the version of the subject
+func (client *CachedSchemaRegistryClient) GetSchemaMetadata(subject string, version int) (*SchemaMetadata, error) {
+	return client.GetSchemaMetadataContext(context.Background(), subject, version)
+}
+
+// GetSchemaMetadataContext is GetSchemaMetadata using the context for the registry requests
+func (client *CachedSchemaRegistryClient) GetSchemaMetadataContext(ctx context.Context, subject string, version int) (*SchemaMetadata, error) {
+	key := SubjectVersion{subject, version}
+	if cachedResult, found := client.metadataCache.get(key); found {
+		return cachedResult.(*SchemaMetadata), nil
+	}
+	schema, err := client.SchemaRegistryClient.GetSchemaMetadataContext(ctx, subject, version)
+	if err != nil {
+		return nil, err
+	}
+	client.metadataCache.add(key, schema)
+	return schema, nil
+}
+
+// GetSchemaBySubjectAndID returns the full schema object with the given id in the context of the subject
+func (client *CachedSchemaRegistryClient) GetSchemaBySubjectAndID(subject string, id int) (*SchemaMetadata, error) {
+	return client.GetSchemaBySubjectAndIDContext(context.Background(), subject, id)
+}
+
+// GetSchemaBySubjectAndIDContext is GetSchemaBySubjectAndID using the context for the registry requests
+func (client *CachedSchemaRegistryClient) GetSchemaBySubjectAndIDContext(ctx context.Context, subject string, id int) (*SchemaMetadata, error) {
+	return client.SchemaRegistryClient.GetSchemaBySubjectAndIDContext(ctx, subject, id)
+}
+
+// CreateSubjectWithReferences will return and cache the id of the schema importing the given references
+func (client *CachedSchemaRegistryClient) CreateSubjectWithReferences(subject string, schema string, references []SchemaReference) (int, error) {
+	return client.CreateSubjectWithReferencesContext(context.Background(), subject, schema, references)
+}
+
+// CreateSubjectWithReferencesContext is CreateSubjectWithReferences using the context for the registry requests
+func (client *CachedSchemaRegistryClient) CreateSubjectWithReferencesContext(ctx context.Context, subject string, schema string, references []SchemaReference) (int, error) {
+	return client.register(ctx, subject, schema, references, func(ctx context.Context) (int, error) {
+		return client.SchemaRegistryClient.CreateSubjectWithReferencesContext(ctx, subject, schema, references)
+	})
+}
+
+// CreateSubjectWithID will return and cache the id of the schema registered with an explicit id and version
+func (client *CachedSchemaRegistryClient) CreateSubjectWithID(subject string, codec *goavro.Codec, id int, version int) (int, error) {
+	return client.CreateSubjectWithIDContext(context.Background(), subject, codec, id, version)
+}
+
+// CreateSubjectWithIDContext is CreateSubjectWithID using the context for the registry requests
+func (client *CachedSchemaRegistryClient) CreateSubjectWithIDContext(ctx context.Context, subject string, codec *goavro.Codec, id int, version int) (int, error) {
+	return client.register(ctx, subject, codec.Schema(), nil, func(ctx context.Context) (int, error) {
+		return client.SchemaRegistryClient.CreateSubjectWithIDContext(ctx, subject, codec, id, version)
+	})
+}
+
+// CreateSubjectWithSchemaType will return and cache the id of the schema of the given type
+func (client *CachedSchemaRegistryClient) CreateSubjectWithSchemaType(subject string, schemaType string, schema string, references []SchemaReference) (int, error) {
+	return client.CreateSubjectWithSchemaTypeContext(context.Background(), subject, schemaType, schema, references)
+}
+
+// CreateSubjectWithSchemaTypeContext is CreateSubjectWithSchemaType using the context for the registry requests
+func (client *CachedSchemaRegistryClient) CreateSubjectWithSchemaTypeContext(ctx context.Context, subject string, schemaType string, schema string, references []SchemaReference) (int, error) {
+	return client.register(ctx, subject, schema, references, func(ctx context.Context) (int, error) {
+		return client.SchemaRegistryClient.CreateSubjectWithSchemaTypeContext(ctx, subject, schemaType, schema, references)
+	})
+}
+
+// LookupSchema will return and cache the registered schema object for the schema under the subject
+func (client *CachedSchemaRegistryClient) LookupSchema(subject string, schema string, references []SchemaReference) (*SchemaMetadata, error) {
+	return client.LookupSchemaContext(context.Background(), subject, schema, references)
+}
+
+// LookupSchemaContext is LookupSchema using the context for the registry requests
+func (client *CachedSchemaRegistryClient) LookupSchemaContext(ctx context.Context, subject string, schema string, references []SchemaReference) (*SchemaMetadata, error) {
+	key := subject + ":" + schema
+	if cachedResult, found := client.lookupCache.get(key); found {
+		return cachedResult.(*SchemaMetadata), nil
+	}
+	metadata, err := client.SchemaRegistryClient.LookupSchemaContext(ctx, subject, schema, references)
+	if err != nil {
+		return nil, err
+	}
+	client.lookupCache.add(key, metadata)
+	return metadata, nil
+}
+
+// CheckCompatibility tests if the codec is compatible with the version of the subject
+func (client *CachedSchemaRegistryClient) CheckCompatibility(subject string, version int, codec *goavro.Codec) (bool, error) {
+	return client.CheckCompatibilityContext(context.Background(), subject, version, codec)
+}
+
+// CheckCompatibilityContext is CheckCompatibility using the context for the registry requests
+func (client *CachedSchemaRegistryClient) CheckCompatibilityContext(ctx context.Context, subject string, version int, codec *goavro.Codec) (bool, error) {
+	return client.SchemaRegistryClient.CheckCompatibilityContext(ctx, subject, version, codec)
+}
+
+// CheckLatestCompatibility tests if the codec is compatible with the latest version of the subject
+func (client *CachedSchemaRegistryClient) CheckLatestCompatibility(subject string, codec *goavro.Codec) (bool, error) {
+	return client.CheckLatestCompatibilityContext(context.Background(), subject, codec)
+}
+
+// CheckLatestCompatibilityContext is CheckLatestCompatibility using the context for the registry requests
+func (client *CachedSchemaRegistryClient) CheckLatestCompatibilityContext(ctx context.Context, subject string, codec *goavro.Codec) (bool, error) {
+	return client.SchemaRegistryClient.CheckLatestCompatibilityContext(ctx, subject, codec)
+}
+
+// GetCompatibility returns the compatibility level of the subject, the global level if the subject has none
+func (client *CachedSchemaRegistryClient) GetCompatibility(subject string) (string, error) {
+	return client.GetCompatibilityContext(context.Background(), subject)
+}
+
+// GetCompatibilityContext is GetCompatibility using the context for the registry requests
+func (client *CachedSchemaRegistryClient) GetCompatibilityContext(ctx context.Context, subject string) (string, error) {
+	return client.SchemaRegistryClient.GetCompatibilityContext(ctx, subject)
+}
+
+// SetCompatibility sets the compatibility level of the subject
+func (client *CachedSchemaRegistryClient) SetCompatibility(subject string, level string) error {
+	return client.SetCompatibilityContext(context.Background(), subject, level)
+}
+
+// SetCompatibilityContext is SetCompatibility using the context for the registry requests
+func (client *CachedSchemaRegistryClient) SetCompatibilityContext(ctx context.Context, subject string, level string) error {
+	return client.SchemaRegistryClient.SetCompatibilityContext(ctx, subject, level)
+}
+
+// GetGlobalCompatibility returns the compatibility level of the subjects without their own level
+func (client *CachedSchemaRegistryClient) GetGlobalCompatibility() (string, error) {
+	return client.GetGlobalCompatibilityContext(context.Background())
+}
+
+// GetGlobalCompatibilityContext is GetGlobalCompatibility using the context for the registry requests
+func (client *CachedSchemaRegistryClient) GetGlobalCompatibilityContext(ctx context.Context) (string, error) {
+	return client.SchemaRegistryClient.GetGlobalCompatibilityContext(ctx)
+}
+
+// SetGlobalCompatibility sets the compatibility level of the subjects without their own level
+func (client *CachedSchemaRegistryClient) SetGlobalCompatibility(level string) error {
+	return client.SetGlobalCompatibilityContext(context.Background(), level)
+}
+
+// SetGlobalCompatibilityContext is SetGlobalCompatibility using the context for the registry requests
+func (client *CachedSchemaRegistryClient) SetGlobalCompatibilityContext(ctx context.Context, level string) error {
+	return client.SchemaRegistryClient.SetGlobalCompatibilityContext(ctx, level)
+}
+
+// GetMode returns the mode of the subject
+func (client *CachedSchemaRegistryClient) GetMode(subject string) (string, error) {
+	return client.GetModeContext(context.Background(), subject)
+}
+
+// GetModeContext is GetMode using the context for the registry requests
+func (client *CachedSchemaRegistryClient) GetModeContext(ctx context.Context, subject string) (string, error) {
+	return client.SchemaRegistryClient.GetModeContext(ctx, subject)
+}
+
+// SetMode sets the mode of the subject
+func (client *CachedSchemaRegistryClient) SetMode(subject string, mode string) error {
+	return client.SetModeContext(context.Background(), subject, mode)
+}
+
+// SetModeContext is SetMode using the context for the registry requests
+func (client *CachedSchemaRegistryClient) SetModeContext(ctx context.Context, subject string, mode string) error {
+	return client.SchemaRegistryClient.SetModeContext(ctx, subject, mode)
+}
+
+// GetGlobalMode returns the mode of the registry
+func (client *CachedSchemaRegistryClient) GetGlobalMode() (string, error) {
+	return client.GetGlobalModeContext(context.Background())
+}
+
+// GetGlobalModeContext is GetGlobalMode using the context for the registry requests
+func (client *CachedSchemaRegistryClient) GetGlobalModeContext(ctx context.Context) (string, error) {
+	return client.SchemaRegistryClient.GetGlobalModeContext(ctx)
+}
+
+// SetGlobalMode sets the mode of the registry
+func (client *CachedSchemaRegistryClient) SetGlobalMode(mode string) error {
+	return client.SetGlobalModeContext(context.Background(), mode)
+}
+
+// SetGlobalModeContext is SetGlobalMode using the context for the registry requests
+func (client *CachedSchemaRegistryClient) SetGlobalModeContext(ctx context.Context, mode string) error {
+	return client.SchemaRegistryClient.SetGlobalModeContext(ctx, mode)
+}
+
+// GetReferencedBy returns the ids of the schemas referencing the version of the subject
+func (client *CachedSchemaRegistryClient) GetReferencedBy(subject string, version int) ([]int, error) {
+	return client.GetReferencedByContext(context.Background(), subject, version)
+}
+
+// GetReferencedByContext is GetReferencedBy using the context for the registry requests
+func (client *CachedSchemaRegistryClient) GetReferencedByContext(ctx context.Context, subject string, version int) ([]int, error) {
+	return client.SchemaRegistryClient.GetReferencedByContext(ctx, subject, version)
+}
+
+// GetVersionsForSchemaID returns the subject versions registered with the schema id
+func (client *CachedSchemaRegistryClient) GetVersionsForSchemaID(id int) ([]SubjectVersion, error) {
+	return client.GetVersionsForSchemaIDContext(context.Background(), id)
+}
+
+// GetVersionsForSchemaIDContext is GetVersionsForSchemaID using the context for the registry requests
+func (client *CachedSchemaRegistryClient) GetVersionsForSchemaIDContext(ctx context.Context, id int) ([]SubjectVersion, error) {
+	return client.SchemaRegistryClient.GetVersionsForSchemaIDContext(ctx, id)
+}
+
+// Ping checks that the schema registry is reachable
+func (client *CachedSchemaRegistryClient) Ping() error {
+	return client.PingContext(context.Background())
+}
+
+// PingContext is Ping using the context for the registry requests
+func (client *CachedSchemaRegistryClient) PingContext(ctx context.Context) error {
+	return client.SchemaRegistryClient.PingContext(ctx)
+}
+
+// ServerInfo returns the version and commit id of the schema registry
+func (client *CachedSchemaRegistryClient) ServerInfo() (*ServerInfo, error) {
+	return client.ServerInfoContext(context.Background())
+}
+
+// ServerInfoContext is ServerInfo using the context for the registry requests
+func (client *CachedSchemaRegistryClient) ServerInfoContext(ctx context.Context) (*ServerInfo, error) {
+	return client.SchemaRegistryClient.ServerInfoContext(ctx)
 }
@@ -1,7 +1,16 @@
 package kafka
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
 )
 
 func TestCachedSchemaRegistryClient_GetSchema(t *testing.T) {
@@ -140,3 +149,149 @@ func TestCachedSchemaRegistryClient_DeleteVersion(t *testing.T) {
 		t.Errorf("Error delete version: %v", err)
 	}
 }
+
+func TestCachedSchemaRegistryClient_GetSchemaMetadataByID(t *testing.T) {
+	testObject := createSchemaRegistryTestObject(t, "test", 1)
+	mockServer := testObject.MockServer
+	defer mockServer.Close()
+	client := NewCachedSchemaRegistryClient([]string{mockServer.URL})
+	client.GetSchemaMetadataByID(1)
+	schema, err := client.GetSchemaMetadataByID(1)
+	if nil != err {
+		t.Errorf("Error getting schema metadata: %v", err)
+	}
+	if schema.ID != testObject.Id {
+		t.Errorf("Ids do not match. Expected: %d, got: %d", testObject.Id, schema.ID)
+	}
+	if schema.Schema != testObject.Codec.Schema() {
+		t.Errorf("Schemas do not match. Expected: %s, got: %s", testObject.Codec.Schema(), schema.Schema)
+	}
+	if testObject.Count > 1 {
+		t.Errorf("Expected call count of 1, got %d", testObject.Count)
+	}
+}
+
+func TestCachedSchemaRegistryClient_GetLatestSchemaMetadata(t *testing.T) {
+	testObject := createSchemaRegistryTestObject(t, "test", 1)
+	mockServer := testObject.MockServer
+	defer mockServer.Close()
+	client := NewCachedSchemaRegistryClient([]string{mockServer.URL})
+	schema, err := client.GetLatestSchemaMetadata(testObject.Subject)
+	if nil != err {
+		t.Errorf("Error getting latest schema metadata: %v", err)
+	}
+	if schema.Subject != testObject.Subject || schema.Version != 1 || schema.ID != testObject.Id {
+		t.Errorf("Unexpected schema metadata: %+v", schema)
+	}
+}
+
+func TestCachedSchemaRegistryClient_CreateSubjectConcurrent(t *testing.T) {
+	var count int32
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		time.Sleep(50 * time.Millisecond)
+		fmt.Fprintf(w, `{"id": 1}`)
+	}))
+	defer mockServer.Close()
+	client := NewCachedSchemaRegistryClient([]string{mockServer.URL})
+	codec, _ := goavro.NewCodec(`"string"`)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if id, err := client.CreateSubject("test-value", codec); err != nil || id != 1 {
+				t.Errorf("Unexpected result %d, %v", id, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if count != 1 {
+		t.Errorf("Expected a single registration, got %d", count)
+	}
+}
+
+func TestCachedSchemaRegistryClient_CreateSubjectConflict(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/subjects/test-value/versions" {
+			http.Error(w, `{"error_code": 409, "message": "Schema being registered is incompatible"}`, 409)
+			return
+		}
+		fmt.Fprintf(w, `{"subject": "test-value", "id": 7, "version": 2}`)
+	}))
+	defer mockServer.Close()
+	client := NewCachedSchemaRegistryClient([]string{mockServer.URL})
+	codec, _ := goavro.NewCodec(`"string"`)
+	id, err := client.CreateSubject("test-value", codec)
+	if err != nil || id != 7 {
+		t.Errorf("Expected id of the already registered schema, got %d, %v", id, err)
+	}
+}
+
+func TestCachedSchemaRegistryClient_GetSchemaConcurrent(t *testing.T) {
+	var count int32
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		time.Sleep(50 * time.Millisecond)
+		fmt.Fprintf(w, `{"schema": "\"string\""}`)
+	}))
+	defer mockServer.Close()
+	client := NewCachedSchemaRegistryClient([]string{mockServer.URL})
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if codec, err := client.GetSchema(1); err != nil || codec.Schema() != `"string"` {
+				t.Errorf("Unexpected result %v, %v", codec, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if count != 1 {
+		t.Errorf("Expected a single lookup, got %d", count)
+	}
+}
+
+func TestCachedSchemaRegistryClient_GetSchemaSharedCancel(t *testing.T) {
+	var count int32
+	received := make(chan struct{}, 1)
+	release := make(chan struct{})
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		received <- struct{}{}
+		<-release
+		fmt.Fprintf(w, `{"schema": "\"string\""}`)
+	}))
+	defer mockServer.Close()
+	defer close(release)
+	client := NewCachedSchemaRegistryClient([]string{mockServer.URL})
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := client.GetSchemaContext(ctx, 1)
+		first <- err
+	}()
+	<-received
+	second := make(chan error, 1)
+	go func() {
+		codec, err := client.GetSchema(1)
+		if err == nil && codec.Schema() != `"string"` {
+			err = fmt.Errorf("unexpected schema %s", codec.Schema())
+		}
+		second <- err
+	}()
+	// the second caller waits for the request of the first one
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	if err := <-first; err != context.Canceled {
+		t.Errorf("Expected the first caller to give up, got %v", err)
+	}
+	release <- struct{}{}
+	if err := <-second; err != nil {
+		t.Errorf("Expected the shared request to succeed for the second caller, got %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected a single lookup, got %d", count)
+	}
+}
@@ -0,0 +1,331 @@
+package kafka
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+// Headers of the chunks of a value split by a producer
+const (
+	ChunkHeaderID    = "chunk.id"
+	ChunkHeaderIndex = "chunk.index"
+	ChunkHeaderCount = "chunk.count"
+)
+
+// ChunkConfig makes the producer split the values of a topic larger than MaxSize into several messages, for clusters
+// whose message.max.bytes is lower than the values. Consumers reassemble the chunks before decoding the value.
+// The chunks share the key of the message, keyless messages are keyed by the chunk id so the chunks are in one
+// partition, the producer rejects chunking with a partitioner that does not send a key to a single partition,
+// e.g. WithRoundRobinPartitioner. The chunks are committed with the message of the last chunk, but a message committed in between
+// commits them, and a consumer restarted after it reports the remaining chunks of the value as decode errors.
+// The AvroAsyncProducer does not split values.
+type ChunkConfig struct {
+	// MaxSize is the max size in bytes of the value of a chunk
+	MaxSize int
+	// MaxPendingBytes bounds the chunks a consumer buffers for the incomplete values of the topic, 64 MiB when zero.
+	// The oldest values are dropped first.
+	MaxPendingBytes int
+	// MaxPendingAge drops the incomplete values whose first chunk was received longer ago, 10 minutes when zero
+	MaxPendingAge time.Duration
+}
+
+const (
+	defaultChunkMaxPendingBytes = 64 << 20
+	defaultChunkMaxPendingAge   = 10 * time.Minute
+)
+
+func (c *ChunkConfig) maxPendingBytes() int {
+	if c == nil || c.MaxPendingBytes <= 0 {
+		return defaultChunkMaxPendingBytes
+	}
+	return c.MaxPendingBytes
+}
+
+func (c *ChunkConfig) maxPendingAge() time.Duration {
+	if c == nil || c.MaxPendingAge <= 0 {
+		return defaultChunkMaxPendingAge
+	}
+	return c.MaxPendingAge
+}
+
+// forPhysicalTopic returns the settings of the logical topic resolved to the physical topic, the defaults when
+// no configured topic is resolved to it
+func (c *Config) forPhysicalTopic(physical string) TopicConfig {
+	if c != nil {
+		for logical := range c.Topics {
+			if c.PhysicalTopic(logical) == physical {
+				return c.ForTopic(logical)
+			}
+		}
+	}
+	return c.ForTopic(physical)
+}
+
+// validateChunking returns an error when a topic splits values but the partitioner may spread the chunks of a value
+// over several partitions
+func validateChunking(c *Config, partitioner sarama.PartitionerConstructor) error {
+	if c == nil || partitioner == nil {
+		return nil
+	}
+	topics := make([]string, 0, len(c.Topics)+1)
+	if c.Defaults.Chunking != nil {
+		topics = append(topics, "")
+	}
+	for logical := range c.Topics {
+		if c.ForTopic(logical).Chunking != nil {
+			topics = append(topics, logical)
+		}
+	}
+	for _, topic := range topics {
+		if err := checkChunkPartitioner(c.PhysicalTopic(topic), partitioner); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkChunkPartitioner(topic string, partitioner sarama.PartitionerConstructor) error {
+	if !partitioner(topic).RequiresConsistency() {
+		return fmt.Errorf("chunking of topic %s requires a partitioner sending a key to a single partition", topic)
+	}
+	return nil
+}
+
+// split returns the chunks of the message of the producer, an error when the partitioner of the producer may
+// spread them over several partitions, e.g. after SetConfig
+func (ap *AvroProducer) split(msg *sarama.ProducerMessage) ([]*sarama.ProducerMessage, error) {
+	chunks, err := ap.config.forPhysicalTopic(msg.Topic).Chunking.split(msg)
+	if err != nil || len(chunks) < 2 || ap.client == nil {
+		return chunks, err
+	}
+	if err := checkChunkPartitioner(msg.Topic, ap.client.Config().Producer.Partitioner); err != nil {
+		return nil, err
+	}
+	return chunks, nil
+}
+
+// split returns the chunks of the message, or the message itself when its value is not larger than MaxSize
+func (c *ChunkConfig) split(msg *sarama.ProducerMessage) ([]*sarama.ProducerMessage, error) {
+	if c == nil || c.MaxSize <= 0 || msg.Value == nil || msg.Value.Length() <= c.MaxSize {
+		return []*sarama.ProducerMessage{msg}, nil
+	}
+	value, err := msg.Value.Encode()
+	if err != nil {
+		return nil, err
+	}
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+	id := hex.EncodeToString(random)
+	key := msg.Key
+	if key == nil {
+		key = sarama.StringEncoder(id)
+	}
+	count := (len(value) + c.MaxSize - 1) / c.MaxSize
+	chunks := make([]*sarama.ProducerMessage, 0, count)
+	for i := 0; i < count; i++ {
+		end := (i + 1) * c.MaxSize
+		if end > len(value) {
+			end = len(value)
+		}
+		headers := make([]sarama.RecordHeader, 0, len(msg.Headers)+3)
+		headers = append(headers, msg.Headers...)
+		headers = append(headers,
+			sarama.RecordHeader{Key: []byte(ChunkHeaderID), Value: []byte(id)},
+			sarama.RecordHeader{Key: []byte(ChunkHeaderIndex), Value: []byte(strconv.Itoa(i))},
+			sarama.RecordHeader{Key: []byte(ChunkHeaderCount), Value: []byte(strconv.Itoa(count))},
+		)
+		chunks = append(chunks, &sarama.ProducerMessage{
+			Topic:     msg.Topic,
+			Key:       key,
+			Value:     sarama.ByteEncoder(value[i*c.MaxSize : end]),
+			Headers:   headers,
+			Metadata:  msg.Metadata,
+			Partition: msg.Partition,
+			Timestamp: msg.Timestamp,
+		})
+	}
+	return chunks, nil
+}
+
+// chunkAssembler collects the chunks of the values split by producers, per partition
+type chunkAssembler struct {
+	lock    sync.Mutex
+	pending map[chunkGroup]*chunkedValue
+}
+
+type chunkGroup struct {
+	topic     string
+	partition int32
+	id        string
+}
+
+type chunkedValue struct {
+	chunks   [][]byte
+	received int
+	size     int
+	// offsets are the offsets of the chunks received, duplicates included
+	offsets []int64
+	started time.Time
+}
+
+// add returns the message with the reassembled value and the offsets of the previous chunks when m is the last
+// missing chunk of a value, nil while chunks are missing. Messages that are not chunks are returned as they are. The
+// reassembled message is the last chunk with the value of all the chunks and without the chunk headers.
+func (a *chunkAssembler) add(m *sarama.ConsumerMessage) (*sarama.ConsumerMessage, []int64, error) {
+	var id string
+	index, count := -1, -1
+	headers := make([]*sarama.RecordHeader, 0, len(m.Headers))
+	for _, header := range m.Headers {
+		if header == nil {
+			continue
+		}
+		var err error
+		switch string(header.Key) {
+		case ChunkHeaderID:
+			id = string(header.Value)
+		case ChunkHeaderIndex:
+			index, err = strconv.Atoi(string(header.Value))
+		case ChunkHeaderCount:
+			count, err = strconv.Atoi(string(header.Value))
+		default:
+			headers = append(headers, header)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid chunk header %s of message %s/%d@%d: %v", header.Key, m.Topic,
+				m.Partition, m.Offset, err)
+		}
+	}
+	if id == "" {
+		return m, nil, nil
+	}
+	if count <= 0 || index < 0 || index >= count {
+		return nil, nil, fmt.Errorf("invalid chunk %d of %d of message %s/%d@%d", index, count, m.Topic,
+			m.Partition, m.Offset)
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	group := chunkGroup{m.Topic, m.Partition, id}
+	value, found := a.pending[group]
+	if !found {
+		// the chunks are produced in order, the first ones were committed before this consumer claimed the partition
+		if index != 0 {
+			return nil, nil, fmt.Errorf("chunk %d of message %s/%d@%d is missing the previous chunks", index, m.Topic,
+				m.Partition, m.Offset)
+		}
+		if a.pending == nil {
+			a.pending = make(map[chunkGroup]*chunkedValue)
+		}
+		value = &chunkedValue{chunks: make([][]byte, count), started: time.Now()}
+		a.pending[group] = value
+	}
+	if len(value.chunks) != count {
+		return nil, nil, fmt.Errorf("chunk %d of message %s/%d@%d has a count of %d, expected %d", index, m.Topic,
+			m.Partition, m.Offset, count, len(value.chunks))
+	}
+	// a producer retry may have sent the chunk twice
+	if value.chunks[index] == nil {
+		value.chunks[index] = m.Value
+		value.received++
+		value.size += len(m.Value)
+	}
+	if value.received < count {
+		value.offsets = append(value.offsets, m.Offset)
+		return nil, nil, nil
+	}
+	delete(a.pending, group)
+	assembled := *m
+	assembled.Value = make([]byte, 0, value.size)
+	for _, chunk := range value.chunks {
+		assembled.Value = append(assembled.Value, chunk...)
+	}
+	assembled.Headers = headers
+	return &assembled, value.offsets, nil
+}
+
+// revoke drops the pending chunks of the revoked partitions
+func (a *chunkAssembler) revoke(claims map[string][]int32) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	for group := range a.pending {
+		for _, partition := range claims[group.topic] {
+			if partition == group.partition {
+				delete(a.pending, group)
+			}
+		}
+	}
+}
+
+// evictedChunks are the chunks of an incomplete value dropped by the assembler
+type evictedChunks struct {
+	chunkGroup
+	offsets []int64
+}
+
+// evict drops the incomplete values of the topic started before now minus maxAge, then the oldest ones until the
+// chunks of the topic are not larger than maxBytes
+func (a *chunkAssembler) evict(topic string, maxBytes int, maxAge time.Duration, now time.Time) []evictedChunks {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	var groups []chunkGroup
+	size := 0
+	for group, value := range a.pending {
+		if group.topic == topic {
+			groups = append(groups, group)
+			size += value.size
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool { return a.pending[groups[i]].started.Before(a.pending[groups[j]].started) })
+	var evicted []evictedChunks
+	for _, group := range groups {
+		value := a.pending[group]
+		if size <= maxBytes && now.Sub(value.started) <= maxAge {
+			break
+		}
+		size -= value.size
+		delete(a.pending, group)
+		evicted = append(evicted, evictedChunks{group, value.offsets})
+	}
+	return evicted
+}
+
+// evictChunks drops the incomplete values buffered for too long or over the max pending bytes of the topic and
+// reports them as decode errors
+func (ac *AvroConsumer) evictChunks(topic string) []evictedChunks {
+	config := ac.config.forPhysicalTopic(topic).Chunking
+	evicted := ac.chunks.evict(topic, config.maxPendingBytes(), config.maxPendingAge(), time.Now())
+	for _, chunks := range evicted {
+		err := fmt.Errorf("dropped the incomplete value %s of %s/%d after %d chunks from offset %d", chunks.id,
+			chunks.topic, chunks.partition, len(chunks.offsets), chunks.offsets[0])
+		orNop(ac.logger).Error("could not decode message", "topic", chunks.topic, "partition", chunks.partition,
+			"offset", chunks.offsets[0], "error", err)
+		if ac.metrics != nil {
+			ac.metrics.MessageConsumed(chunks.topic, err)
+		}
+		if ac.callbacks.OnError != nil {
+			ac.callbacks.OnError(err)
+		}
+	}
+	return evicted
+}
+
+// markEvicted marks the chunks of the dropped values, so they do not hold back the commits of a worker pool
+func (ac *AvroConsumer) markEvicted(session sarama.ConsumerGroupSession, evicted []evictedChunks) {
+	// the manual offsets of a sequential consumer are not held back, marking would commit messages not acked yet
+	if _, tracked := session.(*trackedSession); ac.commitStrategy == CommitManual && !tracked {
+		return
+	}
+	for _, chunks := range evicted {
+		for _, offset := range chunks.offsets {
+			session.MarkOffset(chunks.topic, chunks.partition, offset+1, "")
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package kafka
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+func TestChunking(t *testing.T) {
+	schema := `{"type": "record", "name": "test", "fields" : [{"name": "val", "type": "string"}]}`
+	registry := newMemorySchemaRegistry()
+	producerMock := &testSyncProducer{}
+	producer := &AvroProducer{producer: producerMock, schemaRegistryClient: registry, config: &Config{
+		TopicResolver: PrefixTopicResolver("prod."),
+		Topics:        map[string]TopicConfig{"test": {Chunking: &ChunkConfig{MaxSize: 10}}},
+	}}
+	large := strings.Repeat("a", 25)
+	if err := producer.Add("test", schema, []byte("k"), []byte(`{"val": "`+large+`"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := producer.Add("other", schema, nil, []byte(`{"val": "`+large+`"}`)); err != nil {
+		t.Fatal(err)
+	}
+	// 5 bytes of header, 1 of length and 25 of string
+	if len(producerMock.sent) != 5 {
+		t.Fatalf("Expected 4 chunks and the unsplit message of the other topic, got %d messages", len(producerMock.sent))
+	}
+	for _, chunk := range producerMock.sent[:4] {
+		if key, _ := chunk.Key.Encode(); string(key) != "k" || chunk.Topic != "prod.test" {
+			t.Errorf("Expected the chunks to have the key of the message, got %s %q", chunk.Topic, key)
+		}
+	}
+	keyless, err := (&ChunkConfig{MaxSize: 2}).split(&sarama.ProducerMessage{Value: sarama.StringEncoder("abc")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first, _ := keyless[0].Key.Encode(); len(keyless) != 2 || len(first) == 0 || keyless[1].Key != keyless[0].Key {
+		t.Errorf("Expected the keyless chunks to be keyed by the chunk id, got %v", keyless)
+	}
+
+	var received []Message
+	consumer := &AvroConsumer{SchemaRegistryClient: registry, callbacks: ConsumerCallbacks{
+		OnDataReceived: func(msg Message) { received = append(received, msg) },
+	}}
+	session := newTestSession(nil)
+	for i, sent := range producerMock.sent[:4] {
+		m := consumedChunk(t, sent, int64(10+i))
+		consumer.handle(session, m)
+		if i == 0 {
+			// a producer retry sent the first chunk twice
+			consumer.handle(session, m)
+		}
+		if i < 3 && (len(received) != 0 || len(session.offsets) != 0) {
+			t.Fatalf("Expected chunk %d to be buffered, got %v and offsets %v", i, received, session.offsets)
+		}
+	}
+	if len(received) != 1 || received[0].Value != `{"val":"`+large+`"}` || received[0].Offset != 13 ||
+		len(received[0].Headers) != 0 {
+		t.Fatalf("Expected the reassembled value, got %+v", received)
+	}
+	if session.offsets[0] != 14 {
+		t.Errorf("Expected the last chunk to be committed, got %v", session.offsets)
+	}
+}
+
+func TestChunking_Concurrency(t *testing.T) {
+	schema := `{"type": "record", "name": "test", "fields" : [{"name": "val", "type": "string"}]}`
+	registry := newMemorySchemaRegistry()
+	producerMock := &testSyncProducer{}
+	producer := &AvroProducer{producer: producerMock, schemaRegistryClient: registry, config: &Config{
+		Topics: map[string]TopicConfig{"test": {Chunking: &ChunkConfig{MaxSize: 10}}},
+	}}
+	for _, value := range []string{strings.Repeat("a", 25), "b"} {
+		if err := producer.Add("test", schema, []byte("k"), []byte(`{"val": "`+value+`"}`)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var lock sync.Mutex
+	var received []int64
+	consumer := &AvroConsumer{SchemaRegistryClient: registry, callbacks: ConsumerCallbacks{
+		OnDataReceived: func(msg Message) {
+			lock.Lock()
+			received = append(received, msg.Offset)
+			lock.Unlock()
+		},
+	}}
+	consumer.SetConcurrency(ConcurrencyConfig{Workers: 2})
+	session := newTestSession(nil)
+	handler := &consumerGroupHandler{consumer: consumer}
+	handler.Setup(session)
+	claim := newTestClaim(len(producerMock.sent))
+	for i, sent := range producerMock.sent {
+		claim.messages <- consumedChunk(t, sent, int64(i))
+	}
+	close(claim.messages)
+	handler.ConsumeClaim(session, claim)
+	handler.Cleanup(session)
+	if len(received) != 2 || received[0] != 3 || received[1] != 4 {
+		t.Errorf("Expected the reassembled value and the small value, got offsets %v", received)
+	}
+	if session.offsets[0] != 5 {
+		t.Errorf("Expected the offsets of the chunks to be committed, got %v", session.offsets)
+	}
+}
+
+func TestNewAvroProducer_ChunkingPartitioner(t *testing.T) {
+	config := Config{Topics: map[string]TopicConfig{"test": {Chunking: &ChunkConfig{MaxSize: 10}}}}
+	_, err := NewAvroProducer(nil, nil, WithConfig(config), WithRoundRobinPartitioner())
+	if err == nil || !strings.Contains(err.Error(), "chunking of topic test") {
+		t.Errorf("Expected chunking to be rejected with the round-robin partitioner, got %v", err)
+	}
+
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).SetBroker(broker.Addr(), broker.BrokerID()),
+	})
+	saramaConfig := sarama.NewConfig()
+	saramaConfig.Producer.Partitioner = sarama.NewRoundRobinPartitioner
+	client, err := sarama.NewClient([]string{broker.Addr()}, saramaConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	producerMock := &testSyncProducer{}
+	producer := &AvroProducer{producer: producerMock, client: client, schemaRegistryClient: newMemorySchemaRegistry()}
+	producer.SetConfig(config)
+	schema := `{"type": "record", "name": "test", "fields" : [{"name": "val", "type": "string"}]}`
+	if err := producer.Add("test", schema, []byte("k"), []byte(`{"val": "`+strings.Repeat("a", 25)+`"}`)); err == nil ||
+		!strings.Contains(err.Error(), "chunking") || len(producerMock.sent) != 0 {
+		t.Errorf("Expected the chunks not to be sent with the round-robin partitioner, got %v", err)
+	}
+	if err := validateChunking(&config, sarama.NewHashPartitioner); err != nil {
+		t.Errorf("Expected chunking to be accepted with the hash partitioner, got %v", err)
+	}
+	if err := validateChunking(&Config{Defaults: TopicConfig{Chunking: &ChunkConfig{MaxSize: 10}}},
+		sarama.NewRandomPartitioner); err == nil {
+		t.Error("Expected default chunking to be rejected with the random partitioner")
+	}
+}
+
+func TestChunking_Eviction(t *testing.T) {
+	schema := `{"type": "record", "name": "test", "fields" : [{"name": "val", "type": "string"}]}`
+	registry := newMemorySchemaRegistry()
+	config := &Config{Topics: map[string]TopicConfig{"test": {Chunking: &ChunkConfig{MaxSize: 10, MaxPendingBytes: 5}}}}
+	producerMock := &testSyncProducer{}
+	producer := &AvroProducer{producer: producerMock, schemaRegistryClient: registry, config: config}
+	for _, value := range []string{strings.Repeat("a", 25), "b"} {
+		if err := producer.Add("test", schema, []byte("k"), []byte(`{"val": "`+value+`"}`)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var lock sync.Mutex
+	var errs []error
+	consumer := &AvroConsumer{SchemaRegistryClient: registry, config: config, callbacks: ConsumerCallbacks{
+		OnError: func(err error) {
+			lock.Lock()
+			errs = append(errs, err)
+			lock.Unlock()
+		},
+	}}
+	consumer.SetConcurrency(ConcurrencyConfig{Workers: 2})
+	session := newTestSession(nil)
+	handler := &consumerGroupHandler{consumer: consumer}
+	handler.Setup(session)
+	claim := newTestClaim(len(producerMock.sent))
+	for i, sent := range producerMock.sent {
+		claim.messages <- consumedChunk(t, sent, int64(i))
+	}
+	close(claim.messages)
+	handler.ConsumeClaim(session, claim)
+	handler.Cleanup(session)
+	// the first chunk is dropped, the next ones miss it
+	if len(errs) != 4 || !strings.Contains(errs[0].Error(), "dropped the incomplete value") {
+		t.Errorf("Expected the dropped value and its remaining chunks to be reported, got %v", errs)
+	}
+	if len(consumer.chunks.pending) != 0 || session.offsets[0] != 5 {
+		t.Errorf("Expected the dropped chunks to be committed, got %v", session.offsets)
+	}
+}
+
+func TestChunkAssembler_Evict(t *testing.T) {
+	var assembler chunkAssembler
+	now := time.Now()
+	for i, id := range []string{"old", "new", "other"} {
+		topic := "test"
+		if id == "other" {
+			topic = "other"
+		}
+		if _, _, err := assembler.add(&sarama.ConsumerMessage{Topic: topic, Offset: int64(i), Value: []byte("abc"),
+			Headers: []*sarama.RecordHeader{
+				{Key: []byte(ChunkHeaderID), Value: []byte(id)},
+				{Key: []byte(ChunkHeaderIndex), Value: []byte("0")},
+				{Key: []byte(ChunkHeaderCount), Value: []byte("2")},
+			}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	assembler.pending[chunkGroup{"test", 0, "old"}].started = now.Add(-time.Hour)
+	assembler.pending[chunkGroup{"test", 0, "new"}].started = now
+	if evicted := assembler.evict("test", 10, 2*time.Hour, now); len(evicted) != 0 {
+		t.Errorf("Expected no value to be dropped within the limits, got %v", evicted)
+	}
+	evicted := assembler.evict("test", 10, time.Minute, now)
+	if len(evicted) != 1 || evicted[0].id != "old" || len(evicted[0].offsets) != 1 || evicted[0].offsets[0] != 0 {
+		t.Errorf("Expected the old value to be dropped, got %v", evicted)
+	}
+	evicted = assembler.evict("test", 2, time.Hour, now)
+	if len(evicted) != 1 || evicted[0].id != "new" || len(assembler.pending) != 1 {
+		t.Errorf("Expected the value over the max bytes to be dropped, not the other topic, got %v", evicted)
+	}
+}
+
+// consumedChunk returns the message as consumed at the offset
+func consumedChunk(t *testing.T, sent *sarama.ProducerMessage, offset int64) *sarama.ConsumerMessage {
+	value, err := sent.Value.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &sarama.ConsumerMessage{Topic: sent.Topic, Offset: offset, Value: value}
+	for j := range sent.Headers {
+		m.Headers = append(m.Headers, &sent.Headers[j])
+	}
+	return m
+}
+
+func TestChunkAssembler_MissingChunks(t *testing.T) {
+	var assembler chunkAssembler
+	chunk := func(index string) *sarama.ConsumerMessage {
+		return &sarama.ConsumerMessage{Topic: "test", Value: []byte(index), Headers: []*sarama.RecordHeader{
+			{Key: []byte(ChunkHeaderID), Value: []byte("id")},
+			{Key: []byte(ChunkHeaderIndex), Value: []byte(index)},
+			{Key: []byte(ChunkHeaderCount), Value: []byte("2")},
+		}}
+	}
+	if _, _, err := assembler.add(chunk("1")); err == nil {
+		t.Error("Expected an error for a chunk without the previous chunks")
+	}
+	if assembled, _, err := assembler.add(chunk("0")); err != nil || assembled != nil {
+		t.Fatalf("Expected the first chunk to be buffered, got %v, %v", assembled, err)
+	}
+	assembler.revoke(map[string][]int32{"test": {0}})
+	if len(assembler.pending) != 0 {
+		t.Errorf("Expected the chunks of the revoked partition to be dropped, got %v", assembler.pending)
+	}
+}
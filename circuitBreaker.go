@@ -0,0 +1,76 @@
+package kafka
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without contacting the schema registry while the circuit breaker is open
+var ErrCircuitOpen = fmt.Errorf("schema registry circuit breaker is open")
+
+// circuitBreaker opens after threshold consecutive failed registry calls and fails fast for cooldown,
+// then lets a single call through to probe whether the registry recovered
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	lock      sync.Mutex
+	failures  int
+	openedAt  time.Time
+	probing   bool
+}
+
+// WithRegistryCircuitBreaker fails registry calls fast with ErrCircuitOpen for cooldown after threshold consecutive
+// failures (http errors and 5XX responses). Schemas cached by CachedSchemaRegistryClient keep being served meanwhile.
+func WithRegistryCircuitBreaker(threshold int, cooldown time.Duration) SchemaRegistryOption {
+	return func(client *SchemaRegistryClient) {
+		client.breaker = &circuitBreaker{threshold: threshold, cooldown: cooldown}
+	}
+}
+
+func (b *circuitBreaker) allow() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.failures < b.threshold {
+		return nil
+	}
+	if b.probing || time.Since(b.openedAt) < b.cooldown {
+		return ErrCircuitOpen
+	}
+	b.probing = true
+	return nil
+}
+
+func (b *circuitBreaker) record(failed bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.probing = false
+	if !failed {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openedAt = time.Now()
+	}
+}
+
+// release ends a probe without a verdict, e.g. when the caller cancelled the call
+func (b *circuitBreaker) release() {
+	b.lock.Lock()
+	b.probing = false
+	b.lock.Unlock()
+}
+
+// registryUnavailable reports whether the error means the registry could not serve the request,
+// rather than rejecting it
+func registryUnavailable(err error) bool {
+	if err == nil {
+		return false
+	}
+	registryErr, ok := err.(*Error)
+	if !ok {
+		return true
+	}
+	return httpStatus(registryErr.ErrorCode) >= 500
+}
@@ -0,0 +1,54 @@
+package kafka
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSchemaRegistryClient_WithRegistryCircuitBreaker(t *testing.T) {
+	status := http.StatusServiceUnavailable
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(status)
+		fmt.Fprintf(w, `{"error_code": %d, "message": "status"}`, status)
+	}))
+	defer server.Close()
+
+	client := NewSchemaRegistryClientWithRetries([]string{server.URL}, 0, WithRegistryCircuitBreaker(2, 50*time.Millisecond))
+	client.Ping()
+	client.Ping()
+	if err := client.Ping(); err != ErrCircuitOpen {
+		t.Errorf("Expected the circuit to open after 2 failures, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected 2 registry calls, got %d", calls)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	status = http.StatusNotFound
+	if err := client.Ping(); err == ErrCircuitOpen {
+		t.Errorf("Expected a probe after the cooldown")
+	}
+	if err := client.Ping(); err == ErrCircuitOpen || calls != 4 {
+		t.Errorf("Expected a rejected request to close the circuit, got %v after %d calls", err, calls)
+	}
+}
+
+func TestCachedSchemaRegistryClient_CircuitOpen(t *testing.T) {
+	testObject := createSchemaRegistryTestObject(t, "test", 1)
+	client := NewCachedSchemaRegistryClient([]string{testObject.MockServer.URL}, WithRegistryCircuitBreaker(1, time.Minute))
+	if _, err := client.GetSchema(1); err != nil {
+		t.Errorf("Expected the schema, got %v", err)
+	}
+	client.SchemaRegistryClient.breaker.record(true)
+	if _, err := client.GetSchema(1); err != nil {
+		t.Errorf("Expected the cached schema while the circuit is open, got %v", err)
+	}
+	if _, err := client.GetSchema(2); err != ErrCircuitOpen {
+		t.Errorf("Expected uncached schemas to fail fast, got %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+package kafka
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Shopify/sarama"
+)
+
+// ClaimCheckHeader marks the messages whose value is a ClaimCheckReference to a blob
+const ClaimCheckHeader = "claimcheck"
+
+// ClaimCheck moves the values larger than Threshold to a blob store, the message carries a reference to the blob and
+// the ClaimCheckHeader instead. The whole framed value is stored, so a consumer fetches it back before decoding it.
+type ClaimCheck struct {
+	Store BlobStore
+	// Threshold is the size in bytes above which values are stored in the blob store, defaults to 512KB
+	Threshold int
+	// Prefix is prepended to the keys of the blobs, e.g. "kafka/"
+	Prefix string
+}
+
+// ClaimCheckReference is the value of a message whose value was moved to the blob store
+type ClaimCheckReference struct {
+	Key    string `json:"key"`
+	Size   int    `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// WithClaimCheck makes a producer store the large values in the blob store and a consumer fetch them back.
+// Consumers of topics with claim checks need the option, other consumers fail to decode the references.
+func WithClaimCheck(claimCheck ClaimCheck) Option {
+	return func(o *options) {
+		o.claimCheck = &claimCheck
+	}
+}
+
+func (c *ClaimCheck) threshold() int {
+	if c.Threshold <= 0 {
+		return 512 << 10
+	}
+	return c.Threshold
+}
+
+// store replaces a value larger than the threshold with a reference to its blob, the blobs are keyed by the topic
+// and the digest of the value so retries store the same blob. A nil claim check does nothing.
+func (c *ClaimCheck) store(ctx context.Context, msg *sarama.ProducerMessage) error {
+	if c == nil || msg.Value == nil || msg.Value.Length() <= c.threshold() {
+		return nil
+	}
+	value, err := msg.Value.Encode()
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256(value)
+	reference := ClaimCheckReference{Key: c.Prefix + msg.Topic + "/" + hex.EncodeToString(digest[:]),
+		Size: len(value), SHA256: hex.EncodeToString(digest[:])}
+	if err := c.Store.Put(ctx, reference.Key, value); err != nil {
+		return fmt.Errorf("could not store the value of %d bytes: %v", len(value), err)
+	}
+	referenceValue, err := json.Marshal(reference)
+	if err != nil {
+		return err
+	}
+	msg.Value = sarama.ByteEncoder(referenceValue)
+	msg.Headers = append(msg.Headers, sarama.RecordHeader{Key: []byte(ClaimCheckHeader), Value: []byte("1")})
+	return nil
+}
+
+// fetch returns the message with the value of its blob when it has the ClaimCheckHeader, the message otherwise
+func (c *ClaimCheck) fetch(ctx context.Context, m *sarama.ConsumerMessage) (*sarama.ConsumerMessage, error) {
+	if !hasClaimCheck(m) {
+		return m, nil
+	}
+	if c == nil {
+		return nil, fmt.Errorf("message %s/%d@%d has a claim check, the consumer has no blob store",
+			m.Topic, m.Partition, m.Offset)
+	}
+	var reference ClaimCheckReference
+	if err := json.Unmarshal(m.Value, &reference); err != nil {
+		return nil, fmt.Errorf("invalid claim check of message %s/%d@%d: %v", m.Topic, m.Partition, m.Offset, err)
+	}
+	value, err := c.Store.Get(ctx, reference.Key)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch the value of message %s/%d@%d: %v", m.Topic, m.Partition, m.Offset, err)
+	}
+	digest := sha256.Sum256(value)
+	if len(value) != reference.Size || hex.EncodeToString(digest[:]) != reference.SHA256 {
+		return nil, fmt.Errorf("the blob %s of message %s/%d@%d does not match its claim check", reference.Key,
+			m.Topic, m.Partition, m.Offset)
+	}
+	fetched := *m
+	fetched.Value = value
+	return &fetched, nil
+}
+
+func hasClaimCheck(m *sarama.ConsumerMessage) bool {
+	for _, header := range m.Headers {
+		if header != nil && string(header.Key) == ClaimCheckHeader {
+			return true
+		}
+	}
+	return false
+}
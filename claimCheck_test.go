@@ -0,0 +1,63 @@
+package kafka
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Shopify/sarama"
+)
+
+func TestClaimCheck(t *testing.T) {
+	schema := `{"type": "record", "name": "test", "fields" : [{"name": "val", "type": "string"}]}`
+	registry := newMemorySchemaRegistry()
+	store := &FileBlobStore{Dir: t.TempDir()}
+	producerMock := &testSyncProducer{}
+	producer := &AvroProducer{producer: producerMock, schemaRegistryClient: registry,
+		claimCheck: &ClaimCheck{Store: store, Threshold: 100, Prefix: "kafka/"}}
+	large := strings.Repeat("a", 200)
+	if err := producer.Add("test", schema, []byte("k"), []byte(`{"val": "small"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := producer.Add("test", schema, []byte("k"), []byte(`{"val": "`+large+`"}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	consumer := &AvroConsumer{SchemaRegistryClient: registry, claimCheck: &ClaimCheck{Store: store}}
+	var consumed []*sarama.ConsumerMessage
+	for i, sent := range producerMock.sent {
+		value, err := sent.Value.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := &sarama.ConsumerMessage{Topic: sent.Topic, Offset: int64(i), Value: value}
+		for j := range sent.Headers {
+			m.Headers = append(m.Headers, &sent.Headers[j])
+		}
+		consumed = append(consumed, m)
+	}
+	if hasClaimCheck(consumed[0]) || !hasClaimCheck(consumed[1]) || len(consumed[1].Value) > 200 {
+		t.Fatalf("Expected only the large value to be replaced with a claim check, got %q", consumed[1].Value)
+	}
+	for i, expected := range []string{"small", large} {
+		msg, err := consumer.ProcessAvroMsg(consumed[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msg.Value != `{"val":"`+expected+`"}` {
+			t.Errorf("Unexpected value %s", msg.Value)
+		}
+	}
+
+	if _, err := (&AvroConsumer{SchemaRegistryClient: registry}).ProcessAvroMsg(consumed[1]); err == nil {
+		t.Error("Expected an error decoding a claim check without a blob store")
+	}
+	if err := store.Put(context.Background(), "kafka/test/other", []byte("other")); err != nil {
+		t.Fatal(err)
+	}
+	tampered := *consumed[1]
+	tampered.Value = []byte(`{"key": "kafka/test/other", "size": 5, "sha256": "00"}`)
+	if _, err := consumer.ProcessAvroMsg(&tampered); err == nil {
+		t.Error("Expected an error decoding a blob not matching its claim check")
+	}
+}
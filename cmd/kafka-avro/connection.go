@@ -0,0 +1,151 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/dangkaka/go-kafka-avro"
+)
+
+// errUsage is returned for invalid flags and errHelp for -h, the flag set already printed the usage
+var (
+	errUsage = errors.New("usage")
+	errHelp  = errors.New("help")
+)
+
+// connectionFlags are the broker and registry flags shared by the commands
+type connectionFlags struct {
+	brokers          string
+	registry         string
+	saslUser         string
+	saslPassword     string
+	tls              bool
+	tlsCA            string
+	tlsCert          string
+	tlsKey           string
+	tlsInsecure      bool
+	registryUser     string
+	registryPassword string
+}
+
+func (c *connectionFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&c.brokers, "brokers", "localhost:9092", "comma separated kafka brokers")
+	fs.StringVar(&c.saslUser, "sasl-user", "", "SASL/PLAIN user of the brokers")
+	fs.StringVar(&c.saslPassword, "sasl-password", "", "SASL/PLAIN password of the brokers")
+	c.registerRegistry(fs)
+}
+
+// registerRegistry registers the flags of the schema registry connection only
+func (c *connectionFlags) registerRegistry(fs *flag.FlagSet) {
+	fs.StringVar(&c.registry, "registry", "http://localhost:8081", "comma separated schema registry urls")
+	fs.BoolVar(&c.tls, "tls", false, "connect to the brokers and the registry over TLS")
+	fs.StringVar(&c.tlsCA, "tls-ca", "", "PEM file of the CA certificates, implies -tls")
+	fs.StringVar(&c.tlsCert, "tls-cert", "", "PEM file of the client certificate for mutual TLS, implies -tls")
+	fs.StringVar(&c.tlsKey, "tls-key", "", "PEM file of the client key for mutual TLS")
+	fs.BoolVar(&c.tlsInsecure, "tls-insecure", false, "do not verify the server certificates, implies -tls")
+	fs.StringVar(&c.registryUser, "registry-user", "", "basic auth user of the schema registry")
+	fs.StringVar(&c.registryPassword, "registry-password", "", "basic auth password of the schema registry")
+}
+
+func (c *connectionFlags) brokerList() []string {
+	return splitList(c.brokers)
+}
+
+func (c *connectionFlags) registryList() []string {
+	return splitList(c.registry)
+}
+
+// options returns the library options of the flags
+func (c *connectionFlags) options() ([]kafka.Option, error) {
+	var opts []kafka.Option
+	if c.saslUser != "" {
+		opts = append(opts, kafka.WithSASL(c.saslUser, c.saslPassword))
+	}
+	tlsConfig, err := c.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		opts = append(opts, kafka.WithTLS(tlsConfig))
+	}
+	registryOpts, err := c.registryOptions()
+	if err != nil {
+		return nil, err
+	}
+	if len(registryOpts) > 0 {
+		opts = append(opts, kafka.WithSchemaRegistryOptions(registryOpts...))
+	}
+	return opts, nil
+}
+
+// registryOptions returns the schema registry options of the flags
+func (c *connectionFlags) registryOptions() ([]kafka.SchemaRegistryOption, error) {
+	var opts []kafka.SchemaRegistryOption
+	tlsConfig, err := c.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		opts = append(opts, kafka.WithRegistryTLS(tlsConfig))
+	}
+	if c.registryUser != "" {
+		opts = append(opts, kafka.WithRegistryBasicAuth(c.registryUser, c.registryPassword))
+	}
+	return opts, nil
+}
+
+// tlsConfig returns the TLS configuration of the flags, nil when TLS is not enabled
+func (c *connectionFlags) tlsConfig() (*tls.Config, error) {
+	if !c.tls && c.tlsCA == "" && c.tlsCert == "" && !c.tlsInsecure {
+		return nil, nil
+	}
+	config := &tls.Config{InsecureSkipVerify: c.tlsInsecure}
+	if c.tlsCA != "" {
+		pem, err := ioutil.ReadFile(c.tlsCA)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in %s", c.tlsCA)
+		}
+	}
+	if c.tlsCert != "" {
+		cert, err := tls.LoadX509KeyPair(c.tlsCert, c.tlsKey)
+		if err != nil {
+			return nil, fmt.Errorf("could not load the client certificate: %v", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseFlags parses the arguments of a command
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err == flag.ErrHelp {
+		return errHelp
+	} else if err != nil {
+		return errUsage
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(fs.Output(), "unexpected arguments %v\n", fs.Args())
+		fs.Usage()
+		return errUsage
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/dangkaka/go-kafka-avro"
+)
+
+// record is the JSON line printed for a consumed message
+type record struct {
+	Topic     string            `json:"topic"`
+	Partition int32             `json:"partition"`
+	Offset    int64             `json:"offset"`
+	Timestamp string            `json:"timestamp,omitempty"`
+	Key       *string           `json:"key"`
+	SchemaId  int               `json:"schemaId,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Value     json.RawMessage   `json:"value"`
+}
+
+func runConsume(args []string, stdout io.Writer, stderr io.Writer) error {
+	var connection connectionFlags
+	var topic, group string
+	var fromBeginning bool
+	var maxMessages int
+	fs := flag.NewFlagSet("consume", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: kafka-avro consume -topic <topic> [flags]")
+		fmt.Fprintln(stderr, "\nPrints the consumed messages as JSON lines until interrupted.")
+		fs.PrintDefaults()
+	}
+	connection.register(fs)
+	fs.StringVar(&topic, "topic", "", "topic to consume")
+	fs.StringVar(&group, "group", "", "consumer group committing the offsets, defaults to a new group")
+	fs.BoolVar(&fromBeginning, "from-beginning", false, "read the partitions without committed offset from the "+
+		"beginning instead of the end")
+	fs.IntVar(&maxMessages, "max-messages", 0, "exit after printing this number of messages")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if topic == "" {
+		fmt.Fprintln(stderr, "-topic is required")
+		fs.Usage()
+		return errUsage
+	}
+	if group == "" {
+		group = "kafka-avro-console-consumer-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	}
+
+	opts, err := connection.options()
+	if err != nil {
+		return err
+	}
+	// the defaults of the library consumer, reading from the end unless asked otherwise
+	config := sarama.NewConfig()
+	config.Version = sarama.V2_0_1_0
+	config.Consumer.Return.Errors = true
+	config.Consumer.Offsets.Initial = sarama.OffsetNewest
+	if fromBeginning {
+		config.Consumer.Offsets.Initial = sarama.OffsetOldest
+	}
+	opts = append([]kafka.Option{kafka.WithSaramaConfig(config)}, opts...)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	var lock sync.Mutex
+	printed := 0
+	callbacks := kafka.ConsumerCallbacks{
+		OnProcess: func(msg kafka.Message) error {
+			line, err := formatMessage(msg)
+			if err != nil {
+				return err
+			}
+			lock.Lock()
+			defer lock.Unlock()
+			if maxMessages > 0 && printed >= maxMessages {
+				return nil
+			}
+			if _, err := fmt.Fprintf(stdout, "%s\n", line); err != nil {
+				return err
+			}
+			if printed++; maxMessages > 0 && printed >= maxMessages {
+				cancel()
+			}
+			return nil
+		},
+		OnError: func(err error) {
+			fmt.Fprintln(stderr, "kafka-avro:", err)
+		},
+	}
+	consumer, err := kafka.NewAvroConsumer(connection.brokerList(), connection.registryList(), topic, group,
+		callbacks, opts...)
+	if err != nil {
+		return err
+	}
+	consumer.Consume(ctx)
+	consumer.Close()
+	return nil
+}
+
+// formatMessage returns the JSON line of a message, the value of a tombstone is null and values that are not
+// JSON are quoted
+func formatMessage(msg kafka.Message) ([]byte, error) {
+	r := record{Topic: msg.Topic, Partition: msg.Partition, Offset: msg.Offset, SchemaId: msg.SchemaId,
+		Value: json.RawMessage("null")}
+	if !msg.Timestamp.IsZero() {
+		r.Timestamp = msg.Timestamp.Format(time.RFC3339Nano)
+	}
+	if msg.Key != "" {
+		r.Key = &msg.Key
+	}
+	if !msg.Tombstone {
+		r.Value = json.RawMessage(msg.Value)
+		// the values of PROTOBUF topics are not JSON
+		if !json.Valid(r.Value) {
+			r.Value, _ = json.Marshal(msg.Value)
+		}
+	}
+	for _, header := range msg.Headers {
+		if r.Headers == nil {
+			r.Headers = make(map[string]string)
+		}
+		r.Headers[string(header.Key)] = string(header.Value)
+	}
+	return json.Marshal(r)
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/dangkaka/go-kafka-avro"
+)
+
+func TestFormatMessage(t *testing.T) {
+	for _, test := range []struct {
+		msg  kafka.Message
+		line string
+	}{
+		{kafka.Message{Topic: "test", Offset: 3, SchemaId: 1, Key: "k", Value: `{"val":1}`,
+			Timestamp: time.Unix(1600000000, 0).UTC(),
+			Headers:   []*sarama.RecordHeader{{Key: []byte("trace"), Value: []byte("1")}}},
+			`{"topic":"test","partition":0,"offset":3,"timestamp":"2020-09-13T12:26:40Z","key":"k","schemaId":1,` +
+				`"headers":{"trace":"1"},"value":{"val":1}}`},
+		{kafka.Message{Topic: "test", Partition: 1, Tombstone: true},
+			`{"topic":"test","partition":1,"offset":0,"key":null,"value":null}`},
+		{kafka.Message{Topic: "test", SchemaId: 2, Value: "\x08\x01"},
+			`{"topic":"test","partition":0,"offset":0,"key":null,"schemaId":2,"value":"\b\u0001"}`},
+	} {
+		line, err := formatMessage(test.msg)
+		if err != nil || string(line) != test.line {
+			t.Errorf("Expected %s, got %s, %v", test.line, line, err)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/dangkaka/go-kafka-avro"
+)
+
+// generateFlags are the flags of the generate command
+type generateFlags struct {
+	subject  string
+	version  int
+	schema   string
+	config   kafka.GoCodeConfig
+	out      string
+	registry connectionFlags
+}
+
+func runGenerate(args []string, stdout io.Writer, stderr io.Writer) error {
+	var f generateFlags
+	fs := flag.NewFlagSet("generate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: kafka-avro generate -subject <subject> [flags]")
+		fmt.Fprintln(stderr, "\nWrites Go structs and typed producer, consumer, encode and decode functions for the schema.")
+		fmt.Fprintln(stderr, "In a go:generate directive: //go:generate kafka-avro generate -subject orders-value -out orders_avro.go")
+		fs.PrintDefaults()
+	}
+	f.registry.registerRegistry(fs)
+	fs.StringVar(&f.subject, "subject", "", "subject of the schema")
+	fs.IntVar(&f.version, "version", 0, "version of the subject, defaults to the latest")
+	fs.StringVar(&f.schema, "schema", "", "schema to generate instead of a subject, or @file to read it from a file")
+	fs.StringVar(&f.config.Package, "package", os.Getenv("GOPACKAGE"), "package of the generated file, "+
+		"defaults to the package running go generate")
+	fs.StringVar(&f.config.TypeName, "type", "", "Go type of the top level record, defaults to the record name")
+	fs.StringVar(&f.out, "out", "", "file to write, defaults to stdout")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if (f.subject == "") == (f.schema == "") || f.config.Package == "" {
+		fmt.Fprintln(stderr, "-package and one of -subject and -schema are required")
+		fs.Usage()
+		return errUsage
+	}
+	schema, err := readSchema(f.schema)
+	if err != nil {
+		return err
+	}
+	f.schema = schema
+
+	var registry kafka.SchemaRegistryClientInterface
+	if f.subject != "" {
+		opts, err := f.registry.registryOptions()
+		if err != nil {
+			return err
+		}
+		registry = kafka.NewSchemaRegistryClient(f.registry.registryList(), opts...)
+	}
+	src, err := generate(registry, f)
+	if err != nil {
+		return err
+	}
+	if f.out == "" {
+		_, err = stdout.Write(src)
+		return err
+	}
+	return ioutil.WriteFile(f.out, src, 0644)
+}
+
+// generate returns the Go file of the schema, or of the subject of the registry
+func generate(registry kafka.SchemaRegistryClientInterface, f generateFlags) ([]byte, error) {
+	if f.subject == "" {
+		return kafka.GenerateGo(f.schema, f.config)
+	}
+	return kafka.GenerateGoForSubject(registry, f.subject, f.version, f.config)
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dangkaka/go-kafka-avro"
+	"github.com/dangkaka/go-kafka-avro/kafkatest"
+	"github.com/linkedin/goavro/v2"
+)
+
+func TestGenerate(t *testing.T) {
+	registry := kafkatest.NewSchemaRegistry()
+	schema := `{"type": "record", "name": "test", "fields" : [{"name": "val", "type": "int"}]}`
+	codec, err := goavro.NewCodec(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := registry.CreateSubject("test-value", codec); err != nil {
+		t.Fatal(err)
+	}
+	src, err := generate(registry, generateFlags{subject: "test-value", config: kafka.GoCodeConfig{Package: "events"}})
+	if err != nil || !strings.Contains(string(src), "from subject test-value version 1.") {
+		t.Errorf("Expected the file of the subject, got %s, %v", src, err)
+	}
+	src, err = generate(nil, generateFlags{schema: schema, config: kafka.GoCodeConfig{Package: "events"}})
+	if err != nil || !strings.Contains(string(src), "type Test struct") {
+		t.Errorf("Expected the file of the schema, got %s, %v", src, err)
+	}
+}
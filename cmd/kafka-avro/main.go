@@ -0,0 +1,71 @@
+// Command kafka-avro produces and consumes avro messages framed for the schema registry, like the
+// kafka-avro-console-producer and kafka-avro-console-consumer tools.
+//
+// Usage:
+//
+//	kafka-avro produce -topic test -schema @record.avsc < records.json
+//	kafka-avro consume -topic test -from-beginning
+//	kafka-avro schema compat -subject test-value -schema @record.avsc
+//	kafka-avro generate -subject test-value -package events -out test_avro.go
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+const usage = `Usage: kafka-avro <command> [flags]
+
+Commands:
+  produce   produce the JSON lines of stdin as avro values
+  consume   print the consumed avro values as JSON lines
+  schema    manage the subjects of the schema registry
+  generate  generate Go types and functions from a subject
+
+Run kafka-avro <command> -h for the flags of a command.
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs the command of the arguments and returns the exit code
+func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	var err error
+	switch args[0] {
+	case "produce":
+		err = runProduce(args[1:], stdin, stderr)
+	case "consume":
+		err = runConsume(args[1:], stdout, stderr)
+	case "schema":
+		err = runSchema(args[1:], stdout, stderr)
+	case "generate":
+		err = runGenerate(args[1:], stdout, stderr)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "unknown command %q\n\n%s", args[0], usage)
+		return 2
+	}
+	if err == errHelp {
+		return 0
+	}
+	if err == errUsage {
+		return 2
+	}
+	if err == errIncompatible {
+		fmt.Fprintln(stderr, "kafka-avro:", err)
+		return 3
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "kafka-avro:", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/dangkaka/go-kafka-avro"
+)
+
+// maxLineSize is the max size of a JSON line read by produce
+const maxLineSize = 16 << 20
+
+func runProduce(args []string, stdin io.Reader, stderr io.Writer) error {
+	var connection connectionFlags
+	var topic, schema, subject, keySeparator string
+	var version int
+	fs := flag.NewFlagSet("produce", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: kafka-avro produce -topic <topic> [-schema <schema>|@<file>] [flags] < values.json")
+		fmt.Fprintln(stderr, "\nProduces every JSON line of stdin, encoded with the schema, or the latest version of the subject.")
+		fs.PrintDefaults()
+	}
+	connection.register(fs)
+	fs.StringVar(&topic, "topic", "", "topic to produce to")
+	fs.StringVar(&schema, "schema", "", "value schema registered to the subject, or @file to read it from a file")
+	fs.StringVar(&subject, "subject", "", "value subject, defaults to <topic>-value")
+	fs.IntVar(&version, "version", 0, "version of the subject encoding the values when -schema is not set, "+
+		"defaults to the latest")
+	fs.StringVar(&keySeparator, "key-separator", "", "splits every line into a key and a value at the first separator")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if topic == "" {
+		fmt.Fprintln(stderr, "-topic is required")
+		fs.Usage()
+		return errUsage
+	}
+	schema, err := readSchema(schema)
+	if err != nil {
+		return err
+	}
+
+	opts, err := connection.options()
+	if err != nil {
+		return err
+	}
+	if subject == "" {
+		subject = topic + "-value"
+	}
+	if version > 0 {
+		opts = append(opts, kafka.WithSchemaVersion(subject, version))
+	}
+	producer, err := kafka.NewAvroProducer(connection.brokerList(), connection.registryList(), opts...)
+	if err != nil {
+		return err
+	}
+	defer producer.Close()
+	producer.SetSubjectNameStrategy(func(topic string, isKey bool, schema string) (string, error) {
+		if isKey {
+			return topic + "-key", nil
+		}
+		return subject, nil
+	})
+
+	produced := 0
+	scanner := bufio.NewScanner(stdin)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		key, value, err := parseLine(scanner.Bytes(), keySeparator)
+		if err != nil {
+			return fmt.Errorf("line %d: %v", line, err)
+		}
+		if value == nil {
+			continue
+		}
+		if schema != "" {
+			err = producer.Add(topic, schema, key, value)
+		} else {
+			err = producer.ProduceJSON(topic, key, value)
+		}
+		if err != nil {
+			return fmt.Errorf("line %d: %v", line, err)
+		}
+		produced++
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	fmt.Fprintf(stderr, "produced %d messages to %s\n", produced, topic)
+	return nil
+}
+
+// readSchema returns the schema of a flag, read from a file when the flag is @file
+func readSchema(flag string) (string, error) {
+	if !strings.HasPrefix(flag, "@") {
+		return flag, nil
+	}
+	schema, err := ioutil.ReadFile(flag[1:])
+	return string(schema), err
+}
+
+// parseLine returns the key and the value of a line, the key is nil without separator and the value is nil for
+// blank lines
+func parseLine(line []byte, keySeparator string) ([]byte, []byte, error) {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return nil, nil, nil
+	}
+	if keySeparator == "" {
+		return nil, line, nil
+	}
+	i := bytes.Index(line, []byte(keySeparator))
+	if i < 0 {
+		return nil, nil, fmt.Errorf("no key separator %q", keySeparator)
+	}
+	key, value := line[:i], bytes.TrimSpace(line[i+len(keySeparator):])
+	if len(value) == 0 {
+		return nil, nil, fmt.Errorf("no value after the key")
+	}
+	return key, value, nil
+}
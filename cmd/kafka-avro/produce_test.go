@@ -0,0 +1,37 @@
+package main
+
+import (
+	"io"
+	"testing"
+)
+
+func TestParseLine(t *testing.T) {
+	for _, test := range []struct {
+		line, separator, key, value string
+		err                         bool
+	}{
+		{line: `{"val":1}`, value: `{"val":1}`},
+		{line: "  ", value: ""},
+		{line: `k1:{"val":1}`, separator: ":", key: "k1", value: `{"val":1}`},
+		{line: `k1 | {"val":"a|b"}`, separator: "|", key: "k1 ", value: `{"val":"a|b"}`},
+		{line: `{"val":1}`, separator: "\t", err: true},
+		{line: "k1:", separator: ":", err: true},
+	} {
+		key, value, err := parseLine([]byte(test.line), test.separator)
+		if (err != nil) != test.err || string(key) != test.key || string(value) != test.value {
+			t.Errorf("Unexpected key %q, value %q and error %v for %q", key, value, err, test.line)
+		}
+	}
+}
+
+func TestRun_Usage(t *testing.T) {
+	if code := run(nil, nil, nil, io.Discard); code != 2 {
+		t.Errorf("Expected the usage without command, got %d", code)
+	}
+	if code := run([]string{"produce"}, nil, nil, io.Discard); code != 2 {
+		t.Errorf("Expected the topic to be required, got %d", code)
+	}
+	if code := run([]string{"produce", "-h"}, nil, nil, io.Discard); code != 0 {
+		t.Errorf("Expected the help to succeed, got %d", code)
+	}
+}
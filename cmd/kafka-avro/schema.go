@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/dangkaka/go-kafka-avro"
+	"github.com/linkedin/goavro/v2"
+)
+
+const schemaUsage = `Usage: kafka-avro schema <command> [flags]
+
+Commands:
+  register  register a schema to a subject and print its id
+  list      print the subjects
+  versions  print the versions of a subject
+  get       print a version of a subject, or a schema by id, as JSON
+  delete    delete a subject or a version of it
+  compat    check that a schema is compatible with a version of a subject, exits with 3 when it is not
+
+Run kafka-avro schema <command> -h for the flags of a command.
+`
+
+// errIncompatible makes compat exit with a distinct code, so pipelines can tell it from registry errors
+var errIncompatible = errors.New("the schema is not compatible")
+
+// schemaFlags are the flags of the schema commands, each command uses some of them
+type schemaFlags struct {
+	subject    string
+	schema     string
+	schemaType string
+	version    int
+	id         int
+	deleted    bool
+	permanent  bool
+}
+
+func runSchema(args []string, stdout io.Writer, stderr io.Writer) error {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, schemaUsage)
+		return errUsage
+	}
+	command := args[0]
+	var connection connectionFlags
+	var f schemaFlags
+	fs := flag.NewFlagSet("schema "+command, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	connection.registerRegistry(fs)
+	switch command {
+	case "register":
+		fs.StringVar(&f.subject, "subject", "", "subject to register the schema to")
+		fs.StringVar(&f.schema, "schema", "", "schema, or @file to read it from a file")
+		fs.StringVar(&f.schemaType, "type", kafka.SchemaTypeAvro, "schema type, AVRO, JSON or PROTOBUF")
+	case "list":
+		fs.BoolVar(&f.deleted, "deleted", false, "include the soft deleted subjects")
+	case "versions":
+		fs.StringVar(&f.subject, "subject", "", "subject")
+	case "get":
+		fs.StringVar(&f.subject, "subject", "", "subject")
+		fs.IntVar(&f.version, "version", 0, "version of the subject, defaults to the latest")
+		fs.IntVar(&f.id, "id", 0, "id of the schema, instead of a subject")
+	case "delete":
+		fs.StringVar(&f.subject, "subject", "", "subject")
+		fs.IntVar(&f.version, "version", 0, "version to delete, defaults to the whole subject")
+		fs.BoolVar(&f.permanent, "permanent", false, "hard delete after the soft delete, the schemas are removed")
+	case "compat":
+		fs.StringVar(&f.subject, "subject", "", "subject")
+		fs.StringVar(&f.schema, "schema", "", "avro schema, or @file to read it from a file")
+		fs.IntVar(&f.version, "version", 0, "version of the subject, defaults to the latest")
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, schemaUsage)
+		return errHelp
+	default:
+		fmt.Fprintf(stderr, "unknown schema command %q\n\n%s", command, schemaUsage)
+		return errUsage
+	}
+	if err := parseFlags(fs, args[1:]); err != nil {
+		return err
+	}
+	if f.subject == "" && command != "list" && (command != "get" || f.id == 0) {
+		fmt.Fprintln(stderr, "-subject is required")
+		fs.Usage()
+		return errUsage
+	}
+	schema, err := readSchema(f.schema)
+	if err != nil {
+		return err
+	}
+	f.schema = schema
+
+	opts, err := connection.registryOptions()
+	if err != nil {
+		return err
+	}
+	return schemaCommand(kafka.NewSchemaRegistryClient(connection.registryList(), opts...), command, f, stdout)
+}
+
+// schemaCommand runs the schema command with the registry
+func schemaCommand(registry kafka.SchemaRegistryClientInterface, command string, f schemaFlags, stdout io.Writer) error {
+	switch command {
+	case "register":
+		if f.schema == "" {
+			return fmt.Errorf("-schema is required")
+		}
+		id, err := registry.CreateSubjectWithSchemaType(f.subject, f.schemaType, f.schema, nil)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, id)
+	case "list":
+		list := registry.GetSubjects
+		if f.deleted {
+			list = registry.ListDeletedSubjects
+		}
+		subjects, err := list()
+		if err != nil {
+			return err
+		}
+		for _, subject := range subjects {
+			fmt.Fprintln(stdout, subject)
+		}
+	case "versions":
+		versions, err := registry.GetVersions(f.subject)
+		if err != nil {
+			return err
+		}
+		for _, version := range versions {
+			fmt.Fprintln(stdout, version)
+		}
+	case "get":
+		var metadata *kafka.SchemaMetadata
+		var err error
+		switch {
+		case f.id > 0:
+			metadata, err = registry.GetSchemaMetadataByID(f.id)
+		case f.version > 0:
+			metadata, err = registry.GetSchemaMetadata(f.subject, f.version)
+		default:
+			metadata, err = registry.GetLatestSchemaMetadata(f.subject)
+		}
+		if err != nil {
+			return err
+		}
+		line, err := json.Marshal(metadata)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "%s\n", line)
+	case "delete":
+		var err error
+		if f.version > 0 {
+			err = registry.DeleteVersion(f.subject, f.version)
+		} else {
+			err = registry.DeleteSubject(f.subject)
+		}
+		// the hard delete of an already soft deleted subject succeeds, its error is the one reported
+		if f.permanent {
+			if f.version > 0 {
+				err = registry.DeleteVersionPermanently(f.subject, f.version)
+			} else {
+				err = registry.DeleteSubjectPermanently(f.subject)
+			}
+		}
+		return err
+	case "compat":
+		if f.schema == "" {
+			return fmt.Errorf("-schema is required")
+		}
+		codec, err := goavro.NewCodec(f.schema)
+		if err != nil {
+			return err
+		}
+		var compatible bool
+		if f.version > 0 {
+			compatible, err = registry.CheckCompatibility(f.subject, f.version, codec)
+		} else {
+			compatible, err = registry.CheckLatestCompatibility(f.subject, codec)
+		}
+		if err != nil {
+			return err
+		}
+		if !compatible {
+			fmt.Fprintln(stdout, "incompatible")
+			return errIncompatible
+		}
+		fmt.Fprintln(stdout, "compatible")
+	}
+	return nil
+}
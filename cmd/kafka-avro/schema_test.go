@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/dangkaka/go-kafka-avro"
+	"github.com/dangkaka/go-kafka-avro/kafkatest"
+	"github.com/linkedin/goavro/v2"
+)
+
+// incompatibleRegistry rejects every schema, the memory registry accepts them all
+type incompatibleRegistry struct {
+	*kafkatest.SchemaRegistry
+}
+
+func (incompatibleRegistry) CheckLatestCompatibility(subject string, codec *goavro.Codec) (bool, error) {
+	return false, nil
+}
+
+func TestSchemaCommand(t *testing.T) {
+	registry := kafkatest.NewSchemaRegistry()
+	schema := `{"type": "record", "name": "test", "fields" : [{"name": "val", "type": "int"}]}`
+	output := func(command string, f schemaFlags) string {
+		var stdout bytes.Buffer
+		if err := schemaCommand(registry, command, f, &stdout); err != nil {
+			t.Fatalf("%s: %v", command, err)
+		}
+		return strings.TrimSpace(stdout.String())
+	}
+
+	id := output("register", schemaFlags{subject: "test-value", schema: schema, schemaType: kafka.SchemaTypeAvro})
+	if id == "" {
+		t.Fatalf("Expected the id of the schema")
+	}
+	if subjects := output("list", schemaFlags{}); subjects != "test-value" {
+		t.Errorf("Unexpected subjects %q", subjects)
+	}
+	if versions := output("versions", schemaFlags{subject: "test-value"}); versions != "1" {
+		t.Errorf("Unexpected versions %q", versions)
+	}
+	if got := output("get", schemaFlags{subject: "test-value", version: 1}); !strings.Contains(got, `"version":1`) {
+		t.Errorf("Unexpected metadata %s", got)
+	}
+	if got := output("get", schemaFlags{id: 1}); !strings.Contains(got, `"id":1`) {
+		t.Errorf("Unexpected metadata %s", got)
+	}
+	if got := output("compat", schemaFlags{subject: "test-value", schema: schema}); got != "compatible" {
+		t.Errorf("Expected the schema to be compatible, got %q", got)
+	}
+	var stdout bytes.Buffer
+	err := schemaCommand(incompatibleRegistry{registry}, "compat", schemaFlags{subject: "test-value", schema: schema},
+		&stdout)
+	if err != errIncompatible || strings.TrimSpace(stdout.String()) != "incompatible" {
+		t.Errorf("Expected the schema to be incompatible, got %q, %v", stdout.String(), err)
+	}
+
+	output("delete", schemaFlags{subject: "test-value", permanent: true})
+	if subjects := output("list", schemaFlags{deleted: true}); subjects != "" {
+		t.Errorf("Expected the subject to be hard deleted, got %q", subjects)
+	}
+}
@@ -0,0 +1,425 @@
+package kafka
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// GoCodeConfig controls the file generated by GenerateGo
+type GoCodeConfig struct {
+	// Package is the package of the generated file
+	Package string
+	// TypeName is the Go type of the top level record, defaults to the record name
+	TypeName string
+	// Subject and Version are the origin of the schema written in the header of the file, when known
+	Subject string
+	Version int
+}
+
+// GenerateGo returns a Go file declaring a struct for every record of the schema, tagged the way StructConverter
+// matches fields, a string type for every enum and an array type for every fixed. Unions with null become pointers,
+// other unions interface{}, timestamps and dates time.Time, times time.Duration, decimals big.Rat and uuids UUID.
+// For the top level record T the file has the TSchema constant and functions wired to this package:
+// NewTProducer returns a TypedProducer of T, ConsumeT calls ConsumeInto with T, EncodeT and DecodeT convert T to and
+// from the binary avro encoding of the schema.
+func GenerateGo(schema string, config GoCodeConfig) ([]byte, error) {
+	if config.Package == "" {
+		return nil, fmt.Errorf("a package name is required")
+	}
+	parsed, err := parseSchemaJSON(schema)
+	if err != nil {
+		return nil, err
+	}
+	top, ok := parsed.(map[string]interface{})
+	if !ok || (top["type"] != "record" && top["type"] != "error") {
+		return nil, fmt.Errorf("the top level type of the schema must be a record")
+	}
+	// the schema must compile for the generated functions to work
+	if _, err := NewStructConverter(schema); err != nil {
+		return nil, err
+	}
+	g := &goGenerator{named: make(map[string]string), declared: make(map[string]string), imports: make(map[string]bool),
+		topName: config.TypeName}
+	typeName, err := g.typeOf(top, "")
+	if err != nil {
+		return nil, err
+	}
+	g.imports["context"] = true
+
+	var out bytes.Buffer
+	origin := "a schema"
+	if config.Subject != "" {
+		origin = "subject " + config.Subject
+		if config.Version > 0 {
+			origin += " version " + strconv.Itoa(config.Version)
+		}
+	}
+	fmt.Fprintf(&out, "// Code generated by kafka-avro generate from %s. DO NOT EDIT.\n\n", origin)
+	fmt.Fprintf(&out, "package %s\n\n", config.Package)
+	out.WriteString("import (\n")
+	imports := make([]string, 0, len(g.imports))
+	for imp := range g.imports {
+		imports = append(imports, imp)
+	}
+	sort.Strings(imports)
+	for _, imp := range imports {
+		fmt.Fprintf(&out, "%q\n", imp)
+	}
+	out.WriteString("\n\"github.com/dangkaka/go-kafka-avro\"\n)\n\n")
+	fmt.Fprintf(&out, "// %sSchema is the avro schema of %s, from %s\n", typeName, typeName, origin)
+	fmt.Fprintf(&out, "const %sSchema = %s\n\n", typeName, goStringLiteral(schema))
+	for _, decl := range g.decls {
+		out.WriteString(decl)
+	}
+	converter := lowerFirst(typeName) + "Converter"
+	fmt.Fprintf(&out, `var %[2]s, %[2]sErr = kafka.NewStructConverter(%[1]sSchema)
+
+// New%[1]sProducer returns a producer of %[1]s values to the topic, %[1]sSchema is registered to its value subject
+func New%[1]sProducer(producer *kafka.AvroProducer, topic string) (*kafka.TypedProducer[%[1]s], error) {
+	return kafka.NewTypedProducerWithSchema[%[1]s](producer, topic, %[1]sSchema)
+}
+
+// Consume%[1]s consumes the values of the consumer decoded into %[1]s, see kafka.ConsumeInto
+func Consume%[1]s(ctx context.Context, consumer *kafka.AvroConsumer,
+	handle func(context.Context, %[1]s, kafka.Metadata) error) error {
+	return kafka.ConsumeInto(ctx, consumer, handle)
+}
+
+// Encode%[1]s returns the binary avro encoding of v, without the schema registry framing
+func Encode%[1]s(v %[1]s) ([]byte, error) {
+	if %[2]sErr != nil {
+		return nil, %[2]sErr
+	}
+	native, err := %[2]s.Native(v)
+	if err != nil {
+		return nil, err
+	}
+	return %[2]s.Codec.BinaryFromNative(nil, native)
+}
+
+// Decode%[1]s decodes %[1]s from its binary avro encoding
+func Decode%[1]s(b []byte) (%[1]s, error) {
+	var v %[1]s
+	if %[2]sErr != nil {
+		return v, %[2]sErr
+	}
+	native, _, err := %[2]s.Codec.NativeFromBinary(b)
+	if err != nil {
+		return v, err
+	}
+	err = %[2]s.Decode(native, &v)
+	return v, err
+}
+`, typeName, converter)
+	return format.Source(out.Bytes())
+}
+
+// GenerateGoForSubject is GenerateGo for a version of the subject, 0 for the latest. The schemas the version
+// references are inlined, the subject and the version are written in the header of the file.
+func GenerateGoForSubject(client SchemaRegistryClientInterface, subject string, version int,
+	config GoCodeConfig) ([]byte, error) {
+	var metadata *SchemaMetadata
+	var err error
+	if version > 0 {
+		metadata, err = client.GetSchemaMetadata(subject, version)
+	} else {
+		metadata, err = client.GetLatestSchemaMetadata(subject)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if metadata.SchemaType != "" && metadata.SchemaType != SchemaTypeAvro {
+		return nil, fmt.Errorf("subject %s has a %s schema, only avro schemas can be generated", subject,
+			metadata.SchemaType)
+	}
+	schema := metadata.Schema
+	if len(metadata.References) > 0 {
+		schemas := make(map[string]string)
+		if err := collectSubjectReferences(client, metadata.References, schemas, make(map[string]bool)); err != nil {
+			return nil, err
+		}
+		if schema, err = inlineReferences(schema, schemas); err != nil {
+			return nil, err
+		}
+	}
+	config.Subject, config.Version = subject, metadata.Version
+	return GenerateGo(schema, config)
+}
+
+// collectSubjectReferences is SchemaRegistryClient.collectReferences for any registry client
+func collectSubjectReferences(client SchemaRegistryClientInterface, references []SchemaReference,
+	schemas map[string]string, visited map[string]bool) error {
+	for _, reference := range references {
+		key := reference.Subject + ":" + strconv.Itoa(reference.Version)
+		if visited[key] {
+			continue
+		}
+		visited[key] = true
+		metadata, err := client.GetSchemaMetadata(reference.Subject, reference.Version)
+		if err != nil {
+			return fmt.Errorf("could not resolve reference %s: %s", reference.Name, err)
+		}
+		name := reference.Name
+		if fullName, err := schemaFullName(metadata.Schema); err == nil {
+			name = fullName
+		}
+		schemas[name] = metadata.Schema
+		if err := collectSubjectReferences(client, metadata.References, schemas, visited); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// goGenerator declares the Go types of the named types of a schema in the order they are defined
+type goGenerator struct {
+	// named maps the full names of the named types to their Go types
+	named map[string]string
+	// declared maps the declared Go identifiers to the full names they were declared for
+	declared map[string]string
+	decls    []string
+	imports  map[string]bool
+	// topName is the Go type of the top level record when set
+	topName string
+}
+
+var goPrimitiveTypes = map[string]string{
+	"null": "interface{}", "boolean": "bool", "int": "int32", "long": "int64",
+	"float": "float32", "double": "float64", "bytes": "[]byte", "string": "string",
+}
+
+// typeOf returns the Go type of a schema node, declaring the named types it defines
+func (g *goGenerator) typeOf(node interface{}, namespace string) (string, error) {
+	switch n := node.(type) {
+	case string:
+		if goType, ok := goPrimitiveTypes[n]; ok {
+			return goType, nil
+		}
+		fullName := qualifyName(n, namespace)
+		goType, ok := g.named[fullName]
+		if !ok {
+			return "", fmt.Errorf("unknown named type: %s", fullName)
+		}
+		return goType, nil
+	case []interface{}:
+		var members []interface{}
+		nullable := false
+		for _, branch := range n {
+			if branch == "null" {
+				nullable = true
+				continue
+			}
+			members = append(members, branch)
+		}
+		// named types defined in any member must be declared
+		types := make([]string, len(members))
+		for i, member := range members {
+			goType, err := g.typeOf(member, namespace)
+			if err != nil {
+				return "", err
+			}
+			types[i] = goType
+		}
+		if len(members) != 1 {
+			return "interface{}", nil
+		}
+		if nullable {
+			return "*" + types[0], nil
+		}
+		return types[0], nil
+	case map[string]interface{}:
+		return g.complexType(n, namespace)
+	}
+	return "", fmt.Errorf("unsupported schema: %v", node)
+}
+
+func (g *goGenerator) complexType(node map[string]interface{}, namespace string) (string, error) {
+	typeName, _ := node["type"].(string)
+	if goType, ok := g.logicalType(typeName, node); ok {
+		if typeName == "fixed" {
+			fullName, _ := definedName(node, namespace)
+			g.named[fullName] = goType
+		}
+		return goType, nil
+	}
+	switch typeName {
+	case "record", "error":
+		return g.record(node, namespace)
+	case "enum":
+		return g.enum(node, namespace)
+	case "fixed":
+		fullName, _ := definedName(node, namespace)
+		goName, err := g.declare(fullName, "")
+		if err != nil {
+			return "", err
+		}
+		size, _ := node["size"].(float64)
+		g.decls = append(g.decls, fmt.Sprintf("// %s is the avro fixed %s\ntype %s [%d]byte\n\n",
+			goName, fullName, goName, int(size)))
+		return goName, nil
+	case "array":
+		items, err := g.typeOf(node["items"], namespace)
+		return "[]" + items, err
+	case "map":
+		values, err := g.typeOf(node["values"], namespace)
+		return "map[string]" + values, err
+	}
+	return g.typeOf(node["type"], namespace)
+}
+
+// logicalType returns the Go type StructConverter uses for the logical type of the node, if it has one goavro knows
+func (g *goGenerator) logicalType(typeName string, node map[string]interface{}) (string, bool) {
+	logical, _ := node["logicalType"].(string)
+	switch {
+	case logical == "timestamp-millis" && typeName == "long", logical == "timestamp-micros" && typeName == "long",
+		logical == "date" && typeName == "int":
+		g.imports["time"] = true
+		return "time.Time", true
+	case logical == "time-millis" && typeName == "int", logical == "time-micros" && typeName == "long":
+		g.imports["time"] = true
+		return "time.Duration", true
+	case logical == "decimal" && (typeName == "bytes" || typeName == "fixed"):
+		g.imports["math/big"] = true
+		return "big.Rat", true
+	case logical == "uuid" && typeName == "string":
+		return "kafka.UUID", true
+	}
+	return "", false
+}
+
+func (g *goGenerator) record(node map[string]interface{}, namespace string) (string, error) {
+	fullName, recordNamespace := definedName(node, namespace)
+	preferred := ""
+	if len(g.decls) == 0 {
+		preferred = g.topName
+	}
+	goName, err := g.declare(fullName, preferred)
+	if err != nil {
+		return "", err
+	}
+	// the record is declared before the types its fields define
+	index := len(g.decls)
+	g.decls = append(g.decls, "")
+
+	var decl strings.Builder
+	fmt.Fprintf(&decl, "// %s is the avro record %s\n", goName, fullName)
+	writeDoc(&decl, node["doc"], "", true)
+	fmt.Fprintf(&decl, "type %s struct {\n", goName)
+	fieldNames := make(map[string]bool)
+	fields, _ := node["fields"].([]interface{})
+	for _, f := range fields {
+		field, ok := f.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := field["name"].(string)
+		fieldType, err := g.typeOf(field["type"], recordNamespace)
+		if err != nil {
+			return "", fmt.Errorf("field %s of %s: %v", name, fullName, err)
+		}
+		fieldName := goIdentifier(name)
+		if fieldNames[fieldName] {
+			return "", fmt.Errorf("fields of %s have the same Go name %s", fullName, fieldName)
+		}
+		fieldNames[fieldName] = true
+		writeDoc(&decl, field["doc"], "\t", false)
+		fmt.Fprintf(&decl, "\t%s %s `avro:%q`\n", fieldName, fieldType, name)
+	}
+	decl.WriteString("}\n\n")
+	g.decls[index] = decl.String()
+	return goName, nil
+}
+
+func (g *goGenerator) enum(node map[string]interface{}, namespace string) (string, error) {
+	fullName, _ := definedName(node, namespace)
+	goName, err := g.declare(fullName, "")
+	if err != nil {
+		return "", err
+	}
+	var decl strings.Builder
+	fmt.Fprintf(&decl, "// %s is the avro enum %s\n", goName, fullName)
+	writeDoc(&decl, node["doc"], "", true)
+	fmt.Fprintf(&decl, "type %s string\n\n", goName)
+	symbols, _ := node["symbols"].([]interface{})
+	if len(symbols) > 0 {
+		fmt.Fprintf(&decl, "// The symbols of %s\nconst (\n", goName)
+		for _, s := range symbols {
+			symbol, _ := s.(string)
+			constant := goName + goIdentifier(strings.ToLower(symbol))
+			if other, ok := g.declared[constant]; ok {
+				return "", fmt.Errorf("symbol %s of %s and %s have the same Go name %s", symbol, fullName, other, constant)
+			}
+			g.declared[constant] = fullName
+			fmt.Fprintf(&decl, "\t%s %s = %q\n", constant, goName, symbol)
+		}
+		decl.WriteString(")\n\n")
+	}
+	g.decls = append(g.decls, decl.String())
+	return goName, nil
+}
+
+// declare returns the Go identifier of a named type, preferred or the exported form of its name
+func (g *goGenerator) declare(fullName string, preferred string) (string, error) {
+	goName := preferred
+	if goName == "" {
+		goName = goIdentifier(fullName[strings.LastIndex(fullName, ".")+1:])
+	}
+	if other, ok := g.declared[goName]; ok {
+		return "", fmt.Errorf("%s and %s have the same Go name %s", fullName, other, goName)
+	}
+	g.declared[goName] = fullName
+	g.named[fullName] = goName
+	return goName, nil
+}
+
+// goIdentifier returns the exported camel case form of an avro name, e.g. CustomerId for customer_id
+func goIdentifier(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	identifier := b.String()
+	if identifier == "" || unicode.IsDigit(rune(identifier[0])) {
+		identifier = "X" + identifier
+	}
+	return identifier
+}
+
+func lowerFirst(s string) string {
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+// writeDoc writes the doc of a schema node as a comment, separated from the comment above it when asked
+func writeDoc(b *strings.Builder, doc interface{}, indent string, separate bool) {
+	text, _ := doc.(string)
+	if separate && strings.TrimSpace(text) != "" {
+		fmt.Fprintf(b, "%s//\n", indent)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			fmt.Fprintf(b, "%s// %s\n", indent, line)
+		}
+	}
+}
+
+// goStringLiteral returns a raw string literal of s, or a quoted one when s has a backquote
+func goStringLiteral(s string) string {
+	if strings.Contains(s, "`") {
+		return strconv.Quote(s)
+	}
+	return "`" + s + "`"
+}
@@ -0,0 +1,86 @@
+package kafka
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+func TestGenerateGo(t *testing.T) {
+	schema := `{"type": "record", "name": "Order", "namespace": "com.example", "doc": "An order", "fields": [
+		{"name": "order_id", "type": {"type": "string", "logicalType": "uuid"}},
+		{"name": "created", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+		{"name": "amount", "type": {"type": "bytes", "logicalType": "decimal", "precision": 10, "scale": 2}},
+		{"name": "status", "type": {"type": "enum", "name": "Status", "symbols": ["NEW", "IN_PROGRESS"]}},
+		{"name": "lines", "type": {"type": "array", "items": {"type": "record", "name": "Line", "fields": [
+			{"name": "sku", "type": "string", "doc": "The article"}]}}},
+		{"name": "tags", "type": {"type": "map", "values": "string"}},
+		{"name": "parent", "type": ["null", "Order"], "default": null},
+		{"name": "hash", "type": {"type": "fixed", "name": "MD5", "size": 16}},
+		{"name": "any", "type": ["int", "string"]}
+	]}`
+	src, err := GenerateGo(schema, GoCodeConfig{Package: "orders", Subject: "orders-value", Version: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "orders_avro.go", src, 0); err != nil {
+		t.Fatalf("Expected a valid Go file, got %v:\n%s", err, src)
+	}
+	for _, expected := range []string{
+		"// Code generated by kafka-avro generate from subject orders-value version 2. DO NOT EDIT.",
+		"package orders",
+		"// Order is the avro record com.example.Order\n//\n// An order\ntype Order struct {",
+		"OrderId kafka.UUID `avro:\"order_id\"`",
+		"Created time.Time `avro:\"created\"`",
+		"Amount  big.Rat `avro:\"amount\"`",
+		"Lines   []Line `avro:\"lines\"`",
+		"Tags    map[string]string `avro:\"tags\"`",
+		"Parent  *Order `avro:\"parent\"`",
+		"Any     interface{} `avro:\"any\"`",
+		"StatusInProgress Status = \"IN_PROGRESS\"",
+		"// The article\n\tSku string `avro:\"sku\"`",
+		"type MD5 [16]byte",
+		"func NewOrderProducer(producer *kafka.AvroProducer, topic string) (*kafka.TypedProducer[Order], error)",
+		"func ConsumeOrder(ctx context.Context, consumer *kafka.AvroConsumer,",
+		"func EncodeOrder(v Order) ([]byte, error)",
+		"func DecodeOrder(b []byte) (Order, error)",
+	} {
+		if !strings.Contains(strings.Join(strings.Fields(string(src)), " "), strings.Join(strings.Fields(expected), " ")) {
+			t.Errorf("Expected the generated file to contain %q:\n%s", expected, src)
+		}
+	}
+
+	if _, err := GenerateGo(`{"type": "enum", "name": "Status", "symbols": ["A"]}`, GoCodeConfig{Package: "p"}); err == nil {
+		t.Errorf("Expected an error for a schema that is not a record")
+	}
+	duplicate := `{"type": "record", "name": "Order", "fields": [{"name": "order_id", "type": "int"},
+		{"name": "orderId", "type": "int"}]}`
+	if _, err := GenerateGo(duplicate, GoCodeConfig{Package: "p"}); err == nil {
+		t.Errorf("Expected an error for fields with the same Go name")
+	}
+}
+
+func TestGenerateGoForSubject(t *testing.T) {
+	registry := newMemorySchemaRegistry()
+	line := `{"type": "record", "name": "Line", "namespace": "com.example", "fields": [{"name": "sku", "type": "string"}]}`
+	if _, err := registry.CreateSubjectWithReferences("line-value", line, nil); err != nil {
+		t.Fatal(err)
+	}
+	order := `{"type": "record", "name": "Order", "namespace": "com.example", "fields": [
+		{"name": "lines", "type": {"type": "array", "items": "com.example.Line"}}]}`
+	references := []SchemaReference{{Name: "com.example.Line", Subject: "line-value", Version: 1}}
+	if _, err := registry.CreateSubjectWithReferences("orders-value", order, references); err != nil {
+		t.Fatal(err)
+	}
+	src, err := GenerateGoForSubject(registry, "orders-value", 0, GoCodeConfig{Package: "orders", TypeName: "PurchaseOrder"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"from subject orders-value version 1.", "type PurchaseOrder struct", "type Line struct",
+		"func EncodePurchaseOrder("} {
+		if !strings.Contains(string(src), expected) {
+			t.Errorf("Expected the generated file to contain %q:\n%s", expected, src)
+		}
+	}
+}
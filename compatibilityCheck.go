@@ -0,0 +1,37 @@
+package kafka
+
+import (
+	"fmt"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+// WithCompatibilityCheck makes an AvroProducer check the schemas of Add, AddNative and PrepareMessage against the
+// latest version of their subject with the compatibility endpoint of the registry, when a schema is first used for
+// a topic. Producing with an incompatible schema fails before anything is registered or sent.
+func WithCompatibilityCheck() Option {
+	return func(o *options) {
+		o.schemas.checkCompatibility = true
+	}
+}
+
+// checkCompatibility fails when the schema is not compatible with the latest version of the subject,
+// any schema is compatible with a subject that has no version yet
+func (ap *AvroProducer) checkCompatibility(subject string, codec *goavro.Codec) error {
+	compatible, err := ap.schemaRegistryClient.CheckLatestCompatibility(subject, codec)
+	if IsNotFoundError(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not check the compatibility of the schema with subject %s: %v", subject, err)
+	}
+	if !compatible {
+		level, err := ap.schemaRegistryClient.GetCompatibility(subject)
+		if err != nil {
+			level = "the configured"
+		}
+		return fmt.Errorf("schema %s is not compatible with the latest version of subject %s under %s compatibility",
+			codec.Schema(), subject, level)
+	}
+	return nil
+}
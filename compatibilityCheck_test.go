@@ -0,0 +1,39 @@
+package kafka
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+type incompatibleRegistry struct {
+	*memorySchemaRegistry
+}
+
+func (r incompatibleRegistry) CheckLatestCompatibility(subject string, codec *goavro.Codec) (bool, error) {
+	if _, err := r.memorySchemaRegistry.CheckLatestCompatibility(subject, codec); err != nil {
+		return false, err
+	}
+	return false, nil
+}
+
+func TestAvroProducer_CompatibilityCheck(t *testing.T) {
+	schema := `{"type": "record", "name": "test", "fields" : [{"name": "val", "type": "int"}]}`
+	registry := incompatibleRegistry{newMemorySchemaRegistry()}
+	producer := &testSyncProducer{}
+	avroProducer := &AvroProducer{producer: producer, schemaRegistryClient: registry,
+		schemas: schemaSelection{checkCompatibility: true}}
+	if err := avroProducer.Add("test", schema, nil, []byte(`{"val":1}`)); err != nil {
+		t.Fatalf("Expected a schema of a new subject to be compatible, got %v", err)
+	}
+	codec, _ := goavro.NewCodec(schema)
+	registry.CreateSubject("next-value", codec)
+	err := avroProducer.Add("next", schema, nil, []byte(`{"val":1}`))
+	if err == nil || !strings.Contains(err.Error(), "next-value") || !strings.Contains(err.Error(), CompatibilityBackward) {
+		t.Errorf("Expected a descriptive incompatibility error, got %v", err)
+	}
+	if len(producer.sent) != 1 {
+		t.Errorf("Expected the incompatible message not to be sent, got %d messages", len(producer.sent))
+	}
+}
@@ -0,0 +1,179 @@
+package kafka
+
+import (
+	"hash/fnv"
+	"strconv"
+	"sync"
+
+	"github.com/Shopify/sarama"
+)
+
+// Ordering defines which messages are handled in order when the consumer handles messages concurrently
+type Ordering int
+
+const (
+	// OrderPerPartition handles the messages of a partition one at a time, in offset order
+	OrderPerPartition Ordering = iota
+	// OrderPerKey handles the messages of a key one at a time, in offset order. Messages of a partition with
+	// different keys are handled concurrently, messages without key are ordered by partition.
+	OrderPerKey
+)
+
+// ConcurrencyConfig makes the consumer handle messages with a pool of workers
+type ConcurrencyConfig struct {
+	// Workers is the number of messages handled at the same time
+	Workers int
+	// Ordering defines the messages handled in order, defaults to OrderPerPartition
+	Ordering Ordering
+}
+
+// SetConcurrency makes the consumer handle messages with a pool of workers, so the callbacks run concurrently.
+// The offset of a partition is only marked up to the lowest message that is not handled yet, so a message is not
+// committed before all the messages preceding it in its partition. Offsets marked with MarkOffset are not tracked.
+func (ac *AvroConsumer) SetConcurrency(config ConcurrencyConfig) {
+	ac.concurrency = config
+}
+
+type workerJob struct {
+	session sarama.ConsumerGroupSession
+	msg     *sarama.ConsumerMessage
+	done    *sync.WaitGroup
+}
+
+// workerPool handles the messages of a session, each worker handles its messages in the order they were dispatched
+type workerPool struct {
+	consumer *AvroConsumer
+	ordering Ordering
+	jobs     []chan workerJob
+	stopped  sync.WaitGroup
+}
+
+func newWorkerPool(consumer *AvroConsumer, config ConcurrencyConfig) *workerPool {
+	pool := &workerPool{consumer: consumer, ordering: config.Ordering, jobs: make([]chan workerJob, config.Workers)}
+	for i := range pool.jobs {
+		jobs := make(chan workerJob)
+		pool.jobs[i] = jobs
+		pool.stopped.Add(1)
+		go func() {
+			defer pool.stopped.Done()
+			for job := range jobs {
+				pool.consumer.handle(job.session, job.msg)
+				job.done.Done()
+			}
+		}()
+	}
+	return pool
+}
+
+// worker returns the index of the worker of the message, so the messages of a partition or a key share a worker
+func (p *workerPool) worker(m *sarama.ConsumerMessage) int {
+	hash := fnv.New32a()
+	if p.ordering == OrderPerKey && len(m.Key) > 0 {
+		hash.Write(m.Key)
+	} else {
+		hash.Write([]byte(m.Topic))
+		hash.Write([]byte(strconv.Itoa(int(m.Partition))))
+	}
+	return int(hash.Sum32() % uint32(len(p.jobs)))
+}
+
+// consumeClaim dispatches the messages of the claim to the workers, it returns once they are all handled
+func (p *workerPool) consumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) {
+	tracked := &trackedSession{ConsumerGroupSession: session, tracker: newOffsetTracker()}
+	var done sync.WaitGroup
+	defer done.Wait()
+	for m := range claim.Messages() {
+		if !waitForRetry(session, m) {
+			return
+		}
+		tracked.tracker.start(m.Offset)
+		done.Add(1)
+		p.jobs[p.worker(m)] <- workerJob{session: tracked, msg: m, done: &done}
+	}
+}
+
+func (p *workerPool) stop() {
+	for _, jobs := range p.jobs {
+		close(jobs)
+	}
+	p.stopped.Wait()
+}
+
+// offsetTracker keeps the offsets of a partition that are handled by the workers
+type offsetTracker struct {
+	lock    sync.Mutex
+	pending []int64
+	started map[int64]bool
+	marked  map[int64]bool
+	// joined are the offsets marked with an offset, e.g. the previous chunks of a chunked value
+	joined map[int64][]int64
+}
+
+func newOffsetTracker() *offsetTracker {
+	return &offsetTracker{started: make(map[int64]bool), marked: make(map[int64]bool), joined: make(map[int64][]int64)}
+}
+
+func (t *offsetTracker) start(offset int64) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.pending = append(t.pending, offset)
+	t.started[offset] = true
+}
+
+// join makes marking offset mark the other offsets too, they are not handled on their own
+func (t *offsetTracker) join(offset int64, offsets []int64) {
+	if len(offsets) == 0 {
+		return
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.joined[offset] = append(t.joined[offset], offsets...)
+}
+
+// mark records the handled offset and returns the next offset to commit, or -1 while the lowest pending offset
+// is not handled. It returns false for offsets that were not started, they are committed as they are.
+func (t *offsetTracker) mark(offset int64) (int64, bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if !t.started[offset] {
+		return offset + 1, false
+	}
+	t.marked[offset] = true
+	for _, joined := range t.joined[offset] {
+		if t.started[joined] {
+			t.marked[joined] = true
+		}
+	}
+	delete(t.joined, offset)
+	n := 0
+	for n < len(t.pending) && t.marked[t.pending[n]] {
+		delete(t.marked, t.pending[n])
+		delete(t.started, t.pending[n])
+		n++
+	}
+	if n == 0 {
+		return -1, true
+	}
+	next := t.pending[n-1] + 1
+	t.pending = t.pending[n:]
+	return next, true
+}
+
+// trackedSession marks the offsets of the session in partition order
+type trackedSession struct {
+	sarama.ConsumerGroupSession
+	tracker *offsetTracker
+}
+
+// MarkOffset implements sarama.ConsumerGroupSession, offset is the next offset to consume
+func (s *trackedSession) MarkOffset(topic string, partition int32, offset int64, metadata string) {
+	next, tracked := s.tracker.mark(offset - 1)
+	if !tracked || next >= 0 {
+		s.ConsumerGroupSession.MarkOffset(topic, partition, next, metadata)
+	}
+}
+
+// MarkMessage implements sarama.ConsumerGroupSession
+func (s *trackedSession) MarkMessage(msg *sarama.ConsumerMessage, metadata string) {
+	s.MarkOffset(msg.Topic, msg.Partition, msg.Offset+1, metadata)
+}
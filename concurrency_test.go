@@ -0,0 +1,113 @@
+package kafka
+
+import (
+	"encoding/binary"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/linkedin/goavro/v2"
+)
+
+func TestOffsetTracker(t *testing.T) {
+	tracker := newOffsetTracker()
+	for offset := int64(3); offset < 6; offset++ {
+		tracker.start(offset)
+	}
+	if next, tracked := tracker.mark(4); !tracked || next != -1 {
+		t.Errorf("Expected offset 4 to wait for offset 3, got %d", next)
+	}
+	if next, _ := tracker.mark(3); next != 5 {
+		t.Errorf("Expected offsets up to 4 to be committed, got %d", next)
+	}
+	if next, _ := tracker.mark(5); next != 6 {
+		t.Errorf("Expected offset 5 to be committed, got %d", next)
+	}
+	tracker.start(6)
+	tracker.start(7)
+	tracker.join(7, []int64{6})
+	if next, _ := tracker.mark(7); next != 8 {
+		t.Errorf("Expected the joined offset 6 to be marked with offset 7, got %d", next)
+	}
+	if next, tracked := tracker.mark(9); tracked || next != 10 {
+		t.Errorf("Expected offsets that were not started to be committed as they are, got %d", next)
+	}
+}
+
+func TestAvroConsumer_Concurrency(t *testing.T) {
+	registry := newMemorySchemaRegistry()
+	codec, err := goavro.NewCodec(`{"type": "record", "name": "test", "fields" : [{"name": "val", "type": "int"}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schemaId, err := registry.CreateSubject("test-value", codec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	value := []byte{0, 0, 0, 0, 0, 2}
+	binary.BigEndian.PutUint32(value[1:5], uint32(schemaId))
+
+	release := make(chan struct{})
+	var lock sync.Mutex
+	var handled []int64
+	consumer := &AvroConsumer{SchemaRegistryClient: registry, callbacks: ConsumerCallbacks{
+		OnDataReceived: func(msg Message) {
+			if msg.Key == "slow" {
+				<-release
+			}
+			lock.Lock()
+			handled = append(handled, msg.Offset)
+			lock.Unlock()
+		},
+	}}
+	consumer.SetOffsetCommitStrategy(CommitAfterCallback)
+	consumer.SetConcurrency(ConcurrencyConfig{Workers: 4, Ordering: OrderPerKey})
+
+	session := newTestSession(nil)
+	handler := &consumerGroupHandler{consumer: consumer}
+	handler.Setup(session)
+	pool := handler.pool
+	keys := []string{"slow", "fast"}
+	for pool.worker(&sarama.ConsumerMessage{Key: []byte(keys[0])}) == pool.worker(&sarama.ConsumerMessage{Key: []byte(keys[1])}) {
+		keys[1] += "-"
+	}
+
+	claim := newTestClaim(3)
+	claim.messages <- &sarama.ConsumerMessage{Topic: "test", Offset: 0, Key: []byte(keys[0]), Value: value}
+	claim.messages <- &sarama.ConsumerMessage{Topic: "test", Offset: 1, Key: []byte(keys[1]), Value: value}
+	claim.messages <- &sarama.ConsumerMessage{Topic: "test", Offset: 2, Key: []byte(keys[1]), Value: value}
+	close(claim.messages)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler.ConsumeClaim(session, claim)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		lock.Lock()
+		n := len(handled)
+		lock.Unlock()
+		if n >= 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			close(release)
+			t.Fatalf("Expected the messages of the other key to be handled, got %v", handled)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	lock.Lock()
+	if len(handled) != 2 || handled[0] != 1 || handled[1] != 2 || len(session.offsets) != 0 {
+		t.Errorf("Expected the other key to be handled without committing past the slow message, got %v, %v",
+			handled, session.offsets)
+	}
+	lock.Unlock()
+	close(release)
+	<-done
+	handler.Cleanup(session)
+	if session.offsets[0] != 3 {
+		t.Errorf("Expected all the offsets to be committed, got %v", session.offsets)
+	}
+}
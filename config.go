@@ -0,0 +1,218 @@
+package kafka
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+// SubjectNameStrategy derives the schema registry subject of a topic's values or keys
+type SubjectNameStrategy func(topic string, isKey bool, schema string) (string, error)
+
+// TopicNameStrategy is the default strategy, using "<topic>-value" and "<topic>-key"
+func TopicNameStrategy(topic string, isKey bool, schema string) (string, error) {
+	if isKey {
+		return topic + "-key", nil
+	}
+	return topic + "-value", nil
+}
+
+// RecordNameStrategy uses the full name of the record for keys and values, so a topic can hold several event types
+func RecordNameStrategy(topic string, isKey bool, schema string) (string, error) {
+	return schemaFullName(schema)
+}
+
+// TopicRecordNameStrategy uses "<topic>-<record full name>" for keys and values
+func TopicRecordNameStrategy(topic string, isKey bool, schema string) (string, error) {
+	fullName, err := schemaFullName(schema)
+	if err != nil {
+		return "", err
+	}
+	return topic + "-" + fullName, nil
+}
+
+// RetryPolicy controls how failed messages are retried
+type RetryPolicy struct {
+	MaxRetries int
+	Backoff    time.Duration
+}
+
+// FetchConfig overrides the consumer fetch sizes, zero values keep the consumer defaults
+type FetchConfig struct {
+	// Min, Default and Max are the fetch sizes in bytes, as in sarama's Consumer.Fetch
+	Min     int32
+	Default int32
+	Max     int32
+	// MaxWait is the max time the broker waits for Min bytes
+	MaxWait time.Duration
+}
+
+func (f *FetchConfig) apply(config *sarama.Config) {
+	if f == nil {
+		return
+	}
+	if f.Min > 0 {
+		config.Consumer.Fetch.Min = f.Min
+	}
+	if f.Default > 0 {
+		config.Consumer.Fetch.Default = f.Default
+	}
+	if f.Max > 0 {
+		config.Consumer.Fetch.Max = f.Max
+	}
+	if f.MaxWait > 0 {
+		config.Consumer.MaxWaitTime = f.MaxWait
+	}
+}
+
+// TopicConfig holds the settings that can be overridden per topic. Zero values inherit the defaults.
+type TopicConfig struct {
+	// SchemaType is the serde mode of the topic, AVRO when empty
+	SchemaType string
+	// SubjectNameStrategy derives the subjects of the topic, TopicNameStrategy when nil
+	SubjectNameStrategy SubjectNameStrategy
+	// DeadLetterTopic receives the messages of the topic that cannot be processed
+	DeadLetterTopic string
+	// Retry is the retry policy for the messages of the topic
+	Retry *RetryPolicy
+	// Fetch overrides the fetch sizes used to consume the topic, e.g. small for control topics and large for CDC topics
+	Fetch *FetchConfig
+	// ReaderSchema is the avro schema consumers decode the topic with, the values are resolved from their writer
+	// schema so new fields are ignored and missing ones get their default. The writer schema is used when empty.
+	ReaderSchema string
+	// WireFormat frames the values of the topic with their schema id, ConfluentWireFormat when nil
+	WireFormat WireFormat
+	// Chunking splits the large values of the topic into several messages, values are not split when nil
+	Chunking *ChunkConfig
+}
+
+// SubjectConfig holds the settings that can be overridden per subject. Zero values inherit the defaults.
+type SubjectConfig struct {
+	// Compatibility is the compatibility level expected from the subject, e.g. BACKWARD or FULL_TRANSITIVE
+	Compatibility string
+}
+
+// TopicResolver maps the logical topic names used by the application to physical topic names
+type TopicResolver func(topic string) string
+
+// PrefixTopicResolver prefixes every topic, e.g. with the environment like "prod."
+func PrefixTopicResolver(prefix string) TopicResolver {
+	return func(topic string) string {
+		return prefix + topic
+	}
+}
+
+// MappingTopicResolver maps logical names to physical topics, unmapped topics are resolved by fallback if not nil
+func MappingTopicResolver(mapping map[string]string, fallback TopicResolver) TopicResolver {
+	return func(topic string) string {
+		if physical, ok := mapping[topic]; ok {
+			return physical
+		}
+		if fallback != nil {
+			return fallback(topic)
+		}
+		return topic
+	}
+}
+
+// Config is a layered configuration where global defaults are overridden per topic and per subject,
+// so one producer or consumer can serve heterogeneous topics. Topics are configured by their logical name.
+type Config struct {
+	// TopicResolver maps logical to physical topics for producing, consuming, subjects and dead-letter topics
+	TopicResolver   TopicResolver
+	Defaults        TopicConfig
+	Topics          map[string]TopicConfig
+	SubjectDefaults SubjectConfig
+	Subjects        map[string]SubjectConfig
+}
+
+// ForTopic returns the effective settings of a topic
+func (c *Config) ForTopic(topic string) TopicConfig {
+	if c == nil {
+		return TopicConfig{SchemaType: "AVRO", SubjectNameStrategy: TopicNameStrategy}
+	}
+	result := c.Defaults
+	if override, ok := c.Topics[topic]; ok {
+		if override.SchemaType != "" {
+			result.SchemaType = override.SchemaType
+		}
+		if override.SubjectNameStrategy != nil {
+			result.SubjectNameStrategy = override.SubjectNameStrategy
+		}
+		if override.DeadLetterTopic != "" {
+			result.DeadLetterTopic = override.DeadLetterTopic
+		}
+		if override.Retry != nil {
+			result.Retry = override.Retry
+		}
+		if override.Fetch != nil {
+			result.Fetch = override.Fetch
+		}
+		if override.ReaderSchema != "" {
+			result.ReaderSchema = override.ReaderSchema
+		}
+		if override.WireFormat != nil {
+			result.WireFormat = override.WireFormat
+		}
+		if override.Chunking != nil {
+			result.Chunking = override.Chunking
+		}
+	}
+	if result.SchemaType == "" {
+		result.SchemaType = "AVRO"
+	}
+	if result.SubjectNameStrategy == nil {
+		result.SubjectNameStrategy = TopicNameStrategy
+	}
+	return result
+}
+
+// ForSubject returns the effective settings of a subject
+func (c *Config) ForSubject(subject string) SubjectConfig {
+	if c == nil {
+		return SubjectConfig{}
+	}
+	result := c.SubjectDefaults
+	if override, ok := c.Subjects[subject]; ok && override.Compatibility != "" {
+		result.Compatibility = override.Compatibility
+	}
+	return result
+}
+
+// ApplyCompatibility sets the global compatibility level of the registry to SubjectDefaults.Compatibility and
+// the level of every subject in Subjects to its own, empty levels are left unchanged
+func (c *Config) ApplyCompatibility(client SchemaRegistryClientInterface) error {
+	if c.SubjectDefaults.Compatibility != "" {
+		if err := client.SetGlobalCompatibility(c.SubjectDefaults.Compatibility); err != nil {
+			return fmt.Errorf("could not set the global compatibility: %s", err)
+		}
+	}
+	for subject, config := range c.Subjects {
+		if config.Compatibility == "" {
+			continue
+		}
+		if err := client.SetCompatibility(subject, config.Compatibility); err != nil {
+			return fmt.Errorf("could not set the compatibility of %s: %s", subject, err)
+		}
+	}
+	return nil
+}
+
+// PhysicalTopic returns the physical name of a logical topic
+func (c *Config) PhysicalTopic(topic string) string {
+	if c == nil || c.TopicResolver == nil {
+		return topic
+	}
+	return c.TopicResolver(topic)
+}
+
+// valueSubject returns the value subject of the topic according to its subject name strategy
+func (c *Config) valueSubject(topic string, schema string) (string, error) {
+	return c.ForTopic(topic).SubjectNameStrategy(c.PhysicalTopic(topic), false, schema)
+}
+
+// keySubject returns the key subject of the topic according to its subject name strategy
+func (c *Config) keySubject(topic string, schema string) (string, error) {
+	return c.ForTopic(topic).SubjectNameStrategy(c.PhysicalTopic(topic), true, schema)
+}
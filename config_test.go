@@ -0,0 +1,117 @@
+package kafka
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+func TestConfig_ForTopic(t *testing.T) {
+	defaultRetry := &RetryPolicy{MaxRetries: 3, Backoff: time.Second}
+	config := &Config{
+		Defaults: TopicConfig{DeadLetterTopic: "dlq", Retry: defaultRetry},
+		Topics: map[string]TopicConfig{
+			"cdc": {DeadLetterTopic: "cdc-dlq", SubjectNameStrategy: func(topic string, isKey bool, schema string) (string, error) {
+				return "cdc-subject", nil
+			}},
+		},
+		SubjectDefaults: SubjectConfig{Compatibility: "BACKWARD"},
+		Subjects:        map[string]SubjectConfig{"cdc-subject": {Compatibility: "FULL_TRANSITIVE"}},
+	}
+	other := config.ForTopic("other")
+	if other.DeadLetterTopic != "dlq" || other.Retry != defaultRetry || other.SchemaType != "AVRO" {
+		t.Errorf("Expected defaults, got %+v", other)
+	}
+	if subject, _ := other.SubjectNameStrategy("other", false, ""); subject != "other-value" {
+		t.Errorf("Expected topic name strategy, got %s", subject)
+	}
+	cdc := config.ForTopic("cdc")
+	if cdc.DeadLetterTopic != "cdc-dlq" || cdc.Retry != defaultRetry {
+		t.Errorf("Expected overridden topic config, got %+v", cdc)
+	}
+	subject, _ := config.valueSubject("cdc", "")
+	if subject != "cdc-subject" {
+		t.Errorf("Expected overridden subject, got %s", subject)
+	}
+	if config.ForSubject(subject).Compatibility != "FULL_TRANSITIVE" || config.ForSubject("x").Compatibility != "BACKWARD" {
+		t.Errorf("Unexpected subject config")
+	}
+	var empty *Config
+	if subject, _ := empty.valueSubject("test", ""); subject != "test-value" {
+		t.Errorf("Expected topic name strategy without config, got %s", subject)
+	}
+}
+
+func TestTopicResolver(t *testing.T) {
+	config := &Config{TopicResolver: MappingTopicResolver(map[string]string{"orders": "legacy.orders"}, PrefixTopicResolver("prod."))}
+	if topic := config.PhysicalTopic("orders"); topic != "legacy.orders" {
+		t.Errorf("Expected mapped topic, got %s", topic)
+	}
+	if topic := config.PhysicalTopic("payments"); topic != "prod.payments" {
+		t.Errorf("Expected prefixed topic, got %s", topic)
+	}
+	if subject, _ := config.valueSubject("payments", ""); subject != "prod.payments-value" {
+		t.Errorf("Expected subject of the physical topic, got %s", subject)
+	}
+}
+
+func TestFetchConfig_Apply(t *testing.T) {
+	config := &Config{
+		Defaults: TopicConfig{Fetch: &FetchConfig{Default: 64 * 1024}},
+		Topics:   map[string]TopicConfig{"cdc": {Fetch: &FetchConfig{Default: 8 * 1024 * 1024, Max: 32 * 1024 * 1024}}},
+	}
+	saramaConfig := sarama.NewConfig()
+	config.ForTopic("cdc").Fetch.apply(saramaConfig)
+	if saramaConfig.Consumer.Fetch.Default != 8*1024*1024 || saramaConfig.Consumer.Fetch.Max != 32*1024*1024 || saramaConfig.Consumer.Fetch.Min != 1 {
+		t.Errorf("Unexpected fetch config %+v", saramaConfig.Consumer.Fetch)
+	}
+	saramaConfig = sarama.NewConfig()
+	config.ForTopic("control").Fetch.apply(saramaConfig)
+	if saramaConfig.Consumer.Fetch.Default != 64*1024 {
+		t.Errorf("Expected default fetch override, got %+v", saramaConfig.Consumer.Fetch)
+	}
+}
+
+func TestSubjectNameStrategies(t *testing.T) {
+	schema := `{"type": "record", "name": "Created", "namespace": "com.example", "fields": []}`
+	if subject, _ := RecordNameStrategy("events", false, schema); subject != "com.example.Created" {
+		t.Errorf("Unexpected record name subject: %s", subject)
+	}
+	if subject, _ := TopicRecordNameStrategy("events", true, schema); subject != "events-com.example.Created" {
+		t.Errorf("Unexpected topic record name subject: %s", subject)
+	}
+	if _, err := RecordNameStrategy("events", false, `"string"`); err == nil {
+		t.Errorf("Expected error for unnamed schema")
+	}
+}
+
+func TestConfig_ApplyCompatibility(t *testing.T) {
+	levels := make(map[string]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request configRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		levels[r.URL.Path] = request.Compatibility
+		fmt.Fprintf(w, `{"compatibility": "%s"}`, request.Compatibility)
+	}))
+	defer server.Close()
+	config := &Config{
+		SubjectDefaults: SubjectConfig{Compatibility: CompatibilityBackward},
+		Subjects: map[string]SubjectConfig{
+			"orders-value": {Compatibility: CompatibilityFullTransitive},
+			"events-value": {},
+		},
+	}
+	if err := config.ApplyCompatibility(NewSchemaRegistryClient([]string{server.URL})); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"/config": CompatibilityBackward, "/config/orders-value": CompatibilityFullTransitive}
+	if !reflect.DeepEqual(levels, expected) {
+		t.Errorf("Expected %v, got %v", expected, levels)
+	}
+}
@@ -0,0 +1,206 @@
+package kafka
+
+import (
+	"sync"
+
+	"github.com/Shopify/sarama"
+)
+
+// NotificationType defines the type of a rebalance notification
+type NotificationType uint8
+
+const (
+	UnknownNotification NotificationType = iota
+	// RebalanceStart is sent when the claims of this member are about to be released
+	RebalanceStart
+	// RebalanceOK is sent when a new generation of the group started with the current claims
+	RebalanceOK
+	// RebalanceError is sent when joining the group failed
+	RebalanceError
+)
+
+// String implements fmt.Stringer
+func (t NotificationType) String() string {
+	switch t {
+	case RebalanceStart:
+		return "rebalance start"
+	case RebalanceOK:
+		return "rebalance OK"
+	case RebalanceError:
+		return "rebalance error"
+	}
+	return "unknown"
+}
+
+// Notification is sent on rebalances of the consumer group, it replaces the sarama-cluster notification
+// and adds the membership of this consumer
+type Notification struct {
+	Type NotificationType
+	// Claimed, Released and Current are the partitions by topic gained, lost and owned in this generation
+	Claimed  map[string][]int32
+	Released map[string][]int32
+	Current  map[string][]int32
+	GroupMembership
+}
+
+// GroupMembership describes how this consumer takes part in its group for the current generation
+type GroupMembership struct {
+	MemberID     string
+	GenerationID int32
+	// IsLeader is true when this member computed the partition assignment of the generation
+	IsLeader bool
+}
+
+// leaderStrategy wraps the configured balance strategy, sarama only asks the group leader to plan the assignment
+type leaderStrategy struct {
+	sarama.BalanceStrategy
+	lock    sync.Mutex
+	planned bool
+}
+
+func (s *leaderStrategy) Plan(members map[string]sarama.ConsumerGroupMemberMetadata, topics map[string][]int32) (sarama.BalanceStrategyPlan, error) {
+	s.lock.Lock()
+	s.planned = true
+	s.lock.Unlock()
+	return s.BalanceStrategy.Plan(members, topics)
+}
+
+// takePlanned reports whether the assignment was planned by this member since the last call
+func (s *leaderStrategy) takePlanned() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	planned := s.planned
+	s.planned = false
+	return planned
+}
+
+// consumerGroupHandler implements sarama.ConsumerGroupHandler for the avro consumer.
+// Claims are consumed in their own goroutines, but messages are handled one at a time
+// so callbacks never run concurrently, unless the consumer bootstraps.
+// With SetConcurrency the messages are handled by a pool of workers instead, started for every session.
+type consumerGroupHandler struct {
+	consumer *AvroConsumer
+	lock     sync.Mutex
+	pool     *workerPool
+}
+
+func (h *consumerGroupHandler) Setup(session sarama.ConsumerGroupSession) error {
+	if h.consumer.concurrency.Workers > 1 {
+		h.pool = newWorkerPool(h.consumer, h.consumer.concurrency)
+	}
+	h.consumer.rebalanced(session)
+	return nil
+}
+
+func (h *consumerGroupHandler) Cleanup(session sarama.ConsumerGroupSession) error {
+	orNop(h.consumer.logger).Info("consumer group rebalance started", "group", h.consumer.groupId,
+		"member", session.MemberID(), "generation", session.GenerationID())
+	if h.pool != nil {
+		h.pool.stop()
+		h.pool = nil
+	}
+	h.consumer.revoked(session)
+	h.consumer.notify(&Notification{
+		Type:            RebalanceStart,
+		Current:         session.Claims(),
+		GroupMembership: h.consumer.Membership(),
+	})
+	return nil
+}
+
+func (h *consumerGroupHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	if h.consumer.callbacks.OnBatchReceived != nil {
+		h.consumeBatches(session, claim)
+		return nil
+	}
+	if h.consumer.bootstrap != nil {
+		h.consumer.bootstrapPartition(session, claim, h.handleSequential)
+		return nil
+	}
+	if h.pool != nil {
+		h.pool.consumeClaim(session, claim)
+		return nil
+	}
+	for m := range claim.Messages() {
+		if !waitForRetry(session, m) {
+			return nil
+		}
+		h.handleSequential(session, m)
+	}
+	return nil
+}
+
+func (h *consumerGroupHandler) handleSequential(session sarama.ConsumerGroupSession, m *sarama.ConsumerMessage) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.consumer.handle(session, m)
+}
+
+// rebalanced records the membership of the new generation and notifies the claimed and released partitions
+func (ac *AvroConsumer) rebalanced(session sarama.ConsumerGroupSession) {
+	membership := GroupMembership{
+		MemberID:     session.MemberID(),
+		GenerationID: session.GenerationID(),
+	}
+	if ac.leader != nil {
+		membership.IsLeader = ac.leader.takePlanned()
+	}
+	current := session.Claims()
+	ac.membershipLock.Lock()
+	previous := ac.claims
+	ac.membership = membership
+	ac.claims = current
+	ac.session = session
+	ac.membershipLock.Unlock()
+	ac.applySeeks(session)
+	orNop(ac.logger).Info("consumer group rebalanced", "group", ac.groupId, "member", membership.MemberID,
+		"generation", membership.GenerationID, "leader", membership.IsLeader, "claims", current)
+	if ac.callbacks.OnPartitionsAssigned != nil {
+		ac.callbacks.OnPartitionsAssigned(current)
+	}
+	ac.notify(&Notification{
+		Type:            RebalanceOK,
+		Claimed:         diffClaims(current, previous),
+		Released:        diffClaims(previous, current),
+		Current:         current,
+		GroupMembership: membership,
+	})
+}
+
+// revoked passes the partitions of the ending session to OnPartitionsRevoked
+func (ac *AvroConsumer) revoked(session sarama.ConsumerGroupSession) {
+	ac.chunks.revoke(session.Claims())
+	if ac.callbacks.OnPartitionsRevoked != nil {
+		ac.callbacks.OnPartitionsRevoked(session.Claims())
+	}
+}
+
+// Membership returns the member id, generation id and leadership of this consumer in the current generation
+func (ac *AvroConsumer) Membership() GroupMembership {
+	ac.membershipLock.Lock()
+	defer ac.membershipLock.Unlock()
+	return ac.membership
+}
+
+func (ac *AvroConsumer) notify(notification *Notification) {
+	if ac.callbacks.OnNotification != nil {
+		ac.callbacks.OnNotification(notification)
+	}
+}
+
+// diffClaims returns the partitions of a that are not in b
+func diffClaims(a, b map[string][]int32) map[string][]int32 {
+	diff := make(map[string][]int32)
+	for topic, partitions := range a {
+		owned := make(map[int32]bool, len(b[topic]))
+		for _, partition := range b[topic] {
+			owned[partition] = true
+		}
+		for _, partition := range partitions {
+			if !owned[partition] {
+				diff[topic] = append(diff[topic], partition)
+			}
+		}
+	}
+	return diff
+}
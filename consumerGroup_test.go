@@ -0,0 +1,78 @@
+package kafka
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/Shopify/sarama"
+)
+
+type testSession struct {
+	claims  map[string][]int32
+	offsets map[int32]int64
+	ctx     context.Context
+}
+
+func newTestSession(claims map[string][]int32) *testSession {
+	return &testSession{claims: claims, offsets: make(map[int32]int64), ctx: context.Background()}
+}
+
+func (s *testSession) Claims() map[string][]int32 { return s.claims }
+func (s *testSession) MemberID() string           { return "member-1" }
+func (s *testSession) GenerationID() int32        { return 3 }
+func (s *testSession) Context() context.Context   { return s.ctx }
+func (s *testSession) MarkOffset(topic string, partition int32, offset int64, metadata string) {
+	s.offsets[partition] = offset
+}
+func (s *testSession) ResetOffset(topic string, partition int32, offset int64, metadata string) {
+	s.offsets[partition] = offset
+}
+func (s *testSession) MarkMessage(msg *sarama.ConsumerMessage, metadata string) {
+	s.MarkOffset(msg.Topic, msg.Partition, msg.Offset+1, metadata)
+}
+
+func TestAvroConsumer_Rebalanced(t *testing.T) {
+	var notifications []*Notification
+	leader := &leaderStrategy{BalanceStrategy: sarama.BalanceStrategyRange}
+	consumer := &AvroConsumer{leader: leader, callbacks: ConsumerCallbacks{OnNotification: func(notification *Notification) {
+		notifications = append(notifications, notification)
+	}}}
+
+	leader.Plan(map[string]sarama.ConsumerGroupMemberMetadata{}, map[string][]int32{})
+	consumer.rebalanced(newTestSession(map[string][]int32{"test": {0, 1}}))
+	consumer.rebalanced(newTestSession(map[string][]int32{"test": {1, 2}}))
+
+	if len(notifications) != 2 || notifications[1].Type != RebalanceOK {
+		t.Fatalf("Expected 2 rebalance notifications, got %v", notifications)
+	}
+	if !notifications[0].IsLeader || notifications[1].IsLeader {
+		t.Errorf("Expected leadership of the first generation only")
+	}
+	if !reflect.DeepEqual(notifications[1].Claimed, map[string][]int32{"test": {2}}) ||
+		!reflect.DeepEqual(notifications[1].Released, map[string][]int32{"test": {0}}) {
+		t.Errorf("Unexpected claimed/released partitions: %v / %v", notifications[1].Claimed, notifications[1].Released)
+	}
+	membership := consumer.Membership()
+	if membership.MemberID != "member-1" || membership.GenerationID != 3 {
+		t.Errorf("Unexpected membership: %+v", membership)
+	}
+}
+
+func TestConsumerGroupHandler_PartitionHooks(t *testing.T) {
+	var assigned, revoked []map[string][]int32
+	consumer := &AvroConsumer{callbacks: ConsumerCallbacks{
+		OnPartitionsAssigned: func(partitions map[string][]int32) { assigned = append(assigned, partitions) },
+		OnPartitionsRevoked:  func(partitions map[string][]int32) { revoked = append(revoked, partitions) },
+	}}
+	handler := &consumerGroupHandler{consumer: consumer}
+	session := newTestSession(map[string][]int32{"test": {0, 1}})
+	handler.Setup(session)
+	if len(assigned) != 1 || len(revoked) != 0 || !reflect.DeepEqual(assigned[0], session.claims) {
+		t.Fatalf("Expected the claims to be assigned on setup, got %v, %v", assigned, revoked)
+	}
+	handler.Cleanup(session)
+	if len(assigned) != 1 || len(revoked) != 1 || !reflect.DeepEqual(revoked[0], session.claims) {
+		t.Errorf("Expected the claims to be revoked on cleanup, got %v, %v", assigned, revoked)
+	}
+}
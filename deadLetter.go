@@ -0,0 +1,119 @@
+package kafka
+
+import (
+	"fmt"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/Shopify/sarama"
+)
+
+// Headers added to dead-lettered messages, next to the original headers
+const (
+	DeadLetterHeaderError     = "dlq.error"
+	DeadLetterHeaderTopic     = "dlq.topic"
+	DeadLetterHeaderPartition = "dlq.partition"
+	DeadLetterHeaderOffset    = "dlq.offset"
+)
+
+// DeadLetterConfig enables the dead-letter queue of a consumer for messages that cannot be decoded
+type DeadLetterConfig struct {
+	// Producer publishes the raw messages, see NewDeadLetterProducer
+	Producer sarama.SyncProducer
+	// Topic is used for topics without a DeadLetterTopic in the consumer config, "<topic>-dlq" when empty
+	Topic string
+	// Halt stops the consumer on the first message that cannot be decoded instead of dead-lettering it
+	Halt bool
+}
+
+// DeadLetterError is reported to OnError when the consumer halts on a message, either because Halt is set
+// or because the message could not be dead-lettered. The offset of the message is not committed.
+type DeadLetterError struct {
+	Topic     string
+	Partition int32
+	Offset    int64
+	Err       error
+}
+
+func (e *DeadLetterError) Error() string {
+	return fmt.Sprintf("consumer halted on %s/%d@%d: %v", e.Topic, e.Partition, e.Offset, e.Err)
+}
+
+// NewDeadLetterProducer creates a producer suitable for DeadLetterConfig
+func NewDeadLetterProducer(kafkaServers []string, opts ...Option) (sarama.SyncProducer, error) {
+	return sarama.NewSyncProducer(kafkaServers, applyOptions(defaultAvroProducerConfig(), opts).saramaConfig)
+}
+
+// SetDeadLetterQueue makes the consumer publish messages that cannot be decoded to a dead-letter topic,
+// instead of passing them to OnError and OnDataReceived
+func (ac *AvroConsumer) SetDeadLetterQueue(config DeadLetterConfig) {
+	ac.deadLetter = &config
+}
+
+// deadLetterTopic returns the physical dead-letter topic of a consumed topic
+func (ac *AvroConsumer) deadLetterTopic(topic string) string {
+	logical := ac.logicalTopic(topic)
+	deadLetterTopic := ac.config.ForTopic(logical).DeadLetterTopic
+	if deadLetterTopic == "" {
+		deadLetterTopic = ac.deadLetter.Topic
+	}
+	if deadLetterTopic == "" {
+		deadLetterTopic = logical + "-dlq"
+	}
+	return ac.config.PhysicalTopic(deadLetterTopic)
+}
+
+// deadLetterMsg publishes the raw message with the error, it returns an error if the consumer must halt
+func (ac *AvroConsumer) deadLetterMsg(m *sarama.ConsumerMessage, cause error) error {
+	haltErr := &DeadLetterError{Topic: m.Topic, Partition: m.Partition, Offset: m.Offset, Err: cause}
+	if ac.deadLetter.Halt {
+		return haltErr
+	}
+	headers := make([]sarama.RecordHeader, 0, len(m.Headers)+4)
+	for _, header := range m.Headers {
+		headers = append(headers, *header)
+	}
+	headers = append(headers,
+		sarama.RecordHeader{Key: []byte(DeadLetterHeaderError), Value: []byte(cause.Error())},
+		sarama.RecordHeader{Key: []byte(DeadLetterHeaderTopic), Value: []byte(m.Topic)},
+		sarama.RecordHeader{Key: []byte(DeadLetterHeaderPartition), Value: []byte(strconv.Itoa(int(m.Partition)))},
+		sarama.RecordHeader{Key: []byte(DeadLetterHeaderOffset), Value: []byte(strconv.FormatInt(m.Offset, 10))},
+	)
+	msg := &sarama.ProducerMessage{
+		Topic:   ac.deadLetterTopic(m.Topic),
+		Key:     sarama.ByteEncoder(m.Key),
+		Value:   sarama.ByteEncoder(m.Value),
+		Headers: headers,
+	}
+	if _, _, err := ac.deadLetter.Producer.SendMessage(msg); err != nil {
+		haltErr.Err = fmt.Errorf("could not dead-letter message (%v): %v", cause, err)
+		return haltErr
+	}
+	if ac.metrics != nil {
+		ac.metrics.DeadLettered(m.Topic)
+	}
+	return nil
+}
+
+// halt stops consumption without committing the offset of the current message nor of the ones that follow
+func (ac *AvroConsumer) halt() {
+	atomic.StoreInt32(&ac.halted, 1)
+	ac.runLock.Lock()
+	cancel := ac.cancel
+	ac.runLock.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+}
+
+func (ac *AvroConsumer) haltWith(err error) {
+	orNop(ac.logger).Error("consumer halted", "group", ac.groupId, "error", err)
+	ac.halt()
+	if ac.callbacks.OnError != nil {
+		ac.callbacks.OnError(err)
+	}
+}
+
+func (ac *AvroConsumer) isHalted() bool {
+	return atomic.LoadInt32(&ac.halted) == 1
+}
@@ -0,0 +1,98 @@
+package kafka
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Shopify/sarama"
+)
+
+type testSyncProducer struct {
+	sent []*sarama.ProducerMessage
+	err  error
+}
+
+func (p *testSyncProducer) SendMessage(msg *sarama.ProducerMessage) (int32, int64, error) {
+	if p.err != nil {
+		return 0, 0, p.err
+	}
+	p.sent = append(p.sent, msg)
+	return 0, int64(len(p.sent)), nil
+}
+func (p *testSyncProducer) SendMessages(msgs []*sarama.ProducerMessage) error {
+	for _, msg := range msgs {
+		if _, _, err := p.SendMessage(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+func (p *testSyncProducer) Close() error { return nil }
+
+func headerValue(headers []sarama.RecordHeader, key string) string {
+	for _, header := range headers {
+		if string(header.Key) == key {
+			return string(header.Value)
+		}
+	}
+	return ""
+}
+
+func TestAvroConsumer_DeadLetter(t *testing.T) {
+	producer := &testSyncProducer{}
+	received := 0
+	consumer := &AvroConsumer{callbacks: ConsumerCallbacks{OnDataReceived: func(msg Message) { received++ }}}
+	consumer.SetConfig(Config{Topics: map[string]TopicConfig{"orders": {DeadLetterTopic: "orders-poison"}}})
+	consumer.SetDeadLetterQueue(DeadLetterConfig{Producer: producer})
+	session := newTestSession(nil)
+
+	consumer.handle(session, &sarama.ConsumerMessage{Topic: "orders", Partition: 2, Offset: 7, Value: []byte("corrupt"),
+		Headers: []*sarama.RecordHeader{{Key: []byte("trace"), Value: []byte("abc")}}})
+	consumer.handle(session, &sarama.ConsumerMessage{Topic: "payments", Offset: 1, Value: []byte{1}})
+
+	if len(producer.sent) != 2 || received != 0 {
+		t.Fatalf("Expected 2 dead letters and no data callback, got %d, %d", len(producer.sent), received)
+	}
+	first := producer.sent[0]
+	if first.Topic != "orders-poison" || producer.sent[1].Topic != "payments-dlq" {
+		t.Errorf("Unexpected dead-letter topics: %s, %s", first.Topic, producer.sent[1].Topic)
+	}
+	if headerValue(first.Headers, "trace") != "abc" || headerValue(first.Headers, DeadLetterHeaderOffset) != "7" ||
+		headerValue(first.Headers, DeadLetterHeaderPartition) != "2" || headerValue(first.Headers, DeadLetterHeaderError) == "" {
+		t.Errorf("Unexpected dead-letter headers: %v", first.Headers)
+	}
+	if session.offsets[2] != 8 {
+		t.Errorf("Expected dead-lettered message to be committed")
+	}
+}
+
+func TestAvroConsumer_DeadLetterManualCommit(t *testing.T) {
+	producer := &testSyncProducer{}
+	consumer := &AvroConsumer{SchemaRegistryClient: newMemorySchemaRegistry()}
+	consumer.SetOffsetCommitStrategy(CommitManual)
+	consumer.SetDeadLetterQueue(DeadLetterConfig{Producer: producer})
+	consumer.SetUnframedFallback(UnframedFallback{Action: UnframedSkip})
+	session := newTestSession(nil)
+
+	consumer.handle(session, &sarama.ConsumerMessage{Topic: "orders", Offset: 3, Value: []byte{0, 0, 0, 0, 9, 2}})
+	consumer.handle(session, &sarama.ConsumerMessage{Topic: "orders", Offset: 4, Value: []byte("corrupt")})
+	if len(producer.sent) != 1 || len(session.offsets) != 0 {
+		t.Errorf("Expected manual commits not to be marked, got %d dead letters, %v", len(producer.sent), session.offsets)
+	}
+}
+
+func TestAvroConsumer_DeadLetterHalt(t *testing.T) {
+	var errs []error
+	consumer := &AvroConsumer{callbacks: ConsumerCallbacks{OnError: func(err error) { errs = append(errs, err) }}}
+	consumer.SetDeadLetterQueue(DeadLetterConfig{Producer: &testSyncProducer{err: errors.New("broker down")}})
+	session := newTestSession(nil)
+
+	consumer.handle(session, &sarama.ConsumerMessage{Topic: "orders", Offset: 3, Value: []byte("corrupt")})
+	consumer.handle(session, &sarama.ConsumerMessage{Topic: "orders", Offset: 4, Value: []byte("corrupt")})
+	if len(errs) != 1 || len(session.offsets) != 0 {
+		t.Fatalf("Expected the consumer to halt without committing, got %v, %v", errs, session.offsets)
+	}
+	if deadLetterErr, ok := errs[0].(*DeadLetterError); !ok || deadLetterErr.Offset != 3 {
+		t.Errorf("Expected DeadLetterError, got %v", errs[0])
+	}
+}
@@ -0,0 +1,61 @@
+package kafka
+
+import (
+	"container/list"
+	"sync"
+)
+
+type decodeCacheKey struct {
+	topic     string
+	partition int32
+	offset    int64
+}
+
+type decodeCacheEntry struct {
+	key decodeCacheKey
+	msg Message
+}
+
+// decodeCache is a small LRU of decoded messages by topic, partition and offset,
+// offsets re-delivered after a rebalance are not decoded again
+type decodeCache struct {
+	size    int
+	entries map[decodeCacheKey]*list.Element
+	order   *list.List
+	lock    sync.Mutex
+}
+
+func newDecodeCache(size int) *decodeCache {
+	return &decodeCache{
+		size:    size,
+		entries: make(map[decodeCacheKey]*list.Element, size),
+		order:   list.New(),
+	}
+}
+
+func (c *decodeCache) get(key decodeCacheKey) (Message, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	element, found := c.entries[key]
+	if !found {
+		return Message{}, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*decodeCacheEntry).msg, true
+}
+
+func (c *decodeCache) add(key decodeCacheKey, msg Message) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if element, found := c.entries[key]; found {
+		element.Value.(*decodeCacheEntry).msg = msg
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&decodeCacheEntry{key, msg})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*decodeCacheEntry).key)
+	}
+}
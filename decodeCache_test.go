@@ -0,0 +1,24 @@
+package kafka
+
+import (
+	"testing"
+)
+
+func TestDecodeCache(t *testing.T) {
+	cache := newDecodeCache(2)
+	for i := int64(0); i < 3; i++ {
+		cache.add(decodeCacheKey{"test", 0, i}, Message{Offset: i})
+		if i == 1 {
+			cache.get(decodeCacheKey{"test", 0, 0})
+		}
+	}
+	if _, found := cache.get(decodeCacheKey{"test", 0, 1}); found {
+		t.Errorf("Expected least recently used entry to be evicted")
+	}
+	for _, offset := range []int64{0, 2} {
+		msg, found := cache.get(decodeCacheKey{"test", 0, offset})
+		if !found || msg.Offset != offset {
+			t.Errorf("Expected offset %d to be cached", offset)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package kafka
+
+import (
+	"github.com/linkedin/goavro/v2"
+)
+
+// DecodeMode defines how far the consumer decodes avro values
+type DecodeMode int
+
+const (
+	// DecodeTextual converts the values to textual avro data in Message.Value, the default
+	DecodeTextual DecodeMode = iota
+	// DecodeNative only converts the values to their native goavro form, see Message.Native. Message.Value is empty.
+	DecodeNative
+	// DecodeRaw does not decode the values, Message.Payload is the binary avro data of the value and Message.Codec
+	// its writer schema. Topics with a reader schema or strict validation fail to decode.
+	DecodeRaw
+)
+
+// SetDecodeMode skips the conversions of the avro values the callbacks do not need, e.g. to cut the CPU spent by
+// high-volume consumers that parse Message.Value again. Consumers with a redaction profile can only decode to text.
+func (ac *AvroConsumer) SetDecodeMode(mode DecodeMode) {
+	ac.decodeMode = mode
+}
+
+// Native returns the avro value of the message in native goavro form, nil in DecodeRaw mode
+func (msg Message) Native() interface{} {
+	return msg.native
+}
+
+// Codec returns the schema of the native value of the message, or of its payload in DecodeRaw mode
+func (msg Message) Codec() *goavro.Codec {
+	return msg.codec
+}
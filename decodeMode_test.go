@@ -0,0 +1,92 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/linkedin/goavro/v2"
+)
+
+func newDecodeModeMessage(t testing.TB) (*memorySchemaRegistry, *sarama.ConsumerMessage) {
+	registry := newMemorySchemaRegistry()
+	codec, err := goavro.NewCodec(`{"type": "record", "name": "test", "fields" : [{"name": "val", "type": "int"}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schemaId, err := registry.CreateSubject("test-value", codec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	value := []byte{0, 0, 0, 0, 0, 2}
+	binary.BigEndian.PutUint32(value[1:5], uint32(schemaId))
+	return registry, &sarama.ConsumerMessage{Topic: "test", Key: []byte("k"), Value: value}
+}
+
+func TestAvroConsumer_DecodeMode(t *testing.T) {
+	registry, m := newDecodeModeMessage(t)
+	consumer := &AvroConsumer{SchemaRegistryClient: registry}
+
+	consumer.SetDecodeMode(DecodeNative)
+	msg, err := consumer.ProcessAvroMsg(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	native, ok := msg.Native().(map[string]interface{})
+	if !ok || native["val"] != int32(1) || msg.Value != "" || msg.Codec() == nil || msg.Key != "k" {
+		t.Errorf("Expected the native value only, got %+v", msg)
+	}
+
+	consumer.SetDecodeMode(DecodeRaw)
+	if msg, err = consumer.ProcessAvroMsg(m); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(msg.Payload, []byte{2}) || msg.Native() != nil || msg.Value != "" {
+		t.Errorf("Expected the raw payload only, got %+v", msg)
+	}
+	if native, _, err := msg.Codec().NativeFromBinary(msg.Payload); err != nil || native.(map[string]interface{})["val"] != int32(1) {
+		t.Errorf("Expected the payload to decode with the codec, got %v, %v", native, err)
+	}
+
+	consumer.SetRedactionProfile(RedactionProfile{"val": RedactMask})
+	if _, err := consumer.ProcessAvroMsg(m); err == nil {
+		t.Errorf("Expected redacted consumers to require the textual conversion")
+	}
+}
+
+// codecRegistry returns the same codec for every id, so decode benchmarks do not measure the schema compilation of
+// the memory registry
+type codecRegistry struct {
+	*memorySchemaRegistry
+	codec *goavro.Codec
+}
+
+func (r codecRegistry) GetSchemaContext(ctx context.Context, id int) (*goavro.Codec, error) {
+	return r.codec, nil
+}
+
+func BenchmarkAvroConsumer_DecodeMode(b *testing.B) {
+	memory, m := newDecodeModeMessage(b)
+	codec, err := memory.GetSchema(int(binary.BigEndian.Uint32(m.Value[1:5])))
+	if err != nil {
+		b.Fatal(err)
+	}
+	registry := codecRegistry{memory, codec}
+	for _, mode := range []struct {
+		name string
+		mode DecodeMode
+	}{{"textual", DecodeTextual}, {"native", DecodeNative}, {"raw", DecodeRaw}} {
+		b.Run(mode.name, func(b *testing.B) {
+			consumer := &AvroConsumer{SchemaRegistryClient: registry}
+			consumer.SetDecodeMode(mode.mode)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := consumer.ProcessAvroMsg(m); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package kafka
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/Shopify/sarama"
+	"github.com/linkedin/goavro/v2"
+)
+
+// DualWriteFailurePolicy decides when a dual write is reported as failed
+type DualWriteFailurePolicy int
+
+const (
+	// FailOnEither fails the write if any of the clusters fails
+	FailOnEither DualWriteFailurePolicy = iota
+	// FailOnBoth fails the write only if both clusters fail
+	FailOnBoth
+)
+
+// ClusterResult is the outcome of a write to one cluster
+type ClusterResult struct {
+	Partition int32
+	Offset    int64
+	Err       error
+}
+
+// DualWriteResult holds the per-cluster outcome of a dual write
+type DualWriteResult struct {
+	Primary   ClusterResult
+	Secondary ClusterResult
+}
+
+// Diverged reports whether exactly one of the clusters accepted the message
+func (r DualWriteResult) Diverged() bool {
+	return (r.Primary.Err == nil) != (r.Secondary.Err == nil)
+}
+
+// DualWriteError is returned when a dual write fails according to the failure policy
+type DualWriteError struct {
+	Result DualWriteResult
+}
+
+func (e *DualWriteError) Error() string {
+	return fmt.Sprintf("dual write failed, primary: %v, secondary: %v", e.Result.Primary.Err, e.Result.Secondary.Err)
+}
+
+// DualWriteCallbacks are invoked by the DualWriteProducer
+type DualWriteCallbacks struct {
+	// OnDivergence is called when the message was written to only one of the clusters, e.g. to record it for reconciliation
+	OnDivergence func(topic string, key []byte, value []byte, result DualWriteResult)
+}
+
+// DualWriteProducer sends every message to two clusters, e.g. during a migration.
+// The value is encoded once and framed with the schema id of each cluster's schema registry.
+type DualWriteProducer struct {
+	Primary   *AvroProducer
+	Secondary *AvroProducer
+	policy    DualWriteFailurePolicy
+	callbacks DualWriteCallbacks
+}
+
+// NewDualWriteProducer creates a producer writing to both the primary and the secondary producer
+func NewDualWriteProducer(primary *AvroProducer, secondary *AvroProducer, policy DualWriteFailurePolicy, callbacks DualWriteCallbacks) *DualWriteProducer {
+	return &DualWriteProducer{primary, secondary, policy, callbacks}
+}
+
+// Add encodes the value once and sends it to both clusters concurrently
+func (p *DualWriteProducer) Add(topic string, schema string, key []byte, value []byte) (DualWriteResult, error) {
+	result := DualWriteResult{}
+	avroCodec, err := goavro.NewCodec(schema)
+	if err != nil {
+		return result, err
+	}
+	binaryValue, err := encodeTextual(avroCodec, value)
+	if err != nil {
+		return result, err
+	}
+	var wg sync.WaitGroup
+	send := func(producer *AvroProducer, clusterResult *ClusterResult) {
+		defer wg.Done()
+		schemaId, err := producer.GetSchemaId(topic, avroCodec)
+		if err != nil {
+			clusterResult.Err = err
+			return
+		}
+		clusterResult.Partition, clusterResult.Offset, clusterResult.Err = producer.sendBinary(topic, schemaId, sarama.StringEncoder(key), binaryValue)
+	}
+	wg.Add(2)
+	go send(p.Primary, &result.Primary)
+	go send(p.Secondary, &result.Secondary)
+	wg.Wait()
+
+	if result.Diverged() && p.callbacks.OnDivergence != nil {
+		p.callbacks.OnDivergence(topic, key, value, result)
+	}
+	failed := result.Primary.Err != nil || result.Secondary.Err != nil
+	if p.policy == FailOnBoth {
+		failed = result.Primary.Err != nil && result.Secondary.Err != nil
+	}
+	if failed {
+		return result, &DualWriteError{result}
+	}
+	return result, nil
+}
+
+// Close closes both producers
+func (p *DualWriteProducer) Close() {
+	p.Primary.Close()
+	p.Secondary.Close()
+}
@@ -0,0 +1,51 @@
+package kafka
+
+import (
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/mocks"
+)
+
+func newDualWriteTestProducer(t *testing.T, succeed bool) *AvroProducer {
+	schemaRegistryTestObject := createSchemaRegistryTestObject(t, "test", 1)
+	producerMock := mocks.NewSyncProducer(t, nil)
+	if succeed {
+		producerMock.ExpectSendMessageAndSucceed()
+	} else {
+		producerMock.ExpectSendMessageAndFail(sarama.ErrOutOfBrokers)
+	}
+	return &AvroProducer{producer: producerMock, schemaRegistryClient: NewCachedSchemaRegistryClient([]string{schemaRegistryTestObject.MockServer.URL})}
+}
+
+func TestDualWriteProducer_Add(t *testing.T) {
+	schema := `{"type": "record", "name": "test", "fields" : [{"name": "val", "type": "int", "default": 0}]}`
+	for _, test := range []struct {
+		policy            DualWriteFailurePolicy
+		secondarySucceeds bool
+		expectErr         bool
+		expectDivergence  bool
+	}{
+		{FailOnEither, true, false, false},
+		{FailOnEither, false, true, true},
+		{FailOnBoth, false, false, true},
+	} {
+		diverged := false
+		producer := NewDualWriteProducer(newDualWriteTestProducer(t, true), newDualWriteTestProducer(t, test.secondarySucceeds), test.policy, DualWriteCallbacks{
+			OnDivergence: func(topic string, key []byte, value []byte, result DualWriteResult) {
+				diverged = true
+			},
+		})
+		result, err := producer.Add("test", schema, []byte("key"), []byte(`{"val":1}`))
+		if (err != nil) != test.expectErr {
+			t.Errorf("Unexpected error %v for policy %d", err, test.policy)
+		}
+		if diverged != test.expectDivergence || result.Diverged() != test.expectDivergence {
+			t.Errorf("Expected divergence %v, got %v", test.expectDivergence, diverged)
+		}
+		if result.Primary.Err != nil {
+			t.Errorf("Expected primary to succeed: %v", result.Primary.Err)
+		}
+		producer.Close()
+	}
+}
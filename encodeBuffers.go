@@ -0,0 +1,35 @@
+package kafka
+
+import (
+	"sync"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+// maxPooledBuffer is the capacity above which encoding buffers are not kept, so a few large values do not pin memory
+const maxPooledBuffer = 1 << 20
+
+// encodeBuffers are scratch buffers for binary avro encoding, the encoded values are copied out of them
+var encodeBuffers = sync.Pool{
+	New: func() interface{} {
+		buffer := make([]byte, 0, 1024)
+		return &buffer
+	},
+}
+
+// binaryFromNative encodes the native value into a pooled buffer and returns an exactly sized copy, so the buffer
+// does not grow again for every value
+func binaryFromNative(avroCodec *goavro.Codec, native interface{}) ([]byte, error) {
+	buffer := encodeBuffers.Get().(*[]byte)
+	encoded, err := avroCodec.BinaryFromNative((*buffer)[:0], native)
+	var value []byte
+	if err == nil {
+		value = make([]byte, len(encoded))
+		copy(value, encoded)
+	}
+	if cap(encoded) <= maxPooledBuffer {
+		*buffer = encoded[:0]
+		encodeBuffers.Put(buffer)
+	}
+	return value, err
+}
@@ -0,0 +1,82 @@
+package kafka
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+const benchmarkSchema = `{"type": "record", "name": "test", "fields" : [{"name": "id", "type": "long"},
+	{"name": "name", "type": "string"}, {"name": "tags", "type": {"type": "array", "items": "string"}}]}`
+
+func benchmarkValue() []byte {
+	return []byte(`{"id": 123456789, "name": "` + strings.Repeat("n", 200) + `", "tags": ["a", "b", "c", "d"]}`)
+}
+
+func TestAvroEncoder_Encode(t *testing.T) {
+	encoder := &AvroEncoder{SchemaID: 258, Content: []byte{7, 8}}
+	encoded, err := encoder.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(encoded, []byte{0, 0, 0, 1, 2, 7, 8}) || cap(encoded) != encoder.Length() {
+		t.Errorf("Expected an exactly sized framed value, got %v with capacity %d", encoded, cap(encoded))
+	}
+}
+
+func TestEncodeTextual(t *testing.T) {
+	codec, err := goavro.NewCodec(benchmarkSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := encodeTextual(codec, benchmarkValue())
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := encodeTextual(codec, []byte(`{"id": 1, "name": "", "tags": []}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	native, _, err := codec.NativeFromBinary(first)
+	if err != nil || native.(map[string]interface{})["id"] != int64(123456789) {
+		t.Errorf("Expected the first value not to be overwritten by the next encoding, got %v, %v", native, err)
+	}
+	if len(second) != cap(second) {
+		t.Errorf("Expected an exactly sized value, got length %d and capacity %d", len(second), cap(second))
+	}
+}
+
+func BenchmarkAvroEncoder_Encode(b *testing.B) {
+	encoder := &AvroEncoder{SchemaID: 1, Content: make([]byte, 256)}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		encoder.Encode()
+	}
+}
+
+func BenchmarkEncodeTextual(b *testing.B) {
+	codec, err := goavro.NewCodec(benchmarkSchema)
+	if err != nil {
+		b.Fatal(err)
+	}
+	value := benchmarkValue()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		encodeTextual(codec, value)
+	}
+}
+
+func BenchmarkAvroProducer_PrepareMessage(b *testing.B) {
+	avroProducer := &AvroProducer{schemaRegistryClient: newMemorySchemaRegistry()}
+	value := benchmarkValue()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		msg, err := avroProducer.PrepareMessage("test", benchmarkSchema, []byte("key"), value)
+		if err != nil {
+			b.Fatal(err)
+		}
+		msg.Value.Encode()
+	}
+}
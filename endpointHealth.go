@@ -0,0 +1,149 @@
+package kafka
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	defaultEndpointBackoff = 10 * time.Second
+	maxEndpointBackoff     = 5 * time.Minute
+)
+
+// endpointHealth tracks the registry servers that failed recently. Requests go to the healthy servers first,
+// a failed server is tried last until its backoff expires, the next request sent to it then probes whether it recovered,
+// unless the client probes the failed servers in the background with WithRegistryEndpointProbes.
+// The backoff doubles with every consecutive failure of the server.
+type endpointHealth struct {
+	backoff time.Duration
+	lock    sync.Mutex
+	servers map[string]*endpointState
+}
+
+type endpointState struct {
+	failures       int
+	unhealthyUntil time.Time
+}
+
+func newEndpointHealth(backoff time.Duration) *endpointHealth {
+	return &endpointHealth{backoff: backoff, servers: make(map[string]*endpointState)}
+}
+
+// WithRegistryEndpointBackoff sets how long a registry server that failed is avoided, 10 seconds by default.
+// The backoff doubles with every consecutive failure of the server, up to 5 minutes.
+func WithRegistryEndpointBackoff(backoff time.Duration) SchemaRegistryOption {
+	return func(client *SchemaRegistryClient) {
+		client.endpoints = newEndpointHealth(backoff)
+	}
+}
+
+// WithRegistryEndpointProbes pings the registry servers that failed every interval until the context is done,
+// so a server is used again as soon as it recovered instead of waiting for its backoff to expire and risking
+// a request on it. Without probes, the first request sent to a server after its backoff probes it.
+func WithRegistryEndpointProbes(ctx context.Context, interval time.Duration) SchemaRegistryOption {
+	return func(client *SchemaRegistryClient) {
+		client.probe = &endpointProbe{ctx: ctx, interval: interval}
+	}
+}
+
+// endpointProbe is the schedule of the background probes of a client
+type endpointProbe struct {
+	ctx      context.Context
+	interval time.Duration
+}
+
+// probeEndpoints pings the unhealthy servers of the client every interval until the context is done
+func (client *SchemaRegistryClient) probeEndpoints(probe *endpointProbe) {
+	ticker := time.NewTicker(probe.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			for _, server := range client.endpoints.unhealthy() {
+				// a single server client without retries nor health tracking, so only this server is pinged
+				single := &SchemaRegistryClient{SchemaRegistryConnect: []string{server}, httpClient: client.httpClient,
+					authorize: client.authorize, headers: client.headers, logger: client.logger}
+				err := single.PingContext(probe.ctx)
+				if probe.ctx.Err() != nil {
+					return
+				}
+				orNop(client.logger).Debug("probed schema registry server", "server", server, "error", err)
+				client.endpoints.record(server, registryUnavailable(err))
+			}
+		case <-probe.ctx.Done():
+			return
+		}
+	}
+}
+
+// unhealthy returns the servers that failed and did not serve a request since
+func (h *endpointHealth) unhealthy() []string {
+	if h == nil {
+		return nil
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	servers := make([]string, 0, len(h.servers))
+	for server := range h.servers {
+		servers = append(servers, server)
+	}
+	return servers
+}
+
+// order returns the servers in the order they should be tried: the healthy ones starting from a random one,
+// then the unhealthy ones by the time they recover
+func (h *endpointHealth) order(servers []string) []string {
+	offset := rand.Intn(len(servers))
+	ordered := make([]string, 0, len(servers))
+	for i := range servers {
+		ordered = append(ordered, servers[(i+offset)%len(servers)])
+	}
+	if h == nil {
+		return ordered
+	}
+	now := time.Now()
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	recovery := func(server string) time.Time {
+		if state, ok := h.servers[server]; ok && state.unhealthyUntil.After(now) {
+			return state.unhealthyUntil
+		}
+		return time.Time{}
+	}
+	// insertion sort keeps the random rotation among healthy servers
+	for i := 1; i < len(ordered); i++ {
+		for j := i; j > 0 && recovery(ordered[j]).Before(recovery(ordered[j-1])); j-- {
+			ordered[j], ordered[j-1] = ordered[j-1], ordered[j]
+		}
+	}
+	return ordered
+}
+
+// record marks the server unhealthy after a failed request, or healthy after a served one
+func (h *endpointHealth) record(server string, failed bool) {
+	if h == nil {
+		return
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if !failed {
+		delete(h.servers, server)
+		return
+	}
+	state, ok := h.servers[server]
+	if !ok {
+		state = &endpointState{}
+		h.servers[server] = state
+	}
+	state.failures++
+	backoff := h.backoff
+	for i := 1; i < state.failures && backoff < maxEndpointBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxEndpointBackoff {
+		backoff = maxEndpointBackoff
+	}
+	state.unhealthyUntil = time.Now().Add(backoff)
+}
@@ -0,0 +1,116 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+func TestEndpointHealth_Order(t *testing.T) {
+	health := newEndpointHealth(time.Minute)
+	servers := []string{"a", "b", "c"}
+	health.record("a", true)
+	for i := 0; i < 10; i++ {
+		if ordered := health.order(servers); ordered[2] != "a" {
+			t.Fatalf("Expected the unhealthy server to be tried last, got %v", ordered)
+		}
+	}
+	health.record("b", true)
+	health.record("b", true)
+	if ordered := health.order(servers); ordered[0] != "c" || ordered[1] != "a" || ordered[2] != "b" {
+		t.Errorf("Expected the server recovering last to be tried last, got %v", ordered)
+	}
+	health.record("a", false)
+	health.record("b", false)
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		seen[health.order(servers)[0]] = true
+	}
+	if len(seen) != 3 {
+		t.Errorf("Expected healthy servers to be rotated, got %v", seen)
+	}
+}
+
+func TestEndpointHealth_Backoff(t *testing.T) {
+	health := newEndpointHealth(time.Millisecond)
+	health.record("a", true)
+	time.Sleep(5 * time.Millisecond)
+	if ordered := health.order([]string{"a"}); ordered[0] != "a" {
+		t.Fatalf("Expected %v", ordered)
+	}
+	for i := 0; i < 30; i++ {
+		health.record("a", true)
+	}
+	if until := health.servers["a"].unhealthyUntil; time.Until(until) > maxEndpointBackoff {
+		t.Errorf("Expected the backoff to be capped, got %s", time.Until(until))
+	}
+}
+
+func TestSchemaRegistryClient_Failover(t *testing.T) {
+	var deadCalls, liveCalls int32
+	dead := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&deadCalls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer dead.Close()
+	live := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if body, _ := ioutil.ReadAll(r.Body); len(body) == 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		atomic.AddInt32(&liveCalls, 1)
+		fmt.Fprint(w, `{"id": 1}`)
+	}))
+	defer live.Close()
+	client := NewSchemaRegistryClient([]string{dead.URL, live.URL}, WithRegistryEndpointBackoff(time.Minute))
+	codec, _ := goavro.NewCodec(`"string"`)
+	for i := 0; i < 10; i++ {
+		if _, err := client.CreateSubject("test", codec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if deadCalls > 1 || liveCalls != 10 {
+		t.Errorf("Expected the dead server to be avoided after failing, got %d dead and %d live calls", deadCalls, liveCalls)
+	}
+}
+
+func TestEndpointHealth_Probes(t *testing.T) {
+	var down int32 = 1
+	var pings int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&pings, 1)
+		if atomic.LoadInt32(&down) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, "{}")
+	}))
+	defer server.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client := NewSchemaRegistryClient([]string{server.URL}, WithRegistryEndpointBackoff(time.Hour),
+		WithRegistryEndpointProbes(ctx, 5*time.Millisecond))
+	client.endpoints.record(server.URL, true)
+
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&pings) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if len(client.endpoints.unhealthy()) != 1 {
+		t.Fatalf("Expected a failed probe to keep the server unhealthy")
+	}
+	atomic.StoreInt32(&down, 0)
+	for len(client.endpoints.unhealthy()) != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if len(client.endpoints.unhealthy()) != 0 {
+		t.Errorf("Expected the recovered server to be probed healthy before its backoff expired")
+	}
+}
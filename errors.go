@@ -24,3 +24,27 @@ func newError(resp *http.Response) *Error {
 	}
 	return err
 }
+
+// IsAuthError reports whether the error is an authentication or authorization failure returned by schema registry
+func IsAuthError(err error) bool {
+	registryErr, ok := err.(*Error)
+	if !ok {
+		return false
+	}
+	code := httpStatus(registryErr.ErrorCode)
+	return code == http.StatusUnauthorized || code == http.StatusForbidden
+}
+
+// IsNotFoundError reports whether the error means that the schema, subject or version does not exist in schema registry
+func IsNotFoundError(err error) bool {
+	registryErr, ok := err.(*Error)
+	return ok && httpStatus(registryErr.ErrorCode) == http.StatusNotFound
+}
+
+// httpStatus returns the http status of a schema registry error code, e.g. 404 for 40401
+func httpStatus(code int) int {
+	for code >= 1000 {
+		code /= 100
+	}
+	return code
+}
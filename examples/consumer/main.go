@@ -1,9 +1,11 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"github.com/bsm/sarama-cluster"
 	"github.com/dangkaka/go-kafka-avro"
+	"os"
+	"os/signal"
 )
 
 var kafkaServers = []string{"localhost:9092"}
@@ -18,7 +20,7 @@ func main() {
 		OnError: func(err error) {
 			fmt.Println("Consumer error", err)
 		},
-		OnNotification: func(notification *cluster.Notification) {
+		OnNotification: func(notification *kafka.Notification) {
 			fmt.Println(notification)
 		},
 	}
@@ -27,5 +29,14 @@ func main() {
 	if err != nil {
 		fmt.Println(err)
 	}
-	consumer.Consume()
+	// stop consuming on SIGINT
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	go func() {
+		<-signals
+		cancel()
+	}()
+	consumer.Consume(ctx)
+	consumer.Close()
 }
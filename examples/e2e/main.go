@@ -0,0 +1,302 @@
+// e2e provisions a topic and its schema, produces typed records and a poison message, consumes them back
+// with a dead-letter queue and metrics, and checks that every record arrived unchanged and the poison message
+// was dead-lettered. It runs against the docker-compose setup of the examples by default, pass -brokers and
+// -registry, and the TLS and SASL flags, to run it as an acceptance test against another cluster.
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/dangkaka/go-kafka-avro"
+	"github.com/linkedin/goavro/v2"
+)
+
+const schema = `{
+	"type": "record",
+	"name": "Order",
+	"namespace": "com.example.e2e",
+	"fields": [
+		{"name": "id", "type": "long"},
+		{"name": "customer", "type": "string"},
+		{"name": "amount", "type": "double"}
+	]
+}`
+
+// Order is the typed record produced by the test, its JSON form is the textual avro form of the schema
+type Order struct {
+	ID       int64   `json:"id"`
+	Customer string  `json:"customer"`
+	Amount   float64 `json:"amount"`
+}
+
+// counters counts the consumer events checked by the test
+type counters struct {
+	kafka.NopMetrics
+	consumed     int64
+	deadLettered int64
+}
+
+func (c *counters) MessageConsumed(topic string, err error) { atomic.AddInt64(&c.consumed, 1) }
+func (c *counters) DeadLettered(topic string)               { atomic.AddInt64(&c.deadLettered, 1) }
+
+// settings are the flags of the test
+type settings struct {
+	kafkaServers          []string
+	schemaRegistryServers []string
+	topic                 string
+	partitions            int32
+	replication           int16
+	n                     int
+	timeout               time.Duration
+	keep                  bool
+	tls                   *tls.Config
+	saslUser              string
+	saslPassword          string
+	registryUser          string
+	registryPassword      string
+}
+
+// options returns the producer and consumer options of the TLS and SASL flags
+func (s settings) options() []kafka.Option {
+	var opts []kafka.Option
+	if s.tls != nil {
+		opts = append(opts, kafka.WithTLS(s.tls))
+	}
+	if s.saslUser != "" {
+		opts = append(opts, kafka.WithSASL(s.saslUser, s.saslPassword))
+	}
+	return append(opts, kafka.WithSchemaRegistryOptions(s.registryOptions()...))
+}
+
+func (s settings) registryOptions() []kafka.SchemaRegistryOption {
+	var opts []kafka.SchemaRegistryOption
+	if s.tls != nil {
+		opts = append(opts, kafka.WithRegistryTLS(s.tls))
+	}
+	if s.registryUser != "" {
+		opts = append(opts, kafka.WithRegistryBasicAuth(s.registryUser, s.registryPassword))
+	}
+	return opts
+}
+
+func main() {
+	brokers := flag.String("brokers", "localhost:9092", "comma separated kafka brokers")
+	registry := flag.String("registry", "http://localhost:8081", "comma separated schema registry urls")
+	topic := flag.String("topic", "", "topic to create, a unique name is generated when empty")
+	partitions := flag.Int("partitions", 3, "partitions of the created topic")
+	replication := flag.Int("replication", 1, "replication factor of the created topic")
+	n := flag.Int("n", 100, "number of records to produce")
+	timeout := flag.Duration("timeout", time.Minute, "max time to wait for the records to be consumed")
+	keep := flag.Bool("keep", false, "keep the topic and the subject after the run")
+	useTLS := flag.Bool("tls", false, "connect to the brokers and the registry over TLS")
+	insecure := flag.Bool("tls-insecure", false, "do not verify the certificates of the brokers and the registry")
+	saslUser := flag.String("sasl-user", "", "SASL/PLAIN user of the brokers")
+	saslPassword := flag.String("sasl-password", "", "SASL/PLAIN password of the brokers")
+	registryUser := flag.String("registry-user", "", "basic auth user of the schema registry")
+	registryPassword := flag.String("registry-password", "", "basic auth password of the schema registry")
+	flag.Parse()
+
+	s := settings{
+		kafkaServers:          strings.Split(*brokers, ","),
+		schemaRegistryServers: strings.Split(*registry, ","),
+		topic:                 *topic,
+		partitions:            int32(*partitions),
+		replication:           int16(*replication),
+		n:                     *n,
+		timeout:               *timeout,
+		keep:                  *keep,
+		saslUser:              *saslUser,
+		saslPassword:          *saslPassword,
+		registryUser:          *registryUser,
+		registryPassword:      *registryPassword,
+	}
+	if s.topic == "" {
+		s.topic = fmt.Sprintf("e2e-%d", time.Now().UnixNano())
+	}
+	if *useTLS {
+		s.tls = &tls.Config{InsecureSkipVerify: *insecure}
+	}
+	if err := run(s); err != nil {
+		fmt.Println("FAIL:", err)
+		os.Exit(1)
+	}
+	fmt.Println("PASS")
+}
+
+func run(s settings) error {
+	kafkaServers, schemaRegistryServers, topic, n, timeout := s.kafkaServers, s.schemaRegistryServers, s.topic, s.n, s.timeout
+	// provision the topic and the value schema
+	config := sarama.NewConfig()
+	config.Version = sarama.V2_0_1_0
+	if s.tls != nil {
+		config.Net.TLS.Enable = true
+		config.Net.TLS.Config = s.tls
+	}
+	if s.saslUser != "" {
+		config.Net.SASL.Enable = true
+		config.Net.SASL.User = s.saslUser
+		config.Net.SASL.Password = s.saslPassword
+	}
+	admin, err := sarama.NewClusterAdmin(kafkaServers, config)
+	if err != nil {
+		return fmt.Errorf("could not create cluster admin: %s", err)
+	}
+	defer admin.Close()
+	deadLetterTopic := topic + "-dlq"
+	for _, name := range []string{topic, deadLetterTopic} {
+		err = admin.CreateTopic(name, &sarama.TopicDetail{NumPartitions: s.partitions, ReplicationFactor: s.replication}, false)
+		if err != nil && err != sarama.ErrTopicAlreadyExists {
+			if topicErr, ok := err.(*sarama.TopicError); !ok || topicErr.Err != sarama.ErrTopicAlreadyExists {
+				return fmt.Errorf("could not create topic %s: %s", name, err)
+			}
+		}
+	}
+	registryClient := kafka.NewCachedSchemaRegistryClient(schemaRegistryServers, s.registryOptions()...)
+	if err := registryClient.Ping(); err != nil {
+		return fmt.Errorf("schema registry is not reachable: %s", err)
+	}
+	codec, err := goavro.NewCodec(schema)
+	if err != nil {
+		return err
+	}
+	subject := topic + "-value"
+	schemaId, err := registryClient.CreateSubject(subject, codec)
+	if err != nil {
+		return fmt.Errorf("could not register schema: %s", err)
+	}
+	fmt.Printf("created topic %s and registered schema %d to %s\n", topic, schemaId, subject)
+	if !s.keep {
+		defer func() {
+			admin.DeleteTopic(topic)
+			admin.DeleteTopic(deadLetterTopic)
+			registryClient.DeleteSubject(subject)
+		}()
+	}
+
+	// produce typed records
+	producer, err := kafka.NewAvroProducer(kafkaServers, schemaRegistryServers, s.options()...)
+	if err != nil {
+		return fmt.Errorf("could not create avro producer: %s", err)
+	}
+	defer producer.Close()
+	expected := make(map[string]Order, n)
+	for i := 0; i < n; i++ {
+		order := Order{ID: int64(i), Customer: "customer-" + strconv.Itoa(i%10), Amount: float64(i) * 1.5}
+		value, err := json.Marshal(order)
+		if err != nil {
+			return err
+		}
+		key := strconv.Itoa(i)
+		if err := producer.Add(topic, schema, []byte(key), value); err != nil {
+			return fmt.Errorf("could not produce record %d: %s", i, err)
+		}
+		expected[key] = order
+	}
+	// the poison message is not in the wire format, the consumer dead-letters it
+	deadLetterProducer, err := kafka.NewDeadLetterProducer(kafkaServers, s.options()...)
+	if err != nil {
+		return fmt.Errorf("could not create dead-letter producer: %s", err)
+	}
+	defer deadLetterProducer.Close()
+	_, _, err = deadLetterProducer.SendMessage(&sarama.ProducerMessage{Topic: topic, Key: sarama.StringEncoder("poison"),
+		Value: sarama.StringEncoder("not avro")})
+	if err != nil {
+		return fmt.Errorf("could not produce the poison message: %s", err)
+	}
+	fmt.Printf("produced %d records and a poison message\n", n)
+
+	// consume them back and compare
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	received := make(chan kafka.Message)
+	errs := make(chan error, 1)
+	callbacks := kafka.ConsumerCallbacks{
+		OnDataReceived: func(msg kafka.Message) {
+			select {
+			case received <- msg:
+			case <-ctx.Done():
+			}
+		},
+		OnError: func(err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+		},
+	}
+	metrics := &counters{}
+	consumer, err := kafka.NewAvroConsumer(kafkaServers, schemaRegistryServers, topic, topic+"-group", callbacks,
+		append(s.options(), kafka.WithMetrics(metrics))...)
+	if err != nil {
+		cancel()
+		return fmt.Errorf("could not create avro consumer: %s", err)
+	}
+	consumer.SetDeadLetterQueue(kafka.DeadLetterConfig{Producer: deadLetterProducer, Topic: deadLetterTopic})
+	go consumer.Consume(ctx)
+	defer func() {
+		cancel()
+		consumer.Close()
+	}()
+
+	for len(expected) > 0 {
+		select {
+		case msg := <-received:
+			if msg.SchemaId != schemaId {
+				return fmt.Errorf("record %s has schema %d, expected %d", msg.Key, msg.SchemaId, schemaId)
+			}
+			var order Order
+			if err := json.Unmarshal([]byte(msg.Value), &order); err != nil {
+				return fmt.Errorf("record %s is not a valid order: %s", msg.Key, err)
+			}
+			want, ok := expected[msg.Key]
+			if !ok {
+				return fmt.Errorf("unexpected record %s", msg.Key)
+			}
+			if order != want {
+				return fmt.Errorf("record %s is %+v, expected %+v", msg.Key, order, want)
+			}
+			delete(expected, msg.Key)
+		case err := <-errs:
+			return fmt.Errorf("consumer error: %s", err)
+		case <-ctx.Done():
+			return fmt.Errorf("%d records were not consumed within %s", len(expected), timeout)
+		}
+	}
+	fmt.Printf("consumed %d records\n", n)
+
+	// the poison message follows the records of its partition, it may still be in flight
+	for atomic.LoadInt64(&metrics.deadLettered) == 0 {
+		select {
+		case <-received:
+			return fmt.Errorf("unexpected record after the expected ones")
+		case err := <-errs:
+			return fmt.Errorf("consumer error: %s", err)
+		case <-ctx.Done():
+			return fmt.Errorf("the poison message was not dead-lettered within %s", timeout)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	if consumed := atomic.LoadInt64(&metrics.consumed); consumed < int64(n)+1 {
+		return fmt.Errorf("consumer metrics counted %d messages, expected at least %d", consumed, n+1)
+	}
+	fmt.Printf("dead-lettered the poison message to %s\n", deadLetterTopic)
+
+	// report payload sizes
+	produced := producer.PayloadStats(topic).MessageSize
+	consumed := consumer.PayloadStats(topic).MessageSize
+	fmt.Printf("message size: produced %d (mean %.1f bytes), consumed %d (mean %.1f bytes)\n",
+		produced.Count, produced.Mean, consumed.Count, consumed.Mean)
+	if consumed.Count < int64(n) {
+		return fmt.Errorf("consumer metrics counted %d messages, expected at least %d", consumed.Count, n)
+	}
+	return nil
+}
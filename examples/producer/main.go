@@ -11,6 +11,13 @@ var kafkaServers = []string{"localhost:9092"}
 var schemaRegistryServers = []string{"http://localhost:8081"}
 var topic = "test"
 
+// Example is produced with the schema below, the field names match the avro fields
+type Example struct {
+	Id   string
+	Type string
+	Data string
+}
+
 func main() {
 	var n int
 	schema := `{
@@ -25,23 +32,29 @@ func main() {
 	producer, err := kafka.NewAvroProducer(kafkaServers, schemaRegistryServers)
 	if err != nil {
 		fmt.Printf("Could not create avro producer: %s", err)
+		return
+	}
+	examples, err := kafka.NewTypedProducerWithSchema[Example](producer, topic, schema)
+	if err != nil {
+		fmt.Printf("Could not register the schema: %s", err)
+		return
 	}
 	flag.IntVar(&n, "n", 1, "number")
 	flag.Parse()
 	for i := 0; i < n; i++ {
 		fmt.Println(i)
-		addMsg(producer, schema)
+		addMsg(examples)
 	}
 }
 
-func addMsg(producer *kafka.AvroProducer, schema string) {
-	value := `{
-		"Id": "1",
-		"Type": "example_type",
-		"Data": "example_data"
-	}`
+func addMsg(producer *kafka.TypedProducer[Example]) {
+	value := Example{
+		Id:   "1",
+		Type: "example_type",
+		Data: "example_data",
+	}
 	key := time.Now().String()
-	err := producer.Add(topic, schema, []byte(key), []byte(value))
+	err := producer.Send([]byte(key), value)
 	fmt.Println(key)
 	if err != nil {
 		fmt.Printf("Could not add a msg: %s", err)
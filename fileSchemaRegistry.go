@@ -0,0 +1,108 @@
+package kafka
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dangkaka/go-kafka-avro/internal/memregistry"
+)
+
+// schemaFileTypes are the schema types of the file extensions read by NewFileSchemaRegistry
+var schemaFileTypes = map[string]string{
+	".avsc":  SchemaTypeAvro,
+	".json":  SchemaTypeJSON,
+	".proto": SchemaTypeProtobuf,
+}
+
+type schemaFile struct {
+	version int
+	path    string
+}
+
+// NewFileSchemaRegistry loads a directory of schemas into a read-only registry, for air-gapped environments and CI.
+// The directory has a folder per subject with a file per version: <version>.avsc for avro, <version>.json for
+// JSON Schema and <version>.proto for protobuf, other files are ignored. The id of a schema is derived from
+// its type and content, so producers and consumers loading the same files agree on the ids. A schema whose id is
+// used by another one takes the next free id, the ids then depend on the other files of the directory.
+// Registering a schema that is not in the directory fails.
+func NewFileSchemaRegistry(dir string) (SchemaRegistry, error) {
+	registry := newMemorySchemaRegistry()
+	subjects, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, subject := range subjects {
+		if !subject.IsDir() {
+			continue
+		}
+		files, err := schemaFiles(filepath.Join(dir, subject.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			if err := registry.loadFile(subject.Name(), file); err != nil {
+				return nil, fmt.Errorf("could not load %s: %s", file.path, err)
+			}
+		}
+	}
+	registry.registry.SetGlobalMode(ModeReadOnly)
+	return registry, nil
+}
+
+// schemaFiles returns the schema files of a subject folder by version
+func schemaFiles(dir string) ([]schemaFile, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []schemaFile
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if _, ok := schemaFileTypes[ext]; !ok || entry.IsDir() {
+			continue
+		}
+		version, err := strconv.Atoi(strings.TrimSuffix(entry.Name(), ext))
+		if err != nil || version < 1 {
+			return nil, fmt.Errorf("schema file %s is not named after its version", filepath.Join(dir, entry.Name()))
+		}
+		files = append(files, schemaFile{version: version, path: filepath.Join(dir, entry.Name())})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].version < files[j].version
+	})
+	return files, nil
+}
+
+func (r *memorySchemaRegistry) loadFile(subject string, file schemaFile) error {
+	content, err := ioutil.ReadFile(file.path)
+	if err != nil {
+		return err
+	}
+	schemaType := memregistry.SchemaType(schemaFileTypes[filepath.Ext(file.path)])
+	schema, err := memregistry.Canonical(schemaType, string(content), nil)
+	if err != nil {
+		return err
+	}
+	// the directory is loaded in order, so the registries loading it probe the same ids
+	for id := fileSchemaID(schemaType, schema); ; id = id%0x7fffffff + 1 {
+		_, err = r.registry.Register(subject, schemaType, schema, nil, id, file.version)
+		if registryErr, ok := err.(*Error); !ok || registryErr.ErrorCode != memregistry.CodeIDTaken {
+			return err
+		}
+	}
+}
+
+// fileSchemaID derives a positive schema id from the type and the content of a schema
+func fileSchemaID(schemaType string, schema string) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(schemaType + "\x00" + schema))
+	if id := int(hash.Sum32() & 0x7fffffff); id != 0 {
+		return id
+	}
+	return 1
+}
@@ -0,0 +1,114 @@
+package kafka
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+func writeSchemaFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "schemas")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestNewFileSchemaRegistry(t *testing.T) {
+	first := `{"type": "record", "name": "test", "fields": [{"name": "a", "type": "string"}]}`
+	second := `{
+		"type": "record",
+		"name": "test",
+		"fields": [{"name": "a", "type": "string"}, {"name": "b", "type": "int", "default": 0}]
+	}`
+	dir := writeSchemaFiles(t, map[string]string{
+		"test-value/1.avsc":  first,
+		"test-value/2.avsc":  second,
+		"test-value/README":  "ignored",
+		"other-value/1.avsc": first,
+		"json-value/1.json":  `{"type": "object"}`,
+	})
+	defer os.RemoveAll(dir)
+	registry, err := NewFileSchemaRegistry(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if versions, _ := registry.GetVersions("test-value"); !reflect.DeepEqual(versions, []int{1, 2}) {
+		t.Errorf("Expected versions 1 and 2, got %v", versions)
+	}
+	codec, _ := goavro.NewCodec(first)
+	id, err := registry.CreateSubject("test-value", codec)
+	if err != nil {
+		t.Fatalf("Expected the schema of the files to be found, got %v", err)
+	}
+	if shared, _ := registry.IsSchemaRegistered("other-value", codec); shared != id {
+		t.Errorf("Expected subjects to share the id of a schema, got %d and %d", id, shared)
+	}
+	if id != fileSchemaID("", codec.Schema()) {
+		t.Errorf("Expected the id to be derived from the schema, got %d", id)
+	}
+	latest, err := registry.GetLatestSchemaMetadata("json-value")
+	if err != nil || latest.SchemaType != SchemaTypeJSON {
+		t.Errorf("Expected a JSON schema, got %+v, %v", latest, err)
+	}
+	unknown, _ := goavro.NewCodec(`"string"`)
+	if _, err := registry.CreateSubject("test-value", unknown); err == nil {
+		t.Errorf("Expected schemas missing from the files to be rejected")
+	}
+
+	again, err := NewFileSchemaRegistry(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata, err := again.GetSchemaMetadata("test-value", 1); err != nil || metadata.ID != id {
+		t.Errorf("Expected the ids to be stable across loads, got %+v, %v", metadata, err)
+	}
+}
+
+func TestNewFileSchemaRegistry_Invalid(t *testing.T) {
+	dir := writeSchemaFiles(t, map[string]string{"test-value/latest.avsc": `"string"`})
+	defer os.RemoveAll(dir)
+	if _, err := NewFileSchemaRegistry(dir); err == nil {
+		t.Errorf("Expected files not named after their version to be rejected")
+	}
+	dir = writeSchemaFiles(t, map[string]string{"test-value/1.avsc": `{"type": "unknown"}`})
+	defer os.RemoveAll(dir)
+	if _, err := NewFileSchemaRegistry(dir); err == nil {
+		t.Errorf("Expected invalid schemas to be rejected")
+	}
+}
+
+func TestFileSchemaRegistry_IDCollision(t *testing.T) {
+	schema := `{"type":"record","name":"test","fields":[{"name":"a","type":"string"}]}`
+	dir := writeSchemaFiles(t, map[string]string{"test-value/1.avsc": schema})
+	defer os.RemoveAll(dir)
+	files, err := schemaFiles(filepath.Join(dir, "test-value"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// another schema took the id of the file, e.g. a hash collision
+	registry := newMemorySchemaRegistry()
+	taken := fileSchemaID("", schema)
+	if _, err := registry.registry.Register("other-value", "", `"string"`, nil, taken, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.loadFile("test-value", files[0]); err != nil {
+		t.Fatalf("Expected the schema to take the next free id, got %v", err)
+	}
+	if metadata, err := registry.GetLatestSchemaMetadata("test-value"); err != nil || metadata.ID != taken%0x7fffffff+1 {
+		t.Errorf("Expected the id after %d, got %+v, %v", taken, metadata, err)
+	}
+}
@@ -0,0 +1,295 @@
+package kafka
+
+import (
+	"fmt"
+	"math/big"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+// maxGeneratorDepth bounds recursive schemas, deeper values pick null union branches and empty collections
+const maxGeneratorDepth = 8
+
+const generatorAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// Generator produces random records that are valid for an avro schema, including logical types, unions and enums
+type Generator struct {
+	Codec  *goavro.Codec
+	schema interface{}
+	named  namedSchemas
+	rand   *rand.Rand
+}
+
+// NewGenerator creates a generator for the schema
+func NewGenerator(schema string) (*Generator, error) {
+	codec, err := goavro.NewCodec(schema)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := parseSchemaJSON(schema)
+	if err != nil {
+		return nil, err
+	}
+	generator := &Generator{
+		Codec:  codec,
+		schema: parsed,
+		named:  make(namedSchemas),
+		rand:   rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	generator.named.collect(parsed, "")
+	return generator, nil
+}
+
+// NewGeneratorForSubject creates a generator for the latest schema registered to the subject
+func NewGeneratorForSubject(client SchemaRegistryClientInterface, subject string) (*Generator, error) {
+	codec, err := client.GetLatestSchema(subject)
+	if err != nil {
+		return nil, err
+	}
+	return NewGenerator(codec.Schema())
+}
+
+// Native returns a random record in native goavro form
+func (g *Generator) Native() (interface{}, error) {
+	return g.generate(g.schema, "", 0)
+}
+
+// Textual returns a random record in textual avro form, as accepted by AvroProducer.Add
+func (g *Generator) Textual() ([]byte, error) {
+	native, err := g.Native()
+	if err != nil {
+		return nil, err
+	}
+	return g.Codec.TextualFromNative(nil, native)
+}
+
+func (g *Generator) generate(node interface{}, namespace string, depth int) (interface{}, error) {
+	switch n := node.(type) {
+	case string:
+		if primitiveTypes[n] {
+			return g.primitive(n), nil
+		}
+		fullName := qualifyName(n, namespace)
+		definition, ok := g.named[fullName]
+		if !ok {
+			return nil, fmt.Errorf("unknown named type: %s", fullName)
+		}
+		return g.generate(definition, namespaceOf(fullName), depth)
+	case []interface{}:
+		if len(n) == 0 {
+			return nil, fmt.Errorf("empty union")
+		}
+		branch := n[g.rand.Intn(len(n))]
+		if depth >= maxGeneratorDepth {
+			for _, b := range n {
+				if b == "null" {
+					branch = b
+				}
+			}
+		}
+		if branch == "null" {
+			return nil, nil
+		}
+		value, err := g.generate(branch, namespace, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		return goavro.Union(unionBranchName(branch, namespace), value), nil
+	case map[string]interface{}:
+		return g.complex(n, namespace, depth)
+	}
+	return nil, fmt.Errorf("unsupported schema: %v", node)
+}
+
+func (g *Generator) complex(node map[string]interface{}, namespace string, depth int) (interface{}, error) {
+	typeName, _ := node["type"].(string)
+	if logicalType, ok := node["logicalType"].(string); ok {
+		if value, ok := g.logical(typeName, logicalType, node); ok {
+			return value, nil
+		}
+	}
+	size := 0
+	if depth < maxGeneratorDepth {
+		size = g.rand.Intn(4)
+	}
+	switch typeName {
+	case "record", "error":
+		_, recordNamespace := definedName(node, namespace)
+		record := make(map[string]interface{})
+		fields, _ := node["fields"].([]interface{})
+		for _, f := range fields {
+			field, ok := f.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			value, err := g.generate(field["type"], recordNamespace, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			record[field["name"].(string)] = value
+		}
+		return record, nil
+	case "enum":
+		symbols, _ := node["symbols"].([]interface{})
+		if len(symbols) == 0 {
+			return nil, fmt.Errorf("enum without symbols")
+		}
+		return symbols[g.rand.Intn(len(symbols))], nil
+	case "fixed":
+		fixedSize, _ := node["size"].(float64)
+		return g.bytes(int(fixedSize)), nil
+	case "array":
+		items := make([]interface{}, size)
+		for i := range items {
+			value, err := g.generate(node["items"], namespace, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = value
+		}
+		return items, nil
+	case "map":
+		values := make(map[string]interface{}, size)
+		for i := 0; i < size; i++ {
+			value, err := g.generate(node["values"], namespace, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			values[g.string(8)] = value
+		}
+		return values, nil
+	}
+	return g.generate(node["type"], namespace, depth)
+}
+
+func (g *Generator) logical(typeName string, logicalType string, node map[string]interface{}) (interface{}, bool) {
+	now := time.Now().UTC()
+	switch typeName + "." + logicalType {
+	case "int.date":
+		return now.AddDate(0, 0, -g.rand.Intn(3650)).Truncate(24 * time.Hour), true
+	case "int.time-millis":
+		return time.Duration(g.rand.Int63n(int64(24*time.Hour/time.Millisecond))) * time.Millisecond, true
+	case "long.time-micros":
+		return time.Duration(g.rand.Int63n(int64(24*time.Hour/time.Microsecond))) * time.Microsecond, true
+	case "long.timestamp-millis":
+		return now.Add(-time.Duration(g.rand.Int63n(int64(365 * 24 * time.Hour)))).Truncate(time.Millisecond), true
+	case "long.timestamp-micros":
+		return now.Add(-time.Duration(g.rand.Int63n(int64(365 * 24 * time.Hour)))).Truncate(time.Microsecond), true
+	case "bytes.decimal", "fixed.decimal":
+		precision, _ := node["precision"].(float64)
+		scale, _ := node["scale"].(float64)
+		if precision < 1 {
+			return nil, false
+		}
+		digits := int(precision)
+		if digits > 18 {
+			digits = 18
+		}
+		unscaled := big.NewInt(g.rand.Int63n(pow10(digits)))
+		if g.rand.Intn(2) == 0 {
+			unscaled.Neg(unscaled)
+		}
+		return new(big.Rat).SetFrac(unscaled, big.NewInt(pow10(int(scale)))), true
+	case "string.uuid":
+		b := g.bytes(16)
+		return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), true
+	}
+	return nil, false
+}
+
+func pow10(n int) int64 {
+	result := int64(1)
+	for i := 0; i < n; i++ {
+		result *= 10
+	}
+	return result
+}
+
+func (g *Generator) primitive(typeName string) interface{} {
+	switch typeName {
+	case "boolean":
+		return g.rand.Intn(2) == 1
+	case "int":
+		return g.rand.Int31()
+	case "long":
+		return g.rand.Int63()
+	case "float":
+		return g.rand.Float32()
+	case "double":
+		return g.rand.Float64()
+	case "bytes":
+		return g.bytes(g.rand.Intn(16))
+	case "string":
+		return g.string(1 + g.rand.Intn(16))
+	}
+	return nil
+}
+
+func (g *Generator) bytes(n int) []byte {
+	b := make([]byte, n)
+	g.rand.Read(b)
+	return b
+}
+
+func (g *Generator) string(n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = generatorAlphabet[g.rand.Intn(len(generatorAlphabet))]
+	}
+	return string(b)
+}
+
+// LoadTestConfig controls a load test run
+type LoadTestConfig struct {
+	Topic string
+	// Rate is the target number of messages per second, unlimited when 0
+	Rate float64
+	// Count stops the run after this many messages, Duration after this much time, whichever comes first
+	Count    int
+	Duration time.Duration
+}
+
+// LoadTestResult summarizes a load test run
+type LoadTestResult struct {
+	Sent    int
+	Failed  int
+	Elapsed time.Duration
+}
+
+// RunLoadTest produces random records at the target rate until the configured count or duration is reached
+func RunLoadTest(producer *AvroProducer, generator *Generator, config LoadTestConfig) (LoadTestResult, error) {
+	if config.Count <= 0 && config.Duration <= 0 {
+		return LoadTestResult{}, fmt.Errorf("load test needs a count or a duration")
+	}
+	var tick <-chan time.Time
+	if config.Rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / config.Rate))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	schema := generator.Codec.Schema()
+	result := LoadTestResult{}
+	start := time.Now()
+	for i := 0; config.Count <= 0 || i < config.Count; i++ {
+		if config.Duration > 0 && time.Since(start) >= config.Duration {
+			break
+		}
+		if tick != nil {
+			<-tick
+		}
+		value, err := generator.Textual()
+		if err != nil {
+			return result, err
+		}
+		if err := producer.Add(config.Topic, schema, []byte(strconv.Itoa(i)), value); err != nil {
+			result.Failed++
+		} else {
+			result.Sent++
+		}
+	}
+	result.Elapsed = time.Since(start)
+	return result, nil
+}
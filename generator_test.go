@@ -0,0 +1,65 @@
+package kafka
+
+import (
+	"testing"
+
+	"github.com/Shopify/sarama/mocks"
+)
+
+var generatorTestSchema = `{
+	"type": "record", "name": "Event", "namespace": "com.example",
+	"fields": [
+		{"name": "id", "type": {"type": "string", "logicalType": "uuid"}},
+		{"name": "kind", "type": {"type": "enum", "name": "Kind", "symbols": ["A", "B"]}},
+		{"name": "other", "type": ["null", "Kind"]},
+		{"name": "at", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+		{"name": "when", "type": ["null", {"type": "long", "logicalType": "timestamp-micros"}]},
+		{"name": "day", "type": {"type": "int", "logicalType": "date"}},
+		{"name": "amount", "type": {"type": "bytes", "logicalType": "decimal", "precision": 10, "scale": 2}},
+		{"name": "hash", "type": {"type": "fixed", "name": "Hash", "size": 4}},
+		{"name": "tags", "type": {"type": "array", "items": "string"}},
+		{"name": "attrs", "type": {"type": "map", "values": ["null", "long", "double"]}},
+		{"name": "next", "type": ["null", "Event"]}
+	]
+}`
+
+func TestGenerator(t *testing.T) {
+	generator, err := NewGenerator(generatorTestSchema)
+	if err != nil {
+		t.Fatalf("Error creating generator: %v", err)
+	}
+	for i := 0; i < 100; i++ {
+		native, err := generator.Native()
+		if err != nil {
+			t.Fatalf("Error generating record: %v", err)
+		}
+		if _, err := generator.Codec.BinaryFromNative(nil, native); err != nil {
+			t.Fatalf("Generated record is not valid: %v", err)
+		}
+		if _, err := generator.Textual(); err != nil {
+			t.Fatalf("Error generating textual record: %v", err)
+		}
+	}
+}
+
+func TestRunLoadTest(t *testing.T) {
+	schemaRegistryTestObject := createSchemaRegistryTestObject(t, "test", 1)
+	defer schemaRegistryTestObject.MockServer.Close()
+	producerMock := mocks.NewSyncProducer(t, nil)
+	for i := 0; i < 3; i++ {
+		producerMock.ExpectSendMessageAndSucceed()
+	}
+	avroProducer := &AvroProducer{producer: producerMock, schemaRegistryClient: NewCachedSchemaRegistryClient([]string{schemaRegistryTestObject.MockServer.URL})}
+	defer avroProducer.Close()
+	generator, err := NewGeneratorForSubject(avroProducer.schemaRegistryClient, schemaRegistryTestObject.Subject)
+	if err != nil {
+		t.Fatalf("Error creating generator: %v", err)
+	}
+	result, err := RunLoadTest(avroProducer, generator, LoadTestConfig{Topic: "test", Rate: 1000, Count: 3})
+	if err != nil {
+		t.Fatalf("Error running load test: %v", err)
+	}
+	if result.Sent != 3 || result.Failed != 0 {
+		t.Errorf("Expected 3 sent messages, got %+v", result)
+	}
+}
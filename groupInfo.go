@@ -0,0 +1,63 @@
+package kafka
+
+import (
+	"github.com/Shopify/sarama"
+)
+
+// GroupMember describes a member of a consumer group and the partitions assigned to it
+type GroupMember struct {
+	MemberID   string
+	ClientID   string
+	ClientHost string
+	Assignment map[string][]int32
+}
+
+// GroupDescription describes the state and the members of a consumer group
+type GroupDescription struct {
+	GroupID  string
+	State    string
+	Protocol string
+	Members  []GroupMember
+}
+
+// DescribeGroup returns the current members of the consumer group as seen by the group coordinator,
+// see Membership for the generation id and leadership of this member
+func (ac *AvroConsumer) DescribeGroup() (*GroupDescription, error) {
+	admin, err := sarama.NewClusterAdmin(ac.kafkaServers, ac.saramaConfig)
+	if err != nil {
+		return nil, err
+	}
+	defer admin.Close()
+	groups, err := admin.DescribeConsumerGroups([]string{ac.groupId})
+	if err != nil {
+		return nil, err
+	}
+	if len(groups) == 0 {
+		return nil, sarama.ErrGroupIDNotFound
+	}
+	return newGroupDescription(groups[0])
+}
+
+func newGroupDescription(group *sarama.GroupDescription) (*GroupDescription, error) {
+	if group.Err != sarama.ErrNoError {
+		return nil, group.Err
+	}
+	description := &GroupDescription{
+		GroupID:  group.GroupId,
+		State:    group.State,
+		Protocol: group.Protocol,
+	}
+	for memberID, member := range group.Members {
+		assignment, err := member.GetMemberAssignment()
+		if err != nil {
+			return nil, err
+		}
+		description.Members = append(description.Members, GroupMember{
+			MemberID:   memberID,
+			ClientID:   member.ClientId,
+			ClientHost: member.ClientHost,
+			Assignment: assignment.Topics,
+		})
+	}
+	return description, nil
+}
@@ -0,0 +1,30 @@
+package kafka
+
+import (
+	"testing"
+
+	"github.com/Shopify/sarama"
+)
+
+func TestNewGroupDescription(t *testing.T) {
+	description, err := newGroupDescription(&sarama.GroupDescription{
+		GroupId:  "group",
+		State:    "Stable",
+		Protocol: "range",
+		Members: map[string]*sarama.GroupMemberDescription{
+			"member-1": {ClientId: "client", ClientHost: "/127.0.0.1"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Error converting group description: %v", err)
+	}
+	if description.GroupID != "group" || description.State != "Stable" || len(description.Members) != 1 {
+		t.Errorf("Unexpected group description: %+v", description)
+	}
+	if member := description.Members[0]; member.MemberID != "member-1" || member.ClientID != "client" {
+		t.Errorf("Unexpected group member: %+v", member)
+	}
+	if _, err := newGroupDescription(&sarama.GroupDescription{Err: sarama.ErrGroupAuthorizationFailed}); err == nil {
+		t.Errorf("Expected group error")
+	}
+}
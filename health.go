@@ -0,0 +1,73 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Shopify/sarama"
+)
+
+// Healthy checks that the schema registry answers GET /subjects with our credentials,
+// unlike Ping it needs read access to the subjects
+func (client *SchemaRegistryClient) Healthy(ctx context.Context) error {
+	_, err := client.httpCall(ctx, "GET", subjects, nil)
+	return err
+}
+
+// Healthy checks the schema registry, the caches are bypassed
+func (client *CachedSchemaRegistryClient) Healthy(ctx context.Context) error {
+	return client.SchemaRegistryClient.Healthy(ctx)
+}
+
+// Healthy always succeeds
+func (r *memorySchemaRegistry) Healthy(ctx context.Context) error {
+	return nil
+}
+
+// Healthy checks that the brokers answer a metadata request and that the schema registry is healthy,
+// for use in readiness and liveness probes
+func (ap *AvroProducer) Healthy(ctx context.Context) error {
+	if ap.client != nil {
+		if err := withContext(ctx, func() error { return ap.client.RefreshMetadata() }); err != nil {
+			return err
+		}
+	}
+	return ap.schemaRegistryClient.Healthy(ctx)
+}
+
+// Healthy checks that the consumer is not halted, that the brokers answer a metadata request
+// for the consumed topics and that the schema registry is healthy, for use in readiness and liveness probes
+func (ac *AvroConsumer) Healthy(ctx context.Context) error {
+	if ac.isHalted() {
+		return fmt.Errorf("consumer of group %s is halted", ac.groupId)
+	}
+	if ac.kafkaServers != nil {
+		err := withContext(ctx, func() error {
+			client, err := sarama.NewClient(ac.kafkaServers, ac.saramaConfig)
+			if err != nil {
+				return err
+			}
+			defer client.Close()
+			return client.RefreshMetadata(ac.topics...)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return ac.SchemaRegistryClient.Healthy(ctx)
+}
+
+// withContext runs f and returns its error, or the error of ctx once it is done.
+// The sarama client does not take a context, so f keeps running in the background.
+func withContext(ctx context.Context, f func() error) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- f()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,77 @@
+package kafka
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Shopify/sarama"
+)
+
+func TestSchemaRegistryClient_Healthy(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if r.Header.Get("Authorization") == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`["test-value"]`))
+	}))
+	defer server.Close()
+
+	if err := NewCachedSchemaRegistryClient([]string{server.URL}).Healthy(context.Background()); !IsAuthError(err) {
+		t.Errorf("Expected an auth error, got %v", err)
+	}
+	client := NewCachedSchemaRegistryClient([]string{server.URL}, WithRegistryHeader("Authorization", "Bearer token"))
+	if err := client.Healthy(context.Background()); err != nil {
+		t.Errorf("Expected a healthy registry, got %v", err)
+	}
+	if len(paths) != 2 || paths[1] != "/subjects" {
+		t.Errorf("Expected GET /subjects, got %v", paths)
+	}
+}
+
+func TestAvroProducer_Healthy(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("test", 0, broker.BrokerID()),
+	})
+	client, err := sarama.NewClient([]string{broker.Addr()}, sarama.NewConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	producer := &AvroProducer{client: client, schemaRegistryClient: newMemorySchemaRegistry()}
+	if err := producer.Healthy(context.Background()); err != nil {
+		t.Errorf("Expected a healthy producer, got %v", err)
+	}
+	client.Close()
+	if err := producer.Healthy(context.Background()); err == nil {
+		t.Errorf("Expected a closed producer to be unhealthy")
+	}
+}
+
+func TestAvroConsumer_Healthy(t *testing.T) {
+	consumer := &AvroConsumer{SchemaRegistryClient: newMemorySchemaRegistry()}
+	if err := consumer.Healthy(context.Background()); err != nil {
+		t.Errorf("Expected a healthy consumer, got %v", err)
+	}
+	consumer.halt()
+	if err := consumer.Healthy(context.Background()); err == nil {
+		t.Errorf("Expected a halted consumer to be unhealthy")
+	}
+
+	config := sarama.NewConfig()
+	config.Metadata.Retry.Max = 0
+	consumer = &AvroConsumer{SchemaRegistryClient: newMemorySchemaRegistry(), kafkaServers: []string{"127.0.0.1:1"},
+		saramaConfig: config}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := consumer.Healthy(ctx); err == nil {
+		t.Errorf("Expected unreachable brokers to be unhealthy")
+	}
+}
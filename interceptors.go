@@ -0,0 +1,50 @@
+package kafka
+
+import (
+	"github.com/Shopify/sarama"
+)
+
+// ConsumerInterceptor is middleware of a consumer, e.g. for tracing, metrics or scrubbing personal data.
+// Implementations can embed NopInterceptor to only handle some events.
+type ConsumerInterceptor interface {
+	// OnReceive is called with the raw message before it is decoded, it may change the message
+	OnReceive(m *sarama.ConsumerMessage)
+	// OnDecoded is called with the decoded message or the decode error, it returns the message passed to the
+	// callbacks and the next interceptors
+	OnDecoded(msg Message, err error) Message
+	// OnHandled is called once the callbacks handled the message, with the decode error or the error of OnProcess,
+	// OnBatchReceived or the sink. It is not called for messages dead-lettered or skipped before the callbacks.
+	OnHandled(msg Message, err error)
+}
+
+// NopInterceptor ignores every event
+type NopInterceptor struct{}
+
+func (NopInterceptor) OnReceive(m *sarama.ConsumerMessage)      {}
+func (NopInterceptor) OnDecoded(msg Message, err error) Message { return msg }
+func (NopInterceptor) OnHandled(msg Message, err error)         {}
+
+// AddInterceptor appends an interceptor to the chain of the consumer, interceptors are called in the order they
+// were added. It should be called before consuming.
+func (ac *AvroConsumer) AddInterceptor(interceptor ConsumerInterceptor) {
+	ac.interceptors = append(ac.interceptors, interceptor)
+}
+
+func (ac *AvroConsumer) interceptReceive(m *sarama.ConsumerMessage) {
+	for _, interceptor := range ac.interceptors {
+		interceptor.OnReceive(m)
+	}
+}
+
+func (ac *AvroConsumer) interceptDecoded(msg Message, err error) Message {
+	for _, interceptor := range ac.interceptors {
+		msg = interceptor.OnDecoded(msg, err)
+	}
+	return msg
+}
+
+func (ac *AvroConsumer) interceptHandled(msg Message, err error) {
+	for _, interceptor := range ac.interceptors {
+		interceptor.OnHandled(msg, err)
+	}
+}
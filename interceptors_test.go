@@ -0,0 +1,64 @@
+package kafka
+
+import (
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/linkedin/goavro/v2"
+)
+
+type recordingInterceptor struct {
+	NopInterceptor
+	events []string
+}
+
+func (i *recordingInterceptor) OnReceive(m *sarama.ConsumerMessage) {
+	i.events = append(i.events, "receive")
+}
+
+func (i *recordingInterceptor) OnDecoded(msg Message, err error) Message {
+	i.events = append(i.events, "decoded")
+	msg.Value = `{"val":0}`
+	return msg
+}
+
+func (i *recordingInterceptor) OnHandled(msg Message, err error) {
+	i.events = append(i.events, "handled: "+err.Error())
+}
+
+func TestAvroConsumer_Interceptors(t *testing.T) {
+	registry := newMemorySchemaRegistry()
+	codec, err := goavro.NewCodec(`{"type": "record", "name": "test", "fields" : [{"name": "val", "type": "int"}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schemaId, err := registry.CreateSubject("test-value", codec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	value := []byte{0, 0, 0, 0, 0, 2}
+	binary.BigEndian.PutUint32(value[1:5], uint32(schemaId))
+
+	var processed []string
+	consumer := &AvroConsumer{SchemaRegistryClient: registry, callbacks: ConsumerCallbacks{
+		OnProcess: func(msg Message) error {
+			processed = append(processed, msg.Value)
+			return errors.New("failed")
+		},
+		OnError: func(err error) {},
+	}}
+	interceptor := &recordingInterceptor{}
+	consumer.AddInterceptor(interceptor)
+	consumer.AddInterceptor(NopInterceptor{})
+	consumer.handle(newTestSession(nil), &sarama.ConsumerMessage{Topic: "test", Value: value})
+
+	if !reflect.DeepEqual(processed, []string{`{"val":0}`}) {
+		t.Errorf("Expected the intercepted value to be processed, got %v", processed)
+	}
+	if expected := []string{"receive", "decoded", "handled: failed"}; !reflect.DeepEqual(interceptor.events, expected) {
+		t.Errorf("Expected events %v, got %v", expected, interceptor.events)
+	}
+}
@@ -0,0 +1,34 @@
+package kafka
+
+import (
+	"context"
+
+	"github.com/Shopify/sarama"
+	"github.com/linkedin/goavro/v2"
+)
+
+// AvroMessageProducer is the api of AvroProducer to produce messages, application code depending on it
+// can be tested with kafkatest.MockProducer
+type AvroMessageProducer interface {
+	GetSchemaId(topic string, avroCodec *goavro.Codec) (int, error)
+	Add(topic string, schema string, key []byte, value []byte) error
+	AddWithImports(topic string, schema string, imports []SchemaImport, key []byte, value []byte) error
+	AddNative(topic string, schema string, key []byte, native interface{}) error
+	AddStruct(topic string, schema string, key []byte, value interface{}) error
+	AddWithAvroKey(topic string, keySchema string, schema string, key []byte, value []byte) error
+	AddProtobuf(topic string, schema string, messageIndexes []int, key []byte, value []byte) error
+	Healthy(ctx context.Context) error
+	Close()
+}
+
+// AvroMessageConsumer is the api of AvroConsumer to consume messages, application code depending on it
+// can be tested with kafkatest.MockConsumer
+type AvroMessageConsumer interface {
+	Consume(ctx context.Context)
+	ProcessAvroMsg(m *sarama.ConsumerMessage) (Message, error)
+	ProcessAvroMsgContext(ctx context.Context, m *sarama.ConsumerMessage) (Message, error)
+	MarkOffset(msg Message)
+	Membership() GroupMembership
+	Healthy(ctx context.Context) error
+	Close()
+}
@@ -0,0 +1,6 @@
+package kafka
+
+var (
+	_ AvroMessageProducer = (*AvroProducer)(nil)
+	_ AvroMessageConsumer = (*AvroConsumer)(nil)
+)
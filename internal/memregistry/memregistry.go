@@ -0,0 +1,481 @@
+// Package memregistry is the in-memory schema storage shared by kafkatest.SchemaRegistry and the file-based
+// schema registry of the kafka package, which adapt it to kafka.SchemaRegistry.
+package memregistry
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+// the values of the kafka package, which this package cannot import
+const (
+	schemaTypeAvro        = "AVRO"
+	compatibilityBackward = "BACKWARD"
+
+	// ModeReadWrite and ModeReadOnly are the modes of schema registry
+	ModeReadWrite = "READWRITE"
+	ModeReadOnly  = "READONLY"
+
+	// CodeIDTaken is the error code of a registration with an id used by another schema
+	CodeIDTaken = 42207
+)
+
+// Reference points to a schema registered under another subject
+type Reference struct {
+	Name    string
+	Subject string
+	Version int
+}
+
+// Schema is a registered schema, with the subject and the version when it is read from a subject
+type Schema struct {
+	ID         int
+	Subject    string
+	Version    int
+	SchemaType string
+	References []Reference
+	Schema     string
+}
+
+// SubjectVersion identifies a version of a subject
+type SubjectVersion struct {
+	Subject string
+	Version int
+}
+
+// Registry stores the schemas by id and the versions of the subjects. Schemas registered under several subjects
+// share their id, registering a schema already registered under the subject returns its id in every mode.
+type Registry struct {
+	lock                sync.Mutex
+	newError            func(code int, message string) error
+	schemas             map[int]*Schema
+	subjects            map[string][]*subjectVersion
+	nextID              int
+	compatibility       map[string]string
+	globalCompatibility string
+	modes               map[string]string
+	globalMode          string
+}
+
+type subjectVersion struct {
+	Schema
+	deleted bool
+}
+
+// New creates an empty registry returning the errors built by newError, e.g. kafka.Error
+func New(newError func(code int, message string) error) *Registry {
+	return &Registry{
+		newError:            newError,
+		schemas:             make(map[int]*Schema),
+		subjects:            make(map[string][]*subjectVersion),
+		nextID:              1,
+		compatibility:       make(map[string]string),
+		globalCompatibility: compatibilityBackward,
+		modes:               make(map[string]string),
+		globalMode:          ModeReadWrite,
+	}
+}
+
+func (r *Registry) subjectNotFound(subject string) error {
+	return r.newError(40401, fmt.Sprintf("Subject '%s' not found.", subject))
+}
+
+func (r *Registry) versionNotFound(version int) error {
+	return r.newError(40402, fmt.Sprintf("Version %d not found.", version))
+}
+
+func (r *Registry) schemaNotFound() error {
+	return r.newError(40403, "Schema not found")
+}
+
+// SchemaType returns the type stored for a schema, the registry omits the default AVRO type
+func SchemaType(t string) string {
+	if t == schemaTypeAvro {
+		return ""
+	}
+	return t
+}
+
+// Canonical returns the compact form of avro schemas without references, so their formatting does not matter
+func Canonical(t string, schema string, references []Reference) (string, error) {
+	if t != "" || len(references) > 0 {
+		return schema, nil
+	}
+	codec, err := goavro.NewCodec(schema)
+	if err != nil {
+		return schema, err
+	}
+	return codec.Schema(), nil
+}
+
+// Codec returns the codec of an avro schema
+func Codec(schema *Schema, err error) (*goavro.Codec, error) {
+	if err != nil {
+		return nil, err
+	}
+	if schema.SchemaType != "" {
+		return nil, fmt.Errorf("schema %d is a %s schema", schema.ID, schema.SchemaType)
+	}
+	return goavro.NewCodec(schema.Schema)
+}
+
+func sameSchema(registered *Schema, t string, schema string, references []Reference) bool {
+	return registered.Schema == schema && registered.SchemaType == t &&
+		(len(registered.References) == 0 && len(references) == 0 || reflect.DeepEqual(registered.References, references))
+}
+
+// live returns the versions of the subject that are not soft deleted
+func (r *Registry) live(subject string) []*subjectVersion {
+	var versions []*subjectVersion
+	for _, version := range r.subjects[subject] {
+		if !version.deleted {
+			versions = append(versions, version)
+		}
+	}
+	return versions
+}
+
+func (r *Registry) find(subject string, version int) (*subjectVersion, error) {
+	versions := r.live(subject)
+	if len(versions) == 0 {
+		return nil, r.subjectNotFound(subject)
+	}
+	for _, v := range versions {
+		if v.Version == version {
+			return v, nil
+		}
+	}
+	return nil, r.versionNotFound(version)
+}
+
+func (r *Registry) mode(subject string) string {
+	if mode, ok := r.modes[subject]; ok {
+		return mode
+	}
+	return r.globalMode
+}
+
+// Register registers the schema under the subject and returns its id. The id and the version are assigned when
+// zero, an id used by another schema fails with CodeIDTaken.
+func (r *Registry) Register(subject string, t string, schema string, references []Reference, id int, version int) (int, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	t = SchemaType(t)
+	schema, invalid := Canonical(t, schema, references)
+	for _, v := range r.live(subject) {
+		if sameSchema(&v.Schema, t, schema, references) {
+			return v.ID, nil
+		}
+	}
+	if r.mode(subject) == ModeReadOnly {
+		return 0, r.newError(42205, fmt.Sprintf("Subject %s is in read-only mode", subject))
+	}
+	if invalid != nil {
+		return 0, r.newError(42201, fmt.Sprintf("Invalid schema: %s", invalid))
+	}
+	registered := r.schemaID(t, schema, references)
+	if registered == nil {
+		if id == 0 {
+			id = r.nextID
+		} else if _, taken := r.schemas[id]; taken {
+			return 0, r.newError(CodeIDTaken, fmt.Sprintf("Schema id %d is already used", id))
+		}
+		registered = &Schema{ID: id, SchemaType: t, References: references, Schema: schema}
+		r.schemas[id] = registered
+		if id >= r.nextID {
+			r.nextID = id + 1
+		}
+	}
+	if version == 0 {
+		version = 1
+		if versions := r.subjects[subject]; len(versions) > 0 {
+			version = versions[len(versions)-1].Version + 1
+		}
+	}
+	subjectSchema := *registered
+	subjectSchema.Subject = subject
+	subjectSchema.Version = version
+	r.subjects[subject] = append(r.subjects[subject], &subjectVersion{Schema: subjectSchema})
+	return registered.ID, nil
+}
+
+// schemaID returns the registered schema with the same definition, nil if there is none
+func (r *Registry) schemaID(t string, schema string, references []Reference) *Schema {
+	for _, registered := range r.schemas {
+		if sameSchema(registered, t, schema, references) {
+			return registered
+		}
+	}
+	return nil
+}
+
+// Lookup returns the version of the subject registered with the schema and references
+func (r *Registry) Lookup(subject string, schema string, references []Reference) (*Schema, error) {
+	schema, _ = Canonical("", schema, references)
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	versions := r.live(subject)
+	if len(versions) == 0 {
+		return nil, r.subjectNotFound(subject)
+	}
+	for _, v := range versions {
+		if v.Schema.Schema == schema && (len(v.References) == 0 && len(references) == 0 || reflect.DeepEqual(v.References, references)) {
+			found := v.Schema
+			return &found, nil
+		}
+	}
+	return nil, r.schemaNotFound()
+}
+
+// Version returns the version of the subject
+func (r *Registry) Version(subject string, version int) (*Schema, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	v, err := r.find(subject, version)
+	if err != nil {
+		return nil, err
+	}
+	found := v.Schema
+	return &found, nil
+}
+
+// Latest returns the latest version of the subject
+func (r *Registry) Latest(subject string) (*Schema, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	versions := r.live(subject)
+	if len(versions) == 0 {
+		return nil, r.subjectNotFound(subject)
+	}
+	found := versions[len(versions)-1].Schema
+	return &found, nil
+}
+
+// ByID returns the schema registered with the id
+func (r *Registry) ByID(id int) (*Schema, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	registered, ok := r.schemas[id]
+	if !ok {
+		return nil, r.schemaNotFound()
+	}
+	found := *registered
+	return &found, nil
+}
+
+// BySubjectAndID returns the schema with the id if it is registered under the subject
+func (r *Registry) BySubjectAndID(subject string, id int) (*Schema, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for _, v := range r.live(subject) {
+		if v.ID == id {
+			found := *r.schemas[id]
+			found.Subject = subject
+			return &found, nil
+		}
+	}
+	return nil, r.schemaNotFound()
+}
+
+// Subjects returns the subjects having versions that are not deleted, or all the subjects with deleted
+func (r *Registry) Subjects(deleted bool) []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	subjects := []string{}
+	for subject := range r.subjects {
+		if deleted || len(r.live(subject)) > 0 {
+			subjects = append(subjects, subject)
+		}
+	}
+	sort.Strings(subjects)
+	return subjects
+}
+
+// Versions returns the versions of the subject that are not deleted
+func (r *Registry) Versions(subject string) ([]int, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	versions := r.live(subject)
+	if len(versions) == 0 {
+		return []int{}, r.subjectNotFound(subject)
+	}
+	result := make([]int, len(versions))
+	for i, v := range versions {
+		result[i] = v.Version
+	}
+	return result, nil
+}
+
+// DeleteSubject soft deletes all versions of the subject
+func (r *Registry) DeleteSubject(subject string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	versions := r.live(subject)
+	if len(versions) == 0 {
+		return r.subjectNotFound(subject)
+	}
+	for _, v := range versions {
+		v.deleted = true
+	}
+	return nil
+}
+
+// DeleteVersion soft deletes the version of the subject
+func (r *Registry) DeleteVersion(subject string, version int) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	v, err := r.find(subject, version)
+	if err != nil {
+		return err
+	}
+	v.deleted = true
+	return nil
+}
+
+// DeleteSubjectPermanently removes the subject, it must have been soft deleted first
+func (r *Registry) DeleteSubjectPermanently(subject string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, ok := r.subjects[subject]; !ok {
+		return r.subjectNotFound(subject)
+	}
+	if len(r.live(subject)) > 0 {
+		return r.newError(40405, fmt.Sprintf("Subject '%s' was not deleted first before being permanently deleted", subject))
+	}
+	delete(r.subjects, subject)
+	return nil
+}
+
+// DeleteVersionPermanently removes the version of the subject, it must have been soft deleted first
+func (r *Registry) DeleteVersionPermanently(subject string, version int) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	versions, ok := r.subjects[subject]
+	if !ok {
+		return r.subjectNotFound(subject)
+	}
+	for i, v := range versions {
+		if v.Version != version {
+			continue
+		}
+		if !v.deleted {
+			return r.newError(40407, fmt.Sprintf("Subject '%s' Version %d was not deleted first before being permanently deleted", subject, version))
+		}
+		r.subjects[subject] = append(versions[:i:i], versions[i+1:]...)
+		if len(r.subjects[subject]) == 0 {
+			delete(r.subjects, subject)
+		}
+		return nil
+	}
+	return r.versionNotFound(version)
+}
+
+// ReferencedBy returns the ids of the schemas referencing the version of the subject
+func (r *Registry) ReferencedBy(subject string, version int) ([]int, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, err := r.find(subject, version); err != nil {
+		return []int{}, err
+	}
+	seen := make(map[int]bool)
+	ids := []int{}
+	for s := range r.subjects {
+		for _, v := range r.live(s) {
+			for _, reference := range v.References {
+				if reference.Subject == subject && reference.Version == version && !seen[v.ID] {
+					seen[v.ID] = true
+					ids = append(ids, v.ID)
+				}
+			}
+		}
+	}
+	sort.Ints(ids)
+	return ids, nil
+}
+
+// VersionsForID returns the subject versions registered with the schema id
+func (r *Registry) VersionsForID(id int) ([]SubjectVersion, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, ok := r.schemas[id]; !ok {
+		return []SubjectVersion{}, r.schemaNotFound()
+	}
+	versions := []SubjectVersion{}
+	for subject := range r.subjects {
+		for _, v := range r.live(subject) {
+			if v.ID == id {
+				versions = append(versions, SubjectVersion{Subject: subject, Version: v.Version})
+			}
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		if versions[i].Subject != versions[j].Subject {
+			return versions[i].Subject < versions[j].Subject
+		}
+		return versions[i].Version < versions[j].Version
+	})
+	return versions, nil
+}
+
+// Compatibility returns the compatibility level of the subject, or the global one if it has none
+func (r *Registry) Compatibility(subject string) string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if level, ok := r.compatibility[subject]; ok {
+		return level
+	}
+	return r.globalCompatibility
+}
+
+// SetCompatibility sets the compatibility level of the subject
+func (r *Registry) SetCompatibility(subject string, level string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.compatibility[subject] = level
+}
+
+// GlobalCompatibility returns the global compatibility level, BACKWARD by default
+func (r *Registry) GlobalCompatibility() string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.globalCompatibility
+}
+
+// SetGlobalCompatibility sets the global compatibility level
+func (r *Registry) SetGlobalCompatibility(level string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.globalCompatibility = level
+}
+
+// Mode returns the mode of the subject, or the global one if it has none
+func (r *Registry) Mode(subject string) string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.mode(subject)
+}
+
+// SetMode sets the mode of the subject, registrations fail in READONLY mode
+func (r *Registry) SetMode(subject string, mode string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.modes[subject] = mode
+}
+
+// GlobalMode returns the global mode, READWRITE by default
+func (r *Registry) GlobalMode() string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.globalMode
+}
+
+// SetGlobalMode sets the global mode
+func (r *Registry) SetGlobalMode(mode string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.globalMode = mode
+}
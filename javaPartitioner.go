@@ -0,0 +1,72 @@
+package kafka
+
+import (
+	"github.com/Shopify/sarama"
+)
+
+// WithJavaCompatiblePartitioner partitions keyed messages with murmur2 like the default partitioner of the Java
+// client, so producers in other languages put a key on the same partition. Messages without key are spread in turn.
+func WithJavaCompatiblePartitioner() Option {
+	return WithPartitioner(NewJavaCompatiblePartitioner)
+}
+
+// NewJavaCompatiblePartitioner is a sarama.PartitionerConstructor choosing the partition of a key like the Java client
+func NewJavaCompatiblePartitioner(topic string) sarama.Partitioner {
+	return &javaPartitioner{unkeyed: sarama.NewRoundRobinPartitioner(topic)}
+}
+
+type javaPartitioner struct {
+	unkeyed sarama.Partitioner
+}
+
+// Partition implements sarama.Partitioner
+func (p *javaPartitioner) Partition(msg *sarama.ProducerMessage, numPartitions int32) (int32, error) {
+	if msg.Key == nil {
+		return p.unkeyed.Partition(msg, numPartitions)
+	}
+	key, err := msg.Key.Encode()
+	if err != nil {
+		return -1, err
+	}
+	return int32(murmur2(key)&0x7fffffff) % numPartitions, nil
+}
+
+// RequiresConsistency implements sarama.Partitioner
+func (p *javaPartitioner) RequiresConsistency() bool {
+	return true
+}
+
+// murmur2 is the hash of org.apache.kafka.common.utils.Utils.murmur2
+func murmur2(data []byte) uint32 {
+	const (
+		seed uint32 = 0x9747b28c
+		m    uint32 = 0x5bd1e995
+		r           = 24
+	)
+	length := len(data)
+	h := seed ^ uint32(length)
+	for i := 0; i+4 <= length; i += 4 {
+		k := uint32(data[i]) | uint32(data[i+1])<<8 | uint32(data[i+2])<<16 | uint32(data[i+3])<<24
+		k *= m
+		k ^= k >> r
+		k *= m
+		h *= m
+		h ^= k
+	}
+	tail := length &^ 3
+	switch length % 4 {
+	case 3:
+		h ^= uint32(data[tail+2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(data[tail+1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(data[tail])
+		h *= m
+	}
+	h ^= h >> 13
+	h *= m
+	h ^= h >> 15
+	return h
+}
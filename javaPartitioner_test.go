@@ -0,0 +1,36 @@
+package kafka
+
+import (
+	"testing"
+
+	"github.com/Shopify/sarama"
+)
+
+func TestMurmur2(t *testing.T) {
+	// expected hashes from the tests of the Java client
+	for key, expected := range map[string]int32{
+		"21":                         -973932308,
+		"foobar":                     -790332482,
+		"a-little-bit-long-string":   -985981536,
+		"a-little-bit-longer-string": -1486304829,
+		"lkjh234lh9fiuh90y23oiuhsafujhadof229phr9h19h89h8": -58897971,
+		"abc": 479470107,
+	} {
+		if hash := int32(murmur2([]byte(key))); hash != expected {
+			t.Errorf("Expected murmur2 of %q to be %d, got %d", key, expected, hash)
+		}
+	}
+}
+
+func TestJavaCompatiblePartitioner(t *testing.T) {
+	partitioner := NewJavaCompatiblePartitioner("test")
+	partition, err := partitioner.Partition(&sarama.ProducerMessage{Key: sarama.StringEncoder("foobar")}, 10)
+	if err != nil || partition != int32((-790332482&0x7fffffff)%10) {
+		t.Errorf("Expected the partition of the Java client, got %d, %v", partition, err)
+	}
+	first, _ := partitioner.Partition(&sarama.ProducerMessage{}, 10)
+	second, _ := partitioner.Partition(&sarama.ProducerMessage{}, 10)
+	if second != (first+1)%10 {
+		t.Errorf("Expected messages without key to be spread in turn, got %d then %d", first, second)
+	}
+}
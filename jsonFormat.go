@@ -0,0 +1,80 @@
+package kafka
+
+import (
+	"encoding/json"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+// JSONFormat defines how decoded values are rendered in Message.Value
+type JSONFormat int
+
+const (
+	// AvroJSON is the avro JSON encoding of goavro, union values are wrapped in an object named after their type
+	AvroJSON JSONFormat = iota
+	// PlainJSON unwraps the unions and renders timestamps as RFC 3339 strings, dates as 2006-01-02, times of day
+	// as 15:04:05.000 and decimals as numbers. Bytes and fixed values are base64 strings.
+	PlainJSON
+)
+
+// SetJSONFormat sets how the consumer renders the decoded values, defaults to AvroJSON
+func (ac *AvroConsumer) SetJSONFormat(format JSONFormat) {
+	ac.jsonFormat = format
+}
+
+// structConverters keeps a converter by schema
+type structConverters struct {
+	lock       sync.Mutex
+	converters map[string]*StructConverter
+}
+
+func (c *structConverters) get(codec *goavro.Codec) (*StructConverter, error) {
+	schema := codec.Schema()
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if converter, ok := c.converters[schema]; ok {
+		return converter, nil
+	}
+	converter, err := NewStructConverter(schema)
+	if err != nil {
+		return nil, err
+	}
+	if c.converters == nil {
+		c.converters = make(map[string]*StructConverter)
+	}
+	c.converters[schema] = converter
+	return converter, nil
+}
+
+// plainJSON returns the PlainJSON form of a native value of the codec
+func (c *structConverters) plainJSON(codec *goavro.Codec, native interface{}) ([]byte, error) {
+	converter, err := c.get(codec)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := converter.unwrap(converter.schema, "", native, true)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(plain)
+}
+
+// renderLogical returns the natural JSON form of a native logical type value
+func renderLogical(logicalType string, node map[string]interface{}, native interface{}) interface{} {
+	switch v := native.(type) {
+	case time.Time:
+		if logicalType == "date" {
+			return v.UTC().Format("2006-01-02")
+		}
+		return v.UTC().Format(time.RFC3339Nano)
+	case time.Duration:
+		return time.Time{}.Add(v).Format("15:04:05.000")
+	case *big.Rat:
+		scale, _ := node["scale"].(float64)
+		return json.Number(v.FloatString(int(scale)))
+	}
+	return native
+}
@@ -0,0 +1,56 @@
+package kafka
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/linkedin/goavro/v2"
+)
+
+func TestAvroConsumer_PlainJSON(t *testing.T) {
+	registry := newMemorySchemaRegistry()
+	codec, err := goavro.NewCodec(`{"type": "record", "name": "Payment", "fields": [
+		{"name": "note", "type": ["null", "string"]},
+		{"name": "nested", "type": ["null", {"type": "record", "name": "Ref", "fields": [
+			{"name": "id", "type": ["null", "long"]}]}]},
+		{"name": "at", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+		{"name": "day", "type": {"type": "int", "logicalType": "date"}},
+		{"name": "amount", "type": {"type": "bytes", "logicalType": "decimal", "precision": 8, "scale": 2}}
+	]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schemaId, err := registry.CreateSubject("payments-value", codec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	binaryValue, err := codec.BinaryFromNative(nil, map[string]interface{}{
+		"note":   goavro.Union("string", "rent"),
+		"nested": goavro.Union("Ref", map[string]interface{}{"id": goavro.Union("long", int64(7))}),
+		"at":     at, "day": at, "amount": big.NewRat(1050, 100),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	value := append([]byte{0, 0, 0, 0, 0}, binaryValue...)
+	binary.BigEndian.PutUint32(value[1:5], uint32(schemaId))
+	consumer := &AvroConsumer{SchemaRegistryClient: registry}
+	consumer.SetJSONFormat(PlainJSON)
+
+	msg, err := consumer.ProcessAvroMsg(&sarama.ConsumerMessage{Topic: "payments", Value: value})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded, expected map[string]interface{}
+	json.Unmarshal([]byte(`{"note": "rent", "nested": {"id": 7}, "at": "2020-01-02T03:04:05Z", "day": "2020-01-02",
+		"amount": 10.50}`), &expected)
+	if err := json.Unmarshal([]byte(msg.Value), &decoded); err != nil || !reflect.DeepEqual(decoded, expected) {
+		t.Errorf("Expected %v, got %s", expected, msg.Value)
+	}
+}
@@ -0,0 +1,135 @@
+// Package kafkaprom exports the metrics of producers, consumers and schema registry clients to prometheus
+package kafkaprom
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/dangkaka/go-kafka-avro"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "kafka_avro"
+
+// Metrics implements kafka.Metrics with prometheus collectors, pass it to producers and consumers
+// with kafka.WithMetrics, or to registry clients with kafka.WithRegistryMetrics
+type Metrics struct {
+	produced         *prometheus.CounterVec
+	consumed         *prometheus.CounterVec
+	encode           *prometheus.HistogramVec
+	decode           *prometheus.HistogramVec
+	registryRequests *prometheus.HistogramVec
+	registryRetries  *prometheus.CounterVec
+	cacheLookups     *prometheus.CounterVec
+	deadLettered     *prometheus.CounterVec
+}
+
+// NewMetrics creates the collectors and registers them to registerer, e.g. prometheus.DefaultRegisterer.
+// A single Metrics can be shared by all producers, consumers and registry clients of the application.
+func NewMetrics(registerer prometheus.Registerer) (*Metrics, error) {
+	m := &Metrics{
+		produced: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "messages_produced_total",
+			Help:      "Messages sent by the producers, by topic and result.",
+		}, []string{"topic", "result"}),
+		consumed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "messages_consumed_total",
+			Help:      "Messages decoded by the consumers, by topic and result.",
+		}, []string{"topic", "result"}),
+		encode: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "encode_duration_seconds",
+			Help:      "Time spent encoding messages to avro.",
+			Buckets:   prometheus.ExponentialBuckets(0.00001, 4, 10),
+		}, []string{"topic"}),
+		decode: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "decode_duration_seconds",
+			Help:      "Time spent decoding avro messages, including schema lookups.",
+			Buckets:   prometheus.ExponentialBuckets(0.00001, 4, 10),
+		}, []string{"topic"}),
+		registryRequests: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "registry_request_duration_seconds",
+			Help:      "Schema registry http requests, by method and status code (0 without response).",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "status"}),
+		registryRetries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "registry_retries_total",
+			Help:      "Schema registry requests retried after a failure.",
+		}, []string{"method"}),
+		cacheLookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "registry_cache_lookups_total",
+			Help:      "Lookups in the schema registry client caches, by cache and result.",
+		}, []string{"cache", "result"}),
+		deadLettered: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "messages_dead_lettered_total",
+			Help:      "Consumed messages sent to the dead-letter queue, by consumed topic.",
+		}, []string{"topic"}),
+	}
+	for _, collector := range []prometheus.Collector{m.produced, m.consumed, m.encode, m.decode,
+		m.registryRequests, m.registryRetries, m.cacheLookups, m.deadLettered} {
+		if err := registerer.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "success"
+}
+
+// MessageProduced implements kafka.Metrics
+func (m *Metrics) MessageProduced(topic string, err error) {
+	m.produced.WithLabelValues(topic, result(err)).Inc()
+}
+
+// MessageConsumed implements kafka.Metrics
+func (m *Metrics) MessageConsumed(topic string, err error) {
+	m.consumed.WithLabelValues(topic, result(err)).Inc()
+}
+
+// Encoded implements kafka.Metrics
+func (m *Metrics) Encoded(topic string, duration time.Duration) {
+	m.encode.WithLabelValues(topic).Observe(duration.Seconds())
+}
+
+// Decoded implements kafka.Metrics
+func (m *Metrics) Decoded(topic string, duration time.Duration) {
+	m.decode.WithLabelValues(topic).Observe(duration.Seconds())
+}
+
+// RegistryRequest implements kafka.Metrics
+func (m *Metrics) RegistryRequest(method string, status int, duration time.Duration) {
+	m.registryRequests.WithLabelValues(method, strconv.Itoa(status)).Observe(duration.Seconds())
+}
+
+// RegistryRetry implements kafka.Metrics
+func (m *Metrics) RegistryRetry(method string) {
+	m.registryRetries.WithLabelValues(method).Inc()
+}
+
+// CacheLookup implements kafka.Metrics
+func (m *Metrics) CacheLookup(cache string, hit bool) {
+	lookup := "miss"
+	if hit {
+		lookup = "hit"
+	}
+	m.cacheLookups.WithLabelValues(cache, lookup).Inc()
+}
+
+// DeadLettered implements kafka.Metrics
+func (m *Metrics) DeadLettered(topic string) {
+	m.deadLettered.WithLabelValues(topic).Inc()
+}
+
+var _ kafka.Metrics = (*Metrics)(nil)
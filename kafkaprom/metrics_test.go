@@ -0,0 +1,59 @@
+package kafkaprom
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dangkaka/go-kafka-avro"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetrics_Registry(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"schema": "\"string\""}`)
+	}))
+	defer server.Close()
+	registry := prometheus.NewRegistry()
+	metrics, err := NewMetrics(registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := kafka.NewCachedSchemaRegistryClient([]string{server.URL}, kafka.WithRegistryMetrics(metrics))
+	for i := 0; i < 3; i++ {
+		if _, err := client.GetSchema(1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if count := testutil.CollectAndCount(metrics.registryRequests); count != 1 {
+		t.Errorf("Expected a request series, got %d", count)
+	}
+	if hits := testutil.ToFloat64(metrics.cacheLookups.WithLabelValues("schema", "hit")); hits != 2 {
+		t.Errorf("Expected 2 cache hits, got %v", hits)
+	}
+	if misses := testutil.ToFloat64(metrics.cacheLookups.WithLabelValues("schema", "miss")); misses != 1 {
+		t.Errorf("Expected 1 cache miss, got %v", misses)
+	}
+	if _, err := NewMetrics(registry); err == nil {
+		t.Errorf("Expected registering the collectors twice to fail")
+	}
+}
+
+func TestMetrics_Messages(t *testing.T) {
+	metrics, err := NewMetrics(prometheus.NewRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+	metrics.MessageProduced("orders", nil)
+	metrics.MessageProduced("orders", fmt.Errorf("broker down"))
+	metrics.MessageConsumed("orders", nil)
+	metrics.DeadLettered("orders")
+	if value := testutil.ToFloat64(metrics.produced.WithLabelValues("orders", "error")); value != 1 {
+		t.Errorf("Expected a failed message, got %v", value)
+	}
+	if value := testutil.ToFloat64(metrics.deadLettered.WithLabelValues("orders")); value != 1 {
+		t.Errorf("Expected a dead-lettered message, got %v", value)
+	}
+}
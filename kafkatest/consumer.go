@@ -0,0 +1,172 @@
+package kafkatest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/Shopify/sarama"
+	"github.com/dangkaka/go-kafka-avro"
+)
+
+// MockConsumer implements kafka.AvroMessageConsumer, Consume passes the delivered messages to the callbacks
+// the way AvroConsumer does. Messages in the avro wire format are decoded with the schemas of the registry.
+type MockConsumer struct {
+	Registry   *SchemaRegistry
+	callbacks  kafka.ConsumerCallbacks
+	lock       sync.Mutex
+	queue      []kafka.Message
+	marked     []kafka.Message
+	membership kafka.GroupMembership
+	pending    chan struct{}
+	closed     chan struct{}
+	closeOnce  sync.Once
+}
+
+// NewMockConsumer creates a consumer decoding messages with the schemas of the registry, a new one if it is nil
+func NewMockConsumer(registry *SchemaRegistry, callbacks kafka.ConsumerCallbacks) *MockConsumer {
+	if registry == nil {
+		registry = NewSchemaRegistry()
+	}
+	return &MockConsumer{
+		Registry:  registry,
+		callbacks: callbacks,
+		pending:   make(chan struct{}, 1),
+		closed:    make(chan struct{}),
+	}
+}
+
+// Deliver queues the message for Consume
+func (c *MockConsumer) Deliver(msg kafka.Message) {
+	c.lock.Lock()
+	c.queue = append(c.queue, msg)
+	c.lock.Unlock()
+	select {
+	case c.pending <- struct{}{}:
+	default:
+	}
+}
+
+// DeliverProduced queues the messages recorded by a producer sharing the registry of the consumer,
+// offsets are numbered by topic in the order of the messages
+func (c *MockConsumer) DeliverProduced(messages []ProducedMessage) {
+	offsets := make(map[string]int64)
+	for _, produced := range messages {
+		c.Deliver(kafka.Message{
+			SchemaId:       produced.SchemaId,
+			Topic:          produced.Topic,
+			Offset:         offsets[produced.Topic],
+			Key:            string(produced.Key),
+			Value:          string(produced.Value),
+			MessageIndexes: produced.MessageIndexes,
+		})
+		offsets[produced.Topic]++
+	}
+}
+
+func (c *MockConsumer) next() (kafka.Message, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if len(c.queue) == 0 {
+		return kafka.Message{}, false
+	}
+	msg := c.queue[0]
+	c.queue = c.queue[1:]
+	return msg, true
+}
+
+// Consume passes the delivered messages to OnDataReceived and OnProcess, errors of OnProcess go to OnError.
+// It returns once the context is done or the consumer is closed.
+func (c *MockConsumer) Consume(ctx context.Context) {
+	for {
+		for msg, ok := c.next(); ok; msg, ok = c.next() {
+			if c.callbacks.OnDataReceived != nil {
+				c.callbacks.OnDataReceived(msg)
+			}
+			if c.callbacks.OnProcess != nil {
+				if err := c.callbacks.OnProcess(msg); err != nil && c.callbacks.OnError != nil {
+					c.callbacks.OnError(err)
+				}
+			}
+		}
+		select {
+		case <-c.pending:
+		case <-ctx.Done():
+			return
+		case <-c.closed:
+			return
+		}
+	}
+}
+
+// ProcessAvroMsg decodes a message in the avro wire format
+func (c *MockConsumer) ProcessAvroMsg(m *sarama.ConsumerMessage) (kafka.Message, error) {
+	return c.ProcessAvroMsgContext(context.Background(), m)
+}
+
+// ProcessAvroMsgContext decodes a message in the avro wire format
+func (c *MockConsumer) ProcessAvroMsgContext(ctx context.Context, m *sarama.ConsumerMessage) (kafka.Message, error) {
+	schemaId, payload, err := kafka.ConfluentWireFormat{}.Decode(m)
+	if err != nil {
+		return kafka.Message{}, err
+	}
+	codec, err := c.Registry.GetSchemaContext(ctx, schemaId)
+	if err != nil {
+		return kafka.Message{}, err
+	}
+	native, _, err := codec.NativeFromBinary(payload)
+	if err != nil {
+		return kafka.Message{}, err
+	}
+	textual, err := codec.TextualFromNative(nil, native)
+	if err != nil {
+		return kafka.Message{}, err
+	}
+	return kafka.Message{SchemaId: schemaId, Topic: m.Topic, Partition: m.Partition, Offset: m.Offset,
+		Key: string(m.Key), Value: string(textual)}, nil
+}
+
+// MarkOffset records the message as processed
+func (c *MockConsumer) MarkOffset(msg kafka.Message) {
+	c.lock.Lock()
+	c.marked = append(c.marked, msg)
+	c.lock.Unlock()
+}
+
+// Marked returns the messages passed to MarkOffset
+func (c *MockConsumer) Marked() []kafka.Message {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return append([]kafka.Message(nil), c.marked...)
+}
+
+// SetMembership sets the membership returned by Membership
+func (c *MockConsumer) SetMembership(membership kafka.GroupMembership) {
+	c.lock.Lock()
+	c.membership = membership
+	c.lock.Unlock()
+}
+
+// Membership returns the membership set with SetMembership
+func (c *MockConsumer) Membership() kafka.GroupMembership {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.membership
+}
+
+// Healthy returns an error once the consumer is closed
+func (c *MockConsumer) Healthy(ctx context.Context) error {
+	select {
+	case <-c.closed:
+		return fmt.Errorf("consumer is closed")
+	default:
+		return nil
+	}
+}
+
+// Close stops a running Consume
+func (c *MockConsumer) Close() {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+	})
+}
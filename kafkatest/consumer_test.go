@@ -0,0 +1,69 @@
+package kafkatest
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/dangkaka/go-kafka-avro"
+	"github.com/linkedin/goavro/v2"
+)
+
+var _ kafka.AvroMessageConsumer = (*MockConsumer)(nil)
+
+func TestMockConsumer_Consume(t *testing.T) {
+	registry := NewSchemaRegistry()
+	producer := NewMockProducer(registry)
+	producer.Add("test", testSchema, []byte("a"), []byte(`{"val": 1}`))
+	producer.Add("test", testSchema, []byte("b"), []byte(`{"val": 2}`))
+
+	var received []kafka.Message
+	var errs []error
+	ctx, cancel := context.WithCancel(context.Background())
+	consumer := NewMockConsumer(registry, kafka.ConsumerCallbacks{
+		OnDataReceived: func(msg kafka.Message) {
+			received = append(received, msg)
+		},
+		OnProcess: func(msg kafka.Message) error {
+			if msg.Key == "b" {
+				cancel()
+				return fmt.Errorf("failed %s", msg.Key)
+			}
+			return nil
+		},
+		OnError: func(err error) {
+			errs = append(errs, err)
+		},
+	})
+	consumer.DeliverProduced(producer.Messages())
+	consumer.Consume(ctx)
+	if len(received) != 2 || received[1].Offset != 1 || received[1].Value != `{"val":2}` {
+		t.Errorf("Expected the produced messages, got %+v", received)
+	}
+	if len(errs) != 1 {
+		t.Errorf("Expected the processing error, got %v", errs)
+	}
+}
+
+func TestMockConsumer_ProcessAvroMsg(t *testing.T) {
+	registry := NewSchemaRegistry()
+	codec, _ := goavro.NewCodec(testSchema)
+	id, _ := registry.CreateSubject("test-value", codec)
+	value := make([]byte, 5)
+	binary.BigEndian.PutUint32(value[1:], uint32(id))
+	value, _ = codec.BinaryFromNative(value, map[string]interface{}{"val": 3})
+
+	consumer := NewMockConsumer(registry, kafka.ConsumerCallbacks{})
+	msg, err := consumer.ProcessAvroMsg(&sarama.ConsumerMessage{Topic: "test", Offset: 7, Value: value})
+	if err != nil || msg.SchemaId != id || msg.Value != `{"val":3}` || msg.Offset != 7 {
+		t.Errorf("Expected the message to be decoded, got %+v, %v", msg, err)
+	}
+	consumer.MarkOffset(msg)
+	if marked := consumer.Marked(); len(marked) != 1 || marked[0].Offset != 7 {
+		t.Errorf("Expected the offset to be marked, got %v", marked)
+	}
+	consumer.Close()
+	consumer.Consume(context.Background())
+}
@@ -0,0 +1,196 @@
+package kafkatest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/dangkaka/go-kafka-avro"
+	"github.com/linkedin/goavro/v2"
+)
+
+// ProducedMessage is a message sent with a MockProducer
+type ProducedMessage struct {
+	Topic    string
+	SchemaId int
+	Schema   string
+	// KeySchemaId and KeySchema are set for messages with an avro key
+	KeySchemaId int
+	KeySchema   string
+	Key         []byte
+	// Value is the textual avro data of avro messages, the value as given for the other schema types
+	Value          []byte
+	Imports        []kafka.SchemaImport
+	MessageIndexes []int
+}
+
+// MockProducer implements kafka.AvroMessageProducer by recording the messages. Avro values are validated
+// against their schema and the schemas are registered to the registry of the producer under the topic subjects,
+// the schemas of AddWithImports are not registered.
+type MockProducer struct {
+	// Err is returned by every send when it is set
+	Err      error
+	Registry *SchemaRegistry
+	lock     sync.Mutex
+	messages []ProducedMessage
+	closed   bool
+}
+
+// NewMockProducer creates a producer registering its schemas to the registry, a new one if it is nil
+func NewMockProducer(registry *SchemaRegistry) *MockProducer {
+	if registry == nil {
+		registry = NewSchemaRegistry()
+	}
+	return &MockProducer{Registry: registry}
+}
+
+// Messages returns the messages sent so far
+func (p *MockProducer) Messages() []ProducedMessage {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return append([]ProducedMessage(nil), p.messages...)
+}
+
+// Closed reports whether Close was called
+func (p *MockProducer) Closed() bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.closed
+}
+
+func (p *MockProducer) record(msg ProducedMessage) error {
+	if p.Err != nil {
+		return p.Err
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.messages = append(p.messages, msg)
+	return nil
+}
+
+// textual validates the native value against the codec and returns its textual form
+func textual(codec *goavro.Codec, native interface{}) ([]byte, error) {
+	if _, err := codec.BinaryFromNative(nil, native); err != nil {
+		return nil, err
+	}
+	return codec.TextualFromNative(nil, native)
+}
+
+// GetSchemaId registers the schema under the value subject of the topic
+func (p *MockProducer) GetSchemaId(topic string, avroCodec *goavro.Codec) (int, error) {
+	return p.Registry.CreateSubject(topic+"-value", avroCodec)
+}
+
+// Add records textual avro data
+func (p *MockProducer) Add(topic string, schema string, key []byte, value []byte) error {
+	codec, err := goavro.NewCodec(schema)
+	if err != nil {
+		return err
+	}
+	native, _, err := codec.NativeFromTextual(value)
+	if err != nil {
+		return err
+	}
+	return p.addNative(topic, codec, key, native)
+}
+
+// AddWithImports records textual avro data of a schema importing other schemas, the value is not validated
+func (p *MockProducer) AddWithImports(topic string, schema string, imports []kafka.SchemaImport, key []byte, value []byte) error {
+	return p.record(ProducedMessage{Topic: topic, Schema: schema, Key: key, Value: value, Imports: imports})
+}
+
+// AddNative records a value in the native goavro form
+func (p *MockProducer) AddNative(topic string, schema string, key []byte, native interface{}) error {
+	codec, err := goavro.NewCodec(schema)
+	if err != nil {
+		return err
+	}
+	return p.addNative(topic, codec, key, native)
+}
+
+// AddStruct records a Go value converted with kafka.StructConverter
+func (p *MockProducer) AddStruct(topic string, schema string, key []byte, value interface{}) error {
+	converter, err := kafka.NewStructConverter(schema)
+	if err != nil {
+		return err
+	}
+	native, err := converter.Native(value)
+	if err != nil {
+		return err
+	}
+	return p.addNative(topic, converter.Codec, key, native)
+}
+
+func (p *MockProducer) addNative(topic string, codec *goavro.Codec, key []byte, native interface{}) error {
+	value, err := textual(codec, native)
+	if err != nil {
+		return err
+	}
+	schemaId, err := p.GetSchemaId(topic, codec)
+	if err != nil {
+		return err
+	}
+	return p.record(ProducedMessage{Topic: topic, SchemaId: schemaId, Schema: codec.Schema(), Key: key, Value: value})
+}
+
+// AddWithAvroKey records textual avro data for both the key and the value
+func (p *MockProducer) AddWithAvroKey(topic string, keySchema string, schema string, key []byte, value []byte) error {
+	keyCodec, err := goavro.NewCodec(keySchema)
+	if err != nil {
+		return err
+	}
+	keyNative, _, err := keyCodec.NativeFromTextual(key)
+	if err != nil {
+		return err
+	}
+	if _, err := keyCodec.BinaryFromNative(nil, keyNative); err != nil {
+		return err
+	}
+	keySchemaId, err := p.Registry.CreateSubject(topic+"-key", keyCodec)
+	if err != nil {
+		return err
+	}
+	codec, err := goavro.NewCodec(schema)
+	if err != nil {
+		return err
+	}
+	native, _, err := codec.NativeFromTextual(value)
+	if err != nil {
+		return err
+	}
+	textualValue, err := textual(codec, native)
+	if err != nil {
+		return err
+	}
+	schemaId, err := p.GetSchemaId(topic, codec)
+	if err != nil {
+		return err
+	}
+	return p.record(ProducedMessage{Topic: topic, SchemaId: schemaId, Schema: codec.Schema(),
+		KeySchemaId: keySchemaId, KeySchema: keyCodec.Schema(), Key: key, Value: textualValue})
+}
+
+// AddProtobuf records a serialized protobuf message of the schema
+func (p *MockProducer) AddProtobuf(topic string, schema string, messageIndexes []int, key []byte, value []byte) error {
+	schemaId, err := p.Registry.CreateSubjectWithSchemaType(topic+"-value", kafka.SchemaTypeProtobuf, schema, nil)
+	if err != nil {
+		return err
+	}
+	return p.record(ProducedMessage{Topic: topic, SchemaId: schemaId, Schema: schema, Key: key, Value: value,
+		MessageIndexes: messageIndexes})
+}
+
+// Healthy returns an error once the producer is closed
+func (p *MockProducer) Healthy(ctx context.Context) error {
+	if p.Closed() {
+		return fmt.Errorf("producer is closed")
+	}
+	return nil
+}
+
+// Close marks the producer closed
+func (p *MockProducer) Close() {
+	p.lock.Lock()
+	p.closed = true
+	p.lock.Unlock()
+}
@@ -0,0 +1,46 @@
+package kafkatest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/dangkaka/go-kafka-avro"
+)
+
+var _ kafka.AvroMessageProducer = (*MockProducer)(nil)
+
+const testSchema = `{"type": "record", "name": "test", "fields": [{"name": "val", "type": "int"}]}`
+
+func TestMockProducer(t *testing.T) {
+	producer := NewMockProducer(nil)
+	if err := producer.Add("test", testSchema, []byte("key"), []byte(`{"val": 1}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := producer.AddStruct("test", testSchema, []byte("key"), struct{ Val int }{2}); err == nil {
+		t.Errorf("Expected a struct without the field to be rejected")
+	}
+	if err := producer.AddStruct("test", testSchema, []byte("key"), struct {
+		Val int `avro:"val"`
+	}{2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := producer.Add("test", testSchema, []byte("key"), []byte(`{"val": "one"}`)); err == nil {
+		t.Errorf("Expected an invalid value to be rejected")
+	}
+	messages := producer.Messages()
+	if len(messages) != 2 || string(messages[1].Value) != `{"val":2}` || messages[0].SchemaId != 1 {
+		t.Errorf("Expected the messages to be recorded, got %+v", messages)
+	}
+	if versions, _ := producer.Registry.GetVersions("test-value"); len(versions) != 1 {
+		t.Errorf("Expected the schema to be registered once, got %v", versions)
+	}
+
+	producer.Err = fmt.Errorf("broker down")
+	if err := producer.Add("test", testSchema, nil, []byte(`{"val": 1}`)); err != producer.Err {
+		t.Errorf("Expected the configured error, got %v", err)
+	}
+	producer.Close()
+	if !producer.Closed() {
+		t.Errorf("Expected the producer to be closed")
+	}
+}
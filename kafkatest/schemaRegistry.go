@@ -0,0 +1,412 @@
+// Package kafkatest provides test doubles for the kafka package
+package kafkatest
+
+import (
+	"context"
+
+	"github.com/dangkaka/go-kafka-avro"
+	"github.com/dangkaka/go-kafka-avro/internal/memregistry"
+	"github.com/linkedin/goavro/v2"
+)
+
+// SchemaRegistry is an in-memory schema registry implementing kafka.SchemaRegistry, pass it to producers
+// and consumers with kafka.WithSchemaRegistry to test them without a registry.
+// Schemas registered under several subjects share their id, registering a schema already registered under
+// the subject returns its id in every mode, compatibility checks always succeed and references are stored
+// but not resolved, so GetSchema fails for schemas with references.
+type SchemaRegistry struct {
+	registry *memregistry.Registry
+}
+
+// NewSchemaRegistry creates an empty in-memory schema registry
+func NewSchemaRegistry() *SchemaRegistry {
+	return &SchemaRegistry{registry: memregistry.New(newRegistryError)}
+}
+
+// Healthy always succeeds
+func (r *SchemaRegistry) Healthy(ctx context.Context) error {
+	return nil
+}
+
+func newRegistryError(code int, message string) error {
+	return &kafka.Error{ErrorCode: code, Message: message}
+}
+
+func schemaMetadata(schema *memregistry.Schema, err error) (*kafka.SchemaMetadata, error) {
+	if err != nil {
+		return nil, err
+	}
+	metadata := &kafka.SchemaMetadata{ID: schema.ID, Subject: schema.Subject, Version: schema.Version, SchemaType: schema.SchemaType,
+		Schema: schema.Schema}
+	for _, reference := range schema.References {
+		metadata.References = append(metadata.References, kafka.SchemaReference(reference))
+	}
+	return metadata, nil
+}
+
+func schemaReferences(references []kafka.SchemaReference) []memregistry.Reference {
+	var result []memregistry.Reference
+	for _, reference := range references {
+		result = append(result, memregistry.Reference(reference))
+	}
+	return result
+}
+
+// GetSchema returns a goavro.Codec by unique id
+func (r *SchemaRegistry) GetSchema(id int) (*goavro.Codec, error) {
+	return r.GetSchemaContext(context.Background(), id)
+}
+
+// GetSchemaContext is GetSchema, the context is ignored
+func (r *SchemaRegistry) GetSchemaContext(ctx context.Context, id int) (*goavro.Codec, error) {
+	return memregistry.Codec(r.registry.ByID(id))
+}
+
+// GetSubjects returns the subjects having versions that are not deleted
+func (r *SchemaRegistry) GetSubjects() ([]string, error) {
+	return r.GetSubjectsContext(context.Background())
+}
+
+// GetSubjectsContext is GetSubjects, the context is ignored
+func (r *SchemaRegistry) GetSubjectsContext(ctx context.Context) ([]string, error) {
+	return r.registry.Subjects(false), nil
+}
+
+// GetVersions returns the versions of the subject that are not deleted
+func (r *SchemaRegistry) GetVersions(subject string) ([]int, error) {
+	return r.GetVersionsContext(context.Background(), subject)
+}
+
+// GetVersionsContext is GetVersions, the context is ignored
+func (r *SchemaRegistry) GetVersionsContext(ctx context.Context, subject string) ([]int, error) {
+	return r.registry.Versions(subject)
+}
+
+// GetSchemaByVersion returns a goavro.Codec for the version of the subject
+func (r *SchemaRegistry) GetSchemaByVersion(subject string, version int) (*goavro.Codec, error) {
+	return r.GetSchemaByVersionContext(context.Background(), subject, version)
+}
+
+// GetSchemaByVersionContext is GetSchemaByVersion, the context is ignored
+func (r *SchemaRegistry) GetSchemaByVersionContext(ctx context.Context, subject string, version int) (*goavro.Codec, error) {
+	return memregistry.Codec(r.registry.Version(subject, version))
+}
+
+// GetLatestSchema returns a goavro.Codec for the latest version of the subject
+func (r *SchemaRegistry) GetLatestSchema(subject string) (*goavro.Codec, error) {
+	return r.GetLatestSchemaContext(context.Background(), subject)
+}
+
+// GetLatestSchemaContext is GetLatestSchema, the context is ignored
+func (r *SchemaRegistry) GetLatestSchemaContext(ctx context.Context, subject string) (*goavro.Codec, error) {
+	return memregistry.Codec(r.registry.Latest(subject))
+}
+
+// CreateSubject registers the schema under the subject, registering it again returns the same id
+func (r *SchemaRegistry) CreateSubject(subject string, codec *goavro.Codec) (int, error) {
+	return r.CreateSubjectContext(context.Background(), subject, codec)
+}
+
+// CreateSubjectContext is CreateSubject, the context is ignored
+func (r *SchemaRegistry) CreateSubjectContext(ctx context.Context, subject string, codec *goavro.Codec) (int, error) {
+	return r.registry.Register(subject, "", codec.Schema(), nil, 0, 0)
+}
+
+// IsSchemaRegistered returns the id of the schema if it is registered under the subject
+func (r *SchemaRegistry) IsSchemaRegistered(subject string, codec *goavro.Codec) (int, error) {
+	return r.IsSchemaRegisteredContext(context.Background(), subject, codec)
+}
+
+// IsSchemaRegisteredContext is IsSchemaRegistered, the context is ignored
+func (r *SchemaRegistry) IsSchemaRegisteredContext(ctx context.Context, subject string, codec *goavro.Codec) (int, error) {
+	schema, err := r.registry.Lookup(subject, codec.Schema(), nil)
+	if err != nil {
+		return 0, err
+	}
+	return schema.ID, nil
+}
+
+// DeleteSubject soft deletes all versions of the subject
+func (r *SchemaRegistry) DeleteSubject(subject string) error {
+	return r.DeleteSubjectContext(context.Background(), subject)
+}
+
+// DeleteSubjectContext is DeleteSubject, the context is ignored
+func (r *SchemaRegistry) DeleteSubjectContext(ctx context.Context, subject string) error {
+	return r.registry.DeleteSubject(subject)
+}
+
+// DeleteVersion soft deletes the version of the subject
+func (r *SchemaRegistry) DeleteVersion(subject string, version int) error {
+	return r.DeleteVersionContext(context.Background(), subject, version)
+}
+
+// DeleteVersionContext is DeleteVersion, the context is ignored
+func (r *SchemaRegistry) DeleteVersionContext(ctx context.Context, subject string, version int) error {
+	return r.registry.DeleteVersion(subject, version)
+}
+
+// DeleteSubjectPermanently removes the subject, it must have been soft deleted first
+func (r *SchemaRegistry) DeleteSubjectPermanently(subject string) error {
+	return r.DeleteSubjectPermanentlyContext(context.Background(), subject)
+}
+
+// DeleteSubjectPermanentlyContext is DeleteSubjectPermanently, the context is ignored
+func (r *SchemaRegistry) DeleteSubjectPermanentlyContext(ctx context.Context, subject string) error {
+	return r.registry.DeleteSubjectPermanently(subject)
+}
+
+// DeleteVersionPermanently removes the version of the subject, it must have been soft deleted first
+func (r *SchemaRegistry) DeleteVersionPermanently(subject string, version int) error {
+	return r.DeleteVersionPermanentlyContext(context.Background(), subject, version)
+}
+
+// DeleteVersionPermanentlyContext is DeleteVersionPermanently, the context is ignored
+func (r *SchemaRegistry) DeleteVersionPermanentlyContext(ctx context.Context, subject string, version int) error {
+	return r.registry.DeleteVersionPermanently(subject, version)
+}
+
+// ListDeletedSubjects returns all subjects, including the soft deleted ones
+func (r *SchemaRegistry) ListDeletedSubjects() ([]string, error) {
+	return r.ListDeletedSubjectsContext(context.Background())
+}
+
+// ListDeletedSubjectsContext is ListDeletedSubjects, the context is ignored
+func (r *SchemaRegistry) ListDeletedSubjectsContext(ctx context.Context) ([]string, error) {
+	return r.registry.Subjects(true), nil
+}
+
+// GetSchemaMetadataByID returns the schema registered with the id
+func (r *SchemaRegistry) GetSchemaMetadataByID(id int) (*kafka.SchemaMetadata, error) {
+	return r.GetSchemaMetadataByIDContext(context.Background(), id)
+}
+
+// GetSchemaMetadataByIDContext is GetSchemaMetadataByID, the context is ignored
+func (r *SchemaRegistry) GetSchemaMetadataByIDContext(ctx context.Context, id int) (*kafka.SchemaMetadata, error) {
+	return schemaMetadata(r.registry.ByID(id))
+}
+
+// GetLatestSchemaMetadata returns the latest version of the subject
+func (r *SchemaRegistry) GetLatestSchemaMetadata(subject string) (*kafka.SchemaMetadata, error) {
+	return r.GetLatestSchemaMetadataContext(context.Background(), subject)
+}
+
+// GetLatestSchemaMetadataContext is GetLatestSchemaMetadata, the context is ignored
+func (r *SchemaRegistry) GetLatestSchemaMetadataContext(ctx context.Context, subject string) (*kafka.SchemaMetadata, error) {
+	return schemaMetadata(r.registry.Latest(subject))
+}
+
+// GetSchemaMetadata returns the version of the subject
+func (r *SchemaRegistry) GetSchemaMetadata(subject string, version int) (*kafka.SchemaMetadata, error) {
+	return r.GetSchemaMetadataContext(context.Background(), subject, version)
+}
+
+// GetSchemaMetadataContext is GetSchemaMetadata, the context is ignored
+func (r *SchemaRegistry) GetSchemaMetadataContext(ctx context.Context, subject string, version int) (*kafka.SchemaMetadata, error) {
+	return schemaMetadata(r.registry.Version(subject, version))
+}
+
+// GetSchemaBySubjectAndID returns the schema with the id if it is registered under the subject
+func (r *SchemaRegistry) GetSchemaBySubjectAndID(subject string, id int) (*kafka.SchemaMetadata, error) {
+	return r.GetSchemaBySubjectAndIDContext(context.Background(), subject, id)
+}
+
+// GetSchemaBySubjectAndIDContext is GetSchemaBySubjectAndID, the context is ignored
+func (r *SchemaRegistry) GetSchemaBySubjectAndIDContext(ctx context.Context, subject string, id int) (*kafka.SchemaMetadata, error) {
+	return schemaMetadata(r.registry.BySubjectAndID(subject, id))
+}
+
+// GetReferencedBy returns the ids of the schemas referencing the version of the subject
+func (r *SchemaRegistry) GetReferencedBy(subject string, version int) ([]int, error) {
+	return r.GetReferencedByContext(context.Background(), subject, version)
+}
+
+// GetReferencedByContext is GetReferencedBy, the context is ignored
+func (r *SchemaRegistry) GetReferencedByContext(ctx context.Context, subject string, version int) ([]int, error) {
+	return r.registry.ReferencedBy(subject, version)
+}
+
+// GetVersionsForSchemaID returns the subject versions registered with the schema id
+func (r *SchemaRegistry) GetVersionsForSchemaID(id int) ([]kafka.SubjectVersion, error) {
+	return r.GetVersionsForSchemaIDContext(context.Background(), id)
+}
+
+// GetVersionsForSchemaIDContext is GetVersionsForSchemaID, the context is ignored
+func (r *SchemaRegistry) GetVersionsForSchemaIDContext(ctx context.Context, id int) ([]kafka.SubjectVersion, error) {
+	versions, err := r.registry.VersionsForID(id)
+	result := make([]kafka.SubjectVersion, len(versions))
+	for i, version := range versions {
+		result[i] = kafka.SubjectVersion(version)
+	}
+	return result, err
+}
+
+// CreateSubjectWithReferences registers the avro schema with its references under the subject
+func (r *SchemaRegistry) CreateSubjectWithReferences(subject string, schema string, references []kafka.SchemaReference) (int, error) {
+	return r.CreateSubjectWithReferencesContext(context.Background(), subject, schema, references)
+}
+
+// CreateSubjectWithReferencesContext is CreateSubjectWithReferences, the context is ignored
+func (r *SchemaRegistry) CreateSubjectWithReferencesContext(ctx context.Context, subject string, schema string, references []kafka.SchemaReference) (int, error) {
+	return r.registry.Register(subject, "", schema, schemaReferences(references), 0, 0)
+}
+
+// CreateSubjectWithID registers the schema under the subject with the id and version, the mode is not checked
+func (r *SchemaRegistry) CreateSubjectWithID(subject string, codec *goavro.Codec, id int, version int) (int, error) {
+	return r.CreateSubjectWithIDContext(context.Background(), subject, codec, id, version)
+}
+
+// CreateSubjectWithIDContext is CreateSubjectWithID, the context is ignored
+func (r *SchemaRegistry) CreateSubjectWithIDContext(ctx context.Context, subject string, codec *goavro.Codec, id int, version int) (int, error) {
+	return r.registry.Register(subject, "", codec.Schema(), nil, id, version)
+}
+
+// CreateSubjectWithSchemaType registers a schema of the given type under the subject
+func (r *SchemaRegistry) CreateSubjectWithSchemaType(subject string, schemaType string, schema string, references []kafka.SchemaReference) (int, error) {
+	return r.CreateSubjectWithSchemaTypeContext(context.Background(), subject, schemaType, schema, references)
+}
+
+// CreateSubjectWithSchemaTypeContext is CreateSubjectWithSchemaType, the context is ignored
+func (r *SchemaRegistry) CreateSubjectWithSchemaTypeContext(ctx context.Context, subject string, schemaType string, schema string, references []kafka.SchemaReference) (int, error) {
+	return r.registry.Register(subject, schemaType, schema, schemaReferences(references), 0, 0)
+}
+
+// LookupSchema returns the version of the subject registered with the schema and references
+func (r *SchemaRegistry) LookupSchema(subject string, schema string, references []kafka.SchemaReference) (*kafka.SchemaMetadata, error) {
+	return r.LookupSchemaContext(context.Background(), subject, schema, references)
+}
+
+// LookupSchemaContext is LookupSchema, the context is ignored
+func (r *SchemaRegistry) LookupSchemaContext(ctx context.Context, subject string, schema string, references []kafka.SchemaReference) (*kafka.SchemaMetadata, error) {
+	return schemaMetadata(r.registry.Lookup(subject, schema, schemaReferences(references)))
+}
+
+// CheckCompatibility succeeds for every existing version of the subject
+func (r *SchemaRegistry) CheckCompatibility(subject string, version int, codec *goavro.Codec) (bool, error) {
+	return r.CheckCompatibilityContext(context.Background(), subject, version, codec)
+}
+
+// CheckCompatibilityContext is CheckCompatibility, the context is ignored
+func (r *SchemaRegistry) CheckCompatibilityContext(ctx context.Context, subject string, version int, codec *goavro.Codec) (bool, error) {
+	if _, err := r.registry.Version(subject, version); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// CheckLatestCompatibility succeeds for every existing subject
+func (r *SchemaRegistry) CheckLatestCompatibility(subject string, codec *goavro.Codec) (bool, error) {
+	return r.CheckLatestCompatibilityContext(context.Background(), subject, codec)
+}
+
+// CheckLatestCompatibilityContext is CheckLatestCompatibility, the context is ignored
+func (r *SchemaRegistry) CheckLatestCompatibilityContext(ctx context.Context, subject string, codec *goavro.Codec) (bool, error) {
+	if _, err := r.registry.Latest(subject); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GetCompatibility returns the compatibility level of the subject, or the global one if it has none
+func (r *SchemaRegistry) GetCompatibility(subject string) (string, error) {
+	return r.GetCompatibilityContext(context.Background(), subject)
+}
+
+// GetCompatibilityContext is GetCompatibility, the context is ignored
+func (r *SchemaRegistry) GetCompatibilityContext(ctx context.Context, subject string) (string, error) {
+	return r.registry.Compatibility(subject), nil
+}
+
+// SetCompatibility sets the compatibility level of the subject
+func (r *SchemaRegistry) SetCompatibility(subject string, level string) error {
+	return r.SetCompatibilityContext(context.Background(), subject, level)
+}
+
+// SetCompatibilityContext is SetCompatibility, the context is ignored
+func (r *SchemaRegistry) SetCompatibilityContext(ctx context.Context, subject string, level string) error {
+	r.registry.SetCompatibility(subject, level)
+	return nil
+}
+
+// GetGlobalCompatibility returns the global compatibility level, BACKWARD by default
+func (r *SchemaRegistry) GetGlobalCompatibility() (string, error) {
+	return r.GetGlobalCompatibilityContext(context.Background())
+}
+
+// GetGlobalCompatibilityContext is GetGlobalCompatibility, the context is ignored
+func (r *SchemaRegistry) GetGlobalCompatibilityContext(ctx context.Context) (string, error) {
+	return r.registry.GlobalCompatibility(), nil
+}
+
+// SetGlobalCompatibility sets the global compatibility level
+func (r *SchemaRegistry) SetGlobalCompatibility(level string) error {
+	return r.SetGlobalCompatibilityContext(context.Background(), level)
+}
+
+// SetGlobalCompatibilityContext is SetGlobalCompatibility, the context is ignored
+func (r *SchemaRegistry) SetGlobalCompatibilityContext(ctx context.Context, level string) error {
+	r.registry.SetGlobalCompatibility(level)
+	return nil
+}
+
+// GetMode returns the mode of the subject, or the global one if it has none
+func (r *SchemaRegistry) GetMode(subject string) (string, error) {
+	return r.GetModeContext(context.Background(), subject)
+}
+
+// GetModeContext is GetMode, the context is ignored
+func (r *SchemaRegistry) GetModeContext(ctx context.Context, subject string) (string, error) {
+	return r.registry.Mode(subject), nil
+}
+
+// SetMode sets the mode of the subject, registrations fail in READONLY mode
+func (r *SchemaRegistry) SetMode(subject string, mode string) error {
+	return r.SetModeContext(context.Background(), subject, mode)
+}
+
+// SetModeContext is SetMode, the context is ignored
+func (r *SchemaRegistry) SetModeContext(ctx context.Context, subject string, mode string) error {
+	r.registry.SetMode(subject, mode)
+	return nil
+}
+
+// GetGlobalMode returns the global mode, READWRITE by default
+func (r *SchemaRegistry) GetGlobalMode() (string, error) {
+	return r.GetGlobalModeContext(context.Background())
+}
+
+// GetGlobalModeContext is GetGlobalMode, the context is ignored
+func (r *SchemaRegistry) GetGlobalModeContext(ctx context.Context) (string, error) {
+	return r.registry.GlobalMode(), nil
+}
+
+// SetGlobalMode sets the global mode
+func (r *SchemaRegistry) SetGlobalMode(mode string) error {
+	return r.SetGlobalModeContext(context.Background(), mode)
+}
+
+// SetGlobalModeContext is SetGlobalMode, the context is ignored
+func (r *SchemaRegistry) SetGlobalModeContext(ctx context.Context, mode string) error {
+	r.registry.SetGlobalMode(mode)
+	return nil
+}
+
+// Ping always succeeds
+func (r *SchemaRegistry) Ping() error {
+	return r.PingContext(context.Background())
+}
+
+// PingContext is Ping, the context is ignored
+func (r *SchemaRegistry) PingContext(ctx context.Context) error {
+	return nil
+}
+
+// ServerInfo returns the version "mock"
+func (r *SchemaRegistry) ServerInfo() (*kafka.ServerInfo, error) {
+	return r.ServerInfoContext(context.Background())
+}
+
+// ServerInfoContext is ServerInfo, the context is ignored
+func (r *SchemaRegistry) ServerInfoContext(ctx context.Context) (*kafka.ServerInfo, error) {
+	return &kafka.ServerInfo{Version: "mock"}, nil
+}
@@ -0,0 +1,132 @@
+package kafkatest
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dangkaka/go-kafka-avro"
+	"github.com/linkedin/goavro/v2"
+)
+
+var _ kafka.SchemaRegistry = (*SchemaRegistry)(nil)
+
+func TestSchemaRegistry_Register(t *testing.T) {
+	registry := NewSchemaRegistry()
+	first, _ := goavro.NewCodec(`{"type": "record", "name": "test", "fields": [{"name": "a", "type": "string"}]}`)
+	second, _ := goavro.NewCodec(`{"type": "record", "name": "test", "fields": [{"name": "a", "type": "string"}, {"name": "b", "type": "int", "default": 0}]}`)
+	id, err := registry.CreateSubject("test-value", first)
+	if err != nil || id != 1 {
+		t.Fatalf("Expected id 1, got %d, %v", id, err)
+	}
+	if again, _ := registry.CreateSubject("test-value", first); again != id {
+		t.Errorf("Expected registering again to return %d, got %d", id, again)
+	}
+	if shared, _ := registry.CreateSubject("other-value", first); shared != id {
+		t.Errorf("Expected the id to be shared by subjects, got %d", shared)
+	}
+	secondID, _ := registry.CreateSubject("test-value", second)
+	if secondID != 2 {
+		t.Errorf("Expected id 2, got %d", secondID)
+	}
+
+	if versions, _ := registry.GetVersions("test-value"); !reflect.DeepEqual(versions, []int{1, 2}) {
+		t.Errorf("Expected versions 1 and 2, got %v", versions)
+	}
+	latest, err := registry.GetLatestSchemaMetadata("test-value")
+	if err != nil || latest.ID != secondID || latest.Version != 2 || latest.Subject != "test-value" {
+		t.Errorf("Expected the second schema to be the latest, got %+v, %v", latest, err)
+	}
+	codec, err := registry.GetSchema(id)
+	if err != nil || codec.Schema() != first.Schema() {
+		t.Errorf("Expected the first schema, got %v", err)
+	}
+	if registered, _ := registry.IsSchemaRegistered("test-value", first); registered != id {
+		t.Errorf("Expected the schema to be registered with %d, got %d", id, registered)
+	}
+	if subjects, _ := registry.GetSubjects(); !reflect.DeepEqual(subjects, []string{"other-value", "test-value"}) {
+		t.Errorf("Expected both subjects, got %v", subjects)
+	}
+	usage, _ := registry.GetVersionsForSchemaID(id)
+	expected := []kafka.SubjectVersion{{Subject: "other-value", Version: 1}, {Subject: "test-value", Version: 1}}
+	if !reflect.DeepEqual(usage, expected) {
+		t.Errorf("Expected %v, got %v", expected, usage)
+	}
+}
+
+func TestSchemaRegistry_NotFound(t *testing.T) {
+	registry := NewSchemaRegistry()
+	if _, err := registry.GetSchema(1); !kafka.IsNotFoundError(err) {
+		t.Errorf("Expected a not found error, got %v", err)
+	}
+	if _, err := registry.GetLatestSchema("test-value"); !kafka.IsNotFoundError(err) {
+		t.Errorf("Expected a not found error, got %v", err)
+	}
+	if _, err := registry.CreateSubjectWithSchemaType("test-value", kafka.SchemaTypeAvro, `{`, nil); err == nil {
+		t.Errorf("Expected an invalid schema to be rejected")
+	}
+}
+
+func TestSchemaRegistry_Delete(t *testing.T) {
+	registry := NewSchemaRegistry()
+	codec, _ := goavro.NewCodec(`"string"`)
+	id, _ := registry.CreateSubject("test-value", codec)
+	if err := registry.DeleteSubjectPermanently("test-value"); err == nil {
+		t.Errorf("Expected a hard delete to require a soft delete first")
+	}
+	if err := registry.DeleteSubject("test-value"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := registry.GetVersions("test-value"); !kafka.IsNotFoundError(err) {
+		t.Errorf("Expected the subject to be deleted, got %v", err)
+	}
+	if _, err := registry.GetSchema(id); err != nil {
+		t.Errorf("Expected the schema to stay readable by id, got %v", err)
+	}
+	if subjects, _ := registry.ListDeletedSubjects(); !reflect.DeepEqual(subjects, []string{"test-value"}) {
+		t.Errorf("Expected the deleted subject to be listed, got %v", subjects)
+	}
+	if err := registry.DeleteSubjectPermanently("test-value"); err != nil {
+		t.Fatal(err)
+	}
+	if subjects, _ := registry.ListDeletedSubjects(); len(subjects) != 0 {
+		t.Errorf("Expected the subject to be removed, got %v", subjects)
+	}
+}
+
+func TestSchemaRegistry_References(t *testing.T) {
+	registry := NewSchemaRegistry()
+	address := `{"type": "record", "name": "Address", "fields": [{"name": "city", "type": "string"}]}`
+	person := `{"type": "record", "name": "Person", "fields": [{"name": "address", "type": "Address"}]}`
+	if _, err := registry.CreateSubjectWithReferences("address", address, nil); err != nil {
+		t.Fatal(err)
+	}
+	references := []kafka.SchemaReference{{Name: "Address", Subject: "address", Version: 1}}
+	id, err := registry.CreateSubjectWithReferences("person-value", person, references)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids, _ := registry.GetReferencedBy("address", 1); !reflect.DeepEqual(ids, []int{id}) {
+		t.Errorf("Expected the person schema to reference the address, got %v", ids)
+	}
+	metadata, err := registry.LookupSchema("person-value", person, references)
+	if err != nil || metadata.ID != id {
+		t.Errorf("Expected the schema to be found, got %+v, %v", metadata, err)
+	}
+}
+
+func TestSchemaRegistry_Mode(t *testing.T) {
+	registry := NewSchemaRegistry()
+	codec, _ := goavro.NewCodec(`"string"`)
+	id, _ := registry.CreateSubject("test-value", codec)
+	registry.SetMode("test-value", kafka.ModeReadOnly)
+	other, _ := goavro.NewCodec(`"int"`)
+	if _, err := registry.CreateSubject("test-value", other); err == nil {
+		t.Errorf("Expected registrations to fail in read-only mode")
+	}
+	if registered, err := registry.CreateSubject("test-value", codec); err != nil || registered != id {
+		t.Errorf("Expected the registered schema to keep its id, got %d, %v", registered, err)
+	}
+	if level, _ := registry.GetCompatibility("test-value"); level != kafka.CompatibilityBackward {
+		t.Errorf("Expected the global compatibility, got %s", level)
+	}
+}
@@ -0,0 +1,50 @@
+package kafka
+
+import (
+	"github.com/Shopify/sarama"
+)
+
+// KerberosOptions configures SASL/GSSAPI authentication, with a keytab when KeyTabPath is set, otherwise with a password
+type KerberosOptions struct {
+	// ServiceName is the kerberos service name of the brokers, "kafka" when empty
+	ServiceName string
+	Realm       string
+	Username    string
+	Password    string
+	KeyTabPath  string
+	// ConfigPath is the path of the kerberos configuration, "/etc/krb5.conf" when empty
+	ConfigPath string
+}
+
+// WithKerberos authenticates to the brokers with SASL/GSSAPI
+func WithKerberos(kerberos KerberosOptions) Option {
+	return func(o *options) {
+		o.mutate(func(config *sarama.Config) {
+			config.Net.SASL.Enable = true
+			config.Net.SASL.Mechanism = sarama.SASLTypeGSSAPI
+			config.Net.SASL.GSSAPI = kerberos.gssapiConfig()
+		})
+	}
+}
+
+func (k KerberosOptions) gssapiConfig() sarama.GSSAPIConfig {
+	gssapi := sarama.GSSAPIConfig{
+		AuthType:           sarama.KRB5_USER_AUTH,
+		ServiceName:        k.ServiceName,
+		Realm:              k.Realm,
+		Username:           k.Username,
+		Password:           k.Password,
+		KeyTabPath:         k.KeyTabPath,
+		KerberosConfigPath: k.ConfigPath,
+	}
+	if k.KeyTabPath != "" {
+		gssapi.AuthType = sarama.KRB5_KEYTAB_AUTH
+	}
+	if gssapi.ServiceName == "" {
+		gssapi.ServiceName = "kafka"
+	}
+	if gssapi.KerberosConfigPath == "" {
+		gssapi.KerberosConfigPath = "/etc/krb5.conf"
+	}
+	return gssapi
+}
@@ -0,0 +1,34 @@
+package kafka
+
+import (
+	"testing"
+
+	"github.com/Shopify/sarama"
+)
+
+func TestWithKerberos(t *testing.T) {
+	config := applyOptions(defaultAvroProducerConfig(), []Option{WithKerberos(KerberosOptions{
+		Realm:      "EXAMPLE.COM",
+		Username:   "svc",
+		KeyTabPath: "/etc/svc.keytab",
+	})}).saramaConfig
+	gssapi := config.Net.SASL.GSSAPI
+	if config.Net.SASL.Mechanism != sarama.SASLTypeGSSAPI || gssapi.AuthType != sarama.KRB5_KEYTAB_AUTH {
+		t.Errorf("Expected keytab GSSAPI auth, got %+v", gssapi)
+	}
+	if gssapi.ServiceName != "kafka" || gssapi.KerberosConfigPath != "/etc/krb5.conf" {
+		t.Errorf("Expected defaults, got %+v", gssapi)
+	}
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected valid config, got %v", err)
+	}
+
+	config = applyOptions(defaultAvroProducerConfig(), []Option{WithKerberos(KerberosOptions{
+		Realm:    "EXAMPLE.COM",
+		Username: "svc",
+		Password: "secret",
+	})}).saramaConfig
+	if config.Net.SASL.GSSAPI.AuthType != sarama.KRB5_USER_AUTH {
+		t.Errorf("Expected password GSSAPI auth")
+	}
+}
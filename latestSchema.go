@@ -0,0 +1,38 @@
+package kafka
+
+import (
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+// ProduceJSON encodes the textual Avro value with the latest schema of the value subject of the topic, or the version
+// set with WithSchemaVersion, and sends it, so callers do not manage codecs and schema ids.
+// The subject name strategy must not depend on the schema.
+func (ap *AvroProducer) ProduceJSON(topic string, key []byte, value []byte) error {
+	msg, err := ap.PrepareJSON(topic, key, value)
+	if err != nil {
+		return err
+	}
+	if _, _, err = ap.sendPrepared(msg); err != nil {
+		ap.invalidateTopicSchema(topic, "")
+	}
+	return err
+}
+
+// PrepareJSON is like ProduceJSON, returning the message without sending it
+func (ap *AvroProducer) PrepareJSON(topic string, key []byte, value []byte) (*sarama.ProducerMessage, error) {
+	schema, err := ap.topicSchema(topic, "")
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	binaryValue, err := encodeTextual(schema.codec, value)
+	if err != nil {
+		// the value may match a newer schema
+		ap.invalidateTopicSchema(topic, "")
+		return nil, err
+	}
+	ap.encoded(topic, start)
+	return ap.prepare(topic, schema.id, sarama.StringEncoder(key), binaryValue), nil
+}
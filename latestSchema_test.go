@@ -0,0 +1,46 @@
+package kafka
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+func TestAvroProducer_ProduceJSON(t *testing.T) {
+	registry := newMemorySchemaRegistry()
+	for _, schema := range []string{
+		`{"type": "record", "name": "test", "fields" : [{"name": "val", "type": "int"}]}`,
+		`{"type": "record", "name": "test", "fields" : [{"name": "val", "type": "int"}, {"name": "name", "type": "string", "default": ""}]}`,
+	} {
+		codec, err := goavro.NewCodec(schema)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := registry.CreateSubject("test-value", codec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	latest, err := registry.GetLatestSchemaMetadata("test-value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	producer := &testSyncProducer{}
+	avroProducer := &AvroProducer{producer: producer, schemaRegistryClient: registry}
+	if err := avroProducer.ProduceJSON("test", []byte("key"), []byte(`{"val": 1, "name": "a"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if len(producer.sent) != 1 {
+		t.Fatalf("Expected a message to be sent, got %d", len(producer.sent))
+	}
+	value, _ := producer.sent[0].Value.Encode()
+	if int(binary.BigEndian.Uint32(value[1:5])) != latest.ID {
+		t.Errorf("Expected the latest schema id %d, got %v", latest.ID, value)
+	}
+	if err := avroProducer.ProduceJSON("test", nil, []byte(`{"val": "a"}`)); err == nil {
+		t.Errorf("Expected an error encoding a value not matching the latest schema")
+	}
+	if err := avroProducer.ProduceJSON("unknown", nil, []byte(`{"val": 1}`)); err == nil {
+		t.Errorf("Expected an error for a topic without subject")
+	}
+}
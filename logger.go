@@ -0,0 +1,41 @@
+package kafka
+
+// Logger receives the log messages of producers, consumers and schema registry clients, keyvals are
+// alternating keys and values. *slog.Logger implements it, other loggers need a small adapter.
+type Logger interface {
+	Debug(msg string, keyvals ...interface{})
+	Info(msg string, keyvals ...interface{})
+	Warn(msg string, keyvals ...interface{})
+	Error(msg string, keyvals ...interface{})
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(msg string, keyvals ...interface{}) {}
+func (nopLogger) Info(msg string, keyvals ...interface{})  {}
+func (nopLogger) Warn(msg string, keyvals ...interface{})  {}
+func (nopLogger) Error(msg string, keyvals ...interface{}) {}
+
+// orNop returns the logger, or a logger discarding the messages when it is nil
+func orNop(logger Logger) Logger {
+	if logger == nil {
+		return nopLogger{}
+	}
+	return logger
+}
+
+// WithLogger logs the retries, rebalances and decode errors of a producer or a consumer
+// and of its schema registry client to logger
+func WithLogger(logger Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+		o.registry = append(o.registry, WithRegistryLogger(logger))
+	}
+}
+
+// WithRegistryLogger logs the retried and failed requests of a registry client to logger
+func WithRegistryLogger(logger Logger) SchemaRegistryOption {
+	return func(client *SchemaRegistryClient) {
+		client.logger = logger
+	}
+}
@@ -0,0 +1,80 @@
+package kafka
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/Shopify/sarama"
+)
+
+type logEntry struct {
+	level   string
+	msg     string
+	keyvals []interface{}
+}
+
+type recordingLogger struct {
+	lock    sync.Mutex
+	entries []logEntry
+}
+
+func (l *recordingLogger) log(level, msg string, keyvals []interface{}) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.entries = append(l.entries, logEntry{level: level, msg: msg, keyvals: keyvals})
+}
+
+func (l *recordingLogger) Debug(msg string, keyvals ...interface{}) { l.log("debug", msg, keyvals) }
+func (l *recordingLogger) Info(msg string, keyvals ...interface{})  { l.log("info", msg, keyvals) }
+func (l *recordingLogger) Warn(msg string, keyvals ...interface{})  { l.log("warn", msg, keyvals) }
+func (l *recordingLogger) Error(msg string, keyvals ...interface{}) { l.log("error", msg, keyvals) }
+
+func (l *recordingLogger) levels() []string {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	levels := make([]string, len(l.entries))
+	for i, entry := range l.entries {
+		levels[i] = entry.level
+	}
+	return levels
+}
+
+func TestWithRegistryLogger(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	logger := &recordingLogger{}
+	client := NewSchemaRegistryClientWithRetries([]string{failing.URL}, 2, WithRegistryLogger(logger))
+	client.GetSubjects()
+	if levels := logger.levels(); len(levels) != 2 || levels[0] != "warn" {
+		t.Fatalf("Expected 2 retry warnings, got %v", levels)
+	}
+	if len(logger.entries[0].keyvals)%2 != 0 {
+		t.Errorf("Expected alternating keys and values, got %v", logger.entries[0].keyvals)
+	}
+}
+
+func TestWithLogger_Consumer(t *testing.T) {
+	logger := &recordingLogger{}
+	o := applyOptions(defaultAvroConsumerConfig(), []Option{WithLogger(logger)})
+	consumer := &AvroConsumer{logger: o.logger}
+	session := newTestSession(map[string][]int32{"orders": {0}})
+
+	consumer.rebalanced(session)
+	consumer.handle(session, &sarama.ConsumerMessage{Topic: "orders", Offset: 3, Value: []byte("corrupt")})
+	if levels := logger.levels(); len(levels) != 2 || levels[0] != "info" || levels[1] != "error" {
+		t.Errorf("Expected the rebalance and the decode error to be logged, got %v", levels)
+	}
+	if len(o.registry) != 1 {
+		t.Errorf("Expected WithLogger to configure the registry client")
+	}
+}
+
+func TestNopLogger(t *testing.T) {
+	// a nil logger must not panic
+	consumer := &AvroConsumer{}
+	consumer.handle(newTestSession(nil), &sarama.ConsumerMessage{Topic: "orders", Value: []byte("corrupt")})
+}
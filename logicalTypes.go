@@ -0,0 +1,112 @@
+package kafka
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"reflect"
+	"time"
+)
+
+// UUID is the value of a uuid logical type. StructConverter accepts any [16]byte type for it, e.g. uuid.UUID
+// of github.com/google/uuid, and formats it as the canonical string of the avro value.
+type UUID [16]byte
+
+// String returns the canonical form of the uuid, like 123e4567-e89b-12d3-a456-426614174000
+func (u UUID) String() string {
+	return formatUUID(u[:])
+}
+
+// ParseUUID parses the canonical form of a uuid
+func ParseUUID(s string) (UUID, error) {
+	var u UUID
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return u, fmt.Errorf("invalid uuid: %s", s)
+	}
+	b, err := hex.DecodeString(s[0:8] + s[9:13] + s[14:18] + s[19:23] + s[24:])
+	if err != nil {
+		return u, fmt.Errorf("invalid uuid: %s", s)
+	}
+	copy(u[:], b)
+	return u, nil
+}
+
+func formatUUID(b []byte) string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// isUUIDType reports whether t is a [16]byte array
+func isUUIDType(t reflect.Type) bool {
+	return t.Kind() == reflect.Array && t.Len() == 16 && t.Elem().Kind() == reflect.Uint8
+}
+
+// TimestampMillis returns the timestamp-millis value of t, for the textual values of Add
+func TimestampMillis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+// TimestampMicros returns the timestamp-micros value of t, for the textual values of Add
+func TimestampMicros(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Microsecond)
+}
+
+// FromTimestampMillis returns the UTC time of a timestamp-millis value
+func FromTimestampMillis(millis int64) time.Time {
+	return time.Unix(0, millis*int64(time.Millisecond)).UTC()
+}
+
+// FromTimestampMicros returns the UTC time of a timestamp-micros value
+func FromTimestampMicros(micros int64) time.Time {
+	return time.Unix(0, micros*int64(time.Microsecond)).UTC()
+}
+
+// Date returns the date value of t, the number of days since the unix epoch
+func Date(t time.Time) int32 {
+	days := t.Unix() / 86400
+	if t.Unix() < 0 && t.Unix()%86400 != 0 {
+		days--
+	}
+	return int32(days)
+}
+
+// FromDate returns the UTC midnight of a date value
+func FromDate(days int32) time.Time {
+	return time.Unix(int64(days)*86400, 0).UTC()
+}
+
+// DecimalBytes returns the bytes of a decimal value with the scale, the two's-complement big-endian
+// unscaled value. Digits beyond the scale are rounded half away from zero.
+func DecimalBytes(r *big.Rat, scale int) []byte {
+	unscaled := new(big.Rat).Mul(r, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil)))
+	n := new(big.Int).Quo(unscaled.Num(), unscaled.Denom())
+	remainder := new(big.Int).Rem(unscaled.Num(), unscaled.Denom())
+	if new(big.Int).Mul(new(big.Int).Abs(remainder), big.NewInt(2)).Cmp(unscaled.Denom()) >= 0 {
+		n.Add(n, big.NewInt(int64(unscaled.Sign())))
+	}
+	return twosComplement(n)
+}
+
+// DecimalFromBytes returns the value of the bytes of a decimal with the scale
+func DecimalFromBytes(b []byte, scale int) *big.Rat {
+	n := new(big.Int).SetBytes(b)
+	if len(b) > 0 && b[0]&0x80 != 0 {
+		n.Sub(n, new(big.Int).Lsh(big.NewInt(1), uint(len(b)*8)))
+	}
+	return new(big.Rat).SetFrac(n, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil))
+}
+
+func twosComplement(n *big.Int) []byte {
+	if n.Sign() >= 0 {
+		b := n.Bytes()
+		if len(b) == 0 || b[0]&0x80 != 0 {
+			b = append([]byte{0}, b...)
+		}
+		return b
+	}
+	size := len(n.Bytes()) + 1
+	b := new(big.Int).Add(new(big.Int).Lsh(big.NewInt(1), uint(size*8)), n).Bytes()
+	for len(b) > 1 && b[0] == 0xff && b[1]&0x80 != 0 {
+		b = b[1:]
+	}
+	return b
+}
@@ -0,0 +1,80 @@
+package kafka
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestParseUUID(t *testing.T) {
+	u, err := ParseUUID("123e4567-e89b-12d3-a456-426614174000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.String() != "123e4567-e89b-12d3-a456-426614174000" {
+		t.Errorf("Unexpected uuid %s", u)
+	}
+	for _, s := range []string{"", "123e4567e89b12d3a456426614174000", "123e4567-e89b-12d3-a456-42661417400g"} {
+		if _, err := ParseUUID(s); err == nil {
+			t.Errorf("Expected %q to be invalid", s)
+		}
+	}
+}
+
+func TestTimeHelpers(t *testing.T) {
+	at := time.Date(1969, 12, 31, 12, 0, 0, 0, time.UTC)
+	if Date(at) != -1 || !FromDate(-1).Equal(time.Date(1969, 12, 31, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected date %d", Date(at))
+	}
+	at = time.Date(2020, 1, 2, 3, 4, 5, 6000, time.UTC)
+	if !FromTimestampMicros(TimestampMicros(at)).Equal(at) || !FromTimestampMillis(TimestampMillis(at)).Equal(at.Truncate(time.Millisecond)) {
+		t.Errorf("Expected timestamps to round trip")
+	}
+}
+
+func TestDecimalBytes(t *testing.T) {
+	for _, c := range []struct {
+		value   string
+		bytes   []byte
+		rounded string
+	}{
+		{"0", []byte{0}, "0.00"}, {"1.28", []byte{0, 128}, "1.28"}, {"-1.28", []byte{0x80}, "-1.28"},
+		{"-0.01", []byte{0xff}, "-0.01"}, {"123.456", []byte{0x30, 0x3a}, "123.46"},
+	} {
+		r, _ := new(big.Rat).SetString(c.value)
+		b := DecimalBytes(r, 2)
+		if !bytes.Equal(b, c.bytes) {
+			t.Errorf("Expected %s to be %v, got %v", c.value, c.bytes, b)
+		}
+		if decimal := DecimalFromBytes(b, 2).FloatString(2); decimal != c.rounded {
+			t.Errorf("Expected %v to be %s, got %s", b, c.rounded, decimal)
+		}
+	}
+}
+
+func TestStructConverter_UUID(t *testing.T) {
+	converter, err := NewStructConverter(`{"type": "record", "name": "r", "fields": [
+		{"name": "id", "type": {"type": "string", "logicalType": "uuid"}}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type record struct {
+		ID UUID `avro:"id"`
+	}
+	id, _ := ParseUUID("123e4567-e89b-12d3-a456-426614174000")
+	native, err := converter.Native(record{ID: id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if native.(map[string]interface{})["id"] != id.String() {
+		t.Errorf("Expected the uuid string, got %v", native)
+	}
+	var decoded record
+	if err := converter.Decode(native, &decoded); err != nil || decoded.ID != id {
+		t.Errorf("Expected the uuid to round trip, got %v, %v", decoded.ID, err)
+	}
+	if schema, err := StructSchema(record{}); err != nil || !bytes.Contains([]byte(schema), []byte(`"logicalType":"uuid"`)) {
+		t.Errorf("Expected a uuid field, got %s, %v", schema, err)
+	}
+}
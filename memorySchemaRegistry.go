@@ -0,0 +1,403 @@
+package kafka
+
+import (
+	"context"
+
+	"github.com/dangkaka/go-kafka-avro/internal/memregistry"
+	"github.com/linkedin/goavro/v2"
+)
+
+// memorySchemaRegistry is an in-memory SchemaRegistry backing NewFileSchemaRegistry, the registry of the tests
+// of the package. Like kafkatest.SchemaRegistry, compatibility checks always succeed and references are stored
+// but not resolved, so GetSchema fails for schemas with references.
+type memorySchemaRegistry struct {
+	registry *memregistry.Registry
+}
+
+// newMemorySchemaRegistry creates an empty in-memory schema registry
+func newMemorySchemaRegistry() *memorySchemaRegistry {
+	return &memorySchemaRegistry{registry: memregistry.New(newRegistryError)}
+}
+
+func newRegistryError(code int, message string) error {
+	return &Error{ErrorCode: code, Message: message}
+}
+
+func schemaMetadata(schema *memregistry.Schema, err error) (*SchemaMetadata, error) {
+	if err != nil {
+		return nil, err
+	}
+	metadata := &SchemaMetadata{ID: schema.ID, Subject: schema.Subject, Version: schema.Version, SchemaType: schema.SchemaType,
+		Schema: schema.Schema}
+	for _, reference := range schema.References {
+		metadata.References = append(metadata.References, SchemaReference(reference))
+	}
+	return metadata, nil
+}
+
+func schemaReferences(references []SchemaReference) []memregistry.Reference {
+	var result []memregistry.Reference
+	for _, reference := range references {
+		result = append(result, memregistry.Reference(reference))
+	}
+	return result
+}
+
+// GetSchema returns a goavro.Codec by unique id
+func (r *memorySchemaRegistry) GetSchema(id int) (*goavro.Codec, error) {
+	return r.GetSchemaContext(context.Background(), id)
+}
+
+// GetSchemaContext is GetSchema, the context is ignored
+func (r *memorySchemaRegistry) GetSchemaContext(ctx context.Context, id int) (*goavro.Codec, error) {
+	return memregistry.Codec(r.registry.ByID(id))
+}
+
+// GetSubjects returns the subjects having versions that are not deleted
+func (r *memorySchemaRegistry) GetSubjects() ([]string, error) {
+	return r.GetSubjectsContext(context.Background())
+}
+
+// GetSubjectsContext is GetSubjects, the context is ignored
+func (r *memorySchemaRegistry) GetSubjectsContext(ctx context.Context) ([]string, error) {
+	return r.registry.Subjects(false), nil
+}
+
+// GetVersions returns the versions of the subject that are not deleted
+func (r *memorySchemaRegistry) GetVersions(subject string) ([]int, error) {
+	return r.GetVersionsContext(context.Background(), subject)
+}
+
+// GetVersionsContext is GetVersions, the context is ignored
+func (r *memorySchemaRegistry) GetVersionsContext(ctx context.Context, subject string) ([]int, error) {
+	return r.registry.Versions(subject)
+}
+
+// GetSchemaByVersion returns a goavro.Codec for the version of the subject
+func (r *memorySchemaRegistry) GetSchemaByVersion(subject string, version int) (*goavro.Codec, error) {
+	return r.GetSchemaByVersionContext(context.Background(), subject, version)
+}
+
+// GetSchemaByVersionContext is GetSchemaByVersion, the context is ignored
+func (r *memorySchemaRegistry) GetSchemaByVersionContext(ctx context.Context, subject string, version int) (*goavro.Codec, error) {
+	return memregistry.Codec(r.registry.Version(subject, version))
+}
+
+// GetLatestSchema returns a goavro.Codec for the latest version of the subject
+func (r *memorySchemaRegistry) GetLatestSchema(subject string) (*goavro.Codec, error) {
+	return r.GetLatestSchemaContext(context.Background(), subject)
+}
+
+// GetLatestSchemaContext is GetLatestSchema, the context is ignored
+func (r *memorySchemaRegistry) GetLatestSchemaContext(ctx context.Context, subject string) (*goavro.Codec, error) {
+	return memregistry.Codec(r.registry.Latest(subject))
+}
+
+// CreateSubject registers the schema under the subject, registering it again returns the same id
+func (r *memorySchemaRegistry) CreateSubject(subject string, codec *goavro.Codec) (int, error) {
+	return r.CreateSubjectContext(context.Background(), subject, codec)
+}
+
+// CreateSubjectContext is CreateSubject, the context is ignored
+func (r *memorySchemaRegistry) CreateSubjectContext(ctx context.Context, subject string, codec *goavro.Codec) (int, error) {
+	return r.registry.Register(subject, "", codec.Schema(), nil, 0, 0)
+}
+
+// IsSchemaRegistered returns the id of the schema if it is registered under the subject
+func (r *memorySchemaRegistry) IsSchemaRegistered(subject string, codec *goavro.Codec) (int, error) {
+	return r.IsSchemaRegisteredContext(context.Background(), subject, codec)
+}
+
+// IsSchemaRegisteredContext is IsSchemaRegistered, the context is ignored
+func (r *memorySchemaRegistry) IsSchemaRegisteredContext(ctx context.Context, subject string, codec *goavro.Codec) (int, error) {
+	schema, err := r.registry.Lookup(subject, codec.Schema(), nil)
+	if err != nil {
+		return 0, err
+	}
+	return schema.ID, nil
+}
+
+// DeleteSubject soft deletes all versions of the subject
+func (r *memorySchemaRegistry) DeleteSubject(subject string) error {
+	return r.DeleteSubjectContext(context.Background(), subject)
+}
+
+// DeleteSubjectContext is DeleteSubject, the context is ignored
+func (r *memorySchemaRegistry) DeleteSubjectContext(ctx context.Context, subject string) error {
+	return r.registry.DeleteSubject(subject)
+}
+
+// DeleteVersion soft deletes the version of the subject
+func (r *memorySchemaRegistry) DeleteVersion(subject string, version int) error {
+	return r.DeleteVersionContext(context.Background(), subject, version)
+}
+
+// DeleteVersionContext is DeleteVersion, the context is ignored
+func (r *memorySchemaRegistry) DeleteVersionContext(ctx context.Context, subject string, version int) error {
+	return r.registry.DeleteVersion(subject, version)
+}
+
+// DeleteSubjectPermanently removes the subject, it must have been soft deleted first
+func (r *memorySchemaRegistry) DeleteSubjectPermanently(subject string) error {
+	return r.DeleteSubjectPermanentlyContext(context.Background(), subject)
+}
+
+// DeleteSubjectPermanentlyContext is DeleteSubjectPermanently, the context is ignored
+func (r *memorySchemaRegistry) DeleteSubjectPermanentlyContext(ctx context.Context, subject string) error {
+	return r.registry.DeleteSubjectPermanently(subject)
+}
+
+// DeleteVersionPermanently removes the version of the subject, it must have been soft deleted first
+func (r *memorySchemaRegistry) DeleteVersionPermanently(subject string, version int) error {
+	return r.DeleteVersionPermanentlyContext(context.Background(), subject, version)
+}
+
+// DeleteVersionPermanentlyContext is DeleteVersionPermanently, the context is ignored
+func (r *memorySchemaRegistry) DeleteVersionPermanentlyContext(ctx context.Context, subject string, version int) error {
+	return r.registry.DeleteVersionPermanently(subject, version)
+}
+
+// ListDeletedSubjects returns all subjects, including the soft deleted ones
+func (r *memorySchemaRegistry) ListDeletedSubjects() ([]string, error) {
+	return r.ListDeletedSubjectsContext(context.Background())
+}
+
+// ListDeletedSubjectsContext is ListDeletedSubjects, the context is ignored
+func (r *memorySchemaRegistry) ListDeletedSubjectsContext(ctx context.Context) ([]string, error) {
+	return r.registry.Subjects(true), nil
+}
+
+// GetSchemaMetadataByID returns the schema registered with the id
+func (r *memorySchemaRegistry) GetSchemaMetadataByID(id int) (*SchemaMetadata, error) {
+	return r.GetSchemaMetadataByIDContext(context.Background(), id)
+}
+
+// GetSchemaMetadataByIDContext is GetSchemaMetadataByID, the context is ignored
+func (r *memorySchemaRegistry) GetSchemaMetadataByIDContext(ctx context.Context, id int) (*SchemaMetadata, error) {
+	return schemaMetadata(r.registry.ByID(id))
+}
+
+// GetLatestSchemaMetadata returns the latest version of the subject
+func (r *memorySchemaRegistry) GetLatestSchemaMetadata(subject string) (*SchemaMetadata, error) {
+	return r.GetLatestSchemaMetadataContext(context.Background(), subject)
+}
+
+// GetLatestSchemaMetadataContext is GetLatestSchemaMetadata, the context is ignored
+func (r *memorySchemaRegistry) GetLatestSchemaMetadataContext(ctx context.Context, subject string) (*SchemaMetadata, error) {
+	return schemaMetadata(r.registry.Latest(subject))
+}
+
+// GetSchemaMetadata returns the version of the subject
+func (r *memorySchemaRegistry) GetSchemaMetadata(subject string, version int) (*SchemaMetadata, error) {
+	return r.GetSchemaMetadataContext(context.Background(), subject, version)
+}
+
+// GetSchemaMetadataContext is GetSchemaMetadata, the context is ignored
+func (r *memorySchemaRegistry) GetSchemaMetadataContext(ctx context.Context, subject string, version int) (*SchemaMetadata, error) {
+	return schemaMetadata(r.registry.Version(subject, version))
+}
+
+// GetSchemaBySubjectAndID returns the schema with the id if it is registered under the subject
+func (r *memorySchemaRegistry) GetSchemaBySubjectAndID(subject string, id int) (*SchemaMetadata, error) {
+	return r.GetSchemaBySubjectAndIDContext(context.Background(), subject, id)
+}
+
+// GetSchemaBySubjectAndIDContext is GetSchemaBySubjectAndID, the context is ignored
+func (r *memorySchemaRegistry) GetSchemaBySubjectAndIDContext(ctx context.Context, subject string, id int) (*SchemaMetadata, error) {
+	return schemaMetadata(r.registry.BySubjectAndID(subject, id))
+}
+
+// GetReferencedBy returns the ids of the schemas referencing the version of the subject
+func (r *memorySchemaRegistry) GetReferencedBy(subject string, version int) ([]int, error) {
+	return r.GetReferencedByContext(context.Background(), subject, version)
+}
+
+// GetReferencedByContext is GetReferencedBy, the context is ignored
+func (r *memorySchemaRegistry) GetReferencedByContext(ctx context.Context, subject string, version int) ([]int, error) {
+	return r.registry.ReferencedBy(subject, version)
+}
+
+// GetVersionsForSchemaID returns the subject versions registered with the schema id
+func (r *memorySchemaRegistry) GetVersionsForSchemaID(id int) ([]SubjectVersion, error) {
+	return r.GetVersionsForSchemaIDContext(context.Background(), id)
+}
+
+// GetVersionsForSchemaIDContext is GetVersionsForSchemaID, the context is ignored
+func (r *memorySchemaRegistry) GetVersionsForSchemaIDContext(ctx context.Context, id int) ([]SubjectVersion, error) {
+	versions, err := r.registry.VersionsForID(id)
+	result := make([]SubjectVersion, len(versions))
+	for i, version := range versions {
+		result[i] = SubjectVersion(version)
+	}
+	return result, err
+}
+
+// CreateSubjectWithReferences registers the avro schema with its references under the subject
+func (r *memorySchemaRegistry) CreateSubjectWithReferences(subject string, schema string, references []SchemaReference) (int, error) {
+	return r.CreateSubjectWithReferencesContext(context.Background(), subject, schema, references)
+}
+
+// CreateSubjectWithReferencesContext is CreateSubjectWithReferences, the context is ignored
+func (r *memorySchemaRegistry) CreateSubjectWithReferencesContext(ctx context.Context, subject string, schema string, references []SchemaReference) (int, error) {
+	return r.registry.Register(subject, "", schema, schemaReferences(references), 0, 0)
+}
+
+// CreateSubjectWithID registers the schema under the subject with the id and version, the mode is not checked
+func (r *memorySchemaRegistry) CreateSubjectWithID(subject string, codec *goavro.Codec, id int, version int) (int, error) {
+	return r.CreateSubjectWithIDContext(context.Background(), subject, codec, id, version)
+}
+
+// CreateSubjectWithIDContext is CreateSubjectWithID, the context is ignored
+func (r *memorySchemaRegistry) CreateSubjectWithIDContext(ctx context.Context, subject string, codec *goavro.Codec, id int, version int) (int, error) {
+	return r.registry.Register(subject, "", codec.Schema(), nil, id, version)
+}
+
+// CreateSubjectWithSchemaType registers a schema of the given type under the subject
+func (r *memorySchemaRegistry) CreateSubjectWithSchemaType(subject string, schemaType string, schema string, references []SchemaReference) (int, error) {
+	return r.CreateSubjectWithSchemaTypeContext(context.Background(), subject, schemaType, schema, references)
+}
+
+// CreateSubjectWithSchemaTypeContext is CreateSubjectWithSchemaType, the context is ignored
+func (r *memorySchemaRegistry) CreateSubjectWithSchemaTypeContext(ctx context.Context, subject string, schemaType string, schema string, references []SchemaReference) (int, error) {
+	return r.registry.Register(subject, schemaType, schema, schemaReferences(references), 0, 0)
+}
+
+// LookupSchema returns the version of the subject registered with the schema and references
+func (r *memorySchemaRegistry) LookupSchema(subject string, schema string, references []SchemaReference) (*SchemaMetadata, error) {
+	return r.LookupSchemaContext(context.Background(), subject, schema, references)
+}
+
+// LookupSchemaContext is LookupSchema, the context is ignored
+func (r *memorySchemaRegistry) LookupSchemaContext(ctx context.Context, subject string, schema string, references []SchemaReference) (*SchemaMetadata, error) {
+	return schemaMetadata(r.registry.Lookup(subject, schema, schemaReferences(references)))
+}
+
+// CheckCompatibility succeeds for every existing version of the subject
+func (r *memorySchemaRegistry) CheckCompatibility(subject string, version int, codec *goavro.Codec) (bool, error) {
+	return r.CheckCompatibilityContext(context.Background(), subject, version, codec)
+}
+
+// CheckCompatibilityContext is CheckCompatibility, the context is ignored
+func (r *memorySchemaRegistry) CheckCompatibilityContext(ctx context.Context, subject string, version int, codec *goavro.Codec) (bool, error) {
+	if _, err := r.registry.Version(subject, version); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// CheckLatestCompatibility succeeds for every existing subject
+func (r *memorySchemaRegistry) CheckLatestCompatibility(subject string, codec *goavro.Codec) (bool, error) {
+	return r.CheckLatestCompatibilityContext(context.Background(), subject, codec)
+}
+
+// CheckLatestCompatibilityContext is CheckLatestCompatibility, the context is ignored
+func (r *memorySchemaRegistry) CheckLatestCompatibilityContext(ctx context.Context, subject string, codec *goavro.Codec) (bool, error) {
+	if _, err := r.registry.Latest(subject); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GetCompatibility returns the compatibility level of the subject, or the global one if it has none
+func (r *memorySchemaRegistry) GetCompatibility(subject string) (string, error) {
+	return r.GetCompatibilityContext(context.Background(), subject)
+}
+
+// GetCompatibilityContext is GetCompatibility, the context is ignored
+func (r *memorySchemaRegistry) GetCompatibilityContext(ctx context.Context, subject string) (string, error) {
+	return r.registry.Compatibility(subject), nil
+}
+
+// SetCompatibility sets the compatibility level of the subject
+func (r *memorySchemaRegistry) SetCompatibility(subject string, level string) error {
+	return r.SetCompatibilityContext(context.Background(), subject, level)
+}
+
+// SetCompatibilityContext is SetCompatibility, the context is ignored
+func (r *memorySchemaRegistry) SetCompatibilityContext(ctx context.Context, subject string, level string) error {
+	r.registry.SetCompatibility(subject, level)
+	return nil
+}
+
+// GetGlobalCompatibility returns the global compatibility level, BACKWARD by default
+func (r *memorySchemaRegistry) GetGlobalCompatibility() (string, error) {
+	return r.GetGlobalCompatibilityContext(context.Background())
+}
+
+// GetGlobalCompatibilityContext is GetGlobalCompatibility, the context is ignored
+func (r *memorySchemaRegistry) GetGlobalCompatibilityContext(ctx context.Context) (string, error) {
+	return r.registry.GlobalCompatibility(), nil
+}
+
+// SetGlobalCompatibility sets the global compatibility level
+func (r *memorySchemaRegistry) SetGlobalCompatibility(level string) error {
+	return r.SetGlobalCompatibilityContext(context.Background(), level)
+}
+
+// SetGlobalCompatibilityContext is SetGlobalCompatibility, the context is ignored
+func (r *memorySchemaRegistry) SetGlobalCompatibilityContext(ctx context.Context, level string) error {
+	r.registry.SetGlobalCompatibility(level)
+	return nil
+}
+
+// GetMode returns the mode of the subject, or the global one if it has none
+func (r *memorySchemaRegistry) GetMode(subject string) (string, error) {
+	return r.GetModeContext(context.Background(), subject)
+}
+
+// GetModeContext is GetMode, the context is ignored
+func (r *memorySchemaRegistry) GetModeContext(ctx context.Context, subject string) (string, error) {
+	return r.registry.Mode(subject), nil
+}
+
+// SetMode sets the mode of the subject, registrations fail in READONLY mode
+func (r *memorySchemaRegistry) SetMode(subject string, mode string) error {
+	return r.SetModeContext(context.Background(), subject, mode)
+}
+
+// SetModeContext is SetMode, the context is ignored
+func (r *memorySchemaRegistry) SetModeContext(ctx context.Context, subject string, mode string) error {
+	r.registry.SetMode(subject, mode)
+	return nil
+}
+
+// GetGlobalMode returns the global mode, READWRITE by default
+func (r *memorySchemaRegistry) GetGlobalMode() (string, error) {
+	return r.GetGlobalModeContext(context.Background())
+}
+
+// GetGlobalModeContext is GetGlobalMode, the context is ignored
+func (r *memorySchemaRegistry) GetGlobalModeContext(ctx context.Context) (string, error) {
+	return r.registry.GlobalMode(), nil
+}
+
+// SetGlobalMode sets the global mode
+func (r *memorySchemaRegistry) SetGlobalMode(mode string) error {
+	return r.SetGlobalModeContext(context.Background(), mode)
+}
+
+// SetGlobalModeContext is SetGlobalMode, the context is ignored
+func (r *memorySchemaRegistry) SetGlobalModeContext(ctx context.Context, mode string) error {
+	r.registry.SetGlobalMode(mode)
+	return nil
+}
+
+// Ping always succeeds
+func (r *memorySchemaRegistry) Ping() error {
+	return r.PingContext(context.Background())
+}
+
+// PingContext is Ping, the context is ignored
+func (r *memorySchemaRegistry) PingContext(ctx context.Context) error {
+	return nil
+}
+
+// ServerInfo returns the version "mock"
+func (r *memorySchemaRegistry) ServerInfo() (*ServerInfo, error) {
+	return r.ServerInfoContext(context.Background())
+}
+
+// ServerInfoContext is ServerInfo, the context is ignored
+func (r *memorySchemaRegistry) ServerInfoContext(ctx context.Context) (*ServerInfo, error) {
+	return &ServerInfo{Version: "mock"}, nil
+}
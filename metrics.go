@@ -0,0 +1,53 @@
+package kafka
+
+import (
+	"time"
+)
+
+// Metrics receives the events of producers, consumers and schema registry clients, e.g. to export them to
+// prometheus with the kafkaprom package. Implementations must be safe for concurrent use, they can embed
+// NopMetrics to only handle some events.
+type Metrics interface {
+	// MessageProduced is called once a message was sent or failed to be sent
+	MessageProduced(topic string, err error)
+	// MessageConsumed is called once a consumed message was decoded or failed to be decoded
+	MessageConsumed(topic string, err error)
+	// Encoded and Decoded report the time spent converting a message from and to avro
+	Encoded(topic string, duration time.Duration)
+	Decoded(topic string, duration time.Duration)
+	// RegistryRequest reports a registry http request, status is 0 when no response was received
+	RegistryRequest(method string, status int, duration time.Duration)
+	// RegistryRetry is called before a failed registry request is retried
+	RegistryRetry(method string)
+	// CacheLookup reports a lookup in one of the caches of CachedSchemaRegistryClient
+	CacheLookup(cache string, hit bool)
+	// DeadLettered is called once a message of the topic was sent to the dead-letter queue
+	DeadLettered(topic string)
+}
+
+// NopMetrics ignores every event
+type NopMetrics struct{}
+
+func (NopMetrics) MessageProduced(topic string, err error)                           {}
+func (NopMetrics) MessageConsumed(topic string, err error)                           {}
+func (NopMetrics) Encoded(topic string, duration time.Duration)                      {}
+func (NopMetrics) Decoded(topic string, duration time.Duration)                      {}
+func (NopMetrics) RegistryRequest(method string, status int, duration time.Duration) {}
+func (NopMetrics) RegistryRetry(method string)                                       {}
+func (NopMetrics) CacheLookup(cache string, hit bool)                                {}
+func (NopMetrics) DeadLettered(topic string)                                         {}
+
+// WithMetrics reports the events of a producer or a consumer and of its schema registry client to metrics
+func WithMetrics(metrics Metrics) Option {
+	return func(o *options) {
+		o.metrics = metrics
+		o.registry = append(o.registry, WithRegistryMetrics(metrics))
+	}
+}
+
+// WithRegistryMetrics reports the requests of a registry client, and the cache lookups of a cached client, to metrics
+func WithRegistryMetrics(metrics Metrics) SchemaRegistryOption {
+	return func(client *SchemaRegistryClient) {
+		client.metrics = metrics
+	}
+}
@@ -0,0 +1,75 @@
+package kafka
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+type recordingMetrics struct {
+	NopMetrics
+	lock     sync.Mutex
+	requests []int
+	retries  int
+	lookups  map[string]int
+	decoded  int
+}
+
+func (m *recordingMetrics) RegistryRequest(method string, status int, duration time.Duration) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.requests = append(m.requests, status)
+}
+
+func (m *recordingMetrics) RegistryRetry(method string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.retries++
+}
+
+func (m *recordingMetrics) CacheLookup(cache string, hit bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if hit {
+		m.lookups[cache]++
+	}
+}
+
+func (m *recordingMetrics) Decoded(topic string, duration time.Duration) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.decoded++
+}
+
+func TestWithRegistryMetrics(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	metrics := &recordingMetrics{lookups: make(map[string]int)}
+	client := NewSchemaRegistryClientWithRetries([]string{failing.URL}, 1, WithRegistryMetrics(metrics))
+	client.GetSubjects()
+	if len(metrics.requests) != 2 || metrics.requests[0] != http.StatusServiceUnavailable || metrics.retries != 1 {
+		t.Errorf("Expected 2 requests and a retry, got %v and %d", metrics.requests, metrics.retries)
+	}
+}
+
+func TestWithMetrics_Consumer(t *testing.T) {
+	schemaRegistryTestObject := createSchemaRegistryTestObject(t, "test", 1)
+	metrics := &recordingMetrics{lookups: make(map[string]int)}
+	o := applyOptions(defaultAvroConsumerConfig(), []Option{WithMetrics(metrics)})
+	consumer := &AvroConsumer{SchemaRegistryClient: o.schemaRegistryClient([]string{schemaRegistryTestObject.MockServer.URL}), metrics: o.metrics}
+	msg := &sarama.ConsumerMessage{Topic: "test", Value: getTestAvroMsg(t, schemaRegistryTestObject.Codec)}
+	for i := 0; i < 2; i++ {
+		if _, err := consumer.ProcessAvroMsg(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if metrics.decoded != 2 || metrics.lookups["schema"] != 1 || len(metrics.requests) == 0 {
+		t.Errorf("Expected the decodes, the cache hit and the registry request to be reported, got %+v", metrics)
+	}
+}
@@ -0,0 +1,272 @@
+package kafka
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+// MirrorConfig controls the topics and the retries of a Mirror
+type MirrorConfig struct {
+	// Topics maps the consumed topics, as in Message.Topic, to the topics produced to, the other topics keep their name
+	Topics map[string]string
+	// MirrorKeys re-registers the schemas of the keys framed like ConfluentWireFormat too, e.g. by AddWithAvroKey.
+	// Keys are copied as they are otherwise.
+	MirrorKeys bool
+	// MaxRetries is the number of times a message that could not be mirrored is retried before the mirror stops
+	MaxRetries int
+	// RetryBackoff is the time to wait between retries
+	RetryBackoff time.Duration
+}
+
+// Mirror copies the messages of the topics of a consumer of cluster A to a producer of cluster B, registering the
+// writer schema of every message to the registry of B and framing the payload with the id it has there, since schema
+// ids of different registries differ. Payloads are not decoded, the referenced schemas are registered under their
+// subjects first. Offsets are committed once a message is produced, so messages are mirrored at least once.
+// Timestamps, headers and tombstones are kept, partitions are chosen by the partitioner of the producer.
+type Mirror struct {
+	source      *AvroConsumer
+	destination *AvroProducer
+	config      MirrorConfig
+	lock        sync.Mutex
+	ids         map[mirroredSchema]int
+	cancel      context.CancelFunc
+	err         error
+}
+
+// mirroredSchema identifies the registration of a source schema to a subject of the destination topic
+type mirroredSchema struct {
+	topic    string
+	isKey    bool
+	schemaId int
+}
+
+// NewMirror creates a mirror consuming with source and producing with destination
+func NewMirror(source *AvroConsumer, destination *AvroProducer, config MirrorConfig) *Mirror {
+	return &Mirror{source: source, destination: destination, config: config, ids: make(map[mirroredSchema]int)}
+}
+
+// Run mirrors the messages until the context is cancelled, the consumer is closed, or a message could not be
+// mirrored after the retries, its error is then returned and its offset is not committed
+func (m *Mirror) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	m.lock.Lock()
+	m.cancel, m.err = cancel, nil
+	m.lock.Unlock()
+	m.source.run(ctx, m)
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.err
+}
+
+// Setup implements sarama.ConsumerGroupHandler
+func (m *Mirror) Setup(session sarama.ConsumerGroupSession) error {
+	m.source.rebalanced(session)
+	return nil
+}
+
+// Cleanup implements sarama.ConsumerGroupHandler
+func (m *Mirror) Cleanup(session sarama.ConsumerGroupSession) error {
+	m.source.revoked(session)
+	return nil
+}
+
+// ConsumeClaim implements sarama.ConsumerGroupHandler, the messages of a partition are mirrored in order
+func (m *Mirror) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	for msg := range claim.Messages() {
+		if err := m.mirrorWithRetries(session.Context(), msg); err != nil {
+			m.stop(err)
+			return nil
+		}
+		session.MarkMessage(msg, "")
+	}
+	return nil
+}
+
+func (m *Mirror) mirrorWithRetries(ctx context.Context, msg *sarama.ConsumerMessage) error {
+	for i := 0; ; i++ {
+		err := m.mirror(msg)
+		if err == nil || i >= m.config.MaxRetries {
+			return err
+		}
+		orNop(m.source.logger).Warn("could not mirror message, retrying", "topic", msg.Topic,
+			"partition", msg.Partition, "offset", msg.Offset, "error", err)
+		select {
+		case <-time.After(m.config.RetryBackoff):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+func (m *Mirror) stop(err error) {
+	orNop(m.source.logger).Error("stopping mirror", "error", err)
+	if m.source.callbacks.OnError != nil {
+		m.source.callbacks.OnError(err)
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.err == nil {
+		m.err = err
+	}
+	if m.cancel != nil {
+		m.cancel()
+	}
+}
+
+// mirror produces the message to the destination topic with the schema ids of the destination registry
+func (m *Mirror) mirror(msg *sarama.ConsumerMessage) error {
+	topic := msg.Topic
+	if mapped, ok := m.config.Topics[topic]; ok {
+		topic = mapped
+	}
+	key, err := m.key(topic, msg)
+	if err != nil {
+		return err
+	}
+	sourceFormat := m.source.config.ForTopic(m.source.logicalTopic(msg.Topic)).wireFormat()
+	var out *sarama.ProducerMessage
+	if msg.Value == nil {
+		out = &sarama.ProducerMessage{Topic: m.destination.config.PhysicalTopic(topic), Key: key}
+	} else {
+		schemaId, payload, err := sourceFormat.Decode(msg)
+		if err != nil {
+			return err
+		}
+		destinationId, err := m.schemaId(topic, false, schemaId)
+		if err != nil {
+			return err
+		}
+		out = m.destination.prepare(topic, destinationId, key, payload)
+	}
+	out.Timestamp = msg.Timestamp
+	// the headers of the wire formats carry the schema ids of their registry
+	destinationFormat := m.destination.config.ForTopic(topic).wireFormat()
+	excluded := make(map[string]bool)
+	for _, format := range []WireFormat{sourceFormat, destinationFormat} {
+		_, headers := format.Encode(0, nil)
+		for _, header := range headers {
+			excluded[string(header.Key)] = true
+		}
+	}
+	for _, header := range msg.Headers {
+		if header != nil && !excluded[string(header.Key)] {
+			out.Headers = append(out.Headers, *header)
+		}
+	}
+	_, _, err = m.destination.sendPrepared(out)
+	return err
+}
+
+// key returns the key of the mirrored message, a framed key of MirrorKeys gets the id of the destination registry
+func (m *Mirror) key(topic string, msg *sarama.ConsumerMessage) (sarama.Encoder, error) {
+	if msg.Key == nil {
+		return nil, nil
+	}
+	if !m.config.MirrorKeys || len(msg.Key) < 5 || msg.Key[0] != 0 {
+		return sarama.ByteEncoder(msg.Key), nil
+	}
+	schemaId, err := m.schemaId(topic, true, int(binary.BigEndian.Uint32(msg.Key[1:5])))
+	if err != nil {
+		return nil, err
+	}
+	return &AvroEncoder{SchemaID: schemaId, Content: msg.Key[5:]}, nil
+}
+
+// schemaId returns the id of the source schema in the destination registry, registering it to the value or the key
+// subject of the destination topic
+func (m *Mirror) schemaId(topic string, isKey bool, sourceId int) (int, error) {
+	key := mirroredSchema{topic, isKey, sourceId}
+	m.lock.Lock()
+	id, found := m.ids[key]
+	m.lock.Unlock()
+	if found {
+		return id, nil
+	}
+	metadata, err := m.source.SchemaRegistryClient.GetSchemaMetadataByID(sourceId)
+	if err != nil {
+		return 0, fmt.Errorf("could not get schema %d from the source registry: %v", sourceId, err)
+	}
+	subject, err := m.destination.config.valueSubject(topic, metadata.Schema)
+	if isKey {
+		subject, err = m.destination.config.keySubject(topic, metadata.Schema)
+	}
+	if err != nil {
+		return 0, err
+	}
+	references, err := m.references(metadata.References)
+	if err != nil {
+		return 0, err
+	}
+	id, err = m.register(subject, metadata.SchemaType, metadata.Schema, references)
+	if err != nil {
+		return 0, fmt.Errorf("could not register schema %d of the source registry to %s: %v", sourceId, subject, err)
+	}
+	orNop(m.destination.logger).Info("mirrored schema", "subject", subject, "source", sourceId, "id", id)
+	m.lock.Lock()
+	m.ids[key] = id
+	m.lock.Unlock()
+	return id, nil
+}
+
+// references registers the referenced schemas to their subjects in the destination registry and returns the
+// references to their destination versions
+func (m *Mirror) references(references []SchemaReference) ([]SchemaReference, error) {
+	mirrored := make([]SchemaReference, 0, len(references))
+	for _, reference := range references {
+		metadata, err := m.source.SchemaRegistryClient.GetSchemaMetadata(reference.Subject, reference.Version)
+		if err != nil {
+			return nil, fmt.Errorf("could not resolve reference %s: %v", reference.Name, err)
+		}
+		nested, err := m.references(metadata.References)
+		if err != nil {
+			return nil, err
+		}
+		id, err := m.register(reference.Subject, metadata.SchemaType, metadata.Schema, nested)
+		if err != nil {
+			return nil, fmt.Errorf("could not register reference %s: %v", reference.Name, err)
+		}
+		version, err := m.version(reference.Subject, id)
+		if err != nil {
+			return nil, fmt.Errorf("could not register reference %s: %v", reference.Name, err)
+		}
+		mirrored = append(mirrored, SchemaReference{Name: reference.Name, Subject: reference.Subject, Version: version})
+	}
+	return mirrored, nil
+}
+
+// register registers the schema to the subject of the destination registry and returns its id there. When the
+// producer does not register schemas it is only looked up, which the registry supports for avro schemas.
+func (m *Mirror) register(subject string, schemaType string, schema string, references []SchemaReference) (int, error) {
+	registry := m.destination.schemaRegistryClient
+	if m.destination.schemas.noAutoRegister {
+		metadata, err := registry.LookupSchema(subject, schema, references)
+		if err != nil {
+			return 0, err
+		}
+		return metadata.ID, nil
+	}
+	if schemaType == "" {
+		schemaType = SchemaTypeAvro
+	}
+	return registry.CreateSubjectWithSchemaType(subject, schemaType, schema, references)
+}
+
+// version returns the version of the subject having the schema id in the destination registry
+func (m *Mirror) version(subject string, schemaId int) (int, error) {
+	versions, err := m.destination.schemaRegistryClient.GetVersionsForSchemaID(schemaId)
+	if err != nil {
+		return 0, err
+	}
+	for _, version := range versions {
+		if version.Subject == subject {
+			return version.Version, nil
+		}
+	}
+	return 0, fmt.Errorf("schema %d is not a version of %s", schemaId, subject)
+}
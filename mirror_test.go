@@ -0,0 +1,116 @@
+package kafka
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/linkedin/goavro/v2"
+)
+
+func TestMirror_ConsumeClaim(t *testing.T) {
+	source, destination := newMemorySchemaRegistry(), newMemorySchemaRegistry()
+	other, err := goavro.NewCodec(`{"type": "record", "name": "other", "fields" : [{"name": "name", "type": "string"}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := destination.CreateSubject("other-value", other); err != nil {
+		t.Fatal(err)
+	}
+	codec, err := goavro.NewCodec(`{"type": "record", "name": "test", "fields" : [{"name": "val", "type": "int"}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sourceId, err := source.CreateSubject("test-value", codec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyId, err := source.CreateSubject("test-key", codec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := codec.BinaryFromNative(nil, map[string]interface{}{"val": 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	framed := func(id int) []byte {
+		value := append([]byte{0, 0, 0, 0, 0}, payload...)
+		binary.BigEndian.PutUint32(value[1:5], uint32(id))
+		return value
+	}
+
+	producer := &testSyncProducer{}
+	mirror := NewMirror(&AvroConsumer{SchemaRegistryClient: source},
+		&AvroProducer{producer: producer, schemaRegistryClient: destination},
+		MirrorConfig{Topics: map[string]string{"test": "mirrored"}, MirrorKeys: true})
+	claim := newTestClaim(3)
+	claim.messages <- &sarama.ConsumerMessage{Topic: "test", Offset: 7, Key: []byte("a"), Value: framed(sourceId),
+		Headers: []*sarama.RecordHeader{{Key: []byte("trace"), Value: []byte("1")}}}
+	claim.messages <- &sarama.ConsumerMessage{Topic: "test", Offset: 8, Key: []byte("a")}
+	claim.messages <- &sarama.ConsumerMessage{Topic: "test", Offset: 9, Key: framed(keyId), Value: framed(sourceId)}
+	close(claim.messages)
+	session := newTestSession(nil)
+	if err := mirror.ConsumeClaim(session, claim); err != nil {
+		t.Fatal(err)
+	}
+	if len(producer.sent) != 3 {
+		t.Fatalf("expected 3 mirrored messages, got %d", len(producer.sent))
+	}
+	if session.offsets[0] != 10 {
+		t.Errorf("expected offset 10 to be committed, got %d", session.offsets[0])
+	}
+
+	valueSchema, err := destination.GetLatestSchemaMetadata("mirrored-value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if valueSchema.ID == sourceId {
+		t.Fatalf("expected the destination registry to assign another id than %d", sourceId)
+	}
+	value, err := producer.sent[0].Value.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if producer.sent[0].Topic != "mirrored" || int(binary.BigEndian.Uint32(value[1:5])) != valueSchema.ID ||
+		string(value[5:]) != string(payload) {
+		t.Errorf("unexpected mirrored message %s %v", producer.sent[0].Topic, value)
+	}
+	if len(producer.sent[0].Headers) != 1 || string(producer.sent[0].Headers[0].Key) != "trace" {
+		t.Errorf("expected the headers to be copied, got %v", producer.sent[0].Headers)
+	}
+	if producer.sent[1].Value != nil {
+		t.Errorf("expected the tombstone to be mirrored, got %v", producer.sent[1].Value)
+	}
+
+	key, err := producer.sent[2].Key.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keySchema, err := destination.GetLatestSchemaMetadata("mirrored-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int(binary.BigEndian.Uint32(key[1:5])) != keySchema.ID {
+		t.Errorf("expected the key to be framed with id %d, got %v", keySchema.ID, key)
+	}
+}
+
+func TestMirror_ConsumeClaimStopsOnError(t *testing.T) {
+	mirror := NewMirror(&AvroConsumer{SchemaRegistryClient: newMemorySchemaRegistry()},
+		&AvroProducer{producer: &testSyncProducer{}, schemaRegistryClient: newMemorySchemaRegistry()}, MirrorConfig{})
+	var reported error
+	mirror.source.callbacks.OnError = func(err error) { reported = err }
+	claim := newTestClaim(1)
+	claim.messages <- &sarama.ConsumerMessage{Topic: "test", Offset: 3, Value: []byte{0, 0, 0, 0, 9, 1}}
+	close(claim.messages)
+	session := newTestSession(nil)
+	if err := mirror.ConsumeClaim(session, claim); err != nil {
+		t.Fatal(err)
+	}
+	if reported == nil || mirror.err == nil {
+		t.Error("expected the unknown schema to stop the mirror")
+	}
+	if _, committed := session.offsets[0]; committed {
+		t.Errorf("expected no offset to be committed, got %d", session.offsets[0])
+	}
+}
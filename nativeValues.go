@@ -0,0 +1,241 @@
+package kafka
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+	ratType      = reflect.TypeOf(big.Rat{})
+)
+
+// StructConverter converts Go values into the native goavro form of a schema, wrapping union members
+// the way goavro expects. Struct fields are matched by their `avro` tag, their `json` tag or their name.
+type StructConverter struct {
+	Codec  *goavro.Codec
+	schema interface{}
+	named  namedSchemas
+}
+
+// NewStructConverter creates a converter for the schema
+func NewStructConverter(schema string) (*StructConverter, error) {
+	codec, err := goavro.NewCodec(schema)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := parseSchemaJSON(schema)
+	if err != nil {
+		return nil, err
+	}
+	converter := &StructConverter{Codec: codec, schema: parsed, named: make(namedSchemas)}
+	converter.named.collect(parsed, "")
+	return converter, nil
+}
+
+// Native returns the native goavro form of v
+func (c *StructConverter) Native(v interface{}) (interface{}, error) {
+	return c.convert(c.schema, "", reflect.ValueOf(v))
+}
+
+func (c *StructConverter) convert(node interface{}, namespace string, v reflect.Value) (interface{}, error) {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+	switch n := node.(type) {
+	case string:
+		if primitiveTypes[n] {
+			return primitive(n, v)
+		}
+		fullName := qualifyName(n, namespace)
+		definition, ok := c.named[fullName]
+		if !ok {
+			return nil, fmt.Errorf("unknown named type: %s", fullName)
+		}
+		return c.convert(definition, namespaceOf(fullName), v)
+	case []interface{}:
+		if !v.IsValid() {
+			return nil, nil
+		}
+		for _, branch := range n {
+			if branch == "null" {
+				continue
+			}
+			if value, err := c.convert(branch, namespace, v); err == nil {
+				return goavro.Union(unionBranchName(branch, namespace), value), nil
+			}
+		}
+		return nil, fmt.Errorf("no union member matches %s", v.Type())
+	case map[string]interface{}:
+		return c.complex(n, namespace, v)
+	}
+	return nil, fmt.Errorf("unsupported schema: %v", node)
+}
+
+func (c *StructConverter) complex(node map[string]interface{}, namespace string, v reflect.Value) (interface{}, error) {
+	if !v.IsValid() {
+		return nil, fmt.Errorf("nil value for %v", node["type"])
+	}
+	typeName, _ := node["type"].(string)
+	if logicalType, ok := node["logicalType"]; ok {
+		if logicalType == "uuid" && isUUIDType(v.Type()) {
+			b := make([]byte, 16)
+			reflect.Copy(reflect.ValueOf(b), v)
+			return formatUUID(b), nil
+		}
+		// goavro expects time.Time, time.Duration and *big.Rat for logical types
+		switch v.Type() {
+		case timeType, durationType:
+			return v.Interface(), nil
+		case ratType:
+			rat := v.Interface().(big.Rat)
+			return &rat, nil
+		}
+	}
+	switch typeName {
+	case "record", "error":
+		_, recordNamespace := definedName(node, namespace)
+		record := make(map[string]interface{})
+		fields, _ := node["fields"].([]interface{})
+		for _, f := range fields {
+			field, ok := f.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			name, _ := field["name"].(string)
+			fieldValue, found := lookupField(v, name)
+			if !found {
+				if _, ok := field["default"]; !ok {
+					return nil, fmt.Errorf("missing field %s", name)
+				}
+				// goavro uses the field default
+				continue
+			}
+			value, err := c.convert(field["type"], recordNamespace, fieldValue)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %v", name, err)
+			}
+			record[name] = value
+		}
+		return record, nil
+	case "enum":
+		if v.Kind() != reflect.String {
+			return nil, fmt.Errorf("cannot use %s as enum", v.Type())
+		}
+		return v.String(), nil
+	case "fixed":
+		return bytesOf(v)
+	case "array":
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			return nil, fmt.Errorf("cannot use %s as array", v.Type())
+		}
+		items := make([]interface{}, v.Len())
+		for i := range items {
+			value, err := c.convert(node["items"], namespace, v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			items[i] = value
+		}
+		return items, nil
+	case "map":
+		if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("cannot use %s as map", v.Type())
+		}
+		values := make(map[string]interface{}, v.Len())
+		for _, key := range v.MapKeys() {
+			value, err := c.convert(node["values"], namespace, v.MapIndex(key))
+			if err != nil {
+				return nil, err
+			}
+			values[key.String()] = value
+		}
+		return values, nil
+	}
+	return c.convert(node["type"], namespace, v)
+}
+
+// lookupField returns the struct field or map entry for an avro field name
+func lookupField(v reflect.Value, name string) (reflect.Value, bool) {
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return reflect.Value{}, false
+		}
+		value := v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+		return value, value.IsValid()
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			if fieldName(field) == name {
+				return v.Field(i), true
+			}
+		}
+	}
+	return reflect.Value{}, false
+}
+
+func fieldName(field reflect.StructField) string {
+	for _, key := range []string{"avro", "json"} {
+		if tag := strings.Split(field.Tag.Get(key), ",")[0]; tag != "" && tag != "-" {
+			return tag
+		}
+	}
+	return field.Name
+}
+
+func primitive(typeName string, v reflect.Value) (interface{}, error) {
+	if !v.IsValid() {
+		if typeName == "null" {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("nil value for %s", typeName)
+	}
+	kind := v.Kind()
+	switch {
+	case typeName == "boolean" && kind == reflect.Bool:
+		return v.Bool(), nil
+	case (typeName == "int" || typeName == "long") && kind >= reflect.Int && kind <= reflect.Int64:
+		return v.Int(), nil
+	case (typeName == "int" || typeName == "long") && kind >= reflect.Uint && kind <= reflect.Uint64:
+		if v.Uint() > math.MaxInt64 {
+			return nil, fmt.Errorf("%d overflows %s", v.Uint(), typeName)
+		}
+		return int64(v.Uint()), nil
+	case (typeName == "float" || typeName == "double") && (kind == reflect.Float32 || kind == reflect.Float64):
+		return v.Float(), nil
+	case typeName == "string" && kind == reflect.String:
+		return v.String(), nil
+	case typeName == "bytes":
+		return bytesOf(v)
+	}
+	return nil, fmt.Errorf("cannot use %s as %s", v.Type(), typeName)
+}
+
+func bytesOf(v reflect.Value) ([]byte, error) {
+	switch {
+	case v.Kind() == reflect.String:
+		return []byte(v.String()), nil
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		return v.Bytes(), nil
+	case v.Kind() == reflect.Array && v.Type().Elem().Kind() == reflect.Uint8:
+		b := make([]byte, v.Len())
+		reflect.Copy(reflect.ValueOf(b), v)
+		return b, nil
+	}
+	return nil, fmt.Errorf("cannot use %s as bytes", v.Type())
+}
@@ -0,0 +1,110 @@
+package kafka
+
+import (
+	"math"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+const orderSchema = `{
+	"type": "record",
+	"name": "Order",
+	"namespace": "com.example",
+	"fields": [
+		{"name": "id", "type": "long"},
+		{"name": "status", "type": {"type": "enum", "name": "Status", "symbols": ["NEW", "PAID"]}},
+		{"name": "note", "type": ["null", "string"], "default": null},
+		{"name": "created", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+		{"name": "total", "type": {"type": "bytes", "logicalType": "decimal", "precision": 10, "scale": 2}},
+		{"name": "tags", "type": {"type": "array", "items": "string"}},
+		{"name": "items", "type": {"type": "map", "values": {
+			"type": "record", "name": "Item", "fields": [{"name": "qty", "type": "int"}]
+		}}},
+		{"name": "count", "type": "int", "default": 0}
+	]
+}`
+
+type testStatus string
+
+type testItem struct {
+	Qty int `avro:"qty"`
+}
+
+type testOrder struct {
+	ID      int64               `json:"id"`
+	Status  testStatus          `avro:"status"`
+	Note    *string             `avro:"note"`
+	Created time.Time           `avro:"created"`
+	Total   *big.Rat            `avro:"total"`
+	Tags    []string            `avro:"tags"`
+	Items   map[string]testItem `avro:"items"`
+}
+
+func TestStructConverter_Native(t *testing.T) {
+	converter, err := NewStructConverter(orderSchema)
+	if err != nil {
+		t.Fatalf("Error creating converter: %v", err)
+	}
+	note := "gift"
+	created := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	order := testOrder{1, "PAID", &note, created, big.NewRat(1234, 100), []string{"a"}, map[string]testItem{"x": {2}}}
+	native, err := converter.Native(&order)
+	if err != nil {
+		t.Fatalf("Error converting struct: %v", err)
+	}
+	binary, err := converter.Codec.BinaryFromNative(nil, native)
+	if err != nil {
+		t.Fatalf("Error encoding native: %v", err)
+	}
+	decoded, _, err := converter.Codec.NativeFromBinary(binary)
+	if err != nil {
+		t.Fatalf("Error decoding: %v", err)
+	}
+	record := decoded.(map[string]interface{})
+	if !reflect.DeepEqual(record["note"], goavro.Union("string", "gift")) || record["status"] != "PAID" || record["count"] != int32(0) {
+		t.Errorf("Unexpected record: %v", record)
+	}
+	if !record["created"].(time.Time).Equal(created) || record["total"].(*big.Rat).Cmp(order.Total) != 0 {
+		t.Errorf("Unexpected logical values: %v", record)
+	}
+
+	order.Note = nil
+	if native, err = converter.Native(order); err != nil {
+		t.Fatalf("Error converting struct: %v", err)
+	}
+	if note := native.(map[string]interface{})["note"]; note != nil {
+		t.Errorf("Expected nil note, got %v", note)
+	}
+}
+
+func TestStructConverter_Errors(t *testing.T) {
+	converter, err := NewStructConverter(orderSchema)
+	if err != nil {
+		t.Fatalf("Error creating converter: %v", err)
+	}
+	if _, err := converter.Native(struct{ ID string }{"1"}); err == nil {
+		t.Errorf("Expected error for missing and mistyped fields")
+	}
+	if _, err := converter.Native(map[string]interface{}{"id": "x"}); err == nil {
+		t.Errorf("Expected error for mistyped map entry")
+	}
+	converter, err = NewStructConverter(`{"type": "record", "name": "Counter", "fields": [{"name": "n", "type": "long"}]}`)
+	if err != nil {
+		t.Fatalf("Error creating converter: %v", err)
+	}
+	if _, err := converter.Native(struct {
+		N uint64 `avro:"n"`
+	}{math.MaxUint64}); err == nil {
+		t.Errorf("Expected error for an unsigned value overflowing long")
+	}
+	if native, err := converter.Native(struct {
+		N uint64 `avro:"n"`
+	}{math.MaxInt64}); err != nil ||
+		native.(map[string]interface{})["n"] != int64(math.MaxInt64) {
+		t.Errorf("Expected the largest long to be converted, got %v, %v", native, err)
+	}
+}
@@ -0,0 +1,114 @@
+package kafka
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxNegativeEntries bounds the negative cache, so a flood of distinct unknown ids cannot grow it forever
+const maxNegativeEntries = 10000
+
+type negativeEntry struct {
+	err    error
+	expiry time.Time
+}
+
+// negativeCache remembers not found errors of the registry for a ttl, so repeated lookups of unknown
+// ids and subjects do not hit the registry every time. A nil cache remembers nothing.
+type negativeCache struct {
+	ttl     time.Duration
+	entries map[string]negativeEntry
+	lock    sync.Mutex
+}
+
+func newNegativeCache(ttl time.Duration) *negativeCache {
+	return &negativeCache{ttl: ttl, entries: make(map[string]negativeEntry)}
+}
+
+// EnableNegativeCache remembers not found errors for the ttl, lookups of the same unknown schema id or subject
+// return the remembered error without a registry request. Registering a schema to a subject forgets its errors.
+func (client *CachedSchemaRegistryClient) EnableNegativeCache(ttl time.Duration) {
+	client.misses = newNegativeCache(ttl)
+}
+
+func (c *negativeCache) get(key string) error {
+	if c == nil {
+		return nil
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	entry, found := c.entries[key]
+	if !found {
+		return nil
+	}
+	if time.Now().After(entry.expiry) {
+		delete(c.entries, key)
+		return nil
+	}
+	return entry.err
+}
+
+// add remembers err for the key if it is a not found error
+func (c *negativeCache) add(key string, err error) {
+	if c == nil || !IsNotFoundError(err) {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	now := time.Now()
+	if len(c.entries) >= maxNegativeEntries {
+		for k, entry := range c.entries {
+			if now.After(entry.expiry) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= maxNegativeEntries {
+			return
+		}
+	}
+	c.entries[key] = negativeEntry{err, now.Add(c.ttl)}
+}
+
+// forget removes the error of the key
+func (c *negativeCache) forget(key string) {
+	if c == nil {
+		return
+	}
+	c.lock.Lock()
+	delete(c.entries, key)
+	c.lock.Unlock()
+}
+
+func (c *negativeCache) flush() {
+	if c == nil {
+		return
+	}
+	c.lock.Lock()
+	c.entries = make(map[string]negativeEntry)
+	c.lock.Unlock()
+}
+
+// forgetSubject removes the errors of the lookups of a subject
+func (c *negativeCache) forgetSubject(subject string) {
+	if c == nil {
+		return
+	}
+	prefix := subjectMissKey(subject, "")
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for key := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+func idMissKey(id int) string {
+	return "id:" + strconv.Itoa(id)
+}
+
+func subjectMissKey(subject string, lookup string) string {
+	return "subject:" + subject + ":" + lookup
+}
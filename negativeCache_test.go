@@ -0,0 +1,73 @@
+package kafka
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+func TestCachedSchemaRegistryClient_EnableNegativeCache(t *testing.T) {
+	count := 0
+	registered := false
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count++
+		switch {
+		case r.Method == "POST":
+			registered = true
+			fmt.Fprint(w, `{"id": 1}`)
+		case registered && r.URL.Path == "/subjects/test-value/versions/latest":
+			fmt.Fprint(w, `{"subject": "test-value", "version": 1, "id": 1, "schema": "\"string\""}`)
+		default:
+			http.Error(w, `{"error_code": 40403, "message": "Schema not found"}`, http.StatusNotFound)
+		}
+	}))
+	defer mockServer.Close()
+	client := NewCachedSchemaRegistryClient([]string{mockServer.URL})
+	client.EnableNegativeCache(50 * time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		if _, err := client.GetSchema(42); !IsNotFoundError(err) {
+			t.Errorf("Expected not found error, got %v", err)
+		}
+	}
+	if count != 1 {
+		t.Errorf("Expected a single lookup of the unknown id, got %d", count)
+	}
+	time.Sleep(60 * time.Millisecond)
+	client.GetSchema(42)
+	if count != 2 {
+		t.Errorf("Expected the error to expire, got %d lookups", count)
+	}
+
+	count = 0
+	client.GetLatestSchema("test-value")
+	client.GetLatestSchema("test-value")
+	codec, _ := goavro.NewCodec(`"string"`)
+	if _, err := client.CreateSubject("test-value", codec); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetLatestSchema("test-value"); err != nil {
+		t.Errorf("Expected registering to forget the error of the subject, got %v", err)
+	}
+	if count != 3 {
+		t.Errorf("Expected 3 registry requests, got %d", count)
+	}
+}
+
+func TestNegativeCache_OnlyNotFound(t *testing.T) {
+	cache := newNegativeCache(time.Minute)
+	cache.add("id:1", &Error{50001, "Error in the backend datastore"})
+	cache.add("id:2", fmt.Errorf("connection refused"))
+	if cache.get("id:1") != nil || cache.get("id:2") != nil {
+		t.Errorf("Expected only not found errors to be remembered")
+	}
+	var disabled *negativeCache
+	disabled.add("id:3", &Error{40403, "Schema not found"})
+	if disabled.get("id:3") != nil {
+		t.Errorf("Expected a nil cache to remember nothing")
+	}
+}
@@ -0,0 +1,120 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+// tokenExpiryMargin is how long before its expiry a token is refreshed
+const tokenExpiryMargin = 30 * time.Second
+
+// WithOAuthBearer authenticates to the brokers with SASL/OAUTHBEARER, it requires kafka 2.0 or later
+func WithOAuthBearer(provider sarama.AccessTokenProvider) Option {
+	return func(o *options) {
+		o.mutate(func(config *sarama.Config) {
+			config.Net.SASL.Enable = true
+			config.Net.SASL.Mechanism = sarama.SASLTypeOAuth
+			config.Net.SASL.TokenProvider = provider
+		})
+	}
+}
+
+// ClientCredentialsTokenProvider is a sarama.AccessTokenProvider fetching tokens with the OAuth2 client credentials
+// grant. Tokens are reused until shortly before they expire.
+type ClientCredentialsTokenProvider struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	// Extensions are sent to the broker with every token
+	Extensions map[string]string
+	HTTPClient *http.Client
+
+	lock   sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// NewClientCredentialsTokenProvider creates a token provider for the token endpoint of an OAuth2 server
+func NewClientCredentialsTokenProvider(tokenURL string, clientID string, clientSecret string, scopes ...string) *ClientCredentialsTokenProvider {
+	return &ClientCredentialsTokenProvider{
+		TokenURL:     tokenURL,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Scopes:       scopes,
+		HTTPClient:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Token implements sarama.AccessTokenProvider
+func (p *ClientCredentialsTokenProvider) Token() (*sarama.AccessToken, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.token == "" || time.Now().After(p.expiry) {
+		if err := p.refresh(); err != nil {
+			return nil, err
+		}
+	}
+	return &sarama.AccessToken{Token: p.token, Extensions: p.Extensions}, nil
+}
+
+func (p *ClientCredentialsTokenProvider) refresh() error {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(p.Scopes) > 0 {
+		form.Set("scope", strings.Join(p.Scopes, " "))
+	}
+	req, err := http.NewRequest("POST", p.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(p.ClientID), url.QueryEscape(p.ClientSecret))
+	resp, err := p.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if !okStatus(resp) {
+		return fmt.Errorf("token endpoint returned %s", resp.Status)
+	}
+	var token tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return err
+	}
+	if token.AccessToken == "" {
+		return fmt.Errorf("token endpoint returned no access token")
+	}
+	p.token = token.AccessToken
+	lifetime := time.Duration(token.ExpiresIn)*time.Second - tokenExpiryMargin
+	if lifetime <= 0 {
+		// the server did not tell, refresh regularly
+		lifetime = time.Minute
+	}
+	p.expiry = time.Now().Add(lifetime)
+	return nil
+}
+
+// RegistryTokenProvider returns the provider as a TokenProvider, to authenticate to the schema registry
+// with the same OAuth2 client as the brokers
+func (p *ClientCredentialsTokenProvider) RegistryTokenProvider() TokenProvider {
+	return TokenProviderFunc(func(ctx context.Context) (string, error) {
+		token, err := p.Token()
+		if err != nil {
+			return "", err
+		}
+		return token.Token, nil
+	})
+}
@@ -0,0 +1,53 @@
+package kafka
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Shopify/sarama"
+)
+
+func TestClientCredentialsTokenProvider(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		user, password, _ := r.BasicAuth()
+		if user != "client" || password != "secret" || r.FormValue("grant_type") != "client_credentials" || r.FormValue("scope") != "kafka" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprintf(w, `{"access_token": "token-%d", "expires_in": 3600}`, requests)
+	}))
+	defer server.Close()
+
+	provider := NewClientCredentialsTokenProvider(server.URL, "client", "secret", "kafka")
+	for i := 0; i < 2; i++ {
+		token, err := provider.Token()
+		if err != nil {
+			t.Fatalf("Error getting token: %v", err)
+		}
+		if token.Token != "token-1" {
+			t.Errorf("Expected cached token, got %s", token.Token)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("Expected one token request, got %d", requests)
+	}
+
+	if _, err := NewClientCredentialsTokenProvider(server.URL, "client", "wrong").Token(); err == nil {
+		t.Errorf("Expected error for rejected credentials")
+	}
+}
+
+func TestWithOAuthBearer(t *testing.T) {
+	provider := NewClientCredentialsTokenProvider("http://localhost", "client", "secret")
+	config := applyOptions(defaultAvroConsumerConfig(), []Option{WithOAuthBearer(provider)}).saramaConfig
+	if config.Net.SASL.Mechanism != sarama.SASLTypeOAuth || config.Net.SASL.TokenProvider != provider {
+		t.Errorf("Expected OAUTHBEARER to be configured")
+	}
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected valid config, got %v", err)
+	}
+}
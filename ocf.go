@@ -0,0 +1,121 @@
+package kafka
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+// ProduceOCF produces the records of an avro object container file to the topic, encoding each with the registry
+// schema of the topic for the schema of the file. key returns the key of a record, records have no key when it is
+// nil. It stops at the first error and returns the number of records produced.
+func (ap *AvroProducer) ProduceOCF(topic string, r io.Reader,
+	key func(record interface{}) ([]byte, error)) (int, error) {
+	ocf, err := goavro.NewOCFReader(r)
+	if err != nil {
+		return 0, fmt.Errorf("could not read the object container file: %v", err)
+	}
+	schema := ocf.Codec().Schema()
+	resolved, err := ap.topicSchema(topic, schema)
+	if err != nil {
+		return 0, err
+	}
+	produced := 0
+	for ocf.Scan() {
+		record, err := ocf.Read()
+		if err != nil {
+			return produced, fmt.Errorf("could not read record %d of the object container file: %v", produced, err)
+		}
+		var recordKey []byte
+		if key != nil {
+			if recordKey, err = key(record); err != nil {
+				return produced, err
+			}
+		}
+		if err := ap.sendNative(topic, resolved.codec, resolved.id, recordKey, record); err != nil {
+			ap.invalidateTopicSchema(topic, schema)
+			return produced, err
+		}
+		produced++
+	}
+	if err := ocf.Err(); err != nil {
+		return produced, fmt.Errorf("could not read the object container file: %v", err)
+	}
+	return produced, nil
+}
+
+// OCFSink is a Sink writing the values of the messages to an avro object container file, one block per batch.
+// The values are written with the schema of the file: textual values are decoded with it, so the topics should
+// have this schema or a reader schema resolving to it. Tombstones are skipped.
+type OCFSink struct {
+	// W receives the file, it is synced on Flush when it has a Sync method (e.g. an *os.File), it is not closed
+	W io.Writer
+	// Schema is the schema of the file
+	Schema string
+	// CompressionName is the block compression, goavro.CompressionNullLabel (the default),
+	// goavro.CompressionDeflateLabel or goavro.CompressionSnappyLabel
+	CompressionName string
+	writer          *goavro.OCFWriter
+}
+
+// Open implements Sink, writing the header of the file
+func (s *OCFSink) Open() error {
+	writer, err := goavro.NewOCFWriter(goavro.OCFConfig{W: s.W, Schema: s.Schema, CompressionName: s.CompressionName})
+	if err != nil {
+		return err
+	}
+	s.writer = writer
+	return nil
+}
+
+// WriteBatch implements Sink, the batch is written only when all its values could be converted
+func (s *OCFSink) WriteBatch(msgs []Message) error {
+	records := make([]interface{}, 0, len(msgs))
+	for _, msg := range msgs {
+		if msg.Tombstone {
+			continue
+		}
+		record, err := s.record(msg)
+		if err != nil {
+			return fmt.Errorf("could not convert message %s/%d@%d to the schema of the file: %v",
+				msg.Topic, msg.Partition, msg.Offset, err)
+		}
+		records = append(records, record)
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	return s.writer.Append(records)
+}
+
+// record returns the native value of the message for the file, the values of the native and raw decode modes are
+// used as is when they have the schema of the file
+func (s *OCFSink) record(msg Message) (interface{}, error) {
+	codec := s.writer.Codec()
+	if msg.Value != "" {
+		record, _, err := codec.NativeFromTextual([]byte(msg.Value))
+		return record, err
+	}
+	if msg.Codec() == nil || msg.Codec().Schema() != codec.Schema() {
+		return nil, fmt.Errorf("the message has another schema and no textual value")
+	}
+	if msg.Payload != nil {
+		record, _, err := codec.NativeFromBinary(msg.Payload)
+		return record, err
+	}
+	return msg.Native(), nil
+}
+
+// Flush implements Sink
+func (s *OCFSink) Flush() error {
+	if syncer, ok := s.W.(interface{ Sync() error }); ok {
+		return syncer.Sync()
+	}
+	return nil
+}
+
+// Close implements Sink
+func (s *OCFSink) Close() error {
+	return nil
+}
@@ -0,0 +1,86 @@
+package kafka
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/linkedin/goavro/v2"
+)
+
+func TestOCF_RoundTrip(t *testing.T) {
+	schema := `{"type": "record", "name": "test", "fields" : [{"name": "val", "type": "int"}]}`
+	var file bytes.Buffer
+	writer, err := goavro.NewOCFWriter(goavro.OCFConfig{W: &file, Schema: schema})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Append([]interface{}{map[string]interface{}{"val": 1}, map[string]interface{}{"val": 2}}); err != nil {
+		t.Fatal(err)
+	}
+
+	registry := newMemorySchemaRegistry()
+	producer := &testSyncProducer{}
+	avroProducer := &AvroProducer{producer: producer, schemaRegistryClient: registry}
+	produced, err := avroProducer.ProduceOCF("test", &file, func(record interface{}) ([]byte, error) {
+		return []byte(fmt.Sprint(record.(map[string]interface{})["val"])), nil
+	})
+	if err != nil || produced != 2 || len(producer.sent) != 2 {
+		t.Fatalf("Expected the records to be produced, got %d, %v", produced, err)
+	}
+
+	consumer := &AvroConsumer{SchemaRegistryClient: registry}
+	var msgs []Message
+	for i, sent := range producer.sent {
+		key, _ := sent.Key.Encode()
+		value, _ := sent.Value.Encode()
+		if string(key) != fmt.Sprint(i+1) {
+			t.Errorf("Unexpected key %q", key)
+		}
+		msg, err := consumer.ProcessAvroMsg(&sarama.ConsumerMessage{Topic: "test", Offset: int64(i), Value: value})
+		if err != nil {
+			t.Fatal(err)
+		}
+		msgs = append(msgs, msg)
+	}
+	consumer.SetDecodeMode(DecodeRaw)
+	value, _ := producer.sent[0].Value.Encode()
+	raw, err := consumer.ProcessAvroMsg(&sarama.ConsumerMessage{Topic: "test", Value: value})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs = append(msgs, raw, Message{Tombstone: true})
+
+	var exported bytes.Buffer
+	sink := &OCFSink{W: &exported, Schema: schema, CompressionName: goavro.CompressionDeflateLabel}
+	if err := sink.Open(); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.WriteBatch(msgs); err != nil {
+		t.Fatal(err)
+	}
+	reader, err := goavro.NewOCFReader(&exported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var values []interface{}
+	for reader.Scan() {
+		record, err := reader.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		values = append(values, record.(map[string]interface{})["val"])
+	}
+	if fmt.Sprint(values) != "[1 2 1]" {
+		t.Errorf("Expected the values to be exported without the tombstone, got %v", values)
+	}
+
+	other, err := goavro.NewCodec(`{"type": "record", "name": "other", "fields" : [{"name": "val", "type": "int"}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.WriteBatch([]Message{{native: map[string]interface{}{"val": 1}, codec: other}}); err == nil {
+		t.Errorf("Expected native values of another schema to be rejected")
+	}
+}
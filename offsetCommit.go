@@ -0,0 +1,74 @@
+package kafka
+
+import "github.com/Shopify/sarama"
+
+// OffsetCommitStrategy defines when the offset of a consumed message is marked for commit
+type OffsetCommitStrategy int
+
+const (
+	// CommitBeforeCallback marks the offset before OnDataReceived is called, a crash in the callback loses the message
+	CommitBeforeCallback OffsetCommitStrategy = iota
+	// CommitAfterCallback marks the offset once OnDataReceived returned, giving at-least-once delivery
+	CommitAfterCallback
+	// CommitManual never marks offsets, the application calls Message.Ack, Message.Nack or MarkOffset
+	// once a message is processed
+	CommitManual
+)
+
+// SetOffsetCommitStrategy sets when the offsets of consumed messages are marked, defaults to CommitBeforeCallback
+func (ac *AvroConsumer) SetOffsetCommitStrategy(strategy OffsetCommitStrategy) {
+	ac.commitStrategy = strategy
+}
+
+// markHandled marks a message handled by the consumer itself, e.g. dead-lettered, unless the offsets are manual
+func (ac *AvroConsumer) markHandled(session sarama.ConsumerGroupSession, m *sarama.ConsumerMessage) {
+	if ac.commitStrategy != CommitManual {
+		session.MarkMessage(m, "")
+	}
+}
+
+// MarkOffset marks the message as processed, its offset is committed with the next commit of the group.
+// Messages of partitions that are no longer claimed by this consumer are ignored.
+func (ac *AvroConsumer) MarkOffset(msg Message) {
+	ac.membershipLock.Lock()
+	session := ac.session
+	ac.membershipLock.Unlock()
+	if session == nil {
+		return
+	}
+	for _, partition := range session.Claims()[msg.Topic] {
+		if partition == msg.Partition {
+			session.MarkOffset(msg.Topic, msg.Partition, msg.Offset+1, "")
+			return
+		}
+	}
+}
+
+// messageAck is the delivery of a message, acknowledged with Message.Ack or Message.Nack
+type messageAck struct {
+	consumer *AvroConsumer
+	session  sarama.ConsumerGroupSession
+	msg      *sarama.ConsumerMessage
+}
+
+// Ack marks the message as processed in the session it was received in, committing its offset and the offsets
+// preceding it in its partition with the next commit of the group. It can be called from any goroutine, after
+// the callback returned. Messages that were not received by a consumer group, e.g. from ProcessAvroMsg, are ignored.
+func (msg Message) Ack() {
+	if msg.ack != nil {
+		msg.ack.session.MarkMessage(msg.ack.msg, "")
+	}
+}
+
+// Nack reports that the message failed, it is retried or dead-lettered like a failed OnProcess then acknowledged.
+// The error of republishing the message is returned, the message is not acknowledged then.
+func (msg Message) Nack(cause error) error {
+	if msg.ack == nil {
+		return nil
+	}
+	if err := msg.ack.consumer.processFailed(msg.ack.msg, cause); err != nil {
+		return err
+	}
+	msg.Ack()
+	return nil
+}
@@ -0,0 +1,80 @@
+package kafka
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Shopify/sarama"
+)
+
+func TestAvroConsumer_OffsetCommitStrategy(t *testing.T) {
+	var marked []int64
+	session := newTestSession(nil)
+	consumer := &AvroConsumer{callbacks: ConsumerCallbacks{OnDataReceived: func(msg Message) {
+		marked = append(marked, session.offsets[0])
+	}}}
+	// invalid payloads are still handed to the callback with the decode error
+	m := &sarama.ConsumerMessage{Topic: "test", Offset: 4, Value: []byte{0, 0, 0, 0, 1}}
+	consumer.SchemaRegistryClient = NewCachedSchemaRegistryClient([]string{"http://127.0.0.1:1"})
+
+	consumer.handle(session, m)
+	consumer.SetOffsetCommitStrategy(CommitAfterCallback)
+	m.Offset = 5
+	consumer.handle(session, m)
+	if marked[0] != 5 || marked[1] != 5 || session.offsets[0] != 6 {
+		t.Errorf("Expected offsets to be marked before then after the callback, got %v, %d", marked, session.offsets[0])
+	}
+
+	consumer.SetOffsetCommitStrategy(CommitManual)
+	m.Offset = 6
+	consumer.handle(session, m)
+	if session.offsets[0] != 6 {
+		t.Errorf("Expected manual strategy not to mark offsets")
+	}
+}
+
+func TestAvroConsumer_MarkOffset(t *testing.T) {
+	consumer := &AvroConsumer{}
+	consumer.MarkOffset(Message{Topic: "test", Offset: 1})
+
+	session := newTestSession(map[string][]int32{"test": {0}})
+	consumer.rebalanced(session)
+	consumer.MarkOffset(Message{Topic: "test", Partition: 0, Offset: 1})
+	consumer.MarkOffset(Message{Topic: "test", Partition: 1, Offset: 1})
+	if session.offsets[0] != 2 || len(session.offsets) != 1 {
+		t.Errorf("Expected only the claimed partition to be marked, got %v", session.offsets)
+	}
+}
+
+func TestMessage_AckNack(t *testing.T) {
+	var received []Message
+	producer := &testSyncProducer{}
+	consumer := &AvroConsumer{SchemaRegistryClient: newMemorySchemaRegistry(), callbacks: ConsumerCallbacks{
+		OnDataReceived: func(msg Message) { received = append(received, msg) },
+	}}
+	consumer.SetOffsetCommitStrategy(CommitManual)
+	session := newTestSession(nil)
+	consumer.handle(session, &sarama.ConsumerMessage{Topic: "test", Offset: 4, Value: []byte{0, 0, 0, 0, 1}})
+	consumer.handle(session, &sarama.ConsumerMessage{Topic: "test", Offset: 5, Value: []byte{0, 0, 0, 0, 1}})
+	if len(received) != 2 || len(session.offsets) != 0 {
+		t.Fatalf("Expected the messages to be received without committing, got %d, %v", len(received), session.offsets)
+	}
+
+	received[0].Ack()
+	if session.offsets[0] != 5 {
+		t.Errorf("Expected the acknowledged message to be committed, got %v", session.offsets)
+	}
+	consumer.SetDeadLetterQueue(DeadLetterConfig{Producer: producer})
+	if err := received[1].Nack(errors.New("rejected")); err != nil {
+		t.Fatalf("Error rejecting the message: %v", err)
+	}
+	if len(producer.sent) != 1 || session.offsets[0] != 6 {
+		t.Errorf("Expected the rejected message to be dead-lettered and committed, got %d, %v", len(producer.sent), session.offsets)
+	}
+
+	producer.err = errors.New("broker down")
+	if err := received[0].Nack(errors.New("rejected")); err == nil {
+		t.Errorf("Expected the dead-letter error")
+	}
+	Message{}.Ack()
+}
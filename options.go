@@ -0,0 +1,134 @@
+package kafka
+
+import (
+	"crypto/tls"
+
+	"github.com/Shopify/sarama"
+)
+
+// Option configures a producer or a consumer at construction
+type Option func(*options)
+
+type options struct {
+	saramaConfig   *sarama.Config
+	mutators       []func(*sarama.Config)
+	config         *Config
+	bootstrap      *BootstrapConfig
+	registry       []SchemaRegistryOption
+	schemaRegistry SchemaRegistry
+	metrics        Metrics
+	logger         Logger
+	rateLimit      *rateLimiter
+	schemas        schemaSelection
+	claimCheck     *ClaimCheck
+}
+
+func applyOptions(saramaConfig *sarama.Config, opts []Option) *options {
+	o := &options{saramaConfig: saramaConfig}
+	for _, opt := range opts {
+		opt(o)
+	}
+	for _, mutate := range o.mutators {
+		mutate(o.saramaConfig)
+	}
+	return o
+}
+
+// schemaRegistryClient returns the registry set with WithSchemaRegistry, or a cached client of the servers
+func (o *options) schemaRegistryClient(schemaRegistryServers []string) SchemaRegistry {
+	if o.schemaRegistry != nil {
+		return o.schemaRegistry
+	}
+	return NewCachedSchemaRegistryClient(schemaRegistryServers, o.registry...)
+}
+
+func (o *options) mutate(mutator func(*sarama.Config)) {
+	o.mutators = append(o.mutators, mutator)
+}
+
+// WithSaramaConfig replaces the default sarama configuration, the other options are applied on top of it
+func WithSaramaConfig(config *sarama.Config) Option {
+	return func(o *options) {
+		o.saramaConfig = config
+	}
+}
+
+// WithTLS connects to the brokers over TLS, set Certificates on the tls.Config for mutual TLS
+func WithTLS(tlsConfig *tls.Config) Option {
+	return func(o *options) {
+		o.mutate(func(config *sarama.Config) {
+			config.Net.TLS.Enable = true
+			config.Net.TLS.Config = tlsConfig
+		})
+	}
+}
+
+// WithSASL authenticates to the brokers with SASL/PLAIN
+func WithSASL(user string, password string) Option {
+	return func(o *options) {
+		o.mutate(func(config *sarama.Config) {
+			config.Net.SASL.Enable = true
+			config.Net.SASL.User = user
+			config.Net.SASL.Password = password
+		})
+	}
+}
+
+// WithCompression sets the compression codec of produced messages
+func WithCompression(codec sarama.CompressionCodec) Option {
+	return func(o *options) {
+		o.mutate(func(config *sarama.Config) {
+			config.Producer.Compression = codec
+		})
+	}
+}
+
+// WithPartitioner sets the partitioner of produced messages
+func WithPartitioner(partitioner sarama.PartitionerConstructor) Option {
+	return func(o *options) {
+		o.mutate(func(config *sarama.Config) {
+			config.Producer.Partitioner = partitioner
+		})
+	}
+}
+
+// WithRoundRobinPartitioner spreads produced messages over the partitions in turn, ignoring their keys
+func WithRoundRobinPartitioner() Option {
+	return WithPartitioner(sarama.NewRoundRobinPartitioner)
+}
+
+// WithManualPartitioner sends produced messages to the partition set on them, e.g. with PrepareMessageToPartition.
+// Messages of Add and its variants have no partition set and go to partition 0.
+func WithManualPartitioner() Option {
+	return WithPartitioner(sarama.NewManualPartitioner)
+}
+
+// WithIdempotence enables sarama's idempotent producer, so retries do not introduce duplicates
+func WithIdempotence() Option {
+	return func(o *options) {
+		o.mutate(enableIdempotence)
+	}
+}
+
+// WithConfig sets the layered per-topic and per-subject configuration.
+// Consumers subscribe to the physical topics and apply the fetch sizes of the topic.
+func WithConfig(config Config) Option {
+	return func(o *options) {
+		o.config = &config
+	}
+}
+
+// WithSchemaRegistry makes a producer or a consumer use the registry, the schema registry urls
+// and the schema registry options are then ignored
+func WithSchemaRegistry(registry SchemaRegistry) Option {
+	return func(o *options) {
+		o.schemaRegistry = registry
+	}
+}
+
+// WithBootstrap makes a consumer bootstrap its partitions in parallel until they are caught up
+func WithBootstrap(bootstrap BootstrapConfig) Option {
+	return func(o *options) {
+		o.bootstrap = &bootstrap
+	}
+}
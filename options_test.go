@@ -0,0 +1,61 @@
+package kafka
+
+import (
+	"crypto/tls"
+	"testing"
+
+	"github.com/Shopify/sarama"
+)
+
+func TestApplyOptions(t *testing.T) {
+	base := sarama.NewConfig()
+	tlsConfig := &tls.Config{}
+	o := applyOptions(defaultAvroProducerConfig(), []Option{
+		WithTLS(tlsConfig),
+		WithSASL("user", "secret"),
+		WithCompression(sarama.CompressionSnappy),
+		WithIdempotence(),
+		// replaces the base config even though it comes after the other options
+		WithSaramaConfig(base),
+		WithConfig(Config{TopicResolver: PrefixTopicResolver("prod.")}),
+	})
+	if o.saramaConfig != base {
+		t.Fatalf("Expected options to apply to the given sarama config")
+	}
+	if !base.Net.TLS.Enable || base.Net.TLS.Config != tlsConfig || !base.Net.SASL.Enable || base.Net.SASL.User != "user" {
+		t.Errorf("Expected TLS and SASL to be enabled")
+	}
+	if base.Producer.Compression != sarama.CompressionSnappy || !base.Producer.Idempotent {
+		t.Errorf("Expected producer options to be applied")
+	}
+	if o.config.PhysicalTopic("test") != "prod.test" {
+		t.Errorf("Expected layered config to be set")
+	}
+}
+
+func TestApplyOptions_WithSchemaRegistry(t *testing.T) {
+	registry := NewSchemaRegistryClient([]string{"http://registry:8081"})
+	o := applyOptions(defaultAvroProducerConfig(), []Option{WithSchemaRegistry(registry)})
+	if o.schemaRegistryClient([]string{"http://other:8081"}) != registry {
+		t.Errorf("Expected the given registry to be used")
+	}
+	o = applyOptions(defaultAvroProducerConfig(), nil)
+	if _, ok := o.schemaRegistryClient([]string{"http://other:8081"}).(*CachedSchemaRegistryClient); !ok {
+		t.Errorf("Expected a cached client by default")
+	}
+}
+
+func TestApplyOptions_Partitioners(t *testing.T) {
+	o := applyOptions(defaultAvroProducerConfig(), []Option{WithManualPartitioner()})
+	partitioner := o.saramaConfig.Producer.Partitioner("test")
+	if partition, err := partitioner.Partition(&sarama.ProducerMessage{Partition: 2}, 3); err != nil || partition != 2 {
+		t.Errorf("Expected the partition of the message, got %d, %v", partition, err)
+	}
+	o = applyOptions(defaultAvroProducerConfig(), []Option{WithRoundRobinPartitioner()})
+	partitioner = o.saramaConfig.Producer.Partitioner("test")
+	first, _ := partitioner.Partition(&sarama.ProducerMessage{}, 3)
+	second, _ := partitioner.Partition(&sarama.ProducerMessage{}, 3)
+	if second != (first+1)%3 {
+		t.Errorf("Expected the partitions in turn, got %d then %d", first, second)
+	}
+}
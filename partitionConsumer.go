@@ -0,0 +1,136 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/Shopify/sarama"
+)
+
+// PartitionConsumer reads partitions of a topic from explicit offsets without joining a consumer group, e.g. for
+// backfills and debugging readers. Messages are decoded like the group consumer, no offset is committed.
+type PartitionConsumer struct {
+	Consumer sarama.Consumer
+	decoder  *AvroConsumer
+	topic    string
+	offsets  map[int32]int64
+}
+
+// NewPartitionConsumer reads the partitions of the topic from their offsets, sarama.OffsetOldest and
+// sarama.OffsetNewest are accepted. OnDataReceived and OnProcess are called one message at a time, decode and
+// process errors are passed to OnError.
+func NewPartitionConsumer(kafkaServers []string, schemaRegistryServers []string,
+	topic string, offsets map[int32]int64, callbacks ConsumerCallbacks, opts ...Option) (*PartitionConsumer, error) {
+	if len(offsets) == 0 {
+		return nil, fmt.Errorf("at least one partition is required")
+	}
+	o := applyOptions(defaultAvroConsumerConfig(), opts)
+	logical := topic
+	if o.config != nil {
+		o.config.ForTopic(topic).Fetch.apply(o.saramaConfig)
+		topic = o.config.PhysicalTopic(topic)
+	}
+	consumer, err := sarama.NewConsumer(kafkaServers, o.saramaConfig)
+	if err != nil {
+		return nil, err
+	}
+	decoder := &AvroConsumer{
+		SchemaRegistryClient: o.schemaRegistryClient(schemaRegistryServers),
+		callbacks:            callbacks,
+		kafkaServers:         kafkaServers,
+		topics:               []string{topic},
+		saramaConfig:         o.saramaConfig,
+		config:               o.config,
+		metrics:              o.metrics,
+		logger:               o.logger,
+		rateLimit:            o.rateLimit,
+		claimCheck:           o.claimCheck,
+	}
+	if o.config != nil {
+		decoder.logicalTopics = map[string]string{topic: logical}
+	}
+	return &PartitionConsumer{Consumer: consumer, decoder: decoder, topic: topic, offsets: offsets}, nil
+}
+
+// Consume reads the partitions until the context is cancelled
+func (pc *PartitionConsumer) Consume(ctx context.Context) error {
+	var consumers sync.WaitGroup
+	defer consumers.Wait()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	messages := make(chan *sarama.ConsumerMessage)
+	for partition, offset := range pc.offsets {
+		partitionConsumer, err := pc.Consumer.ConsumePartition(pc.topic, partition, offset)
+		if err != nil {
+			return fmt.Errorf("could not consume %s/%d from offset %d: %v", pc.topic, partition, offset, err)
+		}
+		consumers.Add(1)
+		go func() {
+			defer consumers.Done()
+			defer partitionConsumer.AsyncClose()
+			for {
+				select {
+				case m, ok := <-partitionConsumer.Messages():
+					if !ok {
+						return
+					}
+					select {
+					case messages <- m:
+					case <-ctx.Done():
+						return
+					}
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	for {
+		select {
+		case m := <-messages:
+			pc.handle(ctx, m)
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (pc *PartitionConsumer) handle(ctx context.Context, m *sarama.ConsumerMessage) {
+	callbacks := pc.decoder.callbacks
+	if err := pc.decoder.rateLimit.wait(ctx, len(m.Key)+len(m.Value)); err != nil {
+		return
+	}
+	assembled, _, err := pc.decoder.chunks.add(m)
+	pc.decoder.evictChunks(m.Topic)
+	if err == nil && assembled == nil {
+		return
+	}
+	var msg Message
+	if err == nil {
+		msg, err = pc.decoder.ProcessAvroMsgContext(ctx, assembled)
+	}
+	if pc.decoder.metrics != nil {
+		pc.decoder.metrics.MessageConsumed(m.Topic, err)
+	}
+	if err != nil {
+		orNop(pc.decoder.logger).Error("could not decode message", "topic", m.Topic, "partition", m.Partition,
+			"offset", m.Offset, "error", err)
+		if callbacks.OnError != nil {
+			callbacks.OnError(err)
+		}
+	}
+	if callbacks.OnDataReceived != nil {
+		callbacks.OnDataReceived(msg)
+	}
+	if callbacks.OnProcess != nil && err == nil {
+		if err := callbacks.OnProcess(msg); err != nil && callbacks.OnError != nil {
+			callbacks.OnError(err)
+		}
+	}
+}
+
+// Close closes the sarama consumer, stop Consume first by cancelling its context
+func (pc *PartitionConsumer) Close() error {
+	return pc.Consumer.Close()
+}
@@ -0,0 +1,65 @@
+package kafka
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/mocks"
+	"github.com/linkedin/goavro/v2"
+)
+
+func TestPartitionConsumer_Consume(t *testing.T) {
+	registry := newMemorySchemaRegistry()
+	codec, err := goavro.NewCodec(`{"type": "record", "name": "test", "fields" : [{"name": "val", "type": "int"}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schemaId, err := registry.CreateSubject("test-value", codec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	value := []byte{0, 0, 0, 0, 0, 2}
+	binary.BigEndian.PutUint32(value[1:5], uint32(schemaId))
+
+	consumerMock := mocks.NewConsumer(t, nil)
+	partition := consumerMock.ExpectConsumePartition("test", 1, 42)
+	partition.YieldMessage(&sarama.ConsumerMessage{Value: value})
+	partition.YieldMessage(&sarama.ConsumerMessage{Value: []byte{1}})
+
+	received := make(chan Message, 2)
+	errs := make(chan error, 2)
+	consumer := &PartitionConsumer{Consumer: consumerMock, topic: "test", offsets: map[int32]int64{1: 42},
+		decoder: &AvroConsumer{SchemaRegistryClient: registry, callbacks: ConsumerCallbacks{
+			OnDataReceived: func(msg Message) { received <- msg },
+			OnError:        func(err error) { errs <- err },
+		}}}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- consumer.Consume(ctx)
+	}()
+
+	select {
+	case msg := <-received:
+		if msg.Topic != "test" || msg.Partition != 1 || msg.Value != `{"val":1}` {
+			t.Errorf("Unexpected message %+v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the message to be received")
+	}
+	select {
+	case <-errs:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the decode error to be reported")
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if err := consumer.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,111 @@
+package kafka
+
+import (
+	"sync"
+	"time"
+)
+
+// PartitionWatchCallbacks are invoked by the partition watcher of a producer
+type PartitionWatchCallbacks struct {
+	// OnPartitionCountChange is called when the partition count of a produced topic changes.
+	// Hash partitioned keys are distributed differently from then on.
+	OnPartitionCountChange func(topic string, oldCount int, newCount int)
+	OnError                func(err error)
+}
+
+type partitionLister interface {
+	RefreshMetadata(topics ...string) error
+	Partitions(topic string) ([]int32, error)
+}
+
+type partitionWatcher struct {
+	client    partitionLister
+	callbacks PartitionWatchCallbacks
+	counts    map[string]int
+	lock      sync.Mutex
+	stop      chan struct{}
+	stopOnce  sync.Once
+}
+
+func newPartitionWatcher(client partitionLister, callbacks PartitionWatchCallbacks) *partitionWatcher {
+	return &partitionWatcher{
+		client:    client,
+		callbacks: callbacks,
+		counts:    make(map[string]int),
+		stop:      make(chan struct{}),
+	}
+}
+
+// track starts watching a topic, remembering its current partition count
+func (w *partitionWatcher) track(topic string) {
+	w.lock.Lock()
+	_, found := w.counts[topic]
+	w.lock.Unlock()
+	if found {
+		return
+	}
+	partitions, err := w.client.Partitions(topic)
+	if err != nil {
+		w.onError(err)
+		return
+	}
+	w.lock.Lock()
+	if _, found := w.counts[topic]; !found {
+		w.counts[topic] = len(partitions)
+	}
+	w.lock.Unlock()
+}
+
+// check refreshes the metadata of all tracked topics and reports changed partition counts
+func (w *partitionWatcher) check() {
+	w.lock.Lock()
+	topics := make([]string, 0, len(w.counts))
+	for topic := range w.counts {
+		topics = append(topics, topic)
+	}
+	w.lock.Unlock()
+	if len(topics) == 0 {
+		return
+	}
+	if err := w.client.RefreshMetadata(topics...); err != nil {
+		w.onError(err)
+		return
+	}
+	for _, topic := range topics {
+		partitions, err := w.client.Partitions(topic)
+		if err != nil {
+			w.onError(err)
+			continue
+		}
+		w.lock.Lock()
+		oldCount := w.counts[topic]
+		w.counts[topic] = len(partitions)
+		w.lock.Unlock()
+		if oldCount != len(partitions) && w.callbacks.OnPartitionCountChange != nil {
+			w.callbacks.OnPartitionCountChange(topic, oldCount, len(partitions))
+		}
+	}
+}
+
+func (w *partitionWatcher) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			w.check()
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+func (w *partitionWatcher) close() {
+	w.stopOnce.Do(func() { close(w.stop) })
+}
+
+func (w *partitionWatcher) onError(err error) {
+	if w.callbacks.OnError != nil {
+		w.callbacks.OnError(err)
+	}
+}
@@ -0,0 +1,44 @@
+package kafka
+
+import (
+	"testing"
+)
+
+type testPartitionLister struct {
+	partitions map[string][]int32
+	refreshes  int
+}
+
+func (l *testPartitionLister) RefreshMetadata(topics ...string) error {
+	l.refreshes++
+	return nil
+}
+
+func (l *testPartitionLister) Partitions(topic string) ([]int32, error) {
+	return l.partitions[topic], nil
+}
+
+func TestPartitionWatcher(t *testing.T) {
+	lister := &testPartitionLister{partitions: map[string][]int32{"test": {0, 1}}}
+	var changes [][]int
+	watcher := newPartitionWatcher(lister, PartitionWatchCallbacks{
+		OnPartitionCountChange: func(topic string, oldCount int, newCount int) {
+			changes = append(changes, []int{oldCount, newCount})
+		},
+	})
+	defer watcher.close()
+	watcher.check()
+	if lister.refreshes != 0 {
+		t.Errorf("Expected no refresh without tracked topics")
+	}
+	watcher.track("test")
+	watcher.check()
+	if len(changes) != 0 {
+		t.Errorf("Expected no change, got %v", changes)
+	}
+	lister.partitions["test"] = []int32{0, 1, 2}
+	watcher.check()
+	if len(changes) != 1 || changes[0][0] != 2 || changes[0][1] != 3 {
+		t.Errorf("Expected change from 2 to 3 partitions, got %v", changes)
+	}
+}
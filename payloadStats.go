@@ -0,0 +1,100 @@
+package kafka
+
+import (
+	"strings"
+
+	"github.com/rcrowley/go-metrics"
+)
+
+// same reservoir as the histograms sarama registers itself
+const (
+	metricsReservoirSize = 1028
+	metricsAlphaFactor   = 0.015
+)
+
+// SizeStats is a snapshot of a size histogram
+type SizeStats struct {
+	Count int64
+	Min   int64
+	Max   int64
+	Mean  float64
+	P50   float64
+	P95   float64
+	P99   float64
+}
+
+// PayloadStats holds the payload size histograms of a topic. MessageSize is the encoded avro message size in bytes.
+// BatchSize (bytes) and CompressionRatio (compressed/uncompressed x100) are reported by sarama for produced batches.
+type PayloadStats struct {
+	MessageSize      SizeStats
+	BatchSize        SizeStats
+	CompressionRatio SizeStats
+}
+
+func metricNameForTopic(name string, topic string) string {
+	// sarama converts dots since reporters like Graphite use them as hierarchy separator
+	return name + "-for-topic-" + strings.Replace(topic, ".", "_", -1)
+}
+
+func topicHistogram(registry metrics.Registry, name string, topic string) metrics.Histogram {
+	return registry.GetOrRegister(metricNameForTopic(name, topic), func() metrics.Histogram {
+		return metrics.NewHistogram(metrics.NewExpDecaySample(metricsReservoirSize, metricsAlphaFactor))
+	}).(metrics.Histogram)
+}
+
+func newSizeStats(registry metrics.Registry, name string, topic string) SizeStats {
+	histogram, ok := registry.Get(metricNameForTopic(name, topic)).(metrics.Histogram)
+	if !ok {
+		return SizeStats{}
+	}
+	snapshot := histogram.Snapshot()
+	percentiles := snapshot.Percentiles([]float64{0.5, 0.95, 0.99})
+	return SizeStats{
+		Count: snapshot.Count(),
+		Min:   snapshot.Min(),
+		Max:   snapshot.Max(),
+		Mean:  snapshot.Mean(),
+		P50:   percentiles[0],
+		P95:   percentiles[1],
+		P99:   percentiles[2],
+	}
+}
+
+// MetricRegistry returns the registry holding the producer metrics, shared with sarama
+func (ap *AvroProducer) MetricRegistry() metrics.Registry {
+	ap.metricsOnce.Do(func() {
+		if ap.metricRegistry == nil {
+			ap.metricRegistry = metrics.NewRegistry()
+		}
+	})
+	return ap.metricRegistry
+}
+
+// PayloadStats returns the payload size histograms of a produced topic
+func (ap *AvroProducer) PayloadStats(topic string) PayloadStats {
+	registry := ap.MetricRegistry()
+	topic = ap.config.PhysicalTopic(topic)
+	return PayloadStats{
+		MessageSize:      newSizeStats(registry, "avro-message-size", topic),
+		BatchSize:        newSizeStats(registry, "batch-size", topic),
+		CompressionRatio: newSizeStats(registry, "compression-ratio", topic),
+	}
+}
+
+// MetricRegistry returns the registry holding the consumer metrics, shared with sarama
+func (ac *AvroConsumer) MetricRegistry() metrics.Registry {
+	ac.metricsOnce.Do(func() {
+		if ac.saramaConfig != nil {
+			ac.metricRegistry = ac.saramaConfig.MetricRegistry
+		}
+		if ac.metricRegistry == nil {
+			ac.metricRegistry = metrics.NewRegistry()
+		}
+	})
+	return ac.metricRegistry
+}
+
+// PayloadStats returns the size histogram of the messages consumed from a topic
+func (ac *AvroConsumer) PayloadStats(topic string) PayloadStats {
+	return PayloadStats{MessageSize: newSizeStats(ac.MetricRegistry(), "avro-message-size", topic)}
+}
@@ -0,0 +1,38 @@
+package kafka
+
+import (
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/mocks"
+)
+
+func TestAvroProducer_PayloadStats(t *testing.T) {
+	schemaRegistryTestObject := createSchemaRegistryTestObject(t, "test", 1)
+	defer schemaRegistryTestObject.MockServer.Close()
+	producerMock := mocks.NewSyncProducer(t, nil)
+	producerMock.ExpectSendMessageAndSucceed()
+	producerMock.ExpectSendMessageAndSucceed()
+	avroProducer := &AvroProducer{producer: producerMock, schemaRegistryClient: NewCachedSchemaRegistryClient([]string{schemaRegistryTestObject.MockServer.URL})}
+	defer avroProducer.Close()
+	schema := schemaRegistryTestObject.Codec.Schema()
+	avroProducer.Add("test", schema, []byte("key"), []byte(`{"val":1}`))
+	avroProducer.Add("test", schema, []byte("key"), []byte(`{"val":100000}`))
+	stats := avroProducer.PayloadStats("test")
+	if stats.MessageSize.Count != 2 || stats.MessageSize.Min != 6 || stats.MessageSize.Max != 8 {
+		t.Errorf("Unexpected message size stats: %+v", stats.MessageSize)
+	}
+	if stats.BatchSize.Count != 0 {
+		t.Errorf("Expected no batch size stats without sarama, got %+v", stats.BatchSize)
+	}
+}
+
+func TestAvroConsumer_PayloadStats(t *testing.T) {
+	schemaRegistryTestObject := createSchemaRegistryTestObject(t, "test", 1)
+	defer schemaRegistryTestObject.MockServer.Close()
+	avroConsumer := &AvroConsumer{SchemaRegistryClient: NewCachedSchemaRegistryClient([]string{schemaRegistryTestObject.MockServer.URL})}
+	avroConsumer.ProcessAvroMsg(&sarama.ConsumerMessage{Topic: "test", Value: getTestAvroMsg(t, schemaRegistryTestObject.Codec)})
+	if stats := avroConsumer.PayloadStats("test"); stats.MessageSize.Count != 1 || stats.MessageSize.Max != 6 {
+		t.Errorf("Unexpected message size stats: %+v", stats.MessageSize)
+	}
+}
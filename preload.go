@@ -0,0 +1,69 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+// PreloadSubjects fetches every version of the subjects into the cache, so the first messages of a service
+// do not wait on the registry. Versions that fail to load are skipped, the first error is returned.
+func (client *CachedSchemaRegistryClient) PreloadSubjects(ctx context.Context, subjects ...string) error {
+	var firstErr error
+	fail := func(err error) {
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	for _, subject := range subjects {
+		versions, err := client.SchemaRegistryClient.GetVersionsContext(ctx, subject)
+		if err != nil {
+			fail(fmt.Errorf("could not preload subject %s: %s", subject, err))
+			continue
+		}
+		for _, version := range versions {
+			metadata, err := client.SchemaRegistryClient.getSchemaMetadataInternal(ctx, subject, strconv.Itoa(version))
+			if err != nil {
+				fail(fmt.Errorf("could not preload version %d of subject %s: %s", version, subject, err))
+				continue
+			}
+			if err := client.preload(subject, metadata); err != nil {
+				fail(fmt.Errorf("could not preload version %d of subject %s: %s", version, subject, err))
+			}
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+	return firstErr
+}
+
+// PreloadSchemaIDs fetches the schemas with the given ids into the cache, the first error is returned
+func (client *CachedSchemaRegistryClient) PreloadSchemaIDs(ctx context.Context, ids ...int) error {
+	var firstErr error
+	for _, id := range ids {
+		if _, err := client.GetSchemaContext(ctx, id); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("could not preload schema %d: %s", id, err)
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+	return firstErr
+}
+
+func (client *CachedSchemaRegistryClient) preload(subject string, metadata *SchemaMetadata) error {
+	client.metadataCache.add(metadata.ID, metadata)
+	if metadata.SchemaType != "" && metadata.SchemaType != "AVRO" {
+		return nil
+	}
+	codec, err := goavro.NewCodec(metadata.Schema)
+	if err != nil {
+		return err
+	}
+	client.schemaCache.add(metadata.ID, codec)
+	client.schemaIdCache.add(subject+":"+codec.Schema(), metadata.ID)
+	return nil
+}
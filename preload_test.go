@@ -0,0 +1,40 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCachedSchemaRegistryClient_PreloadSubjects(t *testing.T) {
+	testObject := createSchemaRegistryTestObject(t, "test", 1)
+	client := NewCachedSchemaRegistryClient([]string{testObject.MockServer.URL})
+	if err := client.PreloadSubjects(context.Background(), testObject.Subject); err != nil {
+		t.Fatal(err)
+	}
+	preloaded := testObject.Count
+	if codec, err := client.GetSchema(1); err != nil || codec.Schema() != testObject.Codec.Schema() {
+		t.Errorf("Expected the preloaded schema, got %v", err)
+	}
+	if id, err := client.CreateSubject(testObject.Subject, testObject.Codec); err != nil || id != 1 {
+		t.Errorf("Expected the preloaded id, got %d, %v", id, err)
+	}
+	if testObject.Count != preloaded {
+		t.Errorf("Expected no registry requests after the preload, got %d", testObject.Count-preloaded)
+	}
+
+	if err := client.PreloadSubjects(context.Background(), "unknown-value"); err == nil {
+		t.Errorf("Expected an error for an unknown subject")
+	}
+}
+
+func TestCachedSchemaRegistryClient_PreloadSchemaIDs(t *testing.T) {
+	testObject := createSchemaRegistryTestObject(t, "test", 1)
+	client := NewCachedSchemaRegistryClient([]string{testObject.MockServer.URL})
+	if err := client.PreloadSchemaIDs(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	client.GetSchema(1)
+	if testObject.Count != 1 {
+		t.Errorf("Expected a single registry request, got %d", testObject.Count)
+	}
+}
@@ -0,0 +1,102 @@
+package kafka
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+// RateLimit caps the throughput of a producer or a consumer, zero values are not limited
+type RateLimit struct {
+	MessagesPerSecond float64
+	// BytesPerSecond counts the keys and the encoded values
+	BytesPerSecond float64
+}
+
+// WithRateLimit limits the messages produced or consumed per second with token buckets, allowing bursts of one
+// second. A consumer waits before decoding a message, a producer before sending it.
+func WithRateLimit(limit RateLimit) Option {
+	return func(o *options) {
+		o.rateLimit = newRateLimiter(limit)
+	}
+}
+
+// rateLimiter limits the messages and the bytes, a nil limiter does not wait
+type rateLimiter struct {
+	messages *tokenBucket
+	bytes    *tokenBucket
+}
+
+func newRateLimiter(limit RateLimit) *rateLimiter {
+	return &rateLimiter{messages: newTokenBucket(limit.MessagesPerSecond), bytes: newTokenBucket(limit.BytesPerSecond)}
+}
+
+// wait blocks until a message of size bytes is allowed, or returns the error of the context
+func (l *rateLimiter) wait(ctx context.Context, size int) error {
+	if l == nil {
+		return nil
+	}
+	now := time.Now()
+	delay := l.messages.reserve(now, 1)
+	if bytesDelay := l.bytes.reserve(now, float64(size)); bytesDelay > delay {
+		delay = bytesDelay
+	}
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// tokenBucket refills rate tokens per second up to one second of tokens, a nil bucket is not limited
+type tokenBucket struct {
+	lock   sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64) *tokenBucket {
+	if rate <= 0 {
+		return nil
+	}
+	return &tokenBucket{rate: rate, tokens: rate}
+}
+
+// reserve takes n tokens and returns the time to wait until they are available. Tokens are taken even when
+// missing, so a message larger than the bucket waits for its size instead of blocking forever.
+func (b *tokenBucket) reserve(now time.Time, n float64) time.Duration {
+	if b == nil {
+		return 0
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if !b.last.IsZero() {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.rate {
+			b.tokens = b.rate
+		}
+	}
+	b.last = now
+	b.tokens -= n
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// encodedLength returns the size of an optional key
+func encodedLength(key sarama.Encoder) int {
+	if key == nil {
+		return 0
+	}
+	return key.Length()
+}
@@ -0,0 +1,79 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+func TestTokenBucket(t *testing.T) {
+	bucket := newTokenBucket(10)
+	now := time.Now()
+	for i := 0; i < 10; i++ {
+		if delay := bucket.reserve(now, 1); delay != 0 {
+			t.Fatalf("Expected a burst of 10 tokens, got a delay of %v after %d", delay, i)
+		}
+	}
+	if delay := bucket.reserve(now, 1); delay != 100*time.Millisecond {
+		t.Errorf("Expected to wait for one token, got %v", delay)
+	}
+	if delay := bucket.reserve(now.Add(time.Second), 1); delay != 0 {
+		t.Errorf("Expected the bucket to be refilled, got %v", delay)
+	}
+	if delay := bucket.reserve(now.Add(time.Hour), 30); delay != 2*time.Second {
+		t.Errorf("Expected a large reservation to wait for its size, got %v", delay)
+	}
+	if newTokenBucket(0).reserve(now, 100) != 0 {
+		t.Errorf("Expected a zero rate not to be limited")
+	}
+}
+
+func TestRateLimiter_Wait(t *testing.T) {
+	var limiter *rateLimiter
+	if err := limiter.wait(context.Background(), 100); err != nil {
+		t.Errorf("Expected a nil limiter not to wait, got %v", err)
+	}
+	limiter = newRateLimiter(RateLimit{BytesPerSecond: 10})
+	if err := limiter.wait(context.Background(), 10); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := limiter.wait(ctx, 10); err != context.Canceled {
+		t.Errorf("Expected the wait to be cancelled, got %v", err)
+	}
+}
+
+func TestAvroConsumer_RateLimit(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("test", 0, broker.BrokerID()),
+	})
+	var received int
+	consumer, err := NewAvroConsumer([]string{broker.Addr()}, nil, "test", "group", ConsumerCallbacks{
+		OnDataReceived: func(msg Message) { received++ },
+	}, WithSchemaRegistry(newMemorySchemaRegistry()), WithRateLimit(RateLimit{MessagesPerSecond: 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer consumer.Close()
+	// the second message waits for a token, the ended session stops the wait
+	session := newTestSession(map[string][]int32{"test": {0}})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	session.ctx = ctx
+	for offset := int64(0); offset < 2; offset++ {
+		consumer.handle(session, &sarama.ConsumerMessage{Topic: "test", Offset: offset, Value: []byte{0, 0, 0, 0, 1}})
+	}
+	if received != 1 {
+		t.Errorf("Expected the rate limit to hold back the second message, got %d messages", received)
+	}
+	if _, committed := session.offsets[0]; committed && session.offsets[0] > 1 {
+		t.Errorf("Expected the held back message not to be committed, got offset %d", session.offsets[0])
+	}
+}
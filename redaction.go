@@ -0,0 +1,99 @@
+package kafka
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// RedactionAction defines what happens to a redacted field
+type RedactionAction int
+
+const (
+	// RedactDrop removes the field
+	RedactDrop RedactionAction = iota
+	// RedactHash replaces the field by the hex sha256 of its JSON value, keeping it joinable
+	RedactHash
+	// RedactMask replaces the field by a fixed mask
+	RedactMask
+)
+
+const redactionMask = "****"
+
+var redactionActions = map[string]RedactionAction{
+	"drop": RedactDrop,
+	"hash": RedactHash,
+	"mask": RedactMask,
+}
+
+// RedactionProfile maps dot separated field paths (e.g. "user.email") to the action applied at decode time.
+// Union wrappers and arrays on the path are traversed transparently.
+type RedactionProfile map[string]RedactionAction
+
+// ParseRedactionProfile builds a profile from configuration, mapping field paths to "drop", "hash" or "mask"
+func ParseRedactionProfile(config map[string]string) (RedactionProfile, error) {
+	profile := make(RedactionProfile, len(config))
+	for path, name := range config {
+		action, ok := redactionActions[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown redaction action %q for field %s", name, path)
+		}
+		profile[path] = action
+	}
+	return profile, nil
+}
+
+// Apply redacts the textual avro data and returns the result
+func (profile RedactionProfile) Apply(textual []byte) ([]byte, error) {
+	if len(profile) == 0 {
+		return textual, nil
+	}
+	var data interface{}
+	if err := json.Unmarshal(textual, &data); err != nil {
+		return nil, err
+	}
+	for path, action := range profile {
+		redactPath(data, strings.Split(path, "."), action)
+	}
+	return json.Marshal(data)
+}
+
+func redactPath(node interface{}, path []string, action RedactionAction) {
+	switch n := node.(type) {
+	case []interface{}:
+		for _, item := range n {
+			redactPath(item, path, action)
+		}
+	case map[string]interface{}:
+		value, ok := n[path[0]]
+		if !ok {
+			// descend through a union wrapper like {"com.example.User": {...}}
+			if len(n) == 1 {
+				for _, wrapped := range n {
+					redactPath(wrapped, path, action)
+				}
+			}
+			return
+		}
+		if len(path) > 1 {
+			redactPath(value, path[1:], action)
+			return
+		}
+		switch action {
+		case RedactDrop:
+			delete(n, path[0])
+		case RedactHash:
+			if value != nil {
+				encoded, _ := json.Marshal(value)
+				sum := sha256.Sum256(encoded)
+				n[path[0]] = hex.EncodeToString(sum[:])
+			}
+		case RedactMask:
+			if value != nil {
+				n[path[0]] = redactionMask
+			}
+		}
+	}
+}
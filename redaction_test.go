@@ -0,0 +1,49 @@
+package kafka
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseRedactionProfile(t *testing.T) {
+	profile, err := ParseRedactionProfile(map[string]string{"email": "hash", "ssn": "MASK", "notes": "drop"})
+	if err != nil {
+		t.Fatalf("Error parsing profile: %v", err)
+	}
+	expected := RedactionProfile{"email": RedactHash, "ssn": RedactMask, "notes": RedactDrop}
+	if !reflect.DeepEqual(profile, expected) {
+		t.Errorf("Expected %v, got %v", expected, profile)
+	}
+	if _, err := ParseRedactionProfile(map[string]string{"email": "encrypt"}); err == nil {
+		t.Errorf("Expected error for unknown action")
+	}
+}
+
+func TestRedactionProfile_Apply(t *testing.T) {
+	profile := RedactionProfile{
+		"user.email":    RedactHash,
+		"user.ssn":      RedactMask,
+		"items.secret":  RedactDrop,
+		"missing.field": RedactDrop,
+	}
+	textual := `{"user": {"com.example.User": {"email": "a@b.c", "ssn": "123", "name": "x"}}, "items": [{"secret": 1, "id": 1}, {"secret": 2, "id": 2}]}`
+	redacted, err := profile.Apply([]byte(textual))
+	if err != nil {
+		t.Fatalf("Error applying profile: %v", err)
+	}
+	var result map[string]interface{}
+	json.Unmarshal(redacted, &result)
+	user := result["user"].(map[string]interface{})["com.example.User"].(map[string]interface{})
+	if user["ssn"] != redactionMask || user["name"] != "x" {
+		t.Errorf("Unexpected user after redaction: %v", user)
+	}
+	if email, _ := user["email"].(string); len(email) != 64 {
+		t.Errorf("Expected hashed email, got %v", user["email"])
+	}
+	for _, item := range result["items"].([]interface{}) {
+		if _, found := item.(map[string]interface{})["secret"]; found {
+			t.Errorf("Expected secret to be dropped: %v", item)
+		}
+	}
+}
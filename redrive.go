@@ -0,0 +1,243 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/Shopify/sarama"
+)
+
+// DeadLetter is a message of a dead-letter topic with the origin recorded by the dead-letter queue of a consumer
+type DeadLetter struct {
+	// Topic, Partition and Offset locate the original message, Topic is where the message is republished to
+	Topic     string
+	Partition int32
+	Offset    int64
+	// Error is the reason the message was dead-lettered
+	Error string
+	// Key, Value and Headers are the original message, the headers without the dead-letter headers
+	Key     []byte
+	Value   []byte
+	Headers []sarama.RecordHeader
+	// Message is the message read from the dead-letter topic
+	Message *sarama.ConsumerMessage
+}
+
+// ParseDeadLetter returns the dead letter of a message of a dead-letter topic, an error if its headers are missing
+func ParseDeadLetter(m *sarama.ConsumerMessage) (*DeadLetter, error) {
+	letter := &DeadLetter{Key: m.Key, Value: m.Value, Message: m}
+	found := make(map[string]bool)
+	for _, header := range m.Headers {
+		if header == nil {
+			continue
+		}
+		var err error
+		key := string(header.Key)
+		switch key {
+		case DeadLetterHeaderError:
+			letter.Error = string(header.Value)
+		case DeadLetterHeaderTopic:
+			letter.Topic = string(header.Value)
+		case DeadLetterHeaderPartition:
+			var partition int64
+			partition, err = strconv.ParseInt(string(header.Value), 10, 32)
+			letter.Partition = int32(partition)
+		case DeadLetterHeaderOffset:
+			letter.Offset, err = strconv.ParseInt(string(header.Value), 10, 64)
+		default:
+			letter.Headers = append(letter.Headers, *header)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid header %s of dead letter %s/%d@%d: %v", key, m.Topic, m.Partition, m.Offset, err)
+		}
+		found[key] = true
+	}
+	for _, key := range []string{DeadLetterHeaderTopic, DeadLetterHeaderPartition, DeadLetterHeaderOffset} {
+		if !found[key] {
+			return nil, fmt.Errorf("missing header %s of dead letter %s/%d@%d", key, m.Topic, m.Partition, m.Offset)
+		}
+	}
+	return letter, nil
+}
+
+// RedriveConfig selects and transforms the dead letters republished by a Redrive
+type RedriveConfig struct {
+	// Select chooses the dead letters to republish, all are republished when nil
+	Select func(letter *DeadLetter) bool
+	// Transform may change the topic, key, value or headers of a selected dead letter before it is republished
+	Transform func(letter *DeadLetter) error
+	// DryRun selects and transforms the dead letters without republishing them
+	DryRun bool
+}
+
+// RedriveResult counts the dead letters of a Redrive run
+type RedriveResult struct {
+	Read        int
+	Selected    int
+	Republished int
+}
+
+// Redrive reads a dead-letter topic from its oldest to its newest offset and republishes the selected messages to
+// their original topic. Offsets are not committed, running it again reads the messages again.
+type Redrive struct {
+	Consumer sarama.Consumer
+	Producer sarama.SyncProducer
+	client   sarama.Client
+	topic    string
+	config   RedriveConfig
+	// ranges returns the offsets of the partitions to read, client.GetOffset when nil
+	ranges func() (map[int32]offsetRange, error)
+}
+
+// offsetRange is the offsets of a partition from start to end, end excluded
+type offsetRange struct {
+	start int64
+	end   int64
+}
+
+// NewRedrive creates a redrive of the dead-letter topic, the physical topic as set by the dead-letter queue
+func NewRedrive(kafkaServers []string, topic string, config RedriveConfig, opts ...Option) (*Redrive, error) {
+	client, err := sarama.NewClient(kafkaServers, applyOptions(defaultAvroProducerConfig(), opts).saramaConfig)
+	if err != nil {
+		return nil, err
+	}
+	consumer, err := sarama.NewConsumerFromClient(client)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	producer, err := sarama.NewSyncProducerFromClient(client)
+	if err != nil {
+		consumer.Close()
+		client.Close()
+		return nil, err
+	}
+	return &Redrive{Consumer: consumer, Producer: producer, client: client, topic: topic, config: config}, nil
+}
+
+// Inspect passes the dead letters to fn in the order of their partitions and offsets, until fn returns an error
+func (r *Redrive) Inspect(ctx context.Context, fn func(letter *DeadLetter) error) error {
+	ranges, err := r.offsetRanges()
+	if err != nil {
+		return err
+	}
+	partitions := make([]int32, 0, len(ranges))
+	for partition := range ranges {
+		partitions = append(partitions, partition)
+	}
+	sort.Slice(partitions, func(i, j int) bool { return partitions[i] < partitions[j] })
+	for _, partition := range partitions {
+		if err := r.inspectPartition(ctx, partition, ranges[partition], fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Redrive) inspectPartition(ctx context.Context, partition int32, offsets offsetRange,
+	fn func(letter *DeadLetter) error) error {
+	if offsets.start >= offsets.end {
+		return nil
+	}
+	partitionConsumer, err := r.Consumer.ConsumePartition(r.topic, partition, offsets.start)
+	if err != nil {
+		return fmt.Errorf("could not consume %s/%d from offset %d: %v", r.topic, partition, offsets.start, err)
+	}
+	defer partitionConsumer.Close()
+	for {
+		select {
+		case m, ok := <-partitionConsumer.Messages():
+			if !ok {
+				return fmt.Errorf("%s/%d closed before offset %d", r.topic, partition, offsets.end)
+			}
+			letter, err := ParseDeadLetter(m)
+			if err != nil {
+				return err
+			}
+			if err := fn(letter); err != nil {
+				return err
+			}
+			if m.Offset+1 >= offsets.end {
+				return nil
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Run republishes the selected dead letters after transforming them, or only counts them in a dry run. It stops on
+// the first dead letter that could not be transformed or republished.
+func (r *Redrive) Run(ctx context.Context) (RedriveResult, error) {
+	var result RedriveResult
+	err := r.Inspect(ctx, func(letter *DeadLetter) error {
+		result.Read++
+		if r.config.Select != nil && !r.config.Select(letter) {
+			return nil
+		}
+		result.Selected++
+		if r.config.Transform != nil {
+			if err := r.config.Transform(letter); err != nil {
+				return fmt.Errorf("could not transform dead letter %s/%d@%d: %v", letter.Topic, letter.Partition,
+					letter.Offset, err)
+			}
+		}
+		if r.config.DryRun {
+			return nil
+		}
+		msg := &sarama.ProducerMessage{Topic: letter.Topic, Headers: letter.Headers}
+		if letter.Key != nil {
+			msg.Key = sarama.ByteEncoder(letter.Key)
+		}
+		if letter.Value != nil {
+			msg.Value = sarama.ByteEncoder(letter.Value)
+		}
+		if _, _, err := r.Producer.SendMessage(msg); err != nil {
+			return fmt.Errorf("could not republish dead letter %s/%d@%d: %v", letter.Topic, letter.Partition,
+				letter.Offset, err)
+		}
+		result.Republished++
+		return nil
+	})
+	return result, err
+}
+
+func (r *Redrive) offsetRanges() (map[int32]offsetRange, error) {
+	if r.ranges != nil {
+		return r.ranges()
+	}
+	partitions, err := r.client.Partitions(r.topic)
+	if err != nil {
+		return nil, err
+	}
+	ranges := make(map[int32]offsetRange, len(partitions))
+	for _, partition := range partitions {
+		start, err := r.client.GetOffset(r.topic, partition, sarama.OffsetOldest)
+		if err != nil {
+			return nil, fmt.Errorf("could not list the offsets of %s/%d: %v", r.topic, partition, err)
+		}
+		end, err := r.client.GetOffset(r.topic, partition, sarama.OffsetNewest)
+		if err != nil {
+			return nil, fmt.Errorf("could not list the offsets of %s/%d: %v", r.topic, partition, err)
+		}
+		ranges[partition] = offsetRange{start, end}
+	}
+	return ranges, nil
+}
+
+// Close closes the producer, the consumer and the client created by NewRedrive
+func (r *Redrive) Close() error {
+	err := r.Producer.Close()
+	if consumerErr := r.Consumer.Close(); err == nil {
+		err = consumerErr
+	}
+	if r.client != nil {
+		if clientErr := r.client.Close(); err == nil {
+			err = clientErr
+		}
+	}
+	return err
+}
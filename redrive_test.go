@@ -0,0 +1,87 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/mocks"
+)
+
+// deadLettered returns the message dead-lettered by a consumer as it is read from the dead-letter topic
+func deadLettered(t *testing.T, m *sarama.ConsumerMessage) *sarama.ConsumerMessage {
+	producer := &testSyncProducer{}
+	consumer := &AvroConsumer{deadLetter: &DeadLetterConfig{Producer: producer}}
+	if err := consumer.deadLetterMsg(m, fmt.Errorf("unknown schema")); err != nil {
+		t.Fatal(err)
+	}
+	sent := producer.sent[0]
+	dead := &sarama.ConsumerMessage{Topic: sent.Topic, Key: m.Key, Value: m.Value}
+	for i := range sent.Headers {
+		dead.Headers = append(dead.Headers, &sent.Headers[i])
+	}
+	return dead
+}
+
+func TestParseDeadLetter(t *testing.T) {
+	letter, err := ParseDeadLetter(deadLettered(t, &sarama.ConsumerMessage{Topic: "test", Partition: 2, Offset: 42,
+		Key: []byte("a"), Value: []byte{1}, Headers: []*sarama.RecordHeader{{Key: []byte("trace"), Value: []byte("1")}}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if letter.Topic != "test" || letter.Partition != 2 || letter.Offset != 42 || letter.Error != "unknown schema" ||
+		string(letter.Key) != "a" || len(letter.Headers) != 1 || string(letter.Headers[0].Key) != "trace" {
+		t.Errorf("Unexpected dead letter %+v", letter)
+	}
+	if _, err := ParseDeadLetter(&sarama.ConsumerMessage{Topic: "test-dlq", Value: []byte{1}}); err == nil {
+		t.Error("Expected an error parsing a message without dead-letter headers")
+	}
+}
+
+func TestRedrive_Run(t *testing.T) {
+	for _, dryRun := range []bool{false, true} {
+		consumerMock := mocks.NewConsumer(t, nil)
+		partition := consumerMock.ExpectConsumePartition("test-dlq", 0, 1)
+		for i, value := range []string{"a", "b", "c"} {
+			partition.YieldMessage(deadLettered(t, &sarama.ConsumerMessage{Topic: "test", Offset: int64(i),
+				Value: []byte(value)}))
+		}
+		producer := &testSyncProducer{}
+		redrive := &Redrive{Consumer: consumerMock, Producer: producer, topic: "test-dlq",
+			ranges: func() (map[int32]offsetRange, error) {
+				return map[int32]offsetRange{0: {1, 4}, 1: {5, 5}}, nil
+			},
+			config: RedriveConfig{
+				Select: func(letter *DeadLetter) bool { return letter.Offset != 1 },
+				Transform: func(letter *DeadLetter) error {
+					letter.Value = append(letter.Value, '!')
+					return nil
+				},
+				DryRun: dryRun,
+			}}
+		result, err := redrive.Run(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Read != 3 || result.Selected != 2 {
+			t.Errorf("Unexpected result %+v", result)
+		}
+		if dryRun {
+			if result.Republished != 0 || len(producer.sent) != 0 {
+				t.Errorf("Expected nothing to be republished in a dry run, got %+v", result)
+			}
+			continue
+		}
+		if result.Republished != 2 || len(producer.sent) != 2 {
+			t.Fatalf("Expected 2 dead letters to be republished, got %+v", result)
+		}
+		value, _ := producer.sent[1].Value.Encode()
+		if producer.sent[1].Topic != "test" || string(value) != "c!" || len(producer.sent[1].Headers) != 0 {
+			t.Errorf("Unexpected republished message %+v", producer.sent[1])
+		}
+		if err := redrive.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package kafka
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"time"
+)
+
+type registryCacheEntry struct {
+	key    interface{}
+	value  interface{}
+	expiry time.Time
+}
+
+// registryCache is a LRU of registry responses, unbounded and without expiry unless limits are set
+type registryCache struct {
+	maxEntries int
+	ttl        time.Duration
+	entries    map[interface{}]*list.Element
+	order      *list.List
+	lock       sync.Mutex
+	// name and metrics report the lookups when metrics are set
+	name    string
+	metrics Metrics
+}
+
+func newRegistryCache() *registryCache {
+	return &registryCache{
+		entries: make(map[interface{}]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// SetCacheLimits bounds every cache of the client to maxEntries, evicting the least recently used entries,
+// and expires entries ttl after they were fetched. Zero disables the limit.
+func (client *CachedSchemaRegistryClient) SetCacheLimits(maxEntries int, ttl time.Duration) {
+	for _, cache := range client.caches() {
+		cache.setLimits(maxEntries, ttl)
+	}
+}
+
+// InvalidateSchema removes the codec and the schema object with the given id from the cache
+func (client *CachedSchemaRegistryClient) InvalidateSchema(id int) {
+	client.schemaCache.remove(id)
+	client.metadataCache.remove(id)
+	client.misses.forget(idMissKey(id))
+}
+
+// InvalidateSubject removes the registrations and lookups of the subject from the cache,
+// so they are fetched again after the subject was changed
+func (client *CachedSchemaRegistryClient) InvalidateSubject(subject string) {
+	prefix := subject + ":"
+	bySubject := func(key interface{}) bool {
+		return strings.HasPrefix(key.(string), prefix)
+	}
+	client.schemaIdCache.removeIf(bySubject)
+	client.lookupCache.removeIf(bySubject)
+	client.metadataCache.removeIf(func(key interface{}) bool {
+		version, ok := key.(SubjectVersion)
+		return ok && version.Subject == subject
+	})
+	client.misses.forgetSubject(subject)
+}
+
+// Flush empties every cache of the client
+func (client *CachedSchemaRegistryClient) Flush() {
+	for _, cache := range client.caches() {
+		cache.flush()
+	}
+	client.misses.flush()
+}
+
+func (client *CachedSchemaRegistryClient) caches() []*registryCache {
+	return []*registryCache{client.schemaCache, client.schemaIdCache, client.metadataCache, client.lookupCache}
+}
+
+func (c *registryCache) get(key interface{}) (interface{}, bool) {
+	value, found := c.lookup(key)
+	if c.metrics != nil {
+		c.metrics.CacheLookup(c.name, found)
+	}
+	return value, found
+}
+
+func (c *registryCache) lookup(key interface{}) (interface{}, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	element, found := c.entries[key]
+	if !found {
+		return nil, false
+	}
+	entry := element.Value.(*registryCacheEntry)
+	if c.ttl > 0 && time.Now().After(entry.expiry) {
+		c.removeElement(element)
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return entry.value, true
+}
+
+func (c *registryCache) add(key interface{}, value interface{}) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	entry := &registryCacheEntry{key: key, value: value, expiry: time.Now().Add(c.ttl)}
+	if element, found := c.entries[key]; found {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	c.evict()
+}
+
+func (c *registryCache) remove(key interface{}) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if element, found := c.entries[key]; found {
+		c.removeElement(element)
+	}
+}
+
+func (c *registryCache) removeIf(match func(key interface{}) bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for key, element := range c.entries {
+		if match(key) {
+			c.removeElement(element)
+		}
+	}
+}
+
+func (c *registryCache) flush() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.entries = make(map[interface{}]*list.Element)
+	c.order.Init()
+}
+
+func (c *registryCache) setLimits(maxEntries int, ttl time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.maxEntries, c.ttl = maxEntries, ttl
+	c.evict()
+}
+
+func (c *registryCache) evict() {
+	for c.maxEntries > 0 && c.order.Len() > c.maxEntries {
+		c.removeElement(c.order.Back())
+	}
+}
+
+func (c *registryCache) removeElement(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*registryCacheEntry).key)
+}
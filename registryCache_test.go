@@ -0,0 +1,58 @@
+package kafka
+
+import (
+	"testing"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+func TestRegistryCache_Limits(t *testing.T) {
+	cache := newRegistryCache()
+	cache.setLimits(2, 50*time.Millisecond)
+	cache.add(1, "a")
+	cache.add(2, "b")
+	cache.get(1)
+	cache.add(3, "c")
+	if _, found := cache.get(2); found {
+		t.Errorf("Expected the least recently used entry to be evicted")
+	}
+	if value, found := cache.get(1); !found || value != "a" {
+		t.Errorf("Expected the recently used entry to be kept, got %v", value)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if _, found := cache.get(3); found {
+		t.Errorf("Expected the entry to expire")
+	}
+}
+
+func TestCachedSchemaRegistryClient_Invalidate(t *testing.T) {
+	testObject := createSchemaRegistryTestObject(t, "test", 1)
+	client := NewCachedSchemaRegistryClient([]string{testObject.MockServer.URL})
+	client.GetSchema(1)
+	client.GetSchema(1)
+	if testObject.Count != 1 {
+		t.Errorf("Expected the schema to be cached, got %d requests", testObject.Count)
+	}
+	client.InvalidateSchema(1)
+	client.GetSchema(1)
+	if testObject.Count != 2 {
+		t.Errorf("Expected the invalidated schema to be fetched again, got %d requests", testObject.Count)
+	}
+
+	codec, _ := goavro.NewCodec(`"string"`)
+	client.CreateSubject(testObject.Subject, codec)
+	client.CreateSubject(testObject.Subject, codec)
+	client.InvalidateSubject(testObject.Subject)
+	client.CreateSubject(testObject.Subject, codec)
+	if testObject.Count != 4 {
+		t.Errorf("Expected the invalidated subject to be registered again, got %d requests", testObject.Count)
+	}
+
+	client.Flush()
+	client.GetSchema(1)
+	client.CreateSubject(testObject.Subject, codec)
+	if testObject.Count != 6 {
+		t.Errorf("Expected the flushed caches to be fetched again, got %d requests", testObject.Count)
+	}
+}
@@ -0,0 +1,154 @@
+package kafka
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+// Headers added to messages republished to retry topics
+const (
+	RetryHeaderTopic   = "retry.topic"
+	RetryHeaderAttempt = "retry.attempt"
+	RetryHeaderDue     = "retry.due"
+	RetryHeaderError   = "retry.error"
+)
+
+// RetryConfig enables tiered retry topics for messages that OnProcess fails to process. Attempt n is republished
+// to "<topic>-retry-<delay>" with delay = Backoff * 2^n and consumed again once the delay passed, after MaxRetries
+// attempts the message goes to the dead-letter queue if one is set. Retry topics must exist or be auto-created.
+type RetryConfig struct {
+	// Producer republishes the messages, see NewDeadLetterProducer
+	Producer sarama.SyncProducer
+	// Policy is used for topics without a Retry policy in the consumer config
+	Policy RetryPolicy
+}
+
+// EnableRetryTopics configures the retries and subscribes to the retry topics of the consumed topics,
+// it must be called before Consume
+func (ac *AvroConsumer) EnableRetryTopics(config RetryConfig) {
+	ac.retry = &config
+	if ac.logicalTopics == nil {
+		ac.logicalTopics = make(map[string]string)
+	}
+	topics := ac.topics
+	for _, topic := range topics {
+		logical := ac.logicalTopic(topic)
+		policy := ac.retryPolicy(logical)
+		for attempt := 0; attempt < policy.MaxRetries; attempt++ {
+			retryTopic := ac.config.PhysicalTopic(retryTopicName(logical, retryDelay(policy, attempt)))
+			ac.logicalTopics[retryTopic] = logical
+			ac.topics = append(ac.topics, retryTopic)
+		}
+	}
+}
+
+func (ac *AvroConsumer) retryPolicy(topic string) RetryPolicy {
+	if policy := ac.config.ForTopic(topic).Retry; policy != nil {
+		return *policy
+	}
+	return ac.retry.Policy
+}
+
+func retryDelay(policy RetryPolicy, attempt int) time.Duration {
+	return policy.Backoff << uint(attempt)
+}
+
+// retryTopicName returns the retry topic of a delay, e.g. "orders-retry-5s" or "orders-retry-1m"
+func retryTopicName(topic string, delay time.Duration) string {
+	var suffix string
+	switch {
+	case delay%time.Hour == 0:
+		suffix = strconv.FormatInt(int64(delay/time.Hour), 10) + "h"
+	case delay%time.Minute == 0:
+		suffix = strconv.FormatInt(int64(delay/time.Minute), 10) + "m"
+	case delay%time.Second == 0:
+		suffix = strconv.FormatInt(int64(delay/time.Second), 10) + "s"
+	default:
+		suffix = strconv.FormatInt(int64(delay/time.Millisecond), 10) + "ms"
+	}
+	return topic + "-retry-" + suffix
+}
+
+// processFailed retries or dead-letters a message OnProcess failed on, it returns an error if the consumer must halt
+func (ac *AvroConsumer) processFailed(m *sarama.ConsumerMessage, cause error) error {
+	if ac.retry != nil {
+		logical := ac.logicalTopic(m.Topic)
+		policy := ac.retryPolicy(logical)
+		attempt := retryAttempt(m)
+		if attempt < policy.MaxRetries {
+			return ac.republish(m, logical, policy, attempt, cause)
+		}
+	}
+	if ac.deadLetter != nil {
+		return ac.deadLetterMsg(m, cause)
+	}
+	if ac.callbacks.OnError != nil {
+		ac.callbacks.OnError(cause)
+	}
+	return nil
+}
+
+func (ac *AvroConsumer) republish(m *sarama.ConsumerMessage, topic string, policy RetryPolicy, attempt int, cause error) error {
+	delay := retryDelay(policy, attempt)
+	headers := make([]sarama.RecordHeader, 0, len(m.Headers)+4)
+	for _, header := range m.Headers {
+		switch string(header.Key) {
+		case RetryHeaderTopic, RetryHeaderAttempt, RetryHeaderDue, RetryHeaderError:
+		default:
+			headers = append(headers, *header)
+		}
+	}
+	due := time.Now().Add(delay).UnixNano() / int64(time.Millisecond)
+	headers = append(headers,
+		sarama.RecordHeader{Key: []byte(RetryHeaderTopic), Value: []byte(topic)},
+		sarama.RecordHeader{Key: []byte(RetryHeaderAttempt), Value: []byte(strconv.Itoa(attempt + 1))},
+		sarama.RecordHeader{Key: []byte(RetryHeaderDue), Value: []byte(strconv.FormatInt(due, 10))},
+		sarama.RecordHeader{Key: []byte(RetryHeaderError), Value: []byte(cause.Error())},
+	)
+	msg := &sarama.ProducerMessage{
+		Topic:   ac.config.PhysicalTopic(retryTopicName(topic, delay)),
+		Key:     sarama.ByteEncoder(m.Key),
+		Value:   sarama.ByteEncoder(m.Value),
+		Headers: headers,
+	}
+	if _, _, err := ac.retry.Producer.SendMessage(msg); err != nil {
+		return &DeadLetterError{Topic: m.Topic, Partition: m.Partition, Offset: m.Offset, Err: err}
+	}
+	return nil
+}
+
+func retryHeader(m *sarama.ConsumerMessage, key string) string {
+	for _, header := range m.Headers {
+		if string(header.Key) == key {
+			return string(header.Value)
+		}
+	}
+	return ""
+}
+
+func retryAttempt(m *sarama.ConsumerMessage) int {
+	attempt, _ := strconv.Atoi(retryHeader(m, RetryHeaderAttempt))
+	return attempt
+}
+
+// waitForRetry delays a retried message until it is due, it returns false if the session ended first
+func waitForRetry(session sarama.ConsumerGroupSession, m *sarama.ConsumerMessage) bool {
+	due, err := strconv.ParseInt(retryHeader(m, RetryHeaderDue), 10, 64)
+	if err != nil {
+		return true
+	}
+	wait := time.Until(time.Unix(0, due*int64(time.Millisecond)))
+	if wait <= 0 {
+		return true
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-session.Context().Done():
+		return false
+	}
+}
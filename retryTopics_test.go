@@ -0,0 +1,90 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+func TestRetryTopicName(t *testing.T) {
+	names := map[time.Duration]string{
+		5 * time.Second:        "orders-retry-5s",
+		time.Minute:            "orders-retry-1m",
+		2 * time.Hour:          "orders-retry-2h",
+		500 * time.Millisecond: "orders-retry-500ms",
+	}
+	for delay, expected := range names {
+		if name := retryTopicName("orders", delay); name != expected {
+			t.Errorf("Expected %s, got %s", expected, name)
+		}
+	}
+}
+
+func TestAvroConsumer_EnableRetryTopics(t *testing.T) {
+	consumer := &AvroConsumer{topics: []string{"orders"}}
+	consumer.EnableRetryTopics(RetryConfig{Policy: RetryPolicy{MaxRetries: 2, Backoff: 5 * time.Second}})
+	expected := []string{"orders", "orders-retry-5s", "orders-retry-10s"}
+	if len(consumer.topics) != 3 || consumer.topics[1] != expected[1] || consumer.topics[2] != expected[2] {
+		t.Errorf("Expected topics %v, got %v", expected, consumer.topics)
+	}
+}
+
+func TestAvroConsumer_ProcessFailed(t *testing.T) {
+	retries := &testSyncProducer{}
+	deadLetters := &testSyncProducer{}
+	consumer := &AvroConsumer{topics: []string{"orders"}}
+	consumer.EnableRetryTopics(RetryConfig{Producer: retries, Policy: RetryPolicy{MaxRetries: 1, Backoff: time.Second}})
+	consumer.SetDeadLetterQueue(DeadLetterConfig{Producer: deadLetters})
+
+	m := &sarama.ConsumerMessage{Topic: "orders", Value: []byte("value"),
+		Headers: []*sarama.RecordHeader{{Key: []byte("trace"), Value: []byte("abc")}}}
+	if err := consumer.processFailed(m, errors.New("failed")); err != nil {
+		t.Fatalf("Error retrying message: %v", err)
+	}
+	if len(retries.sent) != 1 || retries.sent[0].Topic != "orders-retry-1s" {
+		t.Fatalf("Expected message to be republished to the first retry topic, got %v", retries.sent)
+	}
+	retried := retries.sent[0]
+	if headerValue(retried.Headers, RetryHeaderAttempt) != "1" || headerValue(retried.Headers, RetryHeaderTopic) != "orders" ||
+		headerValue(retried.Headers, "trace") != "abc" {
+		t.Errorf("Unexpected retry headers: %v", retried.Headers)
+	}
+
+	// the retried message fails again and exhausts its retries
+	m = &sarama.ConsumerMessage{Topic: "orders-retry-1s", Value: []byte("value")}
+	for _, header := range retried.Headers {
+		header := header
+		m.Headers = append(m.Headers, &header)
+	}
+	if err := consumer.processFailed(m, errors.New("failed")); err != nil {
+		t.Fatalf("Error dead-lettering message: %v", err)
+	}
+	if len(retries.sent) != 1 || len(deadLetters.sent) != 1 || deadLetters.sent[0].Topic != "orders-dlq" {
+		t.Errorf("Expected exhausted message to be dead-lettered to the topic queue, got %v", deadLetters.sent)
+	}
+}
+
+func TestWaitForRetry(t *testing.T) {
+	session := newTestSession(nil)
+	due := func(d time.Duration) *sarama.ConsumerMessage {
+		millis := time.Now().Add(d).UnixNano() / int64(time.Millisecond)
+		return &sarama.ConsumerMessage{Headers: []*sarama.RecordHeader{{Key: []byte(RetryHeaderDue), Value: []byte(strconv.FormatInt(millis, 10))}}}
+	}
+	if !waitForRetry(session, &sarama.ConsumerMessage{}) || !waitForRetry(session, due(-time.Second)) {
+		t.Errorf("Expected messages without or past due time not to wait")
+	}
+	start := time.Now()
+	if !waitForRetry(session, due(50*time.Millisecond)) || time.Since(start) < 40*time.Millisecond {
+		t.Errorf("Expected message to wait until it is due")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	session.ctx = ctx
+	if waitForRetry(session, due(time.Hour)) {
+		t.Errorf("Expected waiting to stop with the session")
+	}
+}
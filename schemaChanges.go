@@ -0,0 +1,65 @@
+package kafka
+
+// SchemaChange describes a change of the schema id a producer uses for a topic
+type SchemaChange struct {
+	Topic      string
+	Subject    string
+	OldID      int
+	OldVersion int
+	NewID      int
+	NewVersion int
+}
+
+// ProducerCallbacks are invoked by the AvroProducer
+type ProducerCallbacks struct {
+	// OnSchemaChange is called whenever the schema id used for a topic changes, including the first one picked up,
+	// so operators can audit when running producers started using a new schema
+	OnSchemaChange func(change SchemaChange)
+}
+
+type schemaVersion struct {
+	id      int
+	version int
+}
+
+// SetCallbacks sets the callbacks of the producer, it should be called before producing
+func (ap *AvroProducer) SetCallbacks(callbacks ProducerCallbacks) {
+	ap.callbacks = callbacks
+}
+
+// trackSchemaId reports a schema change if the id differs from the id last used for the topic
+func (ap *AvroProducer) trackSchemaId(topic string, subject string, schema string, references []SchemaReference, schemaId int) {
+	if ap.callbacks.OnSchemaChange == nil {
+		return
+	}
+	ap.schemaIdsLock.Lock()
+	if ap.schemaIds == nil {
+		ap.schemaIds = make(map[string]schemaVersion)
+	}
+	previous := ap.schemaIds[topic]
+	if previous.id == schemaId {
+		ap.schemaIdsLock.Unlock()
+		return
+	}
+	current := schemaVersion{id: schemaId}
+	ap.schemaIds[topic] = current
+	ap.schemaIdsLock.Unlock()
+
+	// the version is only looked up on changes, a failed lookup reports version 0
+	if metadata, err := ap.schemaRegistryClient.LookupSchema(subject, schema, references); err == nil {
+		current.version = metadata.Version
+		ap.schemaIdsLock.Lock()
+		if ap.schemaIds[topic].id == schemaId {
+			ap.schemaIds[topic] = current
+		}
+		ap.schemaIdsLock.Unlock()
+	}
+	ap.callbacks.OnSchemaChange(SchemaChange{
+		Topic:      topic,
+		Subject:    subject,
+		OldID:      previous.id,
+		OldVersion: previous.version,
+		NewID:      current.id,
+		NewVersion: current.version,
+	})
+}
@@ -0,0 +1,39 @@
+package kafka
+
+import (
+	"testing"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+func TestAvroProducer_OnSchemaChange(t *testing.T) {
+	first := createSchemaRegistryTestObject(t, "test", 1)
+	defer first.MockServer.Close()
+	second := createSchemaRegistryTestObject(t, "test", 2)
+	defer second.MockServer.Close()
+	var changes []SchemaChange
+	avroProducer := &AvroProducer{schemaRegistryClient: NewCachedSchemaRegistryClient([]string{first.MockServer.URL})}
+	avroProducer.SetCallbacks(ProducerCallbacks{OnSchemaChange: func(change SchemaChange) {
+		changes = append(changes, change)
+	}})
+	for i := 0; i < 2; i++ {
+		if _, err := avroProducer.GetSchemaId("test", first.Codec); err != nil {
+			t.Fatalf("Error getting schema id: %v", err)
+		}
+	}
+	// a refreshed registry client returns the id of a newly registered version
+	avroProducer.schemaRegistryClient = NewCachedSchemaRegistryClient([]string{second.MockServer.URL})
+	codec, _ := goavro.NewCodec(second.Codec.Schema())
+	if _, err := avroProducer.GetSchemaId("test", codec); err != nil {
+		t.Fatalf("Error getting schema id: %v", err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("Expected 2 schema changes, got %v", changes)
+	}
+	if changes[0].OldID != 0 || changes[0].NewID != 1 || changes[1].OldID != 1 || changes[1].NewID != 2 {
+		t.Errorf("Unexpected schema changes: %+v", changes)
+	}
+	if changes[1].Subject != "test-value" {
+		t.Errorf("Unexpected subject %s", changes[1].Subject)
+	}
+}
@@ -0,0 +1,167 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// SchemaBundle is the portable JSON form of the subjects of a registry written by ExportAllSubjects
+type SchemaBundle struct {
+	Subjects []ExportedSubject `json:"subjects"`
+}
+
+// ExportedSubject holds the versions of a subject in ascending order
+type ExportedSubject struct {
+	Subject  string           `json:"subject"`
+	Versions []SchemaMetadata `json:"versions"`
+}
+
+// ExportAllSubjects writes the versions of all subjects as a JSON SchemaBundle, soft deleted versions are not exported
+func (client *SchemaRegistryClient) ExportAllSubjects(ctx context.Context, w io.Writer) error {
+	subjects, err := client.GetSubjectsContext(ctx)
+	if err != nil {
+		return err
+	}
+	bundle := SchemaBundle{Subjects: make([]ExportedSubject, 0, len(subjects))}
+	for _, subject := range subjects {
+		versions, err := client.GetVersionsContext(ctx, subject)
+		if err != nil {
+			return fmt.Errorf("could not export %s: %s", subject, err)
+		}
+		exported := ExportedSubject{Subject: subject, Versions: make([]SchemaMetadata, 0, len(versions))}
+		for _, version := range versions {
+			metadata, err := client.GetSchemaMetadataContext(ctx, subject, version)
+			if err != nil {
+				return fmt.Errorf("could not export %s version %d: %s", subject, version, err)
+			}
+			exported.Versions = append(exported.Versions, *metadata)
+		}
+		bundle.Subjects = append(bundle.Subjects, exported)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(bundle)
+}
+
+// ImportSubjects registers the versions of a SchemaBundle read from r, e.g. to promote schemas to another environment.
+// The schemas get the ids and versions of the target registry, references between imported subjects are mapped
+// to the imported versions and referenced subjects are imported first.
+func (client *SchemaRegistryClient) ImportSubjects(ctx context.Context, r io.Reader) error {
+	return client.importSubjects(ctx, r, false)
+}
+
+// ImportSubjectsWithIDs registers the versions of a SchemaBundle read from r with their original ids and versions,
+// e.g. to restore a backup. The target registry must be in IMPORT mode.
+func (client *SchemaRegistryClient) ImportSubjectsWithIDs(ctx context.Context, r io.Reader) error {
+	return client.importSubjects(ctx, r, true)
+}
+
+func (client *SchemaRegistryClient) importSubjects(ctx context.Context, r io.Reader, preserveIDs bool) error {
+	var bundle SchemaBundle
+	if err := json.NewDecoder(r).Decode(&bundle); err != nil {
+		return fmt.Errorf("could not read schema bundle: %s", err)
+	}
+	// imported maps the exported versions to the versions of the target registry
+	imported := make(map[SubjectVersion]int)
+	inBundle := make(map[SubjectVersion]bool)
+	for _, subject := range bundle.Subjects {
+		for _, version := range subject.Versions {
+			inBundle[SubjectVersion{Subject: subject.Subject, Version: version.Version}] = true
+		}
+	}
+	next := make([]int, len(bundle.Subjects))
+	for remaining := len(inBundle); remaining > 0; {
+		progress := false
+		for i, subject := range bundle.Subjects {
+			for next[i] < len(subject.Versions) {
+				metadata := subject.Versions[next[i]]
+				references, ready := mapReferences(metadata.References, imported, inBundle)
+				if !ready {
+					break
+				}
+				version, err := client.importVersion(ctx, subject.Subject, metadata, references, preserveIDs)
+				if err != nil {
+					return fmt.Errorf("could not import %s version %d: %s", subject.Subject, metadata.Version, err)
+				}
+				imported[SubjectVersion{Subject: subject.Subject, Version: metadata.Version}] = version
+				next[i]++
+				remaining--
+				progress = true
+			}
+		}
+		if !progress {
+			return fmt.Errorf("schema bundle has %d versions with unresolved references", remaining)
+		}
+	}
+	return nil
+}
+
+// mapReferences maps references to imported subjects to their imported versions, references to subjects
+// that are not in the bundle are kept as they are. It returns false while a referenced version is not imported yet.
+func mapReferences(references []SchemaReference, imported map[SubjectVersion]int, inBundle map[SubjectVersion]bool) ([]SchemaReference, bool) {
+	mapped := make([]SchemaReference, len(references))
+	for i, reference := range references {
+		key := SubjectVersion{Subject: reference.Subject, Version: reference.Version}
+		mapped[i] = reference
+		if !inBundle[key] {
+			continue
+		}
+		version, ok := imported[key]
+		if !ok {
+			return nil, false
+		}
+		mapped[i].Version = version
+	}
+	return mapped, true
+}
+
+// importVersion registers a version and returns its version in the target registry
+func (client *SchemaRegistryClient) importVersion(ctx context.Context, subject string, metadata SchemaMetadata,
+	references []SchemaReference, preserveIDs bool) (int, error) {
+	request := schemaRequest{Schema: metadata.Schema, References: references}
+	if metadata.SchemaType != SchemaTypeAvro {
+		request.SchemaType = metadata.SchemaType
+	}
+	if preserveIDs {
+		request.ID = metadata.ID
+		request.Version = metadata.Version
+	}
+	resp, err := client.postSchemaRequest(ctx, fmt.Sprintf(subjectVersions, subject), request)
+	if err != nil {
+		return 0, err
+	}
+	if preserveIDs {
+		return metadata.Version, nil
+	}
+	id, err := parseID(resp)
+	if err != nil {
+		return 0, err
+	}
+	versions, err := client.GetVersionsForSchemaIDContext(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	for _, version := range versions {
+		if version.Subject == subject {
+			return version.Version, nil
+		}
+	}
+	return 0, fmt.Errorf("schema %d is not registered to %s", id, subject)
+}
+
+// ExportAllSubjects writes the versions of all subjects as a JSON SchemaBundle, soft deleted versions are not exported
+func (client *CachedSchemaRegistryClient) ExportAllSubjects(ctx context.Context, w io.Writer) error {
+	return client.SchemaRegistryClient.ExportAllSubjects(ctx, w)
+}
+
+// ImportSubjects registers the versions of a SchemaBundle read from r with the ids and versions of the registry
+func (client *CachedSchemaRegistryClient) ImportSubjects(ctx context.Context, r io.Reader) error {
+	return client.SchemaRegistryClient.ImportSubjects(ctx, r)
+}
+
+// ImportSubjectsWithIDs registers the versions of a SchemaBundle read from r with their original ids and versions
+func (client *CachedSchemaRegistryClient) ImportSubjectsWithIDs(ctx context.Context, r io.Reader) error {
+	return client.SchemaRegistryClient.ImportSubjectsWithIDs(ctx, r)
+}
@@ -0,0 +1,104 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSchemaRegistryClient_ExportAllSubjects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/subjects":
+			fmt.Fprint(w, `["address", "person"]`)
+		case "/subjects/address/versions", "/subjects/person/versions":
+			fmt.Fprint(w, `[1]`)
+		case "/subjects/address/versions/1":
+			fmt.Fprint(w, `{"subject": "address", "version": 1, "id": 1, "schema": "address"}`)
+		case "/subjects/person/versions/1":
+			fmt.Fprint(w, `{"subject": "person", "version": 1, "id": 2, "schema": "person",
+				"references": [{"name": "Address", "subject": "address", "version": 1}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	var buffer bytes.Buffer
+	if err := NewSchemaRegistryClient([]string{server.URL}).ExportAllSubjects(context.Background(), &buffer); err != nil {
+		t.Fatal(err)
+	}
+	var bundle SchemaBundle
+	if err := json.Unmarshal(buffer.Bytes(), &bundle); err != nil {
+		t.Fatal(err)
+	}
+	if len(bundle.Subjects) != 2 || bundle.Subjects[1].Versions[0].References[0].Subject != "address" {
+		t.Errorf("Expected both subjects with their references, got %+v", bundle)
+	}
+}
+
+func TestSchemaRegistryClient_ImportSubjects(t *testing.T) {
+	var registered []schemaRequest
+	var subjects []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			var request schemaRequest
+			json.NewDecoder(r.Body).Decode(&request)
+			registered = append(registered, request)
+			subjects = append(subjects, strings.Split(r.URL.Path, "/")[2])
+			fmt.Fprintf(w, `{"id": %d}`, 10+len(registered))
+			return
+		}
+		var id, version int
+		fmt.Sscanf(r.URL.Path, "/schemas/ids/%d/versions", &id)
+		// the target registry already has other versions of the subjects
+		version = 4 + id - 10
+		fmt.Fprintf(w, `[{"subject": %q, "version": %d}]`, subjects[id-11], version)
+	}))
+	defer server.Close()
+	// person is listed first but references address, so address is imported first
+	bundle := `{"subjects": [
+		{"subject": "person", "versions": [{"id": 2, "version": 1, "schema": "person",
+			"references": [{"name": "Address", "subject": "address", "version": 1}]}]},
+		{"subject": "address", "versions": [{"id": 1, "version": 1, "schema": "address"}]}
+	]}`
+	if err := NewSchemaRegistryClient([]string{server.URL}).ImportSubjects(context.Background(), strings.NewReader(bundle)); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(subjects, []string{"address", "person"}) {
+		t.Fatalf("Expected the referenced subject to be imported first, got %v", subjects)
+	}
+	expected := []SchemaReference{{Name: "Address", Subject: "address", Version: 5}}
+	if !reflect.DeepEqual(registered[1].References, expected) || registered[1].ID != 0 {
+		t.Errorf("Expected the reference to point to the imported version, got %+v", registered[1])
+	}
+}
+
+func TestSchemaRegistryClient_ImportSubjectsWithIDs(t *testing.T) {
+	var registered []schemaRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request schemaRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		registered = append(registered, request)
+		fmt.Fprintf(w, `{"id": %d}`, request.ID)
+	}))
+	defer server.Close()
+	bundle := `{"subjects": [{"subject": "test", "versions": [{"id": 7, "version": 3, "schemaType": "JSON", "schema": "{}"}]}]}`
+	client := NewSchemaRegistryClient([]string{server.URL})
+	if err := client.ImportSubjectsWithIDs(context.Background(), strings.NewReader(bundle)); err != nil {
+		t.Fatal(err)
+	}
+	if len(registered) != 1 || registered[0].ID != 7 || registered[0].Version != 3 || registered[0].SchemaType != SchemaTypeJSON {
+		t.Errorf("Expected the id, version and type to be kept, got %+v", registered)
+	}
+	unresolved := `{"subjects": [{"subject": "test", "versions": [{"id": 1, "version": 1, "schema": "{}",
+		"references": [{"name": "a", "subject": "test", "version": 2}]}, {"id": 2, "version": 2, "schema": "{}"}]}]}`
+	if err := client.ImportSubjects(context.Background(), strings.NewReader(unresolved)); err == nil {
+		t.Errorf("Expected unresolvable references to fail the import")
+	}
+}
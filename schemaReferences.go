@@ -0,0 +1,330 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+// SchemaImport is a named schema that other schemas reference by its full name.
+// It is registered under its own subject before any schema importing it.
+type SchemaImport struct {
+	Subject string
+	Schema  string
+}
+
+var primitiveTypes = map[string]bool{
+	"null": true, "boolean": true, "int": true, "long": true,
+	"float": true, "double": true, "bytes": true, "string": true,
+}
+
+// referenceResolver walks a parsed avro schema looking for named types that are defined in external schemas.
+// With inline set, the first usage of every external type is replaced by its definition so goavro can compile it.
+type referenceResolver struct {
+	schemas map[string]string
+	defined map[string]bool
+	used    []string
+	inline  bool
+}
+
+func newReferenceResolver(schemas map[string]string, inline bool) *referenceResolver {
+	return &referenceResolver{schemas: schemas, defined: make(map[string]bool), inline: inline}
+}
+
+func qualifyName(name string, namespace string) string {
+	if strings.Contains(name, ".") || namespace == "" {
+		return name
+	}
+	return namespace + "." + name
+}
+
+func namespaceOf(fullName string) string {
+	if i := strings.LastIndex(fullName, "."); i >= 0 {
+		return fullName[:i]
+	}
+	return ""
+}
+
+// namedSchemas maps the full names of named types to their definitions
+type namedSchemas map[string]map[string]interface{}
+
+// collect records the named type definitions of a parsed schema by full name
+func (named namedSchemas) collect(node interface{}, namespace string) {
+	switch n := node.(type) {
+	case []interface{}:
+		for _, branch := range n {
+			named.collect(branch, namespace)
+		}
+	case map[string]interface{}:
+		switch n["type"] {
+		case "record", "error":
+			fullName, recordNamespace := definedName(n, namespace)
+			named[fullName] = n
+			fields, _ := n["fields"].([]interface{})
+			for _, f := range fields {
+				if field, ok := f.(map[string]interface{}); ok {
+					named.collect(field["type"], recordNamespace)
+				}
+			}
+		case "enum", "fixed":
+			fullName, _ := definedName(n, namespace)
+			named[fullName] = n
+		case "array":
+			named.collect(n["items"], namespace)
+		case "map":
+			named.collect(n["values"], namespace)
+		default:
+			named.collect(n["type"], namespace)
+		}
+	}
+}
+
+// unionBranchName returns the name goavro uses to identify a union member
+func unionBranchName(node interface{}, namespace string) string {
+	switch n := node.(type) {
+	case string:
+		if primitiveTypes[n] {
+			return n
+		}
+		return qualifyName(n, namespace)
+	case map[string]interface{}:
+		typeName, _ := n["type"].(string)
+		switch typeName {
+		case "record", "error", "enum", "fixed":
+			fullName, _ := definedName(n, namespace)
+			return fullName
+		}
+		if logicalType, ok := n["logicalType"].(string); ok {
+			return typeName + "." + logicalType
+		}
+		return typeName
+	}
+	return ""
+}
+
+// definedName returns the full name and namespace of a named type definition
+func definedName(node map[string]interface{}, enclosing string) (string, string) {
+	name, _ := node["name"].(string)
+	if strings.Contains(name, ".") {
+		return name, namespaceOf(name)
+	}
+	namespace := enclosing
+	if ns, ok := node["namespace"].(string); ok {
+		namespace = ns
+	}
+	return qualifyName(name, namespace), namespace
+}
+
+func (r *referenceResolver) resolve(node interface{}, namespace string) (interface{}, error) {
+	switch n := node.(type) {
+	case string:
+		if primitiveTypes[n] {
+			return n, nil
+		}
+		fullName := qualifyName(n, namespace)
+		if r.defined[fullName] {
+			return n, nil
+		}
+		schema, found := r.schemas[fullName]
+		if !found {
+			return n, nil
+		}
+		r.defined[fullName] = true
+		r.used = append(r.used, fullName)
+		if !r.inline {
+			return n, nil
+		}
+		var imported interface{}
+		if err := json.Unmarshal([]byte(schema), &imported); err != nil {
+			return nil, fmt.Errorf("cannot parse imported schema %s: %v", fullName, err)
+		}
+		return r.resolve(imported, namespaceOf(fullName))
+	case []interface{}:
+		for i := range n {
+			resolved, err := r.resolve(n[i], namespace)
+			if err != nil {
+				return nil, err
+			}
+			n[i] = resolved
+		}
+		return n, nil
+	case map[string]interface{}:
+		return r.resolveComplex(n, namespace)
+	}
+	return node, nil
+}
+
+func (r *referenceResolver) resolveComplex(node map[string]interface{}, namespace string) (interface{}, error) {
+	var err error
+	switch node["type"] {
+	case "record", "error":
+		fullName, recordNamespace := definedName(node, namespace)
+		r.defined[fullName] = true
+		fields, _ := node["fields"].([]interface{})
+		for _, f := range fields {
+			field, ok := f.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if field["type"], err = r.resolve(field["type"], recordNamespace); err != nil {
+				return nil, err
+			}
+		}
+	case "enum", "fixed":
+		fullName, _ := definedName(node, namespace)
+		r.defined[fullName] = true
+	case "array":
+		if node["items"], err = r.resolve(node["items"], namespace); err != nil {
+			return nil, err
+		}
+	case "map":
+		if node["values"], err = r.resolve(node["values"], namespace); err != nil {
+			return nil, err
+		}
+	default:
+		if node["type"], err = r.resolve(node["type"], namespace); err != nil {
+			return nil, err
+		}
+	}
+	return node, nil
+}
+
+func parseSchemaJSON(schema string) (interface{}, error) {
+	var parsed interface{}
+	err := json.Unmarshal([]byte(schema), &parsed)
+	return parsed, err
+}
+
+// schemaFullName returns the full name of the top level named type of a schema
+func schemaFullName(schema string) (string, error) {
+	parsed, err := parseSchemaJSON(schema)
+	if err != nil {
+		return "", err
+	}
+	node, ok := parsed.(map[string]interface{})
+	if !ok || node["name"] == nil {
+		return "", fmt.Errorf("schema is not a named type: %s", schema)
+	}
+	fullName, _ := definedName(node, "")
+	return fullName, nil
+}
+
+// referencedNames returns the full names of the external types used directly by the schema
+func referencedNames(schema string, schemas map[string]string) ([]string, error) {
+	parsed, err := parseSchemaJSON(schema)
+	if err != nil {
+		return nil, err
+	}
+	resolver := newReferenceResolver(schemas, false)
+	if _, err := resolver.resolve(parsed, ""); err != nil {
+		return nil, err
+	}
+	return resolver.used, nil
+}
+
+// inlineReferences returns a self-contained schema where every external type is defined at its first usage
+func inlineReferences(schema string, schemas map[string]string) (string, error) {
+	parsed, err := parseSchemaJSON(schema)
+	if err != nil {
+		return "", err
+	}
+	resolved, err := newReferenceResolver(schemas, true).resolve(parsed, "")
+	if err != nil {
+		return "", err
+	}
+	inlined, err := json.Marshal(resolved)
+	return string(inlined), err
+}
+
+// sortImports orders the imports so that every schema comes after the schemas it references
+func sortImports(imports []SchemaImport) ([]string, map[string]SchemaImport, map[string]string, error) {
+	byName := make(map[string]SchemaImport, len(imports))
+	schemas := make(map[string]string, len(imports))
+	for _, imp := range imports {
+		fullName, err := schemaFullName(imp.Schema)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		byName[fullName] = imp
+		schemas[fullName] = imp.Schema
+	}
+	var ordered []string
+	state := make(map[string]int)
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case 1:
+			return fmt.Errorf("cyclic schema reference on %s", name)
+		case 2:
+			return nil
+		}
+		state[name] = 1
+		deps, err := referencedNames(schemas[name], schemas)
+		if err != nil {
+			return err
+		}
+		for _, dep := range deps {
+			if dep == name {
+				continue
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		state[name] = 2
+		ordered = append(ordered, name)
+		return nil
+	}
+	for _, imp := range imports {
+		fullName, _ := schemaFullName(imp.Schema)
+		if err := visit(fullName); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	return ordered, byName, schemas, nil
+}
+
+// newCodec compiles a registered schema, the schemas it references are fetched recursively and inlined
+func (client *SchemaRegistryClient) newCodec(ctx context.Context, metadata *SchemaMetadata) (*goavro.Codec, error) {
+	if len(metadata.References) == 0 {
+		return goavro.NewCodec(metadata.Schema)
+	}
+	schemas := make(map[string]string)
+	if err := client.collectReferences(ctx, metadata.References, schemas, make(map[string]bool)); err != nil {
+		return nil, err
+	}
+	schema, err := inlineReferences(metadata.Schema, schemas)
+	if err != nil {
+		return nil, err
+	}
+	return goavro.NewCodec(schema)
+}
+
+// collectReferences fetches the referenced schemas and the schemas they reference, by full name
+func (client *SchemaRegistryClient) collectReferences(ctx context.Context, references []SchemaReference,
+	schemas map[string]string, visited map[string]bool) error {
+	for _, reference := range references {
+		key := reference.Subject + ":" + strconv.Itoa(reference.Version)
+		if visited[key] {
+			continue
+		}
+		visited[key] = true
+		metadata, err := client.getSchemaMetadataInternal(ctx, reference.Subject, strconv.Itoa(reference.Version))
+		if err != nil {
+			return fmt.Errorf("could not resolve reference %s: %s", reference.Name, err)
+		}
+		name := reference.Name
+		if fullName, err := schemaFullName(metadata.Schema); err == nil {
+			name = fullName
+		}
+		schemas[name] = metadata.Schema
+		if err := client.collectReferences(ctx, metadata.References, schemas, visited); err != nil {
+			return err
+		}
+	}
+	return nil
+}
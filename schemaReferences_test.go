@@ -0,0 +1,104 @@
+package kafka
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+var addressSchema = `{"type": "record", "name": "Address", "namespace": "com.example", "fields": [{"name": "street", "type": "string"}, {"name": "country", "type": "Country"}]}`
+var countrySchema = `{"type": "enum", "name": "Country", "namespace": "com.example", "symbols": ["NL", "VN"]}`
+var personSchema = `{"type": "record", "name": "Person", "namespace": "com.example", "fields": [{"name": "home", "type": "Address"}, {"name": "work", "type": ["null", "com.example.Address"]}]}`
+
+func testImports() []SchemaImport {
+	return []SchemaImport{
+		{Subject: "address", Schema: addressSchema},
+		{Subject: "country", Schema: countrySchema},
+	}
+}
+
+func TestSortImports(t *testing.T) {
+	ordered, _, _, err := sortImports(testImports())
+	if err != nil {
+		t.Fatalf("Error sorting imports: %v", err)
+	}
+	if len(ordered) != 2 || ordered[0] != "com.example.Country" || ordered[1] != "com.example.Address" {
+		t.Errorf("Imports not in dependency order: %v", ordered)
+	}
+}
+
+func TestSortImports_Cycle(t *testing.T) {
+	a := `{"type": "record", "name": "A", "fields": [{"name": "b", "type": "B"}]}`
+	b := `{"type": "record", "name": "B", "fields": [{"name": "a", "type": "A"}]}`
+	_, _, _, err := sortImports([]SchemaImport{{"a", a}, {"b", b}})
+	if err == nil {
+		t.Errorf("Expected cyclic reference error")
+	}
+}
+
+func TestInlineReferences(t *testing.T) {
+	_, _, schemas, err := sortImports(testImports())
+	if err != nil {
+		t.Fatalf("Error sorting imports: %v", err)
+	}
+	names, err := referencedNames(personSchema, schemas)
+	if err != nil {
+		t.Fatalf("Error collecting references: %v", err)
+	}
+	if len(names) != 1 || names[0] != "com.example.Address" {
+		t.Errorf("Unexpected direct references: %v", names)
+	}
+	resolved, err := inlineReferences(personSchema, schemas)
+	if err != nil {
+		t.Fatalf("Error inlining references: %v", err)
+	}
+	codec, err := goavro.NewCodec(resolved)
+	if err != nil {
+		t.Fatalf("Resolved schema does not compile: %v", err)
+	}
+	value := `{"home": {"street": "a", "country": "NL"}, "work": {"com.example.Address": {"street": "b", "country": "VN"}}}`
+	if _, _, err := codec.NativeFromTextual([]byte(value)); err != nil {
+		t.Errorf("Could not decode value with resolved schema: %v", err)
+	}
+}
+
+func TestSchemaRegistryClient_GetSchemaWithReferences(t *testing.T) {
+	versions := map[string]SchemaMetadata{
+		"/subjects/address/versions/1": {Subject: "address", Version: 1, Schema: addressSchema,
+			References: []SchemaReference{{Name: "com.example.Country", Subject: "country", Version: 1}}},
+		"/subjects/country/versions/1": {Subject: "country", Version: 1, Schema: countrySchema},
+		"/schemas/ids/3": {Schema: personSchema,
+			References: []SchemaReference{{Name: "com.example.Address", Subject: "address", Version: 1}}},
+	}
+	count := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count++
+		metadata, ok := versions[r.URL.Path]
+		if !ok {
+			http.Error(w, `{"error_code": 40403, "message": "Schema not found"}`, http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(metadata)
+	}))
+	defer server.Close()
+	client := NewCachedSchemaRegistryClient([]string{server.URL})
+
+	codec, err := client.GetSchema(3)
+	if err != nil {
+		t.Fatalf("Expected the references to be resolved, got %v", err)
+	}
+	native := map[string]interface{}{
+		"home": map[string]interface{}{"street": "Main", "country": "NL"},
+		"work": nil,
+	}
+	if _, err := codec.BinaryFromNative(nil, native); err != nil {
+		t.Errorf("Expected a usable codec, got %v", err)
+	}
+	client.GetSchema(3)
+	if count != 3 {
+		t.Errorf("Expected the resolved codec to be cached, got %d requests", count)
+	}
+}
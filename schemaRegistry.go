@@ -2,13 +2,15 @@ package kafka
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/linkedin/goavro/v2"
 	"io"
 	"io/ioutil"
-	"math/rand"
+	"net"
 	"net/http"
+	"net/url"
 	"time"
 )
 
@@ -23,6 +25,80 @@ type SchemaRegistryClientInterface interface {
 	IsSchemaRegistered(string, *goavro.Codec) (int, error)
 	DeleteSubject(string) error
 	DeleteVersion(string, int) error
+	DeleteSubjectPermanently(string) error
+	DeleteVersionPermanently(string, int) error
+	ListDeletedSubjects() ([]string, error)
+	GetSchemaMetadataByID(int) (*SchemaMetadata, error)
+	GetLatestSchemaMetadata(string) (*SchemaMetadata, error)
+	GetSchemaMetadata(string, int) (*SchemaMetadata, error)
+	GetSchemaBySubjectAndID(string, int) (*SchemaMetadata, error)
+	GetReferencedBy(string, int) ([]int, error)
+	GetVersionsForSchemaID(int) ([]SubjectVersion, error)
+	CreateSubjectWithReferences(string, string, []SchemaReference) (int, error)
+	CreateSubjectWithID(string, *goavro.Codec, int, int) (int, error)
+	CreateSubjectWithSchemaType(string, string, string, []SchemaReference) (int, error)
+	LookupSchema(string, string, []SchemaReference) (*SchemaMetadata, error)
+	CheckCompatibility(string, int, *goavro.Codec) (bool, error)
+	CheckLatestCompatibility(string, *goavro.Codec) (bool, error)
+	GetCompatibility(string) (string, error)
+	SetCompatibility(string, string) error
+	GetGlobalCompatibility() (string, error)
+	SetGlobalCompatibility(string) error
+	GetMode(string) (string, error)
+	SetMode(string, string) error
+	GetGlobalMode() (string, error)
+	SetGlobalMode(string) error
+	Ping() error
+	ServerInfo() (*ServerInfo, error)
+}
+
+// SchemaRegistryClientContextInterface adds variants of the client api taking a context,
+// so registry requests can be cancelled and carry deadlines
+type SchemaRegistryClientContextInterface interface {
+	SchemaRegistryClientInterface
+	GetSchemaContext(context.Context, int) (*goavro.Codec, error)
+	GetSubjectsContext(context.Context) ([]string, error)
+	GetVersionsContext(context.Context, string) ([]int, error)
+	GetSchemaByVersionContext(context.Context, string, int) (*goavro.Codec, error)
+	GetLatestSchemaContext(context.Context, string) (*goavro.Codec, error)
+	CreateSubjectContext(context.Context, string, *goavro.Codec) (int, error)
+	IsSchemaRegisteredContext(context.Context, string, *goavro.Codec) (int, error)
+	DeleteSubjectContext(context.Context, string) error
+	DeleteVersionContext(context.Context, string, int) error
+	DeleteSubjectPermanentlyContext(context.Context, string) error
+	DeleteVersionPermanentlyContext(context.Context, string, int) error
+	ListDeletedSubjectsContext(context.Context) ([]string, error)
+	GetSchemaMetadataByIDContext(context.Context, int) (*SchemaMetadata, error)
+	GetLatestSchemaMetadataContext(context.Context, string) (*SchemaMetadata, error)
+	GetSchemaMetadataContext(context.Context, string, int) (*SchemaMetadata, error)
+	GetSchemaBySubjectAndIDContext(context.Context, string, int) (*SchemaMetadata, error)
+	GetReferencedByContext(context.Context, string, int) ([]int, error)
+	GetVersionsForSchemaIDContext(context.Context, int) ([]SubjectVersion, error)
+	CreateSubjectWithReferencesContext(context.Context, string, string, []SchemaReference) (int, error)
+	CreateSubjectWithIDContext(context.Context, string, *goavro.Codec, int, int) (int, error)
+	CreateSubjectWithSchemaTypeContext(context.Context, string, string, string, []SchemaReference) (int, error)
+	LookupSchemaContext(context.Context, string, string, []SchemaReference) (*SchemaMetadata, error)
+	CheckCompatibilityContext(context.Context, string, int, *goavro.Codec) (bool, error)
+	CheckLatestCompatibilityContext(context.Context, string, *goavro.Codec) (bool, error)
+	GetCompatibilityContext(context.Context, string) (string, error)
+	SetCompatibilityContext(context.Context, string, string) error
+	GetGlobalCompatibilityContext(context.Context) (string, error)
+	SetGlobalCompatibilityContext(context.Context, string) error
+	GetModeContext(context.Context, string) (string, error)
+	SetModeContext(context.Context, string, string) error
+	GetGlobalModeContext(context.Context) (string, error)
+	SetGlobalModeContext(context.Context, string) error
+	PingContext(context.Context) error
+	ServerInfoContext(context.Context) (*ServerInfo, error)
+}
+
+// SchemaRegistry is the schema registry used by producers and consumers, implemented by CachedSchemaRegistryClient
+// and SchemaRegistryClient. WithSchemaRegistry replaces the cached client created from the registry urls,
+// e.g. with a mock or a client shared by several producers and consumers.
+type SchemaRegistry interface {
+	SchemaRegistryClientContextInterface
+	// Healthy checks that the registry can list the subjects
+	Healthy(ctx context.Context) error
 }
 
 // SchemaRegistryClient is a basic http client to interact with schema registry
@@ -30,12 +106,30 @@ type SchemaRegistryClient struct {
 	SchemaRegistryConnect []string
 	httpClient            *http.Client
 	retries               int
+	authorize             func(*http.Request) error
+	headers               []func(*http.Request) error
+	netDialer             *net.Dialer
+	breaker               *circuitBreaker
+	endpoints             *endpointHealth
+	probe                 *endpointProbe
+	metrics               Metrics
+	logger                Logger
+	normalize             bool
 }
 
 type schemaResponse struct {
 	Schema string `json:"schema"`
 }
 
+type schemaRequest struct {
+	Schema     string            `json:"schema"`
+	References []SchemaReference `json:"references,omitempty"`
+	SchemaType string            `json:"schemaType,omitempty"`
+	// ID and Version are only accepted by registries in IMPORT mode
+	ID      int `json:"id,omitempty"`
+	Version int `json:"version,omitempty"`
+}
+
 type schemaVersionResponse struct {
 	Subject string `json:"subject"`
 	Version int    `json:"version"`
@@ -43,19 +137,87 @@ type schemaVersionResponse struct {
 	ID      int    `json:"id"`
 }
 
+// SchemaReference points to a schema registered under another subject
+type SchemaReference struct {
+	Name    string `json:"name"`
+	Subject string `json:"subject"`
+	Version int    `json:"version"`
+}
+
+// SchemaMetadata is the full schema object as returned by schema registry
+type SchemaMetadata struct {
+	ID         int               `json:"id"`
+	Subject    string            `json:"subject,omitempty"`
+	Version    int               `json:"version,omitempty"`
+	SchemaType string            `json:"schemaType,omitempty"`
+	References []SchemaReference `json:"references,omitempty"`
+	Schema     string            `json:"schema"`
+}
+
+// SubjectVersion identifies a version of a subject
+type SubjectVersion struct {
+	Subject string `json:"subject"`
+	Version int    `json:"version"`
+}
+
+// ServerInfo holds the version information reported by schema registry
+type ServerInfo struct {
+	Version  string `json:"version"`
+	CommitID string `json:"commitId"`
+}
+
 type idResponse struct {
 	ID int `json:"id"`
 }
 
+type compatibilityResponse struct {
+	IsCompatible bool `json:"is_compatible"`
+}
+
+type configResponse struct {
+	CompatibilityLevel string `json:"compatibilityLevel"`
+}
+
+type configRequest struct {
+	Compatibility string `json:"compatibility"`
+}
+
+type modeMessage struct {
+	Mode string `json:"mode"`
+}
+
 const (
 	schemaByID       = "/schemas/ids/%d"
 	subjects         = "/subjects"
 	subjectVersions  = "/subjects/%s/versions"
 	deleteSubject    = "/subjects/%s"
 	subjectByVersion = "/subjects/%s/versions/%s"
+	root             = "/"
+	metadataVersion  = "/v1/metadata/version"
+	compatibility    = "/compatibility/subjects/%s/versions/%s"
+	referencedBy     = "/subjects/%s/versions/%d/referencedby"
+	schemaVersions   = "/schemas/ids/%d/versions"
+	globalConfig     = "/config"
+	subjectConfig    = "/config/%s"
+	globalMode       = "/mode"
+	subjectMode      = "/mode/%s"
 
 	latestVersion = "latest"
 
+	// compatibility levels of schema registry
+	CompatibilityNone               = "NONE"
+	CompatibilityBackward           = "BACKWARD"
+	CompatibilityBackwardTransitive = "BACKWARD_TRANSITIVE"
+	CompatibilityForward            = "FORWARD"
+	CompatibilityForwardTransitive  = "FORWARD_TRANSITIVE"
+	CompatibilityFull               = "FULL"
+	CompatibilityFullTransitive     = "FULL_TRANSITIVE"
+
+	// modes of schema registry
+	ModeReadWrite = "READWRITE"
+	ModeReadOnly  = "READONLY"
+	ModeImport    = "IMPORT"
+
 	contentType = "application/vnd.schemaregistry.v1+json"
 
 	timeout = 2 * time.Second
@@ -63,37 +225,53 @@ const (
 
 // NewSchemaRegistryClient creates a client to talk with the schema registry at the connect string
 // By default it will retry failed requests (5XX responses and http errors) len(connect) number of times
-func NewSchemaRegistryClient(connect []string) *SchemaRegistryClient {
+func NewSchemaRegistryClient(connect []string, opts ...SchemaRegistryOption) *SchemaRegistryClient {
 	client := &http.Client{
 		Timeout: timeout,
 	}
-	return &SchemaRegistryClient{connect, client, len(connect)}
+	return (&SchemaRegistryClient{
+		SchemaRegistryConnect: connect,
+		httpClient:            client,
+		retries:               len(connect),
+		endpoints:             newEndpointHealth(defaultEndpointBackoff),
+	}).applyOptions(opts)
 }
 
 // NewSchemaRegistryClientWithRetries creates an http client with a configurable amount of retries on 5XX responses
-func NewSchemaRegistryClientWithRetries(connect []string, retries int) *SchemaRegistryClient {
+func NewSchemaRegistryClientWithRetries(connect []string, retries int, opts ...SchemaRegistryOption) *SchemaRegistryClient {
 	client := &http.Client{
 		Timeout: timeout,
 	}
-	return &SchemaRegistryClient{connect, client, retries}
+	return (&SchemaRegistryClient{
+		SchemaRegistryConnect: connect,
+		httpClient:            client,
+		retries:               retries,
+		endpoints:             newEndpointHealth(defaultEndpointBackoff),
+	}).applyOptions(opts)
 }
 
-// GetSchema returns a goavro.Codec by unique id
+// GetSchema returns a goavro.Codec by unique id, references to other subjects are resolved into the codec
 func (client *SchemaRegistryClient) GetSchema(id int) (*goavro.Codec, error) {
-	resp, err := client.httpCall("GET", fmt.Sprintf(schemaByID, id), nil)
-	if nil != err {
-		return nil, err
-	}
-	schema, err := parseSchema(resp)
+	return client.GetSchemaContext(context.Background(), id)
+}
+
+// GetSchemaContext is GetSchema using the context for the registry requests
+func (client *SchemaRegistryClient) GetSchemaContext(ctx context.Context, id int) (*goavro.Codec, error) {
+	schema, err := client.GetSchemaMetadataByIDContext(ctx, id)
 	if nil != err {
 		return nil, err
 	}
-	return goavro.NewCodec(schema.Schema)
+	return client.newCodec(ctx, schema)
 }
 
 // GetSubjects returns a list of all subjects in the schema registry
 func (client *SchemaRegistryClient) GetSubjects() ([]string, error) {
-	resp, err := client.httpCall("GET", subjects, nil)
+	return client.GetSubjectsContext(context.Background())
+}
+
+// GetSubjectsContext is GetSubjects using the context for the registry requests
+func (client *SchemaRegistryClient) GetSubjectsContext(ctx context.Context) ([]string, error) {
+	resp, err := client.httpCall(ctx, "GET", subjects, nil)
 	if nil != err {
 		return []string{}, err
 	}
@@ -104,7 +282,12 @@ func (client *SchemaRegistryClient) GetSubjects() ([]string, error) {
 
 // GetVersions returns a list of the versions of a subject
 func (client *SchemaRegistryClient) GetVersions(subject string) ([]int, error) {
-	resp, err := client.httpCall("GET", fmt.Sprintf(subjectVersions, subject), nil)
+	return client.GetVersionsContext(context.Background(), subject)
+}
+
+// GetVersionsContext is GetVersions using the context for the registry requests
+func (client *SchemaRegistryClient) GetVersionsContext(ctx context.Context, subject string) ([]int, error) {
+	resp, err := client.httpCall(ctx, "GET", fmt.Sprintf(subjectVersions, subject), nil)
 	if nil != err {
 		return []int{}, err
 	}
@@ -113,39 +296,156 @@ func (client *SchemaRegistryClient) GetVersions(subject string) ([]int, error) {
 	return result, err
 }
 
-func (client *SchemaRegistryClient) getSchemaByVersionInternal(subject string, version string) (*goavro.Codec, error) {
-	resp, err := client.httpCall("GET", fmt.Sprintf(subjectByVersion, subject, version), nil)
+func (client *SchemaRegistryClient) getSchemaByVersionInternal(ctx context.Context, subject string, version string) (*goavro.Codec, error) {
+	schema, err := client.getSchemaMetadataInternal(ctx, subject, version)
 	if nil != err {
 		return nil, err
 	}
-	var schema = new(schemaVersionResponse)
+	return client.newCodec(ctx, schema)
+}
+
+func (client *SchemaRegistryClient) getSchemaMetadataInternal(ctx context.Context, subject string, version string) (*SchemaMetadata, error) {
+	resp, err := client.httpCall(ctx, "GET", fmt.Sprintf(subjectByVersion, subject, version), nil)
+	if nil != err {
+		return nil, err
+	}
+	var schema = new(SchemaMetadata)
 	err = json.Unmarshal(resp, &schema)
 	if nil != err {
 		return nil, err
 	}
-
-	return goavro.NewCodec(schema.Schema)
+	return schema, nil
 }
 
 // GetSchemaByVersion returns a goavro.Codec for the version of the subject
 func (client *SchemaRegistryClient) GetSchemaByVersion(subject string, version int) (*goavro.Codec, error) {
-	return client.getSchemaByVersionInternal(subject, fmt.Sprintf("%d", version))
+	return client.GetSchemaByVersionContext(context.Background(), subject, version)
+}
+
+// GetSchemaByVersionContext is GetSchemaByVersion using the context for the registry requests
+func (client *SchemaRegistryClient) GetSchemaByVersionContext(ctx context.Context, subject string, version int) (*goavro.Codec, error) {
+	return client.getSchemaByVersionInternal(ctx, subject, fmt.Sprintf("%d", version))
 }
 
 // GetLatestSchema returns a goavro.Codec for the latest version of the subject
 func (client *SchemaRegistryClient) GetLatestSchema(subject string) (*goavro.Codec, error) {
-	return client.getSchemaByVersionInternal(subject, latestVersion)
+	return client.GetLatestSchemaContext(context.Background(), subject)
+}
+
+// GetLatestSchemaContext is GetLatestSchema using the context for the registry requests
+func (client *SchemaRegistryClient) GetLatestSchemaContext(ctx context.Context, subject string) (*goavro.Codec, error) {
+	return client.getSchemaByVersionInternal(ctx, subject, latestVersion)
+}
+
+// GetSchemaMetadataByID returns the full schema object (type, references, raw schema) by unique id
+func (client *SchemaRegistryClient) GetSchemaMetadataByID(id int) (*SchemaMetadata, error) {
+	return client.GetSchemaMetadataByIDContext(context.Background(), id)
+}
+
+// GetSchemaMetadataByIDContext is GetSchemaMetadataByID using the context for the registry requests
+func (client *SchemaRegistryClient) GetSchemaMetadataByIDContext(ctx context.Context, id int) (*SchemaMetadata, error) {
+	resp, err := client.httpCall(ctx, "GET", fmt.Sprintf(schemaByID, id), nil)
+	if nil != err {
+		return nil, err
+	}
+	var schema = new(SchemaMetadata)
+	err = json.Unmarshal(resp, &schema)
+	if nil != err {
+		return nil, err
+	}
+	schema.ID = id
+	return schema, nil
+}
+
+// GetLatestSchemaMetadata returns the full schema object for the latest version of the subject
+func (client *SchemaRegistryClient) GetLatestSchemaMetadata(subject string) (*SchemaMetadata, error) {
+	return client.GetLatestSchemaMetadataContext(context.Background(), subject)
+}
+
+// GetLatestSchemaMetadataContext is GetLatestSchemaMetadata using the context for the registry requests
+func (client *SchemaRegistryClient) GetLatestSchemaMetadataContext(ctx context.Context, subject string) (*SchemaMetadata, error) {
+	return client.getSchemaMetadataInternal(ctx, subject, latestVersion)
+}
+
+// GetSchemaMetadata returns the full schema object (id, type, references, raw schema) for the version of the subject
+func (client *SchemaRegistryClient) GetSchemaMetadata(subject string, version int) (*SchemaMetadata, error) {
+	return client.GetSchemaMetadataContext(context.Background(), subject, version)
+}
+
+// GetSchemaMetadataContext is GetSchemaMetadata using the context for the registry requests
+func (client *SchemaRegistryClient) GetSchemaMetadataContext(ctx context.Context, subject string, version int) (*SchemaMetadata, error) {
+	return client.getSchemaMetadataInternal(ctx, subject, fmt.Sprintf("%d", version))
+}
+
+// GetSchemaBySubjectAndID returns the full schema object with the given id, the references of the schema
+// are looked up in the context of the subject
+func (client *SchemaRegistryClient) GetSchemaBySubjectAndID(subject string, id int) (*SchemaMetadata, error) {
+	return client.GetSchemaBySubjectAndIDContext(context.Background(), subject, id)
+}
+
+// GetSchemaBySubjectAndIDContext is GetSchemaBySubjectAndID using the context for the registry requests
+func (client *SchemaRegistryClient) GetSchemaBySubjectAndIDContext(ctx context.Context, subject string, id int) (*SchemaMetadata, error) {
+	resp, err := client.httpCall(ctx, "GET", fmt.Sprintf(schemaByID, id)+"?subject="+url.QueryEscape(subject), nil)
+	if nil != err {
+		return nil, err
+	}
+	var schema = new(SchemaMetadata)
+	err = json.Unmarshal(resp, &schema)
+	if nil != err {
+		return nil, err
+	}
+	schema.ID = id
+	schema.Subject = subject
+	return schema, nil
+}
+
+// GetReferencedBy returns the ids of the schemas referencing the version of the subject,
+// a version cannot be deleted while it is referenced
+func (client *SchemaRegistryClient) GetReferencedBy(subject string, version int) ([]int, error) {
+	return client.GetReferencedByContext(context.Background(), subject, version)
+}
+
+// GetReferencedByContext is GetReferencedBy using the context for the registry requests
+func (client *SchemaRegistryClient) GetReferencedByContext(ctx context.Context, subject string, version int) ([]int, error) {
+	resp, err := client.httpCall(ctx, "GET", fmt.Sprintf(referencedBy, subject, version), nil)
+	if nil != err {
+		return []int{}, err
+	}
+	var result = []int{}
+	err = json.Unmarshal(resp, &result)
+	return result, err
+}
+
+// GetVersionsForSchemaID returns the subject versions registered with the schema id
+func (client *SchemaRegistryClient) GetVersionsForSchemaID(id int) ([]SubjectVersion, error) {
+	return client.GetVersionsForSchemaIDContext(context.Background(), id)
+}
+
+// GetVersionsForSchemaIDContext is GetVersionsForSchemaID using the context for the registry requests
+func (client *SchemaRegistryClient) GetVersionsForSchemaIDContext(ctx context.Context, id int) ([]SubjectVersion, error) {
+	resp, err := client.httpCall(ctx, "GET", fmt.Sprintf(schemaVersions, id), nil)
+	if nil != err {
+		return []SubjectVersion{}, err
+	}
+	var result = []SubjectVersion{}
+	err = json.Unmarshal(resp, &result)
+	return result, err
 }
 
 // CreateSubject adds a schema to the subject
 func (client *SchemaRegistryClient) CreateSubject(subject string, codec *goavro.Codec) (int, error) {
+	return client.CreateSubjectContext(context.Background(), subject, codec)
+}
+
+// CreateSubjectContext is CreateSubject using the context for the registry requests
+func (client *SchemaRegistryClient) CreateSubjectContext(ctx context.Context, subject string, codec *goavro.Codec) (int, error) {
 	schema := schemaResponse{codec.Schema()}
 	json, err := json.Marshal(schema)
 	if err != nil {
 		return 0, err
 	}
 	payload := bytes.NewBuffer(json)
-	resp, err := client.httpCall("POST", fmt.Sprintf(subjectVersions, subject), payload)
+	resp, err := client.httpCall(ctx, "POST", client.normalized(fmt.Sprintf(subjectVersions, subject)), payload)
 	if err != nil {
 		return 0, err
 	}
@@ -154,35 +454,366 @@ func (client *SchemaRegistryClient) CreateSubject(subject string, codec *goavro.
 
 // IsSchemaRegistered tests if the schema is registered, if so it returns the unique id of that schema
 func (client *SchemaRegistryClient) IsSchemaRegistered(subject string, codec *goavro.Codec) (int, error) {
+	return client.IsSchemaRegisteredContext(context.Background(), subject, codec)
+}
+
+// IsSchemaRegisteredContext is IsSchemaRegistered using the context for the registry requests
+func (client *SchemaRegistryClient) IsSchemaRegisteredContext(ctx context.Context, subject string, codec *goavro.Codec) (int, error) {
 	schema := schemaResponse{codec.Schema()}
 	json, err := json.Marshal(schema)
 	if err != nil {
 		return 0, err
 	}
 	payload := bytes.NewBuffer(json)
-	resp, err := client.httpCall("POST", fmt.Sprintf(deleteSubject, subject), payload)
+	resp, err := client.httpCall(ctx, "POST", client.normalized(fmt.Sprintf(deleteSubject, subject)), payload)
 	if err != nil {
 		return 0, err
 	}
 	return parseID(resp)
 }
 
-// DeleteSubject deletes a subject. It should only be used in development
+// CreateSubjectWithReferences adds a schema importing the given references to the subject
+func (client *SchemaRegistryClient) CreateSubjectWithReferences(subject string, schema string, references []SchemaReference) (int, error) {
+	return client.CreateSubjectWithReferencesContext(context.Background(), subject, schema, references)
+}
+
+// CreateSubjectWithReferencesContext is CreateSubjectWithReferences using the context for the registry requests
+func (client *SchemaRegistryClient) CreateSubjectWithReferencesContext(ctx context.Context, subject string, schema string, references []SchemaReference) (int, error) {
+	resp, err := client.postSchema(ctx, client.normalized(fmt.Sprintf(subjectVersions, subject)), schema, references)
+	if err != nil {
+		return 0, err
+	}
+	return parseID(resp)
+}
+
+// CreateSubjectWithID adds a schema to the subject with an explicit id and version, so migrated messages keep
+// referencing the right schema. The registry or the subject must be in IMPORT mode.
+func (client *SchemaRegistryClient) CreateSubjectWithID(subject string, codec *goavro.Codec, id int, version int) (int, error) {
+	return client.CreateSubjectWithIDContext(context.Background(), subject, codec, id, version)
+}
+
+// CreateSubjectWithIDContext is CreateSubjectWithID using the context for the registry requests
+func (client *SchemaRegistryClient) CreateSubjectWithIDContext(ctx context.Context, subject string, codec *goavro.Codec, id int, version int) (int, error) {
+	request := schemaRequest{Schema: codec.Schema(), ID: id, Version: version}
+	resp, err := client.postSchemaRequest(ctx, client.normalized(fmt.Sprintf(subjectVersions, subject)), request)
+	if err != nil {
+		return 0, err
+	}
+	return parseID(resp)
+}
+
+// CreateSubjectWithSchemaType adds a schema of the given type, AVRO, JSON or PROTOBUF, to the subject
+func (client *SchemaRegistryClient) CreateSubjectWithSchemaType(subject string, schemaType string, schema string, references []SchemaReference) (int, error) {
+	return client.CreateSubjectWithSchemaTypeContext(context.Background(), subject, schemaType, schema, references)
+}
+
+// CreateSubjectWithSchemaTypeContext is CreateSubjectWithSchemaType using the context for the registry requests
+func (client *SchemaRegistryClient) CreateSubjectWithSchemaTypeContext(ctx context.Context, subject string, schemaType string, schema string, references []SchemaReference) (int, error) {
+	request := schemaRequest{Schema: schema, References: references}
+	if schemaType != SchemaTypeAvro {
+		// the registry defaults to AVRO, older registries only accept AVRO without a type
+		request.SchemaType = schemaType
+	}
+	resp, err := client.postSchemaRequest(ctx, client.normalized(fmt.Sprintf(subjectVersions, subject)), request)
+	if err != nil {
+		return 0, err
+	}
+	return parseID(resp)
+}
+
+// LookupSchema returns the registered schema object, including its version, if the schema is registered to the subject
+func (client *SchemaRegistryClient) LookupSchema(subject string, schema string, references []SchemaReference) (*SchemaMetadata, error) {
+	return client.LookupSchemaContext(context.Background(), subject, schema, references)
+}
+
+// LookupSchemaContext is LookupSchema using the context for the registry requests
+func (client *SchemaRegistryClient) LookupSchemaContext(ctx context.Context, subject string, schema string, references []SchemaReference) (*SchemaMetadata, error) {
+	resp, err := client.postSchema(ctx, client.normalized(fmt.Sprintf(deleteSubject, subject)), schema, references)
+	if err != nil {
+		return nil, err
+	}
+	var metadata = new(SchemaMetadata)
+	err = json.Unmarshal(resp, &metadata)
+	if err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
+func (client *SchemaRegistryClient) postSchema(ctx context.Context, uri string, schema string, references []SchemaReference) ([]byte, error) {
+	return client.postSchemaRequest(ctx, uri, schemaRequest{Schema: schema, References: references})
+}
+
+func (client *SchemaRegistryClient) postSchemaRequest(ctx context.Context, uri string, request schemaRequest) ([]byte, error) {
+	json, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	return client.httpCall(ctx, "POST", uri, bytes.NewBuffer(json))
+}
+
+// DeleteSubject deletes a subject. It should only be used in development.
+// The delete is soft, the schemas stay readable by id and the subject can be restored by registering it again.
 func (client *SchemaRegistryClient) DeleteSubject(subject string) error {
-	_, err := client.httpCall("DELETE", fmt.Sprintf(deleteSubject, subject), nil)
-	return err
+	return client.DeleteSubjectContext(context.Background(), subject)
+}
+
+// DeleteSubjectContext is DeleteSubject using the context for the registry requests
+func (client *SchemaRegistryClient) DeleteSubjectContext(ctx context.Context, subject string) error {
+	return client.deleteInternal(ctx, fmt.Sprintf(deleteSubject, subject), false)
 }
 
 // DeleteVersion deletes a subject. It should only be used in development
 func (client *SchemaRegistryClient) DeleteVersion(subject string, version int) error {
-	_, err := client.httpCall("DELETE", fmt.Sprintf(subjectByVersion, subject, fmt.Sprintf("%d", version)), nil)
+	return client.DeleteVersionContext(context.Background(), subject, version)
+}
+
+// DeleteVersionContext is DeleteVersion using the context for the registry requests
+func (client *SchemaRegistryClient) DeleteVersionContext(ctx context.Context, subject string, version int) error {
+	return client.deleteInternal(ctx, fmt.Sprintf(subjectByVersion, subject, fmt.Sprintf("%d", version)), false)
+}
+
+// DeleteSubjectPermanently hard deletes a subject and its schemas, the subject must have been soft deleted
+// with DeleteSubject first
+func (client *SchemaRegistryClient) DeleteSubjectPermanently(subject string) error {
+	return client.DeleteSubjectPermanentlyContext(context.Background(), subject)
+}
+
+// DeleteSubjectPermanentlyContext is DeleteSubjectPermanently using the context for the registry requests
+func (client *SchemaRegistryClient) DeleteSubjectPermanentlyContext(ctx context.Context, subject string) error {
+	return client.deleteInternal(ctx, fmt.Sprintf(deleteSubject, subject), true)
+}
+
+// DeleteVersionPermanently hard deletes a version of a subject, the version must have been soft deleted
+// with DeleteVersion first
+func (client *SchemaRegistryClient) DeleteVersionPermanently(subject string, version int) error {
+	return client.DeleteVersionPermanentlyContext(context.Background(), subject, version)
+}
+
+// DeleteVersionPermanentlyContext is DeleteVersionPermanently using the context for the registry requests
+func (client *SchemaRegistryClient) DeleteVersionPermanentlyContext(ctx context.Context, subject string, version int) error {
+	return client.deleteInternal(ctx, fmt.Sprintf(subjectByVersion, subject, fmt.Sprintf("%d", version)), true)
+}
+
+// normalized asks the registry to normalize the schema of a registration or lookup, if enabled
+func (client *SchemaRegistryClient) normalized(uri string) string {
+	if client.normalize {
+		return uri + "?normalize=true"
+	}
+	return uri
+}
+
+func (client *SchemaRegistryClient) deleteInternal(ctx context.Context, uri string, permanent bool) error {
+	if permanent {
+		uri += "?permanent=true"
+	}
+	_, err := client.httpCall(ctx, "DELETE", uri, nil)
+	return err
+}
+
+// ListDeletedSubjects returns all subjects, including the soft deleted ones
+func (client *SchemaRegistryClient) ListDeletedSubjects() ([]string, error) {
+	return client.ListDeletedSubjectsContext(context.Background())
+}
+
+// ListDeletedSubjectsContext is ListDeletedSubjects using the context for the registry requests
+func (client *SchemaRegistryClient) ListDeletedSubjectsContext(ctx context.Context) ([]string, error) {
+	resp, err := client.httpCall(ctx, "GET", subjects+"?deleted=true", nil)
+	if nil != err {
+		return []string{}, err
+	}
+	var result = []string{}
+	err = json.Unmarshal(resp, &result)
+	return result, err
+}
+
+// CheckCompatibility tests if the codec is compatible with the version of the subject,
+// according to the compatibility level of the subject
+func (client *SchemaRegistryClient) CheckCompatibility(subject string, version int, codec *goavro.Codec) (bool, error) {
+	return client.CheckCompatibilityContext(context.Background(), subject, version, codec)
+}
+
+// CheckCompatibilityContext is CheckCompatibility using the context for the registry requests
+func (client *SchemaRegistryClient) CheckCompatibilityContext(ctx context.Context, subject string, version int, codec *goavro.Codec) (bool, error) {
+	return client.checkCompatibilityInternal(ctx, subject, fmt.Sprintf("%d", version), codec)
+}
+
+// CheckLatestCompatibility tests if the codec is compatible with the latest version of the subject
+func (client *SchemaRegistryClient) CheckLatestCompatibility(subject string, codec *goavro.Codec) (bool, error) {
+	return client.CheckLatestCompatibilityContext(context.Background(), subject, codec)
+}
+
+// CheckLatestCompatibilityContext is CheckLatestCompatibility using the context for the registry requests
+func (client *SchemaRegistryClient) CheckLatestCompatibilityContext(ctx context.Context, subject string, codec *goavro.Codec) (bool, error) {
+	return client.checkCompatibilityInternal(ctx, subject, latestVersion, codec)
+}
+
+func (client *SchemaRegistryClient) checkCompatibilityInternal(ctx context.Context, subject string, version string, codec *goavro.Codec) (bool, error) {
+	resp, err := client.postSchema(ctx, fmt.Sprintf(compatibility, subject, version), codec.Schema(), nil)
+	if err != nil {
+		return false, err
+	}
+	var result = new(compatibilityResponse)
+	err = json.Unmarshal(resp, &result)
+	if err != nil {
+		return false, err
+	}
+	return result.IsCompatible, nil
+}
+
+// GetCompatibility returns the compatibility level of the subject, the global level if the subject has none
+func (client *SchemaRegistryClient) GetCompatibility(subject string) (string, error) {
+	return client.GetCompatibilityContext(context.Background(), subject)
+}
+
+// GetCompatibilityContext is GetCompatibility using the context for the registry requests
+func (client *SchemaRegistryClient) GetCompatibilityContext(ctx context.Context, subject string) (string, error) {
+	return client.getConfigInternal(ctx, fmt.Sprintf(subjectConfig, subject)+"?defaultToGlobal=true")
+}
+
+// SetCompatibility sets the compatibility level of the subject
+func (client *SchemaRegistryClient) SetCompatibility(subject string, level string) error {
+	return client.SetCompatibilityContext(context.Background(), subject, level)
+}
+
+// SetCompatibilityContext is SetCompatibility using the context for the registry requests
+func (client *SchemaRegistryClient) SetCompatibilityContext(ctx context.Context, subject string, level string) error {
+	return client.setConfigInternal(ctx, fmt.Sprintf(subjectConfig, subject), level)
+}
+
+// GetGlobalCompatibility returns the compatibility level of the subjects without their own level
+func (client *SchemaRegistryClient) GetGlobalCompatibility() (string, error) {
+	return client.GetGlobalCompatibilityContext(context.Background())
+}
+
+// GetGlobalCompatibilityContext is GetGlobalCompatibility using the context for the registry requests
+func (client *SchemaRegistryClient) GetGlobalCompatibilityContext(ctx context.Context) (string, error) {
+	return client.getConfigInternal(ctx, globalConfig)
+}
+
+// SetGlobalCompatibility sets the compatibility level of the subjects without their own level
+func (client *SchemaRegistryClient) SetGlobalCompatibility(level string) error {
+	return client.SetGlobalCompatibilityContext(context.Background(), level)
+}
+
+// SetGlobalCompatibilityContext is SetGlobalCompatibility using the context for the registry requests
+func (client *SchemaRegistryClient) SetGlobalCompatibilityContext(ctx context.Context, level string) error {
+	return client.setConfigInternal(ctx, globalConfig, level)
+}
+
+func (client *SchemaRegistryClient) getConfigInternal(ctx context.Context, uri string) (string, error) {
+	resp, err := client.httpCall(ctx, "GET", uri, nil)
+	if err != nil {
+		return "", err
+	}
+	var config = new(configResponse)
+	err = json.Unmarshal(resp, &config)
+	if err != nil {
+		return "", err
+	}
+	return config.CompatibilityLevel, nil
+}
+
+func (client *SchemaRegistryClient) setConfigInternal(ctx context.Context, uri string, level string) error {
+	json, err := json.Marshal(configRequest{level})
+	if err != nil {
+		return err
+	}
+	_, err = client.httpCall(ctx, "PUT", uri, bytes.NewBuffer(json))
 	return err
 }
 
-func parseSchema(str []byte) (*schemaResponse, error) {
-	var schema = new(schemaResponse)
-	err := json.Unmarshal(str, &schema)
-	return schema, err
+// GetMode returns the mode of the subject
+func (client *SchemaRegistryClient) GetMode(subject string) (string, error) {
+	return client.GetModeContext(context.Background(), subject)
+}
+
+// GetModeContext is GetMode using the context for the registry requests
+func (client *SchemaRegistryClient) GetModeContext(ctx context.Context, subject string) (string, error) {
+	return client.getModeInternal(ctx, fmt.Sprintf(subjectMode, subject))
+}
+
+// SetMode sets the mode of the subject, e.g. IMPORT to register schemas with explicit ids
+func (client *SchemaRegistryClient) SetMode(subject string, mode string) error {
+	return client.SetModeContext(context.Background(), subject, mode)
+}
+
+// SetModeContext is SetMode using the context for the registry requests
+func (client *SchemaRegistryClient) SetModeContext(ctx context.Context, subject string, mode string) error {
+	return client.setModeInternal(ctx, fmt.Sprintf(subjectMode, subject), mode)
+}
+
+// GetGlobalMode returns the mode of the registry
+func (client *SchemaRegistryClient) GetGlobalMode() (string, error) {
+	return client.GetGlobalModeContext(context.Background())
+}
+
+// GetGlobalModeContext is GetGlobalMode using the context for the registry requests
+func (client *SchemaRegistryClient) GetGlobalModeContext(ctx context.Context) (string, error) {
+	return client.getModeInternal(ctx, globalMode)
+}
+
+// SetGlobalMode sets the mode of the registry, the registry must be empty to switch it to IMPORT
+func (client *SchemaRegistryClient) SetGlobalMode(mode string) error {
+	return client.SetGlobalModeContext(context.Background(), mode)
+}
+
+// SetGlobalModeContext is SetGlobalMode using the context for the registry requests
+func (client *SchemaRegistryClient) SetGlobalModeContext(ctx context.Context, mode string) error {
+	return client.setModeInternal(ctx, globalMode, mode)
+}
+
+func (client *SchemaRegistryClient) getModeInternal(ctx context.Context, uri string) (string, error) {
+	resp, err := client.httpCall(ctx, "GET", uri, nil)
+	if err != nil {
+		return "", err
+	}
+	var mode = new(modeMessage)
+	err = json.Unmarshal(resp, &mode)
+	if err != nil {
+		return "", err
+	}
+	return mode.Mode, nil
+}
+
+func (client *SchemaRegistryClient) setModeInternal(ctx context.Context, uri string, mode string) error {
+	json, err := json.Marshal(modeMessage{mode})
+	if err != nil {
+		return err
+	}
+	_, err = client.httpCall(ctx, "PUT", uri, bytes.NewBuffer(json))
+	return err
+}
+
+// Ping checks that the schema registry is reachable and accepts our credentials.
+// Use IsAuthError on the result to distinguish auth failures from connectivity problems.
+func (client *SchemaRegistryClient) Ping() error {
+	return client.PingContext(context.Background())
+}
+
+// PingContext is Ping using the context for the registry requests
+func (client *SchemaRegistryClient) PingContext(ctx context.Context) error {
+	_, err := client.httpCall(ctx, "GET", root, nil)
+	return err
+}
+
+// ServerInfo returns the version and commit id of the schema registry
+func (client *SchemaRegistryClient) ServerInfo() (*ServerInfo, error) {
+	return client.ServerInfoContext(context.Background())
+}
+
+// ServerInfoContext is ServerInfo using the context for the registry requests
+func (client *SchemaRegistryClient) ServerInfoContext(ctx context.Context) (*ServerInfo, error) {
+	resp, err := client.httpCall(ctx, "GET", metadataVersion, nil)
+	if nil != err {
+		return nil, err
+	}
+	var info = new(ServerInfo)
+	err = json.Unmarshal(resp, &info)
+	if nil != err {
+		return nil, err
+	}
+	return info, nil
 }
 
 func parseID(str []byte) (int, error) {
@@ -191,21 +822,73 @@ func parseID(str []byte) (int, error) {
 	return id.ID, err
 }
 
-func (client *SchemaRegistryClient) httpCall(method, uri string, payload io.Reader) ([]byte, error) {
-	nServers := len(client.SchemaRegistryConnect)
-	offset := rand.Intn(nServers)
+func (client *SchemaRegistryClient) httpCall(ctx context.Context, method, uri string, payload io.Reader) ([]byte, error) {
+	if client.breaker == nil {
+		return client.roundTrip(ctx, method, uri, payload)
+	}
+	if err := client.breaker.allow(); err != nil {
+		orNop(client.logger).Debug("schema registry circuit breaker is open", "method", method, "uri", uri)
+		return nil, err
+	}
+	resp, err := client.roundTrip(ctx, method, uri, payload)
+	if ctx.Err() != nil {
+		client.breaker.release()
+	} else {
+		client.breaker.record(registryUnavailable(err))
+	}
+	return resp, err
+}
+
+func (client *SchemaRegistryClient) roundTrip(ctx context.Context, method, uri string, payload io.Reader) ([]byte, error) {
+	servers := client.endpoints.order(client.SchemaRegistryConnect)
+	// the payload is read once so that retries send it again
+	var body []byte
+	if payload != nil {
+		var err error
+		if body, err = ioutil.ReadAll(payload); err != nil {
+			return nil, err
+		}
+	}
 	for i := 0; ; i++ {
-		url := fmt.Sprintf("%s%s", client.SchemaRegistryConnect[(i+offset)%nServers], uri)
-		req, err := http.NewRequest(method, url, payload)
+		server := servers[i%len(servers)]
+		var reader io.Reader
+		if payload != nil {
+			reader = bytes.NewReader(body)
+		}
+		req, err := http.NewRequest(method, fmt.Sprintf("%s%s", server, uri), reader)
 		if err != nil {
 			return nil, err
 		}
+		req = req.WithContext(ctx)
 		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("Accept", contentType)
+		for _, header := range client.headers {
+			if err := header(req); err != nil {
+				return nil, err
+			}
+		}
+		if client.authorize != nil {
+			if err := client.authorize(req); err != nil {
+				return nil, err
+			}
+		}
+		start := time.Now()
 		resp, err := client.httpClient.Do(req)
 		if resp != nil {
 			defer resp.Body.Close()
 		}
-		if i < client.retries && (err != nil || retriable(resp)) {
+		if client.metrics != nil {
+			client.metrics.RegistryRequest(method, statusCode(resp), time.Since(start))
+		}
+		if ctx.Err() == nil {
+			client.endpoints.record(server, err != nil || retriable(resp))
+		}
+		if i < client.retries && ctx.Err() == nil && (err != nil || retriable(resp)) {
+			if client.metrics != nil {
+				client.metrics.RegistryRetry(method)
+			}
+			orNop(client.logger).Warn("retrying schema registry request", "method", method, "url", req.URL.String(),
+				"attempt", i+1, "status", statusCode(resp), "error", err)
 			continue
 		}
 		if err != nil {
@@ -218,6 +901,14 @@ func (client *SchemaRegistryClient) httpCall(method, uri string, payload io.Read
 	}
 }
 
+// statusCode returns the status code of the response, 0 when the request failed
+func statusCode(resp *http.Response) int {
+	if resp == nil {
+		return 0
+	}
+	return resp.StatusCode
+}
+
 func retriable(resp *http.Response) bool {
 	return resp.StatusCode >= 500 && resp.StatusCode < 600
 }
@@ -0,0 +1,211 @@
+package kafka
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// SchemaRegistryOption configures a schema registry client at construction
+type SchemaRegistryOption func(*SchemaRegistryClient)
+
+// WithRegistryTLS sets the TLS configuration of the registry connections, e.g. a custom CA for self-signed
+// certificates, or Certificates for mutual TLS
+func WithRegistryTLS(tlsConfig *tls.Config) SchemaRegistryOption {
+	return func(client *SchemaRegistryClient) {
+		client.transport().TLSClientConfig = tlsConfig
+	}
+}
+
+// WithRegistryTransport replaces the http transport of the registry client
+func WithRegistryTransport(transport http.RoundTripper) SchemaRegistryOption {
+	return func(client *SchemaRegistryClient) {
+		client.httpClient.Transport = transport
+	}
+}
+
+// WithRegistryBasicAuth authenticates every registry request with http basic auth
+func WithRegistryBasicAuth(username string, password string) SchemaRegistryOption {
+	return func(client *SchemaRegistryClient) {
+		client.authorize = func(req *http.Request) error {
+			req.SetBasicAuth(username, password)
+			return nil
+		}
+	}
+}
+
+// TokenProvider returns the bearer token of a registry request, it is called for every request
+// so expiring tokens can be refreshed
+type TokenProvider interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// TokenProviderFunc is a function implementing TokenProvider
+type TokenProviderFunc func(ctx context.Context) (string, error)
+
+// Token implements TokenProvider
+func (f TokenProviderFunc) Token(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// WithRegistryBearerToken authenticates every registry request with a bearer token from the provider
+func WithRegistryBearerToken(provider TokenProvider) SchemaRegistryOption {
+	return func(client *SchemaRegistryClient) {
+		client.authorize = func(req *http.Request) error {
+			token, err := provider.Token(req.Context())
+			if err != nil {
+				return fmt.Errorf("could not get registry token: %s", err)
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+			return nil
+		}
+	}
+}
+
+// WithConfluentCloud authenticates to a Confluent Cloud schema registry with a schema registry API key and secret.
+// Cloud registries only accept TLS, so the connect urls must use https.
+func WithConfluentCloud(apiKey string, apiSecret string) SchemaRegistryOption {
+	return WithRegistryBasicAuth(apiKey, apiSecret)
+}
+
+// WithRegistryNormalize makes the registry normalize schemas when they are registered or looked up,
+// so logically identical schemas with a different field order or whitespace do not register new versions.
+// It requires Confluent Platform 7.1 or later.
+func WithRegistryNormalize() SchemaRegistryOption {
+	return func(client *SchemaRegistryClient) {
+		client.normalize = true
+	}
+}
+
+// WithRegistryHeader sets a static header on every registry request, e.g. a tenant header required by a proxy
+func WithRegistryHeader(key string, value string) SchemaRegistryOption {
+	return WithRegistryHeaderFunc(func(req *http.Request) error {
+		req.Header.Set(key, value)
+		return nil
+	})
+}
+
+// WithRegistryHeaderFunc calls the injector on every registry request before it is sent, so headers
+// can be derived from the request context, e.g. an X-Request-ID. An error aborts the request.
+func WithRegistryHeaderFunc(injector func(req *http.Request) error) SchemaRegistryOption {
+	return func(client *SchemaRegistryClient) {
+		client.headers = append(client.headers, injector)
+	}
+}
+
+// WithRegistryUserAgent sets the User-Agent of the registry requests
+func WithRegistryUserAgent(userAgent string) SchemaRegistryOption {
+	return WithRegistryHeader("User-Agent", userAgent)
+}
+
+// WithRegistryHTTPClient replaces the http client of the registry client, the other transport options modify it
+func WithRegistryHTTPClient(httpClient *http.Client) SchemaRegistryOption {
+	return func(client *SchemaRegistryClient) {
+		client.httpClient = httpClient
+	}
+}
+
+// WithRegistryTimeout sets the timeout of a registry request, including retries it may take retries+1 times as long
+func WithRegistryTimeout(timeout time.Duration) SchemaRegistryOption {
+	return func(client *SchemaRegistryClient) {
+		client.httpClient.Timeout = timeout
+	}
+}
+
+// WithRegistryDialTimeout sets how long to wait for a connection to the registry
+func WithRegistryDialTimeout(timeout time.Duration) SchemaRegistryOption {
+	return func(client *SchemaRegistryClient) {
+		client.dialer().Timeout = timeout
+	}
+}
+
+// WithRegistryKeepAlive sets the keep-alive period of the registry connections, a negative period disables keep-alives
+func WithRegistryKeepAlive(period time.Duration) SchemaRegistryOption {
+	return func(client *SchemaRegistryClient) {
+		client.dialer().KeepAlive = period
+		client.transport().DisableKeepAlives = period < 0
+	}
+}
+
+// WithRegistryProxy sends the registry requests through the proxy instead of the one of the
+// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables, which are respected by default
+func WithRegistryProxy(proxyURL *url.URL) SchemaRegistryOption {
+	return func(client *SchemaRegistryClient) {
+		client.transport().Proxy = http.ProxyURL(proxyURL)
+	}
+}
+
+// WithRegistryDialContext opens the registry connections with dial, e.g. to tunnel them.
+// The dial timeout and keep-alive options do not apply to a custom dial function.
+func WithRegistryDialContext(dial func(ctx context.Context, network, addr string) (net.Conn, error)) SchemaRegistryOption {
+	return func(client *SchemaRegistryClient) {
+		client.transport().DialContext = dial
+	}
+}
+
+// WithRegistryMaxIdleConns sets how many idle connections are kept open to each registry server
+func WithRegistryMaxIdleConns(n int) SchemaRegistryOption {
+	return func(client *SchemaRegistryClient) {
+		transport := client.transport()
+		transport.MaxIdleConns = n
+		transport.MaxIdleConnsPerHost = n
+	}
+}
+
+func (client *SchemaRegistryClient) applyOptions(opts []SchemaRegistryOption) *SchemaRegistryClient {
+	for _, opt := range opts {
+		opt(client)
+	}
+	if client.probe != nil && client.endpoints != nil {
+		go client.probeEndpoints(client.probe)
+	}
+	return client
+}
+
+// transport returns the http.Transport of the client, creating one with the defaults of http.DefaultTransport
+func (client *SchemaRegistryClient) transport() *http.Transport {
+	if transport, ok := client.httpClient.Transport.(*http.Transport); ok {
+		return transport
+	}
+	if client.netDialer == nil {
+		client.netDialer = defaultDialer()
+	}
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           client.netDialer.DialContext,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+	client.httpClient.Transport = transport
+	return transport
+}
+
+// dialer returns the dialer of the registry connections, installing one on the transport if needed
+func (client *SchemaRegistryClient) dialer() *net.Dialer {
+	if client.netDialer == nil {
+		client.netDialer = defaultDialer()
+		client.transport().DialContext = client.netDialer.DialContext
+	}
+	return client.netDialer
+}
+
+// defaultDialer returns a dialer with the defaults of http.DefaultTransport
+func defaultDialer() *net.Dialer {
+	return &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+}
+
+// WithSchemaRegistryOptions configures the schema registry client of a producer or a consumer
+func WithSchemaRegistryOptions(opts ...SchemaRegistryOption) Option {
+	return func(o *options) {
+		o.registry = append(o.registry, opts...)
+	}
+}
@@ -0,0 +1,220 @@
+package kafka
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+func TestSchemaRegistryClient_WithRegistryTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{}`)
+	}))
+	defer server.Close()
+
+	if err := NewSchemaRegistryClient([]string{server.URL}).Ping(); err == nil {
+		t.Errorf("Expected self-signed certificate to be rejected")
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+	client := NewSchemaRegistryClient([]string{server.URL}, WithRegistryTLS(&tls.Config{RootCAs: pool}))
+	if err := client.Ping(); err != nil {
+		t.Errorf("Expected trusted certificate to be accepted, got %v", err)
+	}
+}
+
+func TestSchemaRegistryClient_WithRegistryTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{}`)
+	}))
+	defer server.Close()
+	called := false
+	transport := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		called = true
+		return http.DefaultTransport.RoundTrip(r)
+	})
+	options := applyOptions(defaultAvroProducerConfig(), []Option{WithSchemaRegistryOptions(WithRegistryTransport(transport))})
+	if err := NewSchemaRegistryClient([]string{server.URL}, options.registry...).Ping(); err != nil || !called {
+		t.Errorf("Expected the custom transport to be used, got %v", err)
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestSchemaRegistryClient_WithConfluentCloud(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, secret, ok := r.BasicAuth()
+		if !ok || key != "key" || secret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error_code": 401, "message": "Unauthorized"}`)
+			return
+		}
+		if r.Header.Get("Accept") != contentType {
+			t.Errorf("Expected Accept %s, got %s", contentType, r.Header.Get("Accept"))
+		}
+		fmt.Fprint(w, `{}`)
+	}))
+	defer server.Close()
+
+	if err := NewSchemaRegistryClient([]string{server.URL}).Ping(); !IsAuthError(err) {
+		t.Errorf("Expected auth error without credentials, got %v", err)
+	}
+	client := NewSchemaRegistryClient([]string{server.URL}, WithConfluentCloud("key", "secret"))
+	if err := client.Ping(); err != nil {
+		t.Errorf("Expected credentials to be accepted, got %v", err)
+	}
+}
+
+func TestSchemaRegistryClient_WithRegistryBearerToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token-2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error_code": 401, "message": "Unauthorized"}`)
+			return
+		}
+		fmt.Fprint(w, `{}`)
+	}))
+	defer server.Close()
+
+	calls := 0
+	provider := TokenProviderFunc(func(ctx context.Context) (string, error) {
+		calls++
+		return fmt.Sprintf("token-%d", calls), nil
+	})
+	client := NewSchemaRegistryClientWithRetries([]string{server.URL}, 0, WithRegistryBearerToken(provider))
+	if err := client.Ping(); !IsAuthError(err) {
+		t.Errorf("Expected the first token to be rejected, got %v", err)
+	}
+	if err := client.Ping(); err != nil {
+		t.Errorf("Expected the refreshed token to be accepted, got %v", err)
+	}
+
+	failing := TokenProviderFunc(func(ctx context.Context) (string, error) {
+		return "", fmt.Errorf("token endpoint unavailable")
+	})
+	client = NewSchemaRegistryClient([]string{server.URL}, WithRegistryBearerToken(failing))
+	if err := client.Ping(); err == nil {
+		t.Errorf("Expected the provider error to be returned")
+	}
+}
+
+func TestSchemaRegistryClient_TransportOptions(t *testing.T) {
+	client := NewSchemaRegistryClient([]string{"http://localhost"},
+		WithRegistryTimeout(5*time.Second),
+		WithRegistryDialTimeout(time.Second),
+		WithRegistryKeepAlive(-1),
+		WithRegistryMaxIdleConns(10))
+	if client.httpClient.Timeout != 5*time.Second {
+		t.Errorf("Expected request timeout 5s, got %s", client.httpClient.Timeout)
+	}
+	if client.netDialer.Timeout != time.Second || client.netDialer.KeepAlive != -1 {
+		t.Errorf("Expected dial timeout 1s without keep-alive, got %+v", client.netDialer)
+	}
+	transport := client.transport()
+	if !transport.DisableKeepAlives || transport.MaxIdleConns != 10 || transport.MaxIdleConnsPerHost != 10 {
+		t.Errorf("Expected the transport to be configured, got %+v", transport)
+	}
+
+	httpClient := &http.Client{Timeout: time.Minute}
+	client = NewSchemaRegistryClient([]string{"http://localhost"}, WithRegistryHTTPClient(httpClient))
+	if client.httpClient != httpClient {
+		t.Errorf("Expected the custom http client to be used")
+	}
+}
+
+func TestSchemaRegistryClient_WithRegistryNormalize(t *testing.T) {
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		fmt.Fprint(w, `{"id": 1}`)
+	}))
+	defer server.Close()
+	codec, _ := goavro.NewCodec(`"string"`)
+	NewSchemaRegistryClient([]string{server.URL}).CreateSubject("test-value", codec)
+	client := NewSchemaRegistryClient([]string{server.URL}, WithRegistryNormalize())
+	client.CreateSubject("test-value", codec)
+	client.IsSchemaRegistered("test-value", codec)
+	expected := []string{"", "normalize=true", "normalize=true"}
+	if !reflect.DeepEqual(queries, expected) {
+		t.Errorf("Expected %v, got %v", expected, queries)
+	}
+}
+
+type requestIDKey struct{}
+
+func TestSchemaRegistryClient_WithRegistryHeaders(t *testing.T) {
+	var headers http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header
+		fmt.Fprint(w, `[]`)
+	}))
+	defer server.Close()
+	client := NewSchemaRegistryClient([]string{server.URL},
+		WithRegistryHeader("X-Tenant", "payments"),
+		WithRegistryUserAgent("orders/1.0"),
+		WithRegistryHeaderFunc(func(req *http.Request) error {
+			if id, ok := req.Context().Value(requestIDKey{}).(string); ok {
+				req.Header.Set("X-Request-ID", id)
+			}
+			return nil
+		}))
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "42")
+	if _, err := client.GetSubjectsContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if headers.Get("X-Tenant") != "payments" || headers.Get("User-Agent") != "orders/1.0" || headers.Get("X-Request-ID") != "42" {
+		t.Errorf("Expected the custom headers to be sent, got %v", headers)
+	}
+
+	failing := NewSchemaRegistryClient([]string{server.URL}, WithRegistryHeaderFunc(func(req *http.Request) error {
+		return fmt.Errorf("no request id")
+	}))
+	if _, err := failing.GetSubjects(); err == nil {
+		t.Errorf("Expected the injector error to abort the request")
+	}
+}
+
+func TestSchemaRegistryClient_WithRegistryProxy(t *testing.T) {
+	var requested string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.String()
+		fmt.Fprint(w, `[]`)
+	}))
+	defer proxy.Close()
+	proxyURL, _ := url.Parse(proxy.URL)
+	client := NewSchemaRegistryClient([]string{"http://registry.invalid"}, WithRegistryProxy(proxyURL))
+	if _, err := client.GetSubjects(); err != nil || requested != "http://registry.invalid/subjects" {
+		t.Errorf("Expected the request to go through the proxy, got %s, %v", requested, err)
+	}
+}
+
+func TestSchemaRegistryClient_WithRegistryDialContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[]`)
+	}))
+	defer server.Close()
+	var dialed string
+	dial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dialed = addr
+		return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+	}
+	client := NewSchemaRegistryClient([]string{"http://registry.invalid:8081"},
+		WithRegistryDialContext(dial), WithRegistryDialTimeout(time.Second))
+	if _, err := client.GetSubjects(); err != nil || dialed != "registry.invalid:8081" {
+		t.Errorf("Expected the custom dialer to be used, got %s, %v", dialed, err)
+	}
+}
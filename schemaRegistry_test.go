@@ -1,6 +1,7 @@
 package kafka
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -8,6 +9,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/linkedin/goavro/v2"
 )
@@ -128,3 +130,275 @@ func TestSchemaRegistryClient_Error(t *testing.T) {
 		t.Errorf("Expected error to be %s, got %s", expectedErr.Error(), err.Error())
 	}
 }
+
+func TestSchemaRegistryClient_PingAndServerInfo(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.String() {
+		case root:
+			fmt.Fprintf(w, "{}")
+		case metadataVersion:
+			fmt.Fprintf(w, `{"version": "5.3.0", "commitId": "abc"}`)
+		}
+	}))
+	defer mockServer.Close()
+	client := NewSchemaRegistryClient([]string{mockServer.URL})
+	if err := client.Ping(); err != nil {
+		t.Errorf("Error pinging: %v", err)
+	}
+	info, err := client.ServerInfo()
+	if err != nil {
+		t.Errorf("Error getting server info: %v", err)
+	}
+	if info.Version != "5.3.0" || info.CommitID != "abc" {
+		t.Errorf("Unexpected server info: %+v", info)
+	}
+}
+
+func TestSchemaRegistryClient_PingAuthError(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error_code": 40101, "message": "Unauthorized"}`, 401)
+	}))
+	defer mockServer.Close()
+	client := NewSchemaRegistryClient([]string{mockServer.URL})
+	err := client.Ping()
+	if !IsAuthError(err) {
+		t.Errorf("Expected auth error, got %v", err)
+	}
+	if IsAuthError(&Error{500, "Error in the backend datastore"}) {
+		t.Errorf("Expected server error not to be an auth error")
+	}
+}
+
+func TestSchemaRegistryClient_Context(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	var client SchemaRegistryClientContextInterface = NewCachedSchemaRegistryClient([]string{server.URL})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := client.GetSchemaContext(ctx, 1); err == nil {
+		t.Errorf("Expected the lookup to be cancelled")
+	}
+	if elapsed := time.Since(start); elapsed >= timeout {
+		t.Errorf("Expected the lookup to stop at the deadline, took %s", elapsed)
+	}
+}
+
+func TestSchemaRegistryClient_CheckCompatibility(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request schemaRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		switch r.URL.Path {
+		case fmt.Sprintf(compatibility, "test-value", "1"):
+			fmt.Fprintf(w, `{"is_compatible": %t}`, request.Schema == `"string"`)
+		case fmt.Sprintf(compatibility, "test-value", latestVersion):
+			fmt.Fprint(w, `{"is_compatible": false}`)
+		default:
+			http.Error(w, `{"error_code": 40401, "message": "Subject not found"}`, http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	client := NewCachedSchemaRegistryClient([]string{server.URL})
+	compatible, _ := goavro.NewCodec(`"string"`)
+	incompatible, _ := goavro.NewCodec(`"int"`)
+
+	if ok, err := client.CheckCompatibility("test-value", 1, compatible); err != nil || !ok {
+		t.Errorf("Expected compatible schema, got %t, %v", ok, err)
+	}
+	if ok, err := client.CheckCompatibility("test-value", 1, incompatible); err != nil || ok {
+		t.Errorf("Expected incompatible schema, got %t, %v", ok, err)
+	}
+	if ok, err := client.CheckLatestCompatibility("test-value", compatible); err != nil || ok {
+		t.Errorf("Expected incompatible with the latest version, got %t, %v", ok, err)
+	}
+	if _, err := client.CheckCompatibility("unknown-value", 1, compatible); !IsNotFoundError(err) {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+}
+
+func TestSchemaRegistryClient_Compatibility(t *testing.T) {
+	levels := map[string]string{globalConfig: CompatibilityBackward}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "PUT":
+			var request configRequest
+			json.NewDecoder(r.Body).Decode(&request)
+			levels[r.URL.Path] = request.Compatibility
+			fmt.Fprintf(w, `{"compatibility": "%s"}`, request.Compatibility)
+		case "GET":
+			level, ok := levels[r.URL.Path]
+			if !ok && r.URL.Query().Get("defaultToGlobal") == "true" {
+				level, ok = levels[globalConfig]
+			}
+			if !ok {
+				http.Error(w, `{"error_code": 40408, "message": "Subject does not have subject-level compatibility configured"}`, http.StatusNotFound)
+				return
+			}
+			fmt.Fprintf(w, `{"compatibilityLevel": "%s"}`, level)
+		}
+	}))
+	defer server.Close()
+	client := NewCachedSchemaRegistryClient([]string{server.URL})
+
+	if level, err := client.GetCompatibility("test-value"); err != nil || level != CompatibilityBackward {
+		t.Errorf("Expected the global level, got %s, %v", level, err)
+	}
+	if err := client.SetCompatibility("test-value", CompatibilityFullTransitive); err != nil {
+		t.Fatal(err)
+	}
+	if level, err := client.GetCompatibility("test-value"); err != nil || level != CompatibilityFullTransitive {
+		t.Errorf("Expected the subject level, got %s, %v", level, err)
+	}
+	if err := client.SetGlobalCompatibility(CompatibilityNone); err != nil {
+		t.Fatal(err)
+	}
+	if level, err := client.GetGlobalCompatibility(); err != nil || level != CompatibilityNone {
+		t.Errorf("Expected the new global level, got %s, %v", level, err)
+	}
+}
+
+func TestSchemaRegistryClient_Mode(t *testing.T) {
+	modes := map[string]string{globalMode: ModeReadWrite}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PUT" {
+			var request modeMessage
+			json.NewDecoder(r.Body).Decode(&request)
+			modes[r.URL.Path] = request.Mode
+		}
+		fmt.Fprintf(w, `{"mode": "%s"}`, modes[r.URL.Path])
+	}))
+	defer server.Close()
+	client := NewCachedSchemaRegistryClient([]string{server.URL})
+
+	if mode, err := client.GetGlobalMode(); err != nil || mode != ModeReadWrite {
+		t.Errorf("Expected READWRITE, got %s, %v", mode, err)
+	}
+	if err := client.SetGlobalMode(ModeReadOnly); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.SetMode("test-value", ModeImport); err != nil {
+		t.Fatal(err)
+	}
+	if mode, err := client.GetMode("test-value"); err != nil || mode != ModeImport {
+		t.Errorf("Expected IMPORT, got %s, %v", mode, err)
+	}
+	if modes[globalMode] != ModeReadOnly {
+		t.Errorf("Expected READONLY, got %s", modes[globalMode])
+	}
+}
+
+func TestSchemaRegistryClient_CreateSubjectWithID(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request schemaRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		if r.URL.Path != fmt.Sprintf(subjectVersions, "test-value") || request.Version != 3 {
+			http.Error(w, `{"error_code": 42205, "message": "Subject is not in import mode"}`, http.StatusUnprocessableEntity)
+			return
+		}
+		fmt.Fprintf(w, `{"id": %d}`, request.ID)
+	}))
+	defer server.Close()
+	client := NewCachedSchemaRegistryClient([]string{server.URL})
+	codec, _ := goavro.NewCodec(`"string"`)
+	if id, err := client.CreateSubjectWithID("test-value", codec, 1234, 3); err != nil || id != 1234 {
+		t.Errorf("Expected the explicit id, got %d, %v", id, err)
+	}
+	if id, err := client.CreateSubject("test-value", codec); err != nil || id != 1234 {
+		t.Errorf("Expected the cached id, got %d, %v", id, err)
+	}
+}
+
+func TestSchemaRegistryClient_DeletePermanently(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.String())
+		if r.Method == "GET" {
+			fmt.Fprint(w, `["test-value", "deleted-value"]`)
+			return
+		}
+		fmt.Fprint(w, `[1]`)
+	}))
+	defer server.Close()
+	client := NewCachedSchemaRegistryClient([]string{server.URL})
+	client.DeleteSubject("test-value")
+	client.DeleteSubjectPermanently("test-value")
+	client.DeleteVersion("test-value", 2)
+	client.DeleteVersionPermanently("test-value", 2)
+	subjects, err := client.ListDeletedSubjects()
+	if err != nil || len(subjects) != 2 {
+		t.Errorf("Expected deleted subjects to be listed, got %v, %v", subjects, err)
+	}
+	expected := []string{
+		"DELETE /subjects/test-value",
+		"DELETE /subjects/test-value?permanent=true",
+		"DELETE /subjects/test-value/versions/2",
+		"DELETE /subjects/test-value/versions/2?permanent=true",
+		"GET /subjects?deleted=true",
+	}
+	if !reflect.DeepEqual(requests, expected) {
+		t.Errorf("Expected %v, got %v", expected, requests)
+	}
+}
+
+func TestSchemaRegistryClient_SchemaMetadataAccessors(t *testing.T) {
+	count := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count++
+		switch r.URL.Path {
+		case "/subjects/test-value/versions/2":
+			fmt.Fprint(w, `{"subject": "test-value", "version": 2, "id": 7, "schemaType": "JSON", "schema": "{}"}`)
+		case "/schemas/ids/7":
+			if r.URL.Query().Get("subject") != "test-value" {
+				t.Errorf("Expected the subject to be passed, got %s", r.URL.RawQuery)
+			}
+			fmt.Fprint(w, `{"schemaType": "JSON", "schema": "{}"}`)
+		}
+	}))
+	defer server.Close()
+	client := NewCachedSchemaRegistryClient([]string{server.URL})
+
+	metadata, err := client.GetSchemaMetadata("test-value", 2)
+	if err != nil || metadata.ID != 7 || metadata.Version != 2 || metadata.SchemaType != "JSON" {
+		t.Errorf("Unexpected schema object %+v, %v", metadata, err)
+	}
+	client.GetSchemaMetadata("test-value", 2)
+	if count != 1 {
+		t.Errorf("Expected the schema object to be cached, got %d requests", count)
+	}
+	metadata, err = client.GetSchemaBySubjectAndID("test-value", 7)
+	if err != nil || metadata.ID != 7 || metadata.Subject != "test-value" || metadata.Schema != "{}" {
+		t.Errorf("Unexpected schema object %+v, %v", metadata, err)
+	}
+	client.InvalidateSubject("test-value")
+	client.GetSchemaMetadata("test-value", 2)
+	if count != 3 {
+		t.Errorf("Expected the invalidated schema object to be fetched again, got %d requests", count)
+	}
+}
+
+func TestSchemaRegistryClient_SchemaUsage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case fmt.Sprintf(referencedBy, "address", 1):
+			fmt.Fprint(w, `[3, 4]`)
+		case fmt.Sprintf(schemaVersions, 3):
+			fmt.Fprint(w, `[{"subject": "person-value", "version": 1}, {"subject": "customer-value", "version": 2}]`)
+		}
+	}))
+	defer server.Close()
+	client := NewCachedSchemaRegistryClient([]string{server.URL})
+	ids, err := client.GetReferencedBy("address", 1)
+	if err != nil || !reflect.DeepEqual(ids, []int{3, 4}) {
+		t.Errorf("Expected the referencing ids, got %v, %v", ids, err)
+	}
+	versions, err := client.GetVersionsForSchemaID(3)
+	expected := []SubjectVersion{{"person-value", 1}, {"customer-value", 2}}
+	if err != nil || !reflect.DeepEqual(versions, expected) {
+		t.Errorf("Expected %v, got %v, %v", expected, versions, err)
+	}
+}
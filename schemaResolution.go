@@ -0,0 +1,386 @@
+package kafka
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+// schemaResolver projects the values of a writer schema onto a reader schema following the avro schema resolution
+// rules: fields missing from the reader are dropped, fields missing from the writer get their reader default,
+// numbers are promoted, unions are matched by branch and enums fall back to the reader default symbol.
+type schemaResolver struct {
+	reader      *goavro.Codec
+	writer      interface{}
+	readerNode  interface{}
+	writerNamed namedSchemas
+	readerNamed namedSchemas
+}
+
+func newSchemaResolver(writer *goavro.Codec, reader *goavro.Codec) (*schemaResolver, error) {
+	writerNode, err := parseSchemaJSON(writer.Schema())
+	if err != nil {
+		return nil, err
+	}
+	readerNode, err := parseSchemaJSON(reader.Schema())
+	if err != nil {
+		return nil, err
+	}
+	resolver := &schemaResolver{reader: reader, writer: writerNode, readerNode: readerNode,
+		writerNamed: make(namedSchemas), readerNamed: make(namedSchemas)}
+	resolver.writerNamed.collect(writerNode, "")
+	resolver.readerNamed.collect(readerNode, "")
+	return resolver, nil
+}
+
+// resolve returns the native form of the reader schema for a native value of the writer schema
+func (r *schemaResolver) resolve(native interface{}) (interface{}, error) {
+	projected, err := r.project(r.writer, "", r.readerNode, "", native)
+	if err != nil {
+		return nil, err
+	}
+	// goavro fills in the defaults of the fields left out of the records
+	binaryValue, err := r.reader.BinaryFromNative(nil, projected)
+	if err != nil {
+		return nil, err
+	}
+	resolved, _, err := r.reader.NativeFromBinary(binaryValue)
+	return resolved, err
+}
+
+// deref returns the definition of a named type reference
+func deref(named namedSchemas, node interface{}, namespace string) (interface{}, string, error) {
+	name, ok := node.(string)
+	if !ok || primitiveTypes[name] {
+		return node, namespace, nil
+	}
+	fullName := qualifyName(name, namespace)
+	definition, ok := named[fullName]
+	if !ok {
+		return nil, "", fmt.Errorf("unknown named type: %s", fullName)
+	}
+	return definition, namespaceOf(fullName), nil
+}
+
+func (r *schemaResolver) project(writer interface{}, writerNamespace string, reader interface{}, readerNamespace string,
+	native interface{}) (interface{}, error) {
+	writer, writerNamespace, err := deref(r.writerNamed, writer, writerNamespace)
+	if err != nil {
+		return nil, err
+	}
+	reader, readerNamespace, err = deref(r.readerNamed, reader, readerNamespace)
+	if err != nil {
+		return nil, err
+	}
+	if branches, ok := writer.([]interface{}); ok {
+		branch, value, err := writerBranch(branches, writerNamespace, native)
+		if err != nil {
+			return nil, err
+		}
+		return r.project(branch, writerNamespace, reader, readerNamespace, value)
+	}
+	if branches, ok := reader.([]interface{}); ok {
+		for _, exact := range []bool{true, false} {
+			for _, branch := range branches {
+				definition, branchNamespace, err := deref(r.readerNamed, branch, readerNamespace)
+				if err != nil {
+					return nil, err
+				}
+				if !r.matches(writer, writerNamespace, definition, exact) {
+					continue
+				}
+				value, err := r.project(writer, writerNamespace, definition, branchNamespace, native)
+				if err != nil || value == nil {
+					return value, err
+				}
+				return goavro.Union(unionBranchName(branch, readerNamespace), value), nil
+			}
+		}
+		return nil, fmt.Errorf("no member of the reader union matches %s", typeName(writer))
+	}
+	writerType, readerType := typeName(writer), typeName(reader)
+	switch {
+	case primitiveTypes[writerType] || logicalTypeName(writer) != "":
+		return promote(writer, reader, native)
+	case writerType != readerType:
+		return nil, fmt.Errorf("cannot resolve %s to %s", writerType, readerType)
+	}
+	writerDefinition, _ := writer.(map[string]interface{})
+	readerDefinition, _ := reader.(map[string]interface{})
+	switch writerType {
+	case "record", "error":
+		record, ok := native.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected a record, got %T", native)
+		}
+		_, writerRecordNamespace := definedName(writerDefinition, writerNamespace)
+		_, readerRecordNamespace := definedName(readerDefinition, readerNamespace)
+		writerFields := make(map[string]map[string]interface{})
+		fields, _ := writerDefinition["fields"].([]interface{})
+		for _, f := range fields {
+			if field, ok := f.(map[string]interface{}); ok {
+				name, _ := field["name"].(string)
+				writerFields[name] = field
+			}
+		}
+		projected := make(map[string]interface{})
+		fields, _ = readerDefinition["fields"].([]interface{})
+		for _, f := range fields {
+			field, ok := f.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			name, _ := field["name"].(string)
+			writerField, found := writerFields[name]
+			for _, alias := range aliases(field) {
+				if found {
+					break
+				}
+				writerField, found = writerFields[alias]
+			}
+			if !found {
+				if _, ok := field["default"]; !ok {
+					return nil, fmt.Errorf("reader field %s is missing from the writer schema and has no default", name)
+				}
+				continue
+			}
+			writerName, _ := writerField["name"].(string)
+			value, err := r.project(writerField["type"], writerRecordNamespace, field["type"], readerRecordNamespace, record[writerName])
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %v", name, err)
+			}
+			projected[name] = value
+		}
+		return projected, nil
+	case "enum":
+		symbol, _ := native.(string)
+		symbols, _ := readerDefinition["symbols"].([]interface{})
+		for _, s := range symbols {
+			if s == symbol {
+				return symbol, nil
+			}
+		}
+		if fallback, ok := readerDefinition["default"].(string); ok {
+			return fallback, nil
+		}
+		return nil, fmt.Errorf("symbol %s is not in the reader enum", symbol)
+	case "fixed":
+		if writerDefinition["size"] != readerDefinition["size"] {
+			return nil, fmt.Errorf("cannot resolve fixed of size %v to size %v", writerDefinition["size"], readerDefinition["size"])
+		}
+		return native, nil
+	case "array":
+		items, _ := native.([]interface{})
+		projected := make([]interface{}, len(items))
+		for i, item := range items {
+			value, err := r.project(writerDefinition["items"], writerNamespace, readerDefinition["items"], readerNamespace, item)
+			if err != nil {
+				return nil, err
+			}
+			projected[i] = value
+		}
+		return projected, nil
+	case "map":
+		values, _ := native.(map[string]interface{})
+		projected := make(map[string]interface{}, len(values))
+		for key, item := range values {
+			value, err := r.project(writerDefinition["values"], writerNamespace, readerDefinition["values"], readerNamespace, item)
+			if err != nil {
+				return nil, err
+			}
+			projected[key] = value
+		}
+		return projected, nil
+	}
+	return nil, fmt.Errorf("unsupported schema: %v", writer)
+}
+
+// matches reports whether a writer type can be read as a reader union member, exactly or with promotion
+func (r *schemaResolver) matches(writer interface{}, writerNamespace string, reader interface{}, exact bool) bool {
+	writerType, readerType := typeName(writer), typeName(reader)
+	if primitiveTypes[writerType] || primitiveTypes[readerType] {
+		if logicalTypeName(writer) != logicalTypeName(reader) {
+			return false
+		}
+		if writerType == readerType {
+			return true
+		}
+		return !exact && promotable(writerType, readerType)
+	}
+	if writerType != readerType {
+		return false
+	}
+	switch writerType {
+	case "record", "error", "enum", "fixed":
+		writerName, _ := writer.(map[string]interface{})["name"].(string)
+		readerName, _ := reader.(map[string]interface{})["name"].(string)
+		return unqualified(writerName) == unqualified(readerName)
+	}
+	return true
+}
+
+// writerBranch returns the member of a writer union and the unwrapped value of a native union value
+func writerBranch(branches []interface{}, namespace string, native interface{}) (interface{}, interface{}, error) {
+	if native == nil {
+		return "null", nil, nil
+	}
+	wrapped, ok := native.(map[string]interface{})
+	if !ok || len(wrapped) != 1 {
+		return nil, nil, fmt.Errorf("expected a union value, got %T", native)
+	}
+	for name, value := range wrapped {
+		for _, branch := range branches {
+			if unionBranchName(branch, namespace) == name {
+				return branch, value, nil
+			}
+		}
+		return nil, nil, fmt.Errorf("unknown union member %s", name)
+	}
+	return nil, nil, nil
+}
+
+// promote converts a primitive or logical value of the writer type to the reader type
+func promote(writer interface{}, reader interface{}, native interface{}) (interface{}, error) {
+	writerType, readerType := typeName(writer), typeName(reader)
+	if logicalTypeName(writer) != logicalTypeName(reader) {
+		return nil, fmt.Errorf("cannot resolve %s to %s", describe(writer), describe(reader))
+	}
+	if writerType == readerType {
+		return native, nil
+	}
+	if !promotable(writerType, readerType) {
+		return nil, fmt.Errorf("cannot resolve %s to %s", writerType, readerType)
+	}
+	switch v := native.(type) {
+	case int32:
+		switch readerType {
+		case "long":
+			return int64(v), nil
+		case "float":
+			return float32(v), nil
+		}
+		return float64(v), nil
+	case int64:
+		if readerType == "float" {
+			return float32(v), nil
+		}
+		return float64(v), nil
+	case float32:
+		return float64(v), nil
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return string(v), nil
+	}
+	return nil, fmt.Errorf("cannot promote %T to %s", native, readerType)
+}
+
+func promotable(writerType, readerType string) bool {
+	switch writerType {
+	case "int":
+		return readerType == "long" || readerType == "float" || readerType == "double"
+	case "long":
+		return readerType == "float" || readerType == "double"
+	case "float":
+		return readerType == "double"
+	case "string":
+		return readerType == "bytes"
+	case "bytes":
+		return readerType == "string"
+	}
+	return false
+}
+
+// typeName returns the avro type of a schema node, the primitive name for primitives and primitive logical types
+func typeName(node interface{}) string {
+	switch n := node.(type) {
+	case string:
+		return n
+	case []interface{}:
+		return "union"
+	case map[string]interface{}:
+		switch t := n["type"].(type) {
+		case string:
+			return t
+		default:
+			return typeName(t)
+		}
+	}
+	return ""
+}
+
+func logicalTypeName(node interface{}) string {
+	if n, ok := node.(map[string]interface{}); ok {
+		logicalType, _ := n["logicalType"].(string)
+		return logicalType
+	}
+	return ""
+}
+
+func describe(node interface{}) string {
+	if logicalType := logicalTypeName(node); logicalType != "" {
+		return typeName(node) + "." + logicalType
+	}
+	return typeName(node)
+}
+
+func aliases(field map[string]interface{}) []string {
+	list, _ := field["aliases"].([]interface{})
+	names := make([]string, 0, len(list))
+	for _, alias := range list {
+		if name, ok := alias.(string); ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func unqualified(name string) string {
+	if i := len(namespaceOf(name)); i > 0 {
+		return name[i+1:]
+	}
+	return name
+}
+
+// readerSchemas keeps the reader codecs and the resolvers by writer and reader schema
+type readerSchemas struct {
+	lock      sync.Mutex
+	codecs    map[string]*goavro.Codec
+	resolvers map[readerKey]*schemaResolver
+}
+
+type readerKey struct {
+	writer string
+	reader string
+}
+
+// project resolves a native value of the writer codec to the reader schema, returning the reader codec
+func (s *readerSchemas) project(writer *goavro.Codec, reader string, native interface{}) (interface{}, *goavro.Codec, error) {
+	key := readerKey{writer.Schema(), reader}
+	s.lock.Lock()
+	resolver, ok := s.resolvers[key]
+	if !ok {
+		if s.codecs == nil {
+			s.codecs = make(map[string]*goavro.Codec)
+			s.resolvers = make(map[readerKey]*schemaResolver)
+		}
+		codec, found := s.codecs[reader]
+		var err error
+		if !found {
+			if codec, err = goavro.NewCodec(reader); err != nil {
+				s.lock.Unlock()
+				return nil, nil, fmt.Errorf("invalid reader schema: %v", err)
+			}
+			s.codecs[reader] = codec
+		}
+		if resolver, err = newSchemaResolver(writer, codec); err != nil {
+			s.lock.Unlock()
+			return nil, nil, err
+		}
+		s.resolvers[key] = resolver
+	}
+	s.lock.Unlock()
+	resolved, err := resolver.resolve(native)
+	return resolved, resolver.reader, err
+}
@@ -0,0 +1,109 @@
+package kafka
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/linkedin/goavro/v2"
+)
+
+const testWriterSchema = `{"type": "record", "name": "User", "namespace": "v1", "fields": [
+	{"name": "id", "type": "int"},
+	{"name": "name", "type": "string"},
+	{"name": "nick", "type": ["null", "string"]},
+	{"name": "role", "type": {"type": "enum", "name": "Role", "symbols": ["ADMIN", "GUEST", "OWNER"]}},
+	{"name": "scores", "type": {"type": "array", "items": "float"}},
+	{"name": "added", "type": "string"}
+]}`
+
+const testReaderSchema = `{"type": "record", "name": "User", "namespace": "v2", "fields": [
+	{"name": "id", "type": "long"},
+	{"name": "fullName", "type": "string", "aliases": ["name"]},
+	{"name": "nick", "type": ["null", "string"], "default": null},
+	{"name": "role", "type": {"type": "enum", "name": "Role", "symbols": ["ADMIN", "GUEST"], "default": "GUEST"}},
+	{"name": "scores", "type": {"type": "array", "items": "double"}},
+	{"name": "country", "type": "string", "default": "NL"}
+]}`
+
+func TestSchemaResolver(t *testing.T) {
+	writer, err := goavro.NewCodec(testWriterSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, err := goavro.NewCodec(testReaderSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver, err := newSchemaResolver(writer, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolved, err := resolver.resolve(map[string]interface{}{"id": int32(1), "name": "ann", "nick": goavro.Union("string", "a"),
+		"role": "OWNER", "scores": []interface{}{float32(1.5)}, "added": "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{"id": int64(1), "fullName": "ann", "nick": map[string]interface{}{"string": "a"},
+		"role": "GUEST", "scores": []interface{}{1.5}, "country": "NL"}
+	if !reflect.DeepEqual(resolved, expected) {
+		t.Errorf("Expected %v, got %v", expected, resolved)
+	}
+}
+
+func TestSchemaResolver_Incompatible(t *testing.T) {
+	writer, _ := goavro.NewCodec(`{"type": "record", "name": "r", "fields": [{"name": "a", "type": "string"}]}`)
+	for _, schema := range []string{
+		`{"type": "record", "name": "r", "fields": [{"name": "a", "type": "int"}]}`,
+		`{"type": "record", "name": "r", "fields": [{"name": "b", "type": "int"}]}`,
+	} {
+		reader, err := goavro.NewCodec(schema)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resolver, err := newSchemaResolver(writer, reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := resolver.resolve(map[string]interface{}{"a": "x"}); err == nil {
+			t.Errorf("Expected %s not to resolve", schema)
+		}
+	}
+}
+
+func TestAvroConsumer_ReaderSchema(t *testing.T) {
+	registry := newMemorySchemaRegistry()
+	writer, err := goavro.NewCodec(testWriterSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schemaId, err := registry.CreateSubject("users-value", writer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binaryValue, err := writer.BinaryFromNative(nil, map[string]interface{}{"id": int32(1), "name": "ann", "nick": nil,
+		"role": "ADMIN", "scores": []interface{}{}, "added": "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	value := append([]byte{0, 0, 0, 0, 0}, binaryValue...)
+	binary.BigEndian.PutUint32(value[1:5], uint32(schemaId))
+	config := &Config{Topics: map[string]TopicConfig{"users": {ReaderSchema: testReaderSchema}}}
+	consumer := &AvroConsumer{SchemaRegistryClient: registry, config: config}
+
+	msg, err := consumer.ProcessAvroMsgContext(context.Background(), &sarama.ConsumerMessage{Topic: "users", Value: value})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded, expected map[string]interface{}
+	json.Unmarshal([]byte(`{"id":1,"fullName":"ann","nick":null,"role":"ADMIN","scores":[],"country":"NL"}`), &expected)
+	if err := json.Unmarshal([]byte(msg.Value), &decoded); err != nil || !reflect.DeepEqual(decoded, expected) {
+		t.Errorf("Expected %v, got %s", expected, msg.Value)
+	}
+	if msg.SchemaId != schemaId {
+		t.Errorf("Expected the writer schema id %d, got %d", schemaId, msg.SchemaId)
+	}
+}
@@ -0,0 +1,87 @@
+package kafka
+
+import (
+	"fmt"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+// schemaSelection picks the schemas a producer encodes with, mirroring the auto.register.schemas, use.latest.version
+// and pinned version settings of the Confluent serializers
+type schemaSelection struct {
+	noAutoRegister bool
+	useLatest      bool
+	versions       map[string]int
+	// checkCompatibility checks the given schemas before using them, see WithCompatibilityCheck
+	checkCompatibility bool
+}
+
+// WithAutoRegisterSchemas sets whether producers register the schemas they produce with, enabled by default.
+// When disabled the schemas are looked up in their subjects and producing with an unregistered schema fails,
+// e.g. in production environments where schemas are registered by a deployment pipeline.
+func WithAutoRegisterSchemas(enabled bool) Option {
+	return func(o *options) {
+		o.schemas.noAutoRegister = !enabled
+	}
+}
+
+// WithUseLatestVersion makes an AvroProducer encode the values of Add, AddNative and PrepareMessage with the latest
+// schema of the value subject instead of the given schema, the values must be valid for the latest schema
+func WithUseLatestVersion(enabled bool) Option {
+	return func(o *options) {
+		o.schemas.useLatest = enabled
+	}
+}
+
+// WithSchemaVersion makes producers encode the values of the subject with a version of it instead of the given
+// schema, like WithUseLatestVersion. It takes precedence over WithUseLatestVersion for the subject.
+func WithSchemaVersion(subject string, version int) Option {
+	return func(o *options) {
+		if o.schemas.versions == nil {
+			o.schemas.versions = make(map[string]int)
+		}
+		o.schemas.versions[subject] = version
+	}
+}
+
+// version returns the version to encode the subject with, 0 for the latest one, or false to use the given schema
+func (s schemaSelection) version(subject string) (int, bool) {
+	if version, pinned := s.versions[subject]; pinned {
+		return version, true
+	}
+	return 0, s.useLatest
+}
+
+// schemaId registers the schema to the subject, or looks it up when auto registration is disabled
+func (s schemaSelection) schemaId(registry SchemaRegistry, subject string, codec *goavro.Codec) (int, error) {
+	if !s.noAutoRegister {
+		return registry.CreateSubject(subject, codec)
+	}
+	schemaId, err := registry.IsSchemaRegistered(subject, codec)
+	if err != nil {
+		return 0, fmt.Errorf("schema is not registered to subject %s and auto registration is disabled: %v", subject, err)
+	}
+	return schemaId, nil
+}
+
+// subjectSchema looks up a version of the subject, 0 for the latest one
+func subjectSchema(registry SchemaRegistry, subject string, version int) (topicSchema, error) {
+	var metadata *SchemaMetadata
+	var err error
+	if version == 0 {
+		metadata, err = registry.GetLatestSchemaMetadata(subject)
+	} else {
+		metadata, err = registry.GetSchemaMetadata(subject, version)
+	}
+	if err != nil {
+		return topicSchema{}, err
+	}
+	if metadata.SchemaType != "" && metadata.SchemaType != SchemaTypeAvro {
+		return topicSchema{}, fmt.Errorf("schema of subject %s is a %s schema", subject, metadata.SchemaType)
+	}
+	codec, err := registry.GetSchema(metadata.ID)
+	if err != nil {
+		return topicSchema{}, err
+	}
+	return topicSchema{id: metadata.ID, codec: codec}, nil
+}
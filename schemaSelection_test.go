@@ -0,0 +1,54 @@
+package kafka
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+func TestAvroProducer_SchemaSelection(t *testing.T) {
+	v1 := `{"type": "record", "name": "test", "fields" : [{"name": "val", "type": "int"}]}`
+	v2 := `{"type": "record", "name": "test", "fields" : [{"name": "val", "type": "int"}, {"name": "name", "type": "string", "default": ""}]}`
+	registry := newMemorySchemaRegistry()
+	ids := make([]int, 2)
+	for i, schema := range []string{v1, v2} {
+		codec, err := goavro.NewCodec(schema)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ids[i], err = registry.CreateSubject("test-value", codec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	unregistered := `{"type": "record", "name": "test", "fields" : [{"name": "other", "type": "int", "default": 0}]}`
+	schemaId := func(opts ...Option) (int, error) {
+		o := applyOptions(defaultAvroProducerConfig(), opts)
+		producer := &testSyncProducer{}
+		avroProducer := &AvroProducer{producer: producer, schemaRegistryClient: registry, schemas: o.schemas}
+		if err := avroProducer.Add("test", v1, nil, []byte(`{"val":1}`)); err != nil {
+			return 0, err
+		}
+		value, _ := producer.sent[0].Value.Encode()
+		return int(binary.BigEndian.Uint32(value[1:5])), nil
+	}
+
+	if id, err := schemaId(WithAutoRegisterSchemas(false)); err != nil || id != ids[0] {
+		t.Errorf("Expected the registered schema to be looked up, got %d, %v", id, err)
+	}
+	if id, err := schemaId(WithUseLatestVersion(true)); err != nil || id != ids[1] {
+		t.Errorf("Expected the latest schema, got %d, %v", id, err)
+	}
+	if id, err := schemaId(WithUseLatestVersion(true), WithSchemaVersion("test-value", 1)); err != nil || id != ids[0] {
+		t.Errorf("Expected the pinned version, got %d, %v", id, err)
+	}
+
+	avroProducer := &AvroProducer{producer: &testSyncProducer{}, schemaRegistryClient: registry,
+		schemas: schemaSelection{noAutoRegister: true}}
+	if err := avroProducer.Add("test", unregistered, nil, []byte(`{"other":1}`)); err == nil {
+		t.Errorf("Expected an error producing with an unregistered schema")
+	}
+	if versions, _ := registry.GetVersions("test-value"); len(versions) != 2 {
+		t.Errorf("Expected no schema to be registered, got versions %v", versions)
+	}
+}
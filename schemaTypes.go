@@ -0,0 +1,121 @@
+package kafka
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Shopify/sarama"
+)
+
+// schema types of schema registry, a topic selects its type with TopicConfig.SchemaType
+const (
+	SchemaTypeAvro     = "AVRO"
+	SchemaTypeJSON     = "JSON"
+	SchemaTypeProtobuf = "PROTOBUF"
+)
+
+// encodeMessageIndexes returns the zigzag varint encoded path of a message type in a protobuf schema,
+// the first message type [0] is encoded as a single 0
+func encodeMessageIndexes(indexes []int) []byte {
+	if len(indexes) == 0 || (len(indexes) == 1 && indexes[0] == 0) {
+		return []byte{0}
+	}
+	buf := make([]byte, binary.MaxVarintLen64*(len(indexes)+1))
+	n := binary.PutVarint(buf, int64(len(indexes)))
+	for _, index := range indexes {
+		n += binary.PutVarint(buf[n:], int64(index))
+	}
+	return buf[:n]
+}
+
+// decodeMessageIndexes returns the message type path at the start of a protobuf payload and its encoded length
+func decodeMessageIndexes(payload []byte) ([]int, int, error) {
+	count, n := binary.Varint(payload)
+	if n <= 0 || count < 0 {
+		return nil, 0, fmt.Errorf("invalid protobuf message indexes")
+	}
+	if count == 0 {
+		return []int{0}, n, nil
+	}
+	if count > int64(len(payload)) {
+		return nil, 0, fmt.Errorf("invalid protobuf message indexes")
+	}
+	indexes := make([]int, count)
+	for i := range indexes {
+		index, m := binary.Varint(payload[n:])
+		if m <= 0 {
+			return nil, 0, fmt.Errorf("invalid protobuf message indexes")
+		}
+		indexes[i] = int(index)
+		n += m
+	}
+	return indexes, n, nil
+}
+
+// GetSchemaIdForType registers a JSON Schema or Protobuf schema to the value subject of the topic
+func (ap *AvroProducer) GetSchemaIdForType(topic string, schemaType string, schema string) (int, error) {
+	subject, err := ap.config.valueSubject(topic, schema)
+	if err != nil {
+		return 0, err
+	}
+	schemaId, err := ap.schemaRegistryClient.CreateSubjectWithSchemaType(subject, schemaType, schema, nil)
+	if err != nil {
+		return 0, err
+	}
+	ap.trackSchemaId(topic, subject, schema, nil, schemaId)
+	return schemaId, nil
+}
+
+// AddProtobuf sends a serialized protobuf value, messageIndexes is the path of its message type in the schema,
+// e.g. [0] for the first message or [1, 0] for the first message nested in the second one
+func (ap *AvroProducer) AddProtobuf(topic string, schema string, messageIndexes []int, key []byte, value []byte) error {
+	return ap.addSchemaType(topic, SchemaTypeProtobuf, schema, messageIndexes, key, value)
+}
+
+// addSchemaType sends a value of a topic that is not AVRO, JSON values are sent as they are
+func (ap *AvroProducer) addSchemaType(topic string, schemaType string, schema string, messageIndexes []int, key []byte, value []byte) error {
+	var payload []byte
+	switch schemaType {
+	case SchemaTypeJSON:
+		if !json.Valid(value) {
+			return fmt.Errorf("value is not valid JSON")
+		}
+		payload = value
+	case SchemaTypeProtobuf:
+		payload = append(encodeMessageIndexes(messageIndexes), value...)
+	default:
+		return fmt.Errorf("unsupported schema type: %s", schemaType)
+	}
+	schemaId, err := ap.GetSchemaIdForType(topic, schemaType, schema)
+	if err != nil {
+		return err
+	}
+	_, _, err = ap.sendBinary(topic, schemaId, sarama.StringEncoder(key), payload)
+	return err
+}
+
+// decodeSchemaType returns the message of a topic that is not AVRO. JSON values are returned as they are,
+// protobuf values are returned serialized, with the path of their message type in MessageIndexes.
+func (ac *AvroConsumer) decodeSchemaType(m *sarama.ConsumerMessage, schemaId int, payload []byte, schemaType string) (Message, error) {
+	msg := Message{SchemaId: schemaId, Topic: m.Topic, Partition: m.Partition, Offset: m.Offset, Key: string(m.Key),
+		Timestamp: m.Timestamp, Headers: m.Headers}
+	switch schemaType {
+	case SchemaTypeJSON:
+		value, err := ac.redaction.Apply(payload)
+		if err != nil {
+			return Message{}, err
+		}
+		msg.Value = string(value)
+	case SchemaTypeProtobuf:
+		indexes, n, err := decodeMessageIndexes(payload)
+		if err != nil {
+			return Message{}, fmt.Errorf("message %s/%d@%d: %s", m.Topic, m.Partition, m.Offset, err)
+		}
+		msg.MessageIndexes = indexes
+		msg.Value = string(payload[n:])
+	default:
+		return Message{}, fmt.Errorf("unsupported schema type: %s", schemaType)
+	}
+	return msg, nil
+}
@@ -0,0 +1,84 @@
+package kafka
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/mocks"
+)
+
+func TestMessageIndexes(t *testing.T) {
+	for _, indexes := range [][]int{{0}, {1}, {1, 0}, {3, 2, 1}} {
+		encoded := encodeMessageIndexes(indexes)
+		decoded, n, err := decodeMessageIndexes(append(encoded, 0xff))
+		if err != nil || n != len(encoded) || !reflect.DeepEqual(decoded, indexes) {
+			t.Errorf("Expected %v, got %v, %d, %v", indexes, decoded, n, err)
+		}
+	}
+	if encoded := encodeMessageIndexes(nil); !reflect.DeepEqual(encoded, []byte{0}) {
+		t.Errorf("Expected the first message type to be a single 0, got %v", encoded)
+	}
+	if _, _, err := decodeMessageIndexes([]byte{0x10}); err == nil {
+		t.Errorf("Expected truncated indexes to be rejected")
+	}
+}
+
+func schemaTypeRoundTrip(t *testing.T, schemaType string, send func(*AvroProducer) error) Message {
+	var registered schemaRequest
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&registered)
+		fmt.Fprint(w, `{"id": 5}`)
+	}))
+	defer mockServer.Close()
+	var value []byte
+	producerMock := mocks.NewSyncProducer(t, nil)
+	producerMock.ExpectSendMessageWithCheckerFunctionAndSucceed(func(v []byte) error {
+		value = v
+		return nil
+	})
+	config := Config{Topics: map[string]TopicConfig{"test": {SchemaType: schemaType}}}
+	producer := &AvroProducer{producer: producerMock, schemaRegistryClient: NewCachedSchemaRegistryClient([]string{mockServer.URL})}
+	producer.SetConfig(config)
+	defer producer.Close()
+	if err := send(producer); err != nil {
+		t.Fatalf("Error adding msg: %v", err)
+	}
+	if registered.SchemaType != schemaType {
+		t.Errorf("Expected schema type %s to be registered, got %s", schemaType, registered.SchemaType)
+	}
+
+	consumer := &AvroConsumer{config: &config}
+	msg, err := consumer.ProcessAvroMsg(&sarama.ConsumerMessage{Topic: "test", Key: []byte("key"), Value: value})
+	if err != nil {
+		t.Fatalf("Error decoding msg: %v", err)
+	}
+	if msg.SchemaId != 5 || msg.Key != "key" {
+		t.Errorf("Unexpected message %+v", msg)
+	}
+	return msg
+}
+
+func TestSchemaTypeJSON(t *testing.T) {
+	schema := `{"type": "object", "properties": {"val": {"type": "integer"}}}`
+	msg := schemaTypeRoundTrip(t, SchemaTypeJSON, func(producer *AvroProducer) error {
+		return producer.Add("test", schema, []byte("key"), []byte(`{"val": 1}`))
+	})
+	if msg.Value != `{"val": 1}` {
+		t.Errorf("Expected the JSON value, got %s", msg.Value)
+	}
+}
+
+func TestSchemaTypeProtobuf(t *testing.T) {
+	schema := `syntax = "proto3"; message Outer { message Inner { int32 val = 1; } }`
+	msg := schemaTypeRoundTrip(t, SchemaTypeProtobuf, func(producer *AvroProducer) error {
+		return producer.AddProtobuf("test", schema, []int{0, 0}, []byte("key"), []byte{0x08, 0x01})
+	})
+	if msg.Value != "\x08\x01" || !reflect.DeepEqual(msg.MessageIndexes, []int{0, 0}) {
+		t.Errorf("Expected the serialized inner message, got %q %v", msg.Value, msg.MessageIndexes)
+	}
+}
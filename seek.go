@@ -0,0 +1,117 @@
+package kafka
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+// Seek makes the consumer read the partition from offset, topic being the consumed topic as in Message.Topic.
+// The partition must be claimed by this consumer: the consumer joins the group again and the partition starts from
+// offset in the next generation, if this consumer still claims it. The messages in flight are still handled.
+func (ac *AvroConsumer) Seek(topic string, partition int32, offset int64) error {
+	return ac.seek(topic, map[int32]int64{partition: offset})
+}
+
+// SeekToTime makes the consumer read the partitions of the topic it claims from the first message at or after t,
+// or from the end of the partitions that have no such message. It is Seek with the offsets found by ListOffsets.
+func (ac *AvroConsumer) SeekToTime(topic string, t time.Time) error {
+	partitions := ac.claimed(topic)
+	if len(partitions) == 0 {
+		return fmt.Errorf("no partition of topic %s is claimed by this consumer", topic)
+	}
+	client, err := sarama.NewClient(ac.kafkaServers, ac.saramaConfig)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	offsets, err := offsetsForTime(client, topic, partitions, t)
+	if err != nil {
+		return err
+	}
+	return ac.seek(topic, offsets)
+}
+
+// offsetsForTime returns the offsets of the first messages at or after t, or the end offsets of the partitions
+func offsetsForTime(client sarama.Client, topic string, partitions []int32, t time.Time) (map[int32]int64, error) {
+	timestamp := t.UnixNano() / int64(time.Millisecond)
+	offsets := make(map[int32]int64, len(partitions))
+	for _, partition := range partitions {
+		offset, err := client.GetOffset(topic, partition, timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("could not list the offsets of %s/%d: %v", topic, partition, err)
+		}
+		if offset < 0 {
+			if offset, err = client.GetOffset(topic, partition, sarama.OffsetNewest); err != nil {
+				return nil, fmt.Errorf("could not list the offsets of %s/%d: %v", topic, partition, err)
+			}
+		}
+		offsets[partition] = offset
+	}
+	return offsets, nil
+}
+
+// claimed returns the partitions of the topic claimed by this consumer in the current generation
+func (ac *AvroConsumer) claimed(topic string) []int32 {
+	ac.membershipLock.Lock()
+	defer ac.membershipLock.Unlock()
+	return ac.claims[topic]
+}
+
+// seek records the offsets to reset in the next generation and leaves the current one
+func (ac *AvroConsumer) seek(topic string, offsets map[int32]int64) error {
+	claimed := make(map[int32]bool)
+	for _, partition := range ac.claimed(topic) {
+		claimed[partition] = true
+	}
+	for partition := range offsets {
+		if !claimed[partition] {
+			return fmt.Errorf("partition %s/%d is not claimed by this consumer", topic, partition)
+		}
+	}
+	ac.membershipLock.Lock()
+	if ac.seeks == nil {
+		ac.seeks = make(map[string]map[int32]int64)
+	}
+	if ac.seeks[topic] == nil {
+		ac.seeks[topic] = make(map[int32]int64)
+	}
+	for partition, offset := range offsets {
+		ac.seeks[topic][partition] = offset
+	}
+	ac.membershipLock.Unlock()
+
+	ac.runLock.Lock()
+	rejoin := ac.rejoin
+	ac.runLock.Unlock()
+	if rejoin != nil {
+		rejoin()
+	}
+	return nil
+}
+
+// applySeeks resets the offsets of the pending seeks claimed by the new session before its claims start,
+// the seeks of partitions claimed by other members are dropped
+func (ac *AvroConsumer) applySeeks(session sarama.ConsumerGroupSession) {
+	ac.membershipLock.Lock()
+	seeks := ac.seeks
+	ac.seeks = nil
+	ac.membershipLock.Unlock()
+	claims := session.Claims()
+	for topic, partitions := range seeks {
+		for partition, offset := range partitions {
+			claimed := false
+			for _, p := range claims[topic] {
+				claimed = claimed || p == partition
+			}
+			if !claimed {
+				orNop(ac.logger).Warn("dropping seek of a partition claimed by another member", "topic", topic,
+					"partition", partition, "offset", offset)
+				continue
+			}
+			orNop(ac.logger).Info("seeking partition", "topic", topic, "partition", partition, "offset", offset)
+			session.ResetOffset(topic, partition, offset, "")
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package kafka
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+func TestAvroConsumer_Seek(t *testing.T) {
+	consumer := &AvroConsumer{}
+	consumer.rebalanced(newTestSession(map[string][]int32{"test": {0, 1}}))
+	if err := consumer.Seek("test", 2, 5); err == nil {
+		t.Errorf("Expected an error seeking a partition that is not claimed")
+	}
+	rejoined := 0
+	consumer.rejoin = func() { rejoined++ }
+	if err := consumer.Seek("test", 0, 42); err != nil {
+		t.Fatal(err)
+	}
+	if err := consumer.Seek("test", 1, 7); err != nil {
+		t.Fatal(err)
+	}
+
+	session := newTestSession(map[string][]int32{"test": {0}})
+	consumer.rebalanced(session)
+	if rejoined != 2 || !reflect.DeepEqual(session.offsets, map[int32]int64{0: 42}) {
+		t.Errorf("Expected the claimed partition to be reset in the next generation, got %d, %v", rejoined, session.offsets)
+	}
+	session = newTestSession(map[string][]int32{"test": {0, 1}})
+	consumer.rebalanced(session)
+	if len(session.offsets) != 0 {
+		t.Errorf("Expected the seeks to be applied once, got %v", session.offsets)
+	}
+}
+
+func TestOffsetsForTime(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	at := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	timestamp := at.UnixNano() / int64(time.Millisecond)
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("test", 0, broker.BrokerID()).
+			SetLeader("test", 1, broker.BrokerID()),
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetOffset("test", 0, timestamp, 12).
+			SetOffset("test", 1, timestamp, -1).
+			SetOffset("test", 1, sarama.OffsetNewest, 30),
+	})
+	client, err := sarama.NewClient([]string{broker.Addr()}, sarama.NewConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	offsets, err := offsetsForTime(client, "test", []int32{0, 1}, at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(offsets, map[int32]int64{0: 12, 1: 30}) {
+		t.Errorf("Expected the offsets at the time and the end offset, got %v", offsets)
+	}
+}
@@ -0,0 +1,248 @@
+package kafka
+
+import (
+	"context"
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+// Sink writes decoded messages to an external system, e.g. a database or an object store
+type Sink interface {
+	// Open is called once before the first batch is written
+	Open() error
+	// WriteBatch writes a batch of messages, it is retried on error
+	WriteBatch(msgs []Message) error
+	// Flush makes previously written batches durable, offsets are committed only after it succeeds
+	Flush() error
+	// Close is called once when the runner stops
+	Close() error
+}
+
+// SinkConfig controls batching, retries and dead-lettering of a SinkRunner
+type SinkConfig struct {
+	// BatchSize is the max number of messages handed to WriteBatch, defaults to 100
+	BatchSize int
+	// BatchTimeout is the max time a message waits before its batch is written, defaults to 1s
+	BatchTimeout time.Duration
+	// MaxRetries is the number of times a failed batch is retried before dead-lettering it
+	MaxRetries int
+	// RetryBackoff is the time to wait between retries
+	RetryBackoff time.Duration
+	// DeadLetter receives batches that failed after all retries. If it returns nil the offsets are
+	// committed and the runner continues, otherwise the runner stops with the returned error.
+	DeadLetter func(msgs []Message, err error) error
+}
+
+type offsetMarker interface {
+	MarkPartitionOffset(topic string, partition int32, offset int64, metadata string)
+}
+
+// sessionMarker marks the offsets of processed messages in a consumer group session, which expects the next offset
+type sessionMarker struct {
+	session sarama.ConsumerGroupSession
+}
+
+func (m sessionMarker) MarkPartitionOffset(topic string, partition int32, offset int64, metadata string) {
+	m.session.MarkOffset(topic, partition, offset+1, metadata)
+}
+
+type sessionMessage struct {
+	session sarama.ConsumerGroupSession
+	msg     *sarama.ConsumerMessage
+}
+
+// SinkRunner consumes avro messages and writes them in batches to a Sink, committing offsets after each batch
+type SinkRunner struct {
+	consumer *AvroConsumer
+	marker   offsetMarker
+	sink     Sink
+	config   SinkConfig
+	batch    []Message
+	offsets  map[string]map[int32]int64
+	messages chan sessionMessage
+	flushes  chan chan error
+	stopped  chan struct{}
+}
+
+// NewSinkRunner creates a runner writing the messages of the consumer to the sink
+func NewSinkRunner(consumer *AvroConsumer, sink Sink, config SinkConfig) *SinkRunner {
+	if config.BatchSize <= 0 {
+		config.BatchSize = 100
+	}
+	if config.BatchTimeout <= 0 {
+		config.BatchTimeout = time.Second
+	}
+	return &SinkRunner{
+		consumer: consumer,
+		sink:     sink,
+		config:   config,
+		offsets:  make(map[string]map[int32]int64),
+		messages: make(chan sessionMessage),
+		flushes:  make(chan chan error),
+		stopped:  make(chan struct{}),
+	}
+}
+
+// Run opens the sink and writes batches until the context is cancelled, the consumer is closed, or a batch
+// cannot be written nor dead-lettered. Messages that cannot be decoded are dead-lettered by the consumer when it has
+// a dead-letter queue, otherwise passed to OnError, their offsets are committed with the next batch.
+func (r *SinkRunner) Run(ctx context.Context) error {
+	if err := r.sink.Open(); err != nil {
+		return err
+	}
+	defer r.sink.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	consumed := make(chan struct{})
+	go func() {
+		defer close(consumed)
+		r.consumer.run(ctx, r)
+	}()
+	// the pending batch is written by Cleanup before the session is released, the consumer must be stopped
+	// before the sink is closed
+	defer func() { <-consumed }()
+	defer cancel()
+	defer close(r.stopped)
+
+	ticker := time.NewTicker(r.config.BatchTimeout)
+	defer ticker.Stop()
+	for {
+		select {
+		case sm := <-r.messages:
+			r.marker = sessionMarker{sm.session}
+			msg, handled, err := r.consumer.receive(sinkSession{sm.session, r}, sm.msg)
+			if handled {
+				if err != nil && r.consumer.isHalted() {
+					return err
+				}
+				continue
+			}
+			if err != nil {
+				r.track(sm.msg.Topic, sm.msg.Partition, sm.msg.Offset)
+				continue
+			}
+			if err := r.add(ctx, msg); err != nil {
+				return err
+			}
+		case flushed := <-r.flushes:
+			err := r.flush(ctx)
+			flushed <- err
+			if err != nil {
+				return err
+			}
+		case <-ticker.C:
+			if err := r.flush(ctx); err != nil {
+				return err
+			}
+		case <-consumed:
+			return r.flush(ctx)
+		}
+	}
+}
+
+// sinkSession is the session of the messages received by the runner, the messages dead-lettered or skipped
+// by the consumer are marked with the next batch
+type sinkSession struct {
+	sarama.ConsumerGroupSession
+	runner *SinkRunner
+}
+
+// MarkMessage implements sarama.ConsumerGroupSession
+func (s sinkSession) MarkMessage(m *sarama.ConsumerMessage, metadata string) {
+	s.runner.track(m.Topic, m.Partition, m.Offset)
+}
+
+// Setup implements sarama.ConsumerGroupHandler
+func (r *SinkRunner) Setup(session sarama.ConsumerGroupSession) error {
+	r.consumer.rebalanced(session)
+	return nil
+}
+
+// Cleanup implements sarama.ConsumerGroupHandler, the pending batch is written before the claims are released
+func (r *SinkRunner) Cleanup(session sarama.ConsumerGroupSession) error {
+	defer r.consumer.revoked(session)
+	flushed := make(chan error, 1)
+	select {
+	case r.flushes <- flushed:
+		return <-flushed
+	case <-r.stopped:
+		return nil
+	}
+}
+
+// ConsumeClaim implements sarama.ConsumerGroupHandler, forwarding the messages to the batching loop
+func (r *SinkRunner) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	for m := range claim.Messages() {
+		select {
+		case r.messages <- sessionMessage{session, m}:
+		case <-r.stopped:
+			return nil
+		}
+	}
+	return nil
+}
+
+func (r *SinkRunner) add(ctx context.Context, msg Message) error {
+	r.batch = append(r.batch, msg)
+	r.track(msg.Topic, msg.Partition, msg.Offset)
+	if len(r.batch) >= r.config.BatchSize {
+		return r.flush(ctx)
+	}
+	return nil
+}
+
+func (r *SinkRunner) track(topic string, partition int32, offset int64) {
+	partitions, ok := r.offsets[topic]
+	if !ok {
+		partitions = make(map[int32]int64)
+		r.offsets[topic] = partitions
+	}
+	if current, ok := partitions[partition]; !ok || offset > current {
+		partitions[partition] = offset
+	}
+}
+
+func (r *SinkRunner) flush(ctx context.Context) error {
+	if len(r.batch) > 0 {
+		err := r.write(ctx, r.batch)
+		for _, msg := range r.batch {
+			r.consumer.interceptHandled(msg, err)
+		}
+		if err != nil {
+			// a batch whose retries were cut short by the context is neither dead-lettered nor committed
+			if r.config.DeadLetter == nil || err == ctx.Err() {
+				return err
+			}
+			if err := r.config.DeadLetter(r.batch, err); err != nil {
+				return err
+			}
+		}
+		r.batch = nil
+	}
+	for topic, partitions := range r.offsets {
+		for partition, offset := range partitions {
+			r.marker.MarkPartitionOffset(topic, partition, offset, "")
+		}
+	}
+	r.offsets = make(map[string]map[int32]int64)
+	return nil
+}
+
+func (r *SinkRunner) write(ctx context.Context, msgs []Message) error {
+	var err error
+	for i := 0; ; i++ {
+		err = r.sink.WriteBatch(msgs)
+		if err == nil {
+			err = r.sink.Flush()
+		}
+		if err == nil || i >= r.config.MaxRetries {
+			return err
+		}
+		select {
+		case <-time.After(r.config.RetryBackoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
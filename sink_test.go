@@ -0,0 +1,172 @@
+package kafka
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/linkedin/goavro/v2"
+)
+
+type testSink struct {
+	batches [][]Message
+	flushes int
+	fail    int
+}
+
+func (s *testSink) Open() error  { return nil }
+func (s *testSink) Close() error { return nil }
+func (s *testSink) Flush() error { s.flushes++; return nil }
+func (s *testSink) WriteBatch(msgs []Message) error {
+	if s.fail > 0 {
+		s.fail--
+		return errors.New("write failed")
+	}
+	s.batches = append(s.batches, msgs)
+	return nil
+}
+
+type testOffsetMarker map[int32]int64
+
+func (m testOffsetMarker) MarkPartitionOffset(topic string, partition int32, offset int64, metadata string) {
+	m[partition] = offset
+}
+
+func newTestSinkRunner(sink Sink, config SinkConfig) (*SinkRunner, testOffsetMarker) {
+	marker := testOffsetMarker{}
+	runner := NewSinkRunner(&AvroConsumer{}, sink, config)
+	runner.marker = marker
+	return runner, marker
+}
+
+func TestSinkRunner_Batching(t *testing.T) {
+	sink := &testSink{}
+	runner, marker := newTestSinkRunner(sink, SinkConfig{BatchSize: 2})
+	for i := int64(0); i < 3; i++ {
+		if err := runner.add(context.Background(), Message{Topic: "test", Partition: 0, Offset: i}); err != nil {
+			t.Fatalf("Error adding msg: %v", err)
+		}
+	}
+	if len(sink.batches) != 1 || len(sink.batches[0]) != 2 {
+		t.Errorf("Expected one batch of 2 messages, got %v", sink.batches)
+	}
+	if marker[0] != 1 {
+		t.Errorf("Expected offset 1 to be marked, got %d", marker[0])
+	}
+	if err := runner.flush(context.Background()); err != nil {
+		t.Fatalf("Error flushing: %v", err)
+	}
+	if len(sink.batches) != 2 || marker[0] != 2 || sink.flushes != 2 {
+		t.Errorf("Expected remaining message to be flushed, got %v", sink.batches)
+	}
+}
+
+func TestSinkRunner_Retries(t *testing.T) {
+	sink := &testSink{fail: 2}
+	runner, marker := newTestSinkRunner(sink, SinkConfig{BatchSize: 1, MaxRetries: 2})
+	if err := runner.add(context.Background(), Message{Topic: "test", Partition: 1, Offset: 5}); err != nil {
+		t.Fatalf("Error adding msg: %v", err)
+	}
+	if len(sink.batches) != 1 || marker[1] != 5 {
+		t.Errorf("Expected batch to succeed after retries")
+	}
+}
+
+func TestSinkRunner_DeadLetter(t *testing.T) {
+	sink := &testSink{fail: 10}
+	var deadLettered []Message
+	runner, marker := newTestSinkRunner(sink, SinkConfig{BatchSize: 1, DeadLetter: func(msgs []Message, err error) error {
+		deadLettered = append(deadLettered, msgs...)
+		return nil
+	}})
+	if err := runner.add(context.Background(), Message{Topic: "test", Partition: 0, Offset: 3}); err != nil {
+		t.Fatalf("Error adding msg: %v", err)
+	}
+	if len(deadLettered) != 1 || marker[0] != 3 {
+		t.Errorf("Expected batch to be dead-lettered and committed")
+	}
+
+	runner, _ = newTestSinkRunner(&testSink{fail: 10}, SinkConfig{BatchSize: 1})
+	if err := runner.add(context.Background(), Message{Topic: "test", Partition: 0, Offset: 3}); err == nil {
+		t.Errorf("Expected error without dead letter handler")
+	}
+}
+
+func TestSinkRunner_RetryCancelled(t *testing.T) {
+	sink := &testSink{fail: 10}
+	deadLettered := false
+	runner, marker := newTestSinkRunner(sink, SinkConfig{BatchSize: 1, MaxRetries: 5, RetryBackoff: time.Hour,
+		DeadLetter: func(msgs []Message, err error) error {
+			deadLettered = true
+			return nil
+		}})
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if err := runner.add(ctx, Message{Topic: "test", Partition: 0, Offset: 3}); err != context.Canceled {
+		t.Fatalf("Expected the retries to stop with the context, got %v", err)
+	}
+	if deadLettered || len(marker) != 0 {
+		t.Errorf("Expected the batch to be neither dead-lettered nor committed, got %v", marker)
+	}
+}
+
+func TestSessionMarker(t *testing.T) {
+	session := newTestSession(nil)
+	sessionMarker{session}.MarkPartitionOffset("test", 0, 7, "")
+	if session.offsets[0] != 8 {
+		t.Errorf("Expected the next offset to be marked, got %d", session.offsets[0])
+	}
+}
+
+// claimConsumerGroup consumes a single claim once, then behaves like a closed consumer group
+type claimConsumerGroup struct {
+	session *testSession
+	claim   *testClaim
+}
+
+func (g *claimConsumerGroup) Consume(ctx context.Context, topics []string, handler sarama.ConsumerGroupHandler) error {
+	handler.Setup(g.session)
+	handler.ConsumeClaim(g.session, g.claim)
+	handler.Cleanup(g.session)
+	return sarama.ErrClosedConsumerGroup
+}
+func (g *claimConsumerGroup) Errors() <-chan error { return make(chan error) }
+func (g *claimConsumerGroup) Close() error         { return nil }
+
+func TestSinkRunner_Run(t *testing.T) {
+	registry := newMemorySchemaRegistry()
+	codec, err := goavro.NewCodec(`{"type": "record", "name": "test", "fields" : [{"name": "val", "type": "int"}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schemaId, err := registry.CreateSubject("test-value", codec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	value := []byte{0, 0, 0, 0, 0, 2}
+	binary.BigEndian.PutUint32(value[1:5], uint32(schemaId))
+
+	claim := newTestClaim(2)
+	claim.messages <- &sarama.ConsumerMessage{Topic: "test", Offset: 0, Value: value}
+	claim.messages <- &sarama.ConsumerMessage{Topic: "test", Offset: 1, Value: []byte("corrupt")}
+	close(claim.messages)
+	session := newTestSession(nil)
+	producer := &testSyncProducer{}
+	consumer := &AvroConsumer{Consumer: &claimConsumerGroup{session, claim}, SchemaRegistryClient: registry}
+	consumer.SetDeadLetterQueue(DeadLetterConfig{Producer: producer})
+	sink := &testSink{}
+
+	if err := NewSinkRunner(consumer, sink, SinkConfig{BatchSize: 10}).Run(context.Background()); err != nil {
+		t.Fatalf("Error running the sink: %v", err)
+	}
+	if len(sink.batches) != 1 || len(sink.batches[0]) != 1 || len(producer.sent) != 1 {
+		t.Errorf("Expected the decoded message to be written and the corrupt one dead-lettered, got %v, %d",
+			sink.batches, len(producer.sent))
+	}
+	if session.offsets[0] != 2 {
+		t.Errorf("Expected the offsets to be committed with the batch, got %v", session.offsets)
+	}
+}
@@ -0,0 +1,142 @@
+package kafka
+
+import (
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"time"
+)
+
+// SourceRecord is a record emitted by a Source, its value is the textual avro (JSON) form of the schema
+type SourceRecord struct {
+	Topic  string
+	Schema string
+	Key    []byte
+	Value  []byte
+	// Position is the source position after this record, it is persisted once the record is produced
+	Position []byte
+}
+
+// Source reads records from an external system, e.g. a database table or an HTTP api
+type Source interface {
+	// Open is called once with the last persisted position, nil when starting fresh
+	Open(position []byte) error
+	// Poll returns the next records, an empty result makes the runner wait before polling again
+	Poll() ([]SourceRecord, error)
+	// Close is called once when the runner stops
+	Close() error
+}
+
+// OffsetStore persists the position of a Source between runs
+type OffsetStore interface {
+	Load() ([]byte, error)
+	Save(position []byte) error
+}
+
+// FileOffsetStore is an OffsetStore keeping the position in a local file
+type FileOffsetStore struct {
+	Path string
+}
+
+// Load returns the stored position, nil if nothing was stored yet
+func (s *FileOffsetStore) Load() ([]byte, error) {
+	position, err := ioutil.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return position, err
+}
+
+// Save atomically replaces the stored position
+func (s *FileOffsetStore) Save(position []byte) error {
+	tmp := s.Path + ".tmp"
+	if err := ioutil.WriteFile(tmp, position, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.Path)
+}
+
+// SourceConfig controls polling and position persistence of a SourceRunner
+type SourceConfig struct {
+	// PollInterval is the time to wait after a poll returned no records, defaults to 1s
+	PollInterval time.Duration
+	// OffsetStore persists source positions, positions are not persisted when nil
+	OffsetStore OffsetStore
+}
+
+// SourceRunner polls a Source and produces its records with the avro producer,
+// which registers the schemas and encodes the values
+type SourceRunner struct {
+	producer *AvroProducer
+	source   Source
+	config   SourceConfig
+}
+
+// NewSourceRunner creates a runner producing the records of the source
+func NewSourceRunner(producer *AvroProducer, source Source, config SourceConfig) *SourceRunner {
+	if config.PollInterval <= 0 {
+		config.PollInterval = time.Second
+	}
+	return &SourceRunner{producer, source, config}
+}
+
+// Run opens the source and produces its records until SIGINT is received or an error occurs
+func (r *SourceRunner) Run() error {
+	var position []byte
+	var err error
+	if r.config.OffsetStore != nil {
+		if position, err = r.config.OffsetStore.Load(); err != nil {
+			return err
+		}
+	}
+	if err := r.source.Open(position); err != nil {
+		return err
+	}
+	defer r.source.Close()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	defer signal.Stop(signals)
+
+	for {
+		n, err := r.poll()
+		if err != nil {
+			return err
+		}
+		wait := time.Duration(0)
+		if n == 0 {
+			wait = r.config.PollInterval
+		}
+		select {
+		case <-signals:
+			return nil
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (r *SourceRunner) poll() (int, error) {
+	records, err := r.source.Poll()
+	if err != nil {
+		return 0, err
+	}
+	var position []byte
+	for _, record := range records {
+		if err = r.producer.Add(record.Topic, record.Schema, record.Key, record.Value); err != nil {
+			break
+		}
+		if record.Position != nil {
+			position = record.Position
+		}
+	}
+	// persist the position of the last produced record, even if a later one failed
+	if position != nil && r.config.OffsetStore != nil {
+		if saveErr := r.config.OffsetStore.Save(position); saveErr != nil && err == nil {
+			err = saveErr
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(records), nil
+}
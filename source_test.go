@@ -0,0 +1,80 @@
+package kafka
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Shopify/sarama/mocks"
+)
+
+type testSource struct {
+	records [][]SourceRecord
+}
+
+func (s *testSource) Open(position []byte) error { return nil }
+func (s *testSource) Close() error               { return nil }
+func (s *testSource) Poll() ([]SourceRecord, error) {
+	if len(s.records) == 0 {
+		return nil, nil
+	}
+	records := s.records[0]
+	s.records = s.records[1:]
+	return records, nil
+}
+
+func TestFileOffsetStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "offsets")
+	if err != nil {
+		t.Fatalf("Could not create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	store := &FileOffsetStore{filepath.Join(dir, "position")}
+	position, err := store.Load()
+	if err != nil || position != nil {
+		t.Errorf("Expected empty position, got %s, %v", position, err)
+	}
+	if err := store.Save([]byte("42")); err != nil {
+		t.Fatalf("Error saving position: %v", err)
+	}
+	position, err = store.Load()
+	if err != nil || string(position) != "42" {
+		t.Errorf("Expected position 42, got %s, %v", position, err)
+	}
+}
+
+type memoryOffsetStore struct {
+	position []byte
+}
+
+func (s *memoryOffsetStore) Load() ([]byte, error)      { return s.position, nil }
+func (s *memoryOffsetStore) Save(position []byte) error { s.position = position; return nil }
+
+func TestSourceRunner_Poll(t *testing.T) {
+	schemaRegistryTestObject := createSchemaRegistryTestObject(t, "test", 1)
+	defer schemaRegistryTestObject.MockServer.Close()
+	producerMock := mocks.NewSyncProducer(t, nil)
+	producerMock.ExpectSendMessageAndSucceed()
+	producerMock.ExpectSendMessageAndSucceed()
+	avroProducer := &AvroProducer{producer: producerMock, schemaRegistryClient: NewCachedSchemaRegistryClient([]string{schemaRegistryTestObject.MockServer.URL})}
+	defer avroProducer.Close()
+	schema := schemaRegistryTestObject.Codec.Schema()
+	source := &testSource{records: [][]SourceRecord{{
+		{Topic: "test", Schema: schema, Key: []byte("1"), Value: []byte(`{"val":1}`), Position: []byte("1")},
+		{Topic: "test", Schema: schema, Key: []byte("2"), Value: []byte(`{"val":2}`), Position: []byte("2")},
+	}}}
+	store := &memoryOffsetStore{}
+	runner := NewSourceRunner(avroProducer, source, SourceConfig{OffsetStore: store})
+	n, err := runner.poll()
+	if err != nil || n != 2 {
+		t.Errorf("Expected 2 records to be produced, got %d, %v", n, err)
+	}
+	if string(store.position) != "2" {
+		t.Errorf("Expected position 2 to be saved, got %s", store.position)
+	}
+	n, err = runner.poll()
+	if err != nil || n != 0 {
+		t.Errorf("Expected no records, got %d, %v", n, err)
+	}
+}
@@ -0,0 +1,114 @@
+package kafka
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+// StrictMode enables defense-in-depth validation of decoded messages
+type StrictMode struct {
+	// ValidateUTF8 rejects messages with string fields or map keys that are not valid UTF-8
+	ValidateUTF8 bool
+	// RecordNames maps topics to the full name the writer schema's record must have
+	RecordNames map[string]string
+}
+
+// InvalidUTF8Error is returned in strict mode when a decoded string is not valid UTF-8
+type InvalidUTF8Error struct {
+	Topic     string
+	Partition int32
+	Offset    int64
+	Field     string
+}
+
+func (e *InvalidUTF8Error) Error() string {
+	return fmt.Sprintf("invalid UTF-8 in field %s of %s/%d@%d", e.Field, e.Topic, e.Partition, e.Offset)
+}
+
+// SchemaNameMismatchError is returned in strict mode when the writer schema is not the record expected for the topic
+type SchemaNameMismatchError struct {
+	Topic    string
+	SchemaId int
+	Expected string
+	Actual   string
+}
+
+func (e *SchemaNameMismatchError) Error() string {
+	return fmt.Sprintf("schema %d of topic %s is %s, expected %s", e.SchemaId, e.Topic, e.Actual, e.Expected)
+}
+
+type strictValidator struct {
+	mode        StrictMode
+	schemaNames sync.Map
+}
+
+// SetStrictMode enables strict validation of the messages decoded by this consumer
+func (ac *AvroConsumer) SetStrictMode(mode StrictMode) {
+	ac.strict = &strictValidator{mode: mode}
+}
+
+func (v *strictValidator) validateSchema(topic string, schemaId int, codec *goavro.Codec) error {
+	expected, ok := v.mode.RecordNames[topic]
+	if !ok {
+		return nil
+	}
+	actual, found := v.schemaNames.Load(schemaId)
+	if !found {
+		name, err := schemaFullName(codec.Schema())
+		if err != nil {
+			return err
+		}
+		actual, _ = v.schemaNames.LoadOrStore(schemaId, name)
+	}
+	if actual != expected {
+		return &SchemaNameMismatchError{topic, schemaId, expected, actual.(string)}
+	}
+	return nil
+}
+
+func (v *strictValidator) validateNative(topic string, partition int32, offset int64, native interface{}) error {
+	if !v.mode.ValidateUTF8 {
+		return nil
+	}
+	if field, ok := findInvalidUTF8(native, ""); !ok {
+		return &InvalidUTF8Error{topic, partition, offset, field}
+	}
+	return nil
+}
+
+// findInvalidUTF8 returns the path of the first invalid string and false, union wrappers appear as path elements
+func findInvalidUTF8(native interface{}, path string) (string, bool) {
+	switch n := native.(type) {
+	case string:
+		return path, utf8.ValidString(n)
+	case []interface{}:
+		for i, item := range n {
+			if field, ok := findInvalidUTF8(item, fmt.Sprintf("%s[%d]", path, i)); !ok {
+				return field, false
+			}
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(n))
+		for key := range n {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			field := key
+			if path != "" {
+				field = path + "." + key
+			}
+			if !utf8.ValidString(key) {
+				return field, false
+			}
+			if field, ok := findInvalidUTF8(n[key], field); !ok {
+				return field, false
+			}
+		}
+	}
+	return path, true
+}
@@ -0,0 +1,38 @@
+package kafka
+
+import (
+	"testing"
+
+	"github.com/Shopify/sarama"
+)
+
+func TestFindInvalidUTF8(t *testing.T) {
+	native := map[string]interface{}{
+		"name": "valid",
+		"tags": []interface{}{"a", string([]byte{0xff, 0xfe})},
+	}
+	field, ok := findInvalidUTF8(native, "")
+	if ok || field != "tags[1]" {
+		t.Errorf("Expected invalid field tags[1], got %s", field)
+	}
+	if _, ok := findInvalidUTF8(map[string]interface{}{"name": "valid", "n": int32(1)}, ""); !ok {
+		t.Errorf("Expected valid record")
+	}
+}
+
+func TestAvroConsumer_StrictSchemaName(t *testing.T) {
+	schemaRegistryTestObject := createSchemaRegistryTestObject(t, "test", 1)
+	defer schemaRegistryTestObject.MockServer.Close()
+	avroConsumer := &AvroConsumer{SchemaRegistryClient: NewCachedSchemaRegistryClient([]string{schemaRegistryTestObject.MockServer.URL})}
+	consumerMsg := &sarama.ConsumerMessage{Topic: "test", Value: getTestAvroMsg(t, schemaRegistryTestObject.Codec)}
+
+	avroConsumer.SetStrictMode(StrictMode{ValidateUTF8: true, RecordNames: map[string]string{"test": "test"}})
+	if _, err := avroConsumer.ProcessAvroMsg(consumerMsg); err != nil {
+		t.Errorf("Expected valid message, got %v", err)
+	}
+	avroConsumer.SetStrictMode(StrictMode{RecordNames: map[string]string{"test": "com.example.Other"}})
+	_, err := avroConsumer.ProcessAvroMsg(consumerMsg)
+	if mismatch, ok := err.(*SchemaNameMismatchError); !ok || mismatch.Actual != "test" {
+		t.Errorf("Expected schema name mismatch, got %v", err)
+	}
+}
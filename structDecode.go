@@ -0,0 +1,335 @@
+package kafka
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+)
+
+// Decode stores the native goavro form of the schema in the value pointed to by out, unwrapping unions.
+// Record fields are matched to struct fields the way Native matches them, fields missing on either side are
+// left alone, so a struct can read a subset of the record, or a record written before its fields were added.
+func (c *StructConverter) Decode(native interface{}, out interface{}) error {
+	v := reflect.ValueOf(out)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("cannot decode into %T, a non-nil pointer is required", out)
+	}
+	return c.decode(c.schema, "", native, v.Elem())
+}
+
+func (c *StructConverter) decode(node interface{}, namespace string, native interface{}, v reflect.Value) error {
+	if native == nil {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	if v.Kind() == reflect.Interface && v.NumMethod() == 0 {
+		plain, err := c.unwrap(node, namespace, native, false)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(plain))
+		return nil
+	}
+	if v.Kind() == reflect.Ptr && v.Type() != reflect.TypeOf(native) {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return c.decode(node, namespace, native, v.Elem())
+	}
+	switch n := node.(type) {
+	case string:
+		if primitiveTypes[n] {
+			return decodePrimitive(n, native, v)
+		}
+		fullName := qualifyName(n, namespace)
+		definition, ok := c.named[fullName]
+		if !ok {
+			return fmt.Errorf("unknown named type: %s", fullName)
+		}
+		return c.decode(definition, namespaceOf(fullName), native, v)
+	case []interface{}:
+		wrapped, ok := native.(map[string]interface{})
+		if !ok || len(wrapped) != 1 {
+			return fmt.Errorf("expected a union value, got %T", native)
+		}
+		for name, value := range wrapped {
+			for _, branch := range n {
+				if unionBranchName(branch, namespace) == name {
+					return c.decode(branch, namespace, value, v)
+				}
+			}
+			return fmt.Errorf("unknown union member %s", name)
+		}
+	case map[string]interface{}:
+		return c.decodeComplex(n, namespace, native, v)
+	}
+	return fmt.Errorf("unsupported schema: %v", node)
+}
+
+func (c *StructConverter) decodeComplex(node map[string]interface{}, namespace string, native interface{}, v reflect.Value) error {
+	if logicalType, ok := node["logicalType"]; ok {
+		// goavro returns time.Time, time.Duration and *big.Rat for logical types
+		value := reflect.ValueOf(native)
+		switch {
+		case logicalType == "uuid" && isUUIDType(v.Type()):
+			s, _ := native.(string)
+			u, err := ParseUUID(s)
+			if err != nil {
+				return err
+			}
+			reflect.Copy(v, reflect.ValueOf(u[:]))
+			return nil
+		case value.Type().AssignableTo(v.Type()):
+			v.Set(value)
+			return nil
+		case v.Type() == ratType && value.Type() == reflect.TypeOf(&big.Rat{}):
+			v.Set(value.Elem())
+			return nil
+		}
+	}
+	typeName, _ := node["type"].(string)
+	switch typeName {
+	case "record", "error":
+		record, ok := native.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("expected a record, got %T", native)
+		}
+		_, recordNamespace := definedName(node, namespace)
+		fields, _ := node["fields"].([]interface{})
+		for _, f := range fields {
+			field, ok := f.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			name, _ := field["name"].(string)
+			value, found := record[name]
+			if !found {
+				continue
+			}
+			if err := c.decodeField(field["type"], recordNamespace, name, value, v); err != nil {
+				return fmt.Errorf("field %s: %v", name, err)
+			}
+		}
+		return nil
+	case "enum":
+		symbol, ok := native.(string)
+		if !ok || v.Kind() != reflect.String {
+			return fmt.Errorf("cannot decode enum into %s", v.Type())
+		}
+		v.SetString(symbol)
+		return nil
+	case "fixed":
+		return decodeBytes(native, v)
+	case "array":
+		items, ok := native.([]interface{})
+		if !ok || v.Kind() != reflect.Slice {
+			return fmt.Errorf("cannot decode array into %s", v.Type())
+		}
+		slice := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := c.decode(node["items"], namespace, item, slice.Index(i)); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	case "map":
+		values, ok := native.(map[string]interface{})
+		if !ok || v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("cannot decode map into %s", v.Type())
+		}
+		m := reflect.MakeMapWithSize(v.Type(), len(values))
+		for key, value := range values {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := c.decode(node["values"], namespace, value, elem); err != nil {
+				return err
+			}
+			m.SetMapIndex(reflect.ValueOf(key).Convert(v.Type().Key()), elem)
+		}
+		v.Set(m)
+		return nil
+	}
+	return c.decode(node["type"], namespace, native, v)
+}
+
+// unwrap returns the native value with the union wrappers removed, natural renders the logical types for JSON
+func (c *StructConverter) unwrap(node interface{}, namespace string, native interface{}, natural bool) (interface{}, error) {
+	if native == nil {
+		return nil, nil
+	}
+	switch n := node.(type) {
+	case string:
+		if primitiveTypes[n] {
+			return native, nil
+		}
+		fullName := qualifyName(n, namespace)
+		definition, ok := c.named[fullName]
+		if !ok {
+			return nil, fmt.Errorf("unknown named type: %s", fullName)
+		}
+		return c.unwrap(definition, namespaceOf(fullName), native, natural)
+	case []interface{}:
+		wrapped, ok := native.(map[string]interface{})
+		if !ok || len(wrapped) != 1 {
+			return nil, fmt.Errorf("expected a union value, got %T", native)
+		}
+		for name, value := range wrapped {
+			for _, branch := range n {
+				if unionBranchName(branch, namespace) == name {
+					return c.unwrap(branch, namespace, value, natural)
+				}
+			}
+			return nil, fmt.Errorf("unknown union member %s", name)
+		}
+	case map[string]interface{}:
+		typeName, _ := n["type"].(string)
+		switch typeName {
+		case "record", "error":
+			record, ok := native.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("expected a record, got %T", native)
+			}
+			_, recordNamespace := definedName(n, namespace)
+			plain := make(map[string]interface{}, len(record))
+			fields, _ := n["fields"].([]interface{})
+			for _, f := range fields {
+				field, ok := f.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				name, _ := field["name"].(string)
+				if value, found := record[name]; found {
+					unwrapped, err := c.unwrap(field["type"], recordNamespace, value, natural)
+					if err != nil {
+						return nil, fmt.Errorf("field %s: %v", name, err)
+					}
+					plain[name] = unwrapped
+				}
+			}
+			return plain, nil
+		case "array":
+			items, ok := native.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("expected an array, got %T", native)
+			}
+			plain := make([]interface{}, len(items))
+			for i, item := range items {
+				unwrapped, err := c.unwrap(n["items"], namespace, item, natural)
+				if err != nil {
+					return nil, err
+				}
+				plain[i] = unwrapped
+			}
+			return plain, nil
+		case "map":
+			values, ok := native.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("expected a map, got %T", native)
+			}
+			plain := make(map[string]interface{}, len(values))
+			for key, value := range values {
+				unwrapped, err := c.unwrap(n["values"], namespace, value, natural)
+				if err != nil {
+					return nil, err
+				}
+				plain[key] = unwrapped
+			}
+			return plain, nil
+		case "enum", "fixed":
+			return native, nil
+		}
+		if logicalType, ok := n["logicalType"].(string); ok {
+			if natural {
+				return renderLogical(logicalType, n, native), nil
+			}
+			return native, nil
+		}
+		return c.unwrap(n["type"], namespace, native, natural)
+	}
+	return nil, fmt.Errorf("unsupported schema: %v", node)
+}
+
+// decodeField stores a record field in the matching struct field or map entry of v
+func (c *StructConverter) decodeField(node interface{}, namespace string, name string, native interface{}, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath == "" && field.Tag.Get("avro") != "-" && fieldName(field) == name {
+				return c.decode(node, namespace, native, v.Field(i))
+			}
+		}
+		return nil
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			break
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if err := c.decode(node, namespace, native, elem); err != nil {
+			return err
+		}
+		v.SetMapIndex(reflect.ValueOf(name).Convert(v.Type().Key()), elem)
+		return nil
+	}
+	return fmt.Errorf("cannot decode record into %s", v.Type())
+}
+
+func decodePrimitive(typeName string, native interface{}, v reflect.Value) error {
+	kind := v.Kind()
+	switch value := native.(type) {
+	case bool:
+		if kind == reflect.Bool {
+			v.SetBool(value)
+			return nil
+		}
+	case int32, int64:
+		i := reflect.ValueOf(value).Int()
+		switch {
+		case kind >= reflect.Int && kind <= reflect.Int64 && !v.OverflowInt(i):
+			v.SetInt(i)
+			return nil
+		case kind >= reflect.Uint && kind <= reflect.Uint64 && i >= 0 && !v.OverflowUint(uint64(i)):
+			v.SetUint(uint64(i))
+			return nil
+		case kind == reflect.Float32 || kind == reflect.Float64:
+			v.SetFloat(float64(i))
+			return nil
+		}
+	case float32, float64:
+		if kind == reflect.Float32 || kind == reflect.Float64 {
+			v.SetFloat(reflect.ValueOf(value).Float())
+			return nil
+		}
+	case string:
+		if kind == reflect.String {
+			v.SetString(value)
+			return nil
+		}
+	case []byte:
+		return decodeBytes(value, v)
+	}
+	return fmt.Errorf("cannot decode %s into %s", typeName, v.Type())
+}
+
+func decodeBytes(native interface{}, v reflect.Value) error {
+	b, ok := native.([]byte)
+	if !ok {
+		return fmt.Errorf("expected bytes, got %T", native)
+	}
+	switch {
+	case v.Kind() == reflect.String:
+		v.SetString(string(b))
+		return nil
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		v.SetBytes(append([]byte(nil), b...))
+		return nil
+	case v.Kind() == reflect.Array && v.Type().Elem().Kind() == reflect.Uint8 && v.Len() == len(b):
+		reflect.Copy(v, reflect.ValueOf(b))
+		return nil
+	}
+	return fmt.Errorf("cannot decode bytes into %s", v.Type())
+}
@@ -0,0 +1,100 @@
+package kafka
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testShipment struct {
+	ID      int64             `avro:"id"`
+	Status  string            `avro:"status"`
+	Note    *string           `avro:"note"`
+	Created time.Time         `avro:"created"`
+	Total   big.Rat           `avro:"total"`
+	Lines   []testShipmentRow `avro:"lines"`
+	Tags    map[string]int    `avro:"tags"`
+	Payload []byte            `avro:"payload"`
+}
+
+type testShipmentRow struct {
+	Sku string `avro:"sku"`
+	Qty uint16 `avro:"qty"`
+}
+
+const testShipmentSchema = `{"type": "record", "name": "Shipment", "namespace": "test", "fields": [
+	{"name": "id", "type": "long"},
+	{"name": "status", "type": {"type": "enum", "name": "Status", "symbols": ["NEW", "SENT"]}},
+	{"name": "note", "type": ["null", "string"], "default": null},
+	{"name": "created", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+	{"name": "total", "type": {"type": "bytes", "logicalType": "decimal", "precision": 10, "scale": 2}},
+	{"name": "lines", "type": {"type": "array", "items": {"type": "record", "name": "Line", "fields": [
+		{"name": "sku", "type": "string"}, {"name": "qty", "type": "int"}]}}},
+	{"name": "tags", "type": {"type": "map", "values": "int"}},
+	{"name": "payload", "type": {"type": "fixed", "name": "Payload", "size": 2}},
+	{"name": "added", "type": "string", "default": ""}
+]}`
+
+func TestStructConverter_Decode(t *testing.T) {
+	converter, err := NewStructConverter(testShipmentSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	note := "fragile"
+	in := testShipment{ID: 7, Status: "SENT", Note: &note, Created: time.Unix(1600000000, 0).UTC(),
+		Total: *big.NewRat(1234, 100), Lines: []testShipmentRow{{Sku: "a", Qty: 2}}, Tags: map[string]int{"x": 1},
+		Payload: []byte{1, 2}}
+	native, err := converter.Native(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binaryValue, err := converter.Codec.BinaryFromNative(nil, native)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, _, err := converter.Codec.NativeFromBinary(binaryValue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out testShipment
+	if err := converter.Decode(decoded, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Total.Cmp(&in.Total) != 0 || !out.Created.Equal(in.Created) {
+		t.Errorf("Unexpected logical values %v, %v", out.Total.String(), out.Created)
+	}
+	out.Total, in.Total = big.Rat{}, big.Rat{}
+	out.Created, in.Created = time.Time{}, time.Time{}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("Expected %+v, got %+v", in, out)
+	}
+
+	var subset struct {
+		ID   int64   `avro:"id"`
+		Note *string `avro:"note"`
+	}
+	if err := converter.Decode(decoded, &subset); err != nil || subset.ID != 7 || *subset.Note != note {
+		t.Errorf("Expected a subset of the fields to be decoded, got %+v, %v", subset, err)
+	}
+	var generic map[string]interface{}
+	if err := converter.Decode(decoded, &generic); err != nil || generic["note"] != note {
+		t.Errorf("Expected unwrapped unions in a map, got %v, %v", generic, err)
+	}
+}
+
+func TestStructConverter_DecodeErrors(t *testing.T) {
+	converter, err := NewStructConverter(`{"type": "record", "name": "r", "fields": [{"name": "n", "type": "int"}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out struct {
+		N int8 `avro:"n"`
+	}
+	if err := converter.Decode(map[string]interface{}{"n": int32(1000)}, &out); err == nil {
+		t.Errorf("Expected an overflow error")
+	}
+	if err := converter.Decode(map[string]interface{}{"n": int32(1)}, out); err == nil {
+		t.Errorf("Expected an error for a non pointer")
+	}
+}
@@ -0,0 +1,137 @@
+package kafka
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"reflect"
+)
+
+// StructSchema returns the avro schema of the struct type of v, the way StructConverter reads it.
+// Fields are named by their `avro` tag, their `json` tag or their name, pointers become unions with null
+// defaulting to null, time.Time a timestamp-millis long, [16]byte types named UUID a uuid string and nested structs
+// records named after their type. Unsigned integers that do not fit in an int become a long, StructConverter.Native
+// returns an error for values above math.MaxInt64.
+func StructSchema(v interface{}) (string, error) {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return "", fmt.Errorf("cannot derive an avro record from %v", t)
+	}
+	schema, err := (&structSchema{defined: make(map[reflect.Type]string)}).of(t)
+	if err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(schema)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// structSchema defines each named type once, later uses refer to it by its full name
+type structSchema struct {
+	defined map[reflect.Type]string
+}
+
+func (s *structSchema) of(t reflect.Type) (interface{}, error) {
+	switch t {
+	case timeType:
+		return map[string]interface{}{"type": "long", "logicalType": "timestamp-millis"}, nil
+	case durationType:
+		return "long", nil
+	case ratType:
+		return nil, fmt.Errorf("cannot derive the precision and scale of a decimal, use an explicit schema")
+	}
+	if isUUIDType(t) && t.Name() == "UUID" {
+		return map[string]interface{}{"type": "string", "logicalType": "uuid"}, nil
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean", nil
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return "int", nil
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return "long", nil
+	case reflect.Float32:
+		return "float", nil
+	case reflect.Float64:
+		return "double", nil
+	case reflect.String:
+		return "string", nil
+	case reflect.Ptr:
+		elem, err := s.of(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return []interface{}{"null", elem}, nil
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "bytes", nil
+		}
+		items, err := s.of(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"type": "array", "items": items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("cannot use %s as map, the keys must be strings", t)
+		}
+		values, err := s.of(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"type": "map", "values": values}, nil
+	case reflect.Struct:
+		return s.record(t)
+	}
+	return nil, fmt.Errorf("cannot derive an avro type from %s", t)
+}
+
+func (s *structSchema) record(t reflect.Type) (interface{}, error) {
+	if t.Name() == "" {
+		return nil, fmt.Errorf("cannot derive an avro record from anonymous struct %s", t)
+	}
+	if fullName, ok := s.defined[t]; ok {
+		return fullName, nil
+	}
+	record := map[string]interface{}{"type": "record", "name": t.Name()}
+	fullName := t.Name()
+	if namespace := path.Base(t.PkgPath()); namespace != "." && namespace != "/" && isAvroName(namespace) {
+		record["namespace"] = namespace
+		fullName = namespace + "." + t.Name()
+	}
+	s.defined[t] = fullName
+	fields := make([]interface{}, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" || field.Tag.Get("avro") == "-" {
+			continue
+		}
+		fieldType, err := s.of(field.Type)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %v", field.Name, err)
+		}
+		definition := map[string]interface{}{"name": fieldName(field), "type": fieldType}
+		if field.Type.Kind() == reflect.Ptr {
+			definition["default"] = nil
+		}
+		fields = append(fields, definition)
+	}
+	record["fields"] = fields
+	return record, nil
+}
+
+// isAvroName reports whether name is a valid avro name: a letter or underscore followed by letters, digits or underscores
+func isAvroName(name string) bool {
+	for i, r := range name {
+		letter := r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+		if !letter && (i == 0 || r < '0' || r > '9') {
+			return false
+		}
+	}
+	return name != ""
+}
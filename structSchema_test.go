@@ -0,0 +1,69 @@
+package kafka
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+type testAddress struct {
+	Street string `avro:"street"`
+}
+
+type testCustomer struct {
+	ID       int64            `avro:"id"`
+	Name     string           `json:"name"`
+	Score    float64          `avro:"score"`
+	Tags     []string         `avro:"tags"`
+	Extra    map[string]int32 `avro:"extra"`
+	Home     testAddress      `avro:"home"`
+	Work     *testAddress     `avro:"work"`
+	Created  time.Time        `avro:"created"`
+	Avatar   []byte           `avro:"avatar"`
+	Ignored  string           `avro:"-"`
+	internal string
+	Labels   map[string]string `avro:"labels"`
+}
+
+func TestStructSchema(t *testing.T) {
+	schema, err := StructSchema(&testCustomer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	codec, err := goavro.NewCodec(schema)
+	if err != nil {
+		t.Fatalf("Invalid derived schema %s: %v", schema, err)
+	}
+	converter, err := NewStructConverter(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	customer := testCustomer{ID: 1, Name: "ann", Tags: []string{"a"}, Home: testAddress{Street: "x"},
+		Work: &testAddress{Street: "y"}, Created: time.Unix(1600000000, 0).UTC()}
+	native, err := converter.Native(customer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := codec.BinaryFromNative(nil, native); err != nil {
+		t.Errorf("Could not encode with the derived schema: %v", err)
+	}
+	if codec.Schema() == "" || !strings.Contains(schema, `"name":"testCustomer"`) || strings.Contains(schema, "Ignored") {
+		t.Errorf("Unexpected schema %s", schema)
+	}
+}
+
+func TestStructSchema_Errors(t *testing.T) {
+	for _, v := range []interface{}{1, struct{ A int }{}, struct{ M map[int]string }{}, nil} {
+		if _, err := StructSchema(v); err == nil {
+			t.Errorf("Expected an error for %T", v)
+		}
+	}
+	type recursive struct {
+		Next *recursive
+	}
+	if _, err := StructSchema(recursive{}); err != nil {
+		t.Errorf("Expected recursive types to refer to their name, got %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+package kafka
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+// defaultTopicSchemaTTL is the time a producer uses a resolved schema before resolving it again
+const defaultTopicSchemaTTL = 5 * time.Minute
+
+// topicSchema is a value schema of a topic with its id and codec
+type topicSchema struct {
+	id    int
+	codec *goavro.Codec
+}
+
+// topicSchemaKey identifies a value schema of a topic, an empty schema is the schema selected for the value subject,
+// the latest one unless a version is pinned
+type topicSchemaKey struct {
+	topic  string
+	schema string
+}
+
+// SetSchemaCacheTTL sets the time the producer uses the schema id and the codec resolved for a topic and a schema,
+// or for the latest schema of a topic, before resolving them again. Defaults to 5 minutes, zero never expires them.
+// Cached schemas are also resolved again after a message failed to be encoded or sent with them.
+// Setting the ttl empties the cache.
+func (ap *AvroProducer) SetSchemaCacheTTL(ttl time.Duration) {
+	cache := ap.topicSchemaCache()
+	cache.setLimits(0, ttl)
+	cache.flush()
+}
+
+func (ap *AvroProducer) topicSchemaCache() *registryCache {
+	ap.topicSchemasOnce.Do(func() {
+		ap.topicSchemas = newRegistryCache()
+		ap.topicSchemas.setLimits(0, defaultTopicSchemaTTL)
+	})
+	return ap.topicSchemas
+}
+
+// topicSchema returns the id and the codec of the value schema of the topic, the schema is compiled and registered
+// on the first use so the hot produce path does no registry work
+func (ap *AvroProducer) topicSchema(topic string, schema string) (topicSchema, error) {
+	key := topicSchemaKey{topic, schema}
+	if cached, found := ap.topicSchemaCache().get(key); found {
+		return cached.(topicSchema), nil
+	}
+	resolved, err := ap.resolveTopicSchema(topic, schema)
+	if err != nil {
+		return topicSchema{}, err
+	}
+	ap.topicSchemaCache().add(key, resolved)
+	return resolved, nil
+}
+
+// resolveTopicSchema compiles and registers the schema, or looks up the version of the value subject selected
+// for the producer
+func (ap *AvroProducer) resolveTopicSchema(topic string, schema string) (topicSchema, error) {
+	subject, err := ap.config.valueSubject(topic, schema)
+	if err != nil {
+		return topicSchema{}, fmt.Errorf("could not find the value subject of topic %s: %v", topic, err)
+	}
+	version, selected := ap.schemas.version(subject)
+	if schema == "" || selected {
+		resolved, err := subjectSchema(ap.schemaRegistryClient, subject, version)
+		if err != nil {
+			return topicSchema{}, err
+		}
+		ap.trackSchemaId(topic, subject, resolved.codec.Schema(), nil, resolved.id)
+		return resolved, nil
+	}
+	codec, err := goavro.NewCodec(schema)
+	if err != nil {
+		return topicSchema{}, err
+	}
+	if ap.schemas.checkCompatibility {
+		if err := ap.checkCompatibility(subject, codec); err != nil {
+			return topicSchema{}, err
+		}
+	}
+	schemaId, err := ap.GetSchemaId(topic, codec)
+	if err != nil {
+		return topicSchema{}, err
+	}
+	return topicSchema{id: schemaId, codec: codec}, nil
+}
+
+// invalidateTopicSchema drops a cached schema after a message failed with it, e.g. when the subject was changed
+func (ap *AvroProducer) invalidateTopicSchema(topic string, schema string) {
+	ap.topicSchemaCache().remove(topicSchemaKey{topic, schema})
+}
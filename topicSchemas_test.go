@@ -0,0 +1,54 @@
+package kafka
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+type countingRegistry struct {
+	*memorySchemaRegistry
+	created int
+}
+
+func (r *countingRegistry) CreateSubject(subject string, codec *goavro.Codec) (int, error) {
+	r.created++
+	return r.memorySchemaRegistry.CreateSubject(subject, codec)
+}
+
+func TestAvroProducer_TopicSchemaCache(t *testing.T) {
+	schema := `{"type": "record", "name": "test", "fields" : [{"name": "val", "type": "int"}]}`
+	registry := &countingRegistry{memorySchemaRegistry: newMemorySchemaRegistry()}
+	producer := &testSyncProducer{}
+	avroProducer := &AvroProducer{producer: producer, schemaRegistryClient: registry}
+	for i := 0; i < 3; i++ {
+		if err := avroProducer.Add("test", schema, nil, []byte(`{"val":1}`)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if registry.created != 1 {
+		t.Errorf("Expected the schema to be registered once, got %d registrations", registry.created)
+	}
+
+	producer.err = errors.New("failed")
+	if err := avroProducer.Add("test", schema, nil, []byte(`{"val":1}`)); err == nil {
+		t.Fatal("Expected the send to fail")
+	}
+	producer.err = nil
+	if err := avroProducer.Add("test", schema, nil, []byte(`{"val":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	if registry.created != 2 {
+		t.Errorf("Expected the schema to be resolved again after a failure, got %d registrations", registry.created)
+	}
+
+	avroProducer.SetSchemaCacheTTL(time.Nanosecond)
+	avroProducer.Add("test", schema, nil, []byte(`{"val":1}`))
+	time.Sleep(time.Millisecond)
+	avroProducer.Add("test", schema, nil, []byte(`{"val":1}`))
+	if registry.created != 4 {
+		t.Errorf("Expected the schema to be resolved again after the ttl, got %d registrations", registry.created)
+	}
+}
@@ -0,0 +1,58 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Metadata describes the message a value consumed with ConsumeInto was decoded from
+type Metadata struct {
+	SchemaId  int
+	Topic     string
+	Partition int32
+	Offset    int64
+	Key       string
+	Timestamp time.Time
+	// Tombstone is true for a message with a nil value, the value passed with it is the zero value
+	Tombstone bool
+}
+
+// ConsumeInto consumes like Consume, decoding the avro values into T with StructConverter.Decode and passing them
+// to handle, which becomes the OnProcess callback of the consumer. A value that cannot be decoded into T, or an error
+// of handle, is retried or dead-lettered like a failed OnProcess.
+// The values are decoded from the binary data, so ConsumeInto returns an error when the consumer has a redaction
+// profile, rather than exposing the redacted fields. It also returns an error when OnProcess or OnBatchReceived is set.
+func ConsumeInto[T any](ctx context.Context, consumer *AvroConsumer, handle func(context.Context, T, Metadata) error) error {
+	if consumer.decodeMode == DecodeRaw {
+		return fmt.Errorf("cannot decode values into %T, the consumer does not decode values", *new(T))
+	}
+	if len(consumer.redaction) > 0 {
+		return fmt.Errorf("cannot decode values into %T, the consumer has a redaction profile", *new(T))
+	}
+	if consumer.callbacks.OnProcess != nil || consumer.callbacks.OnBatchReceived != nil {
+		return fmt.Errorf("cannot decode values into %T, the consumer already has a process callback", *new(T))
+	}
+	consumer.callbacks.OnProcess = func(msg Message) error {
+		metadata := Metadata{SchemaId: msg.SchemaId, Topic: msg.Topic, Partition: msg.Partition, Offset: msg.Offset,
+			Key: msg.Key, Timestamp: msg.Timestamp, Tombstone: msg.Tombstone}
+		if msg.Tombstone {
+			var zero T
+			return handle(ctx, zero, metadata)
+		}
+		if msg.codec == nil {
+			return fmt.Errorf("message %s/%d@%d has no avro value", msg.Topic, msg.Partition, msg.Offset)
+		}
+		converter, err := consumer.converters.get(msg.codec)
+		if err != nil {
+			return err
+		}
+		var value T
+		if err := converter.Decode(msg.native, &value); err != nil {
+			return err
+		}
+		return handle(ctx, value, metadata)
+	}
+	consumer.Consume(ctx)
+	return nil
+}
@@ -0,0 +1,88 @@
+package kafka
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/linkedin/goavro/v2"
+)
+
+type testReading struct {
+	Sensor string  `avro:"sensor"`
+	Value  float64 `avro:"value"`
+}
+
+func TestConsumeInto(t *testing.T) {
+	registry := newMemorySchemaRegistry()
+	codec, err := goavro.NewCodec(`{"type": "record", "name": "Reading", "fields": [
+		{"name": "sensor", "type": "string"}, {"name": "value", "type": "double"}, {"name": "unit", "type": "string"}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schemaId, err := registry.CreateSubject("readings-value", codec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binaryValue, err := codec.BinaryFromNative(nil, map[string]interface{}{"sensor": "s1", "value": 21.5, "unit": "C"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	value := append([]byte{0, 0, 0, 0, 0}, binaryValue...)
+	binary.BigEndian.PutUint32(value[1:5], uint32(schemaId))
+
+	var errs []error
+	group := &testConsumerGroup{started: make(chan struct{})}
+	consumer := &AvroConsumer{Consumer: group, SchemaRegistryClient: registry,
+		callbacks: ConsumerCallbacks{OnError: func(err error) { errs = append(errs, err) }}}
+	var readings []testReading
+	var metadata []Metadata
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	var consumeErr error
+	go func() {
+		defer close(done)
+		consumeErr = ConsumeInto(ctx, consumer, func(ctx context.Context, reading testReading, m Metadata) error {
+			readings = append(readings, reading)
+			metadata = append(metadata, m)
+			if len(readings) > 1 {
+				return fmt.Errorf("failed")
+			}
+			return nil
+		})
+	}()
+	<-group.started
+	session := newTestSession(nil)
+	consumer.handle(session, &sarama.ConsumerMessage{Topic: "readings", Offset: 4, Key: []byte("k"), Value: value})
+	consumer.handle(session, &sarama.ConsumerMessage{Topic: "readings", Offset: 5, Value: value})
+	cancel()
+	<-done
+	if consumeErr != nil {
+		t.Fatalf("Error consuming: %v", consumeErr)
+	}
+
+	if len(readings) != 2 || readings[0] != (testReading{Sensor: "s1", Value: 21.5}) {
+		t.Fatalf("Unexpected readings %+v", readings)
+	}
+	if metadata[0].SchemaId != schemaId || metadata[0].Offset != 4 || metadata[0].Key != "k" {
+		t.Errorf("Unexpected metadata %+v", metadata[0])
+	}
+	if len(errs) != 1 {
+		t.Errorf("Expected the failed reading to be reported, got %v", errs)
+	}
+}
+
+func TestConsumeInto_Refused(t *testing.T) {
+	handle := func(ctx context.Context, reading testReading, m Metadata) error { return nil }
+	consumer := &AvroConsumer{}
+	consumer.SetRedactionProfile(RedactionProfile{"sensor": RedactMask})
+	if err := ConsumeInto(context.Background(), consumer, handle); err == nil {
+		t.Errorf("Expected a consumer with a redaction profile to be refused")
+	}
+	consumer = &AvroConsumer{callbacks: ConsumerCallbacks{OnProcess: func(msg Message) error { return nil }}}
+	if err := ConsumeInto(context.Background(), consumer, handle); err == nil {
+		t.Errorf("Expected a consumer with an OnProcess callback to be refused")
+	}
+}
@@ -0,0 +1,71 @@
+package kafka
+
+import (
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+// TypedProducer produces Go values of type T to a topic of an AvroProducer. The values are converted
+// with a StructConverter, so struct fields are matched by their `avro` tag, their `json` tag or their name.
+type TypedProducer[T any] struct {
+	producer  *AvroProducer
+	topic     string
+	converter *StructConverter
+	schemaId  int
+}
+
+// NewTypedProducer creates a producer of T for the topic, the schema is derived from T with StructSchema
+// and registered under the value subject of the topic
+func NewTypedProducer[T any](producer *AvroProducer, topic string) (*TypedProducer[T], error) {
+	var zero T
+	schema, err := StructSchema(zero)
+	if err != nil {
+		return nil, err
+	}
+	return NewTypedProducerWithSchema[T](producer, topic, schema)
+}
+
+// NewTypedProducerWithSchema is NewTypedProducer using the schema instead of deriving it from T
+func NewTypedProducerWithSchema[T any](producer *AvroProducer, topic string, schema string) (*TypedProducer[T], error) {
+	converter, err := NewStructConverter(schema)
+	if err != nil {
+		return nil, err
+	}
+	schemaId, err := producer.GetSchemaId(topic, converter.Codec)
+	if err != nil {
+		return nil, err
+	}
+	return &TypedProducer[T]{producer: producer, topic: topic, converter: converter, schemaId: schemaId}, nil
+}
+
+// SchemaId returns the id of the registered schema
+func (p *TypedProducer[T]) SchemaId() int {
+	return p.schemaId
+}
+
+// Schema returns the schema of the produced values
+func (p *TypedProducer[T]) Schema() string {
+	return p.converter.Codec.Schema()
+}
+
+// Send encodes the value and sends it with the key
+func (p *TypedProducer[T]) Send(key []byte, value T) error {
+	_, _, err := p.SendWithOffset(key, value)
+	return err
+}
+
+// SendWithOffset is Send returning the partition and the offset of the message
+func (p *TypedProducer[T]) SendWithOffset(key []byte, value T) (int32, int64, error) {
+	start := time.Now()
+	native, err := p.converter.Native(value)
+	if err != nil {
+		return 0, 0, err
+	}
+	binaryValue, err := p.converter.Codec.BinaryFromNative(nil, native)
+	if err != nil {
+		return 0, 0, err
+	}
+	p.producer.encoded(p.topic, start)
+	return p.producer.sendBinary(p.topic, p.schemaId, sarama.StringEncoder(key), binaryValue)
+}
@@ -0,0 +1,58 @@
+package kafka
+
+import (
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"github.com/Shopify/sarama/mocks"
+)
+
+type testPurchase struct {
+	ID       int64   `avro:"id"`
+	Customer string  `avro:"customer"`
+	Amount   float64 `avro:"amount"`
+}
+
+func TestTypedProducer_Send(t *testing.T) {
+	registry := newMemorySchemaRegistry()
+	producerMock := mocks.NewSyncProducer(t, nil)
+	var value []byte
+	producerMock.ExpectSendMessageWithCheckerFunctionAndSucceed(func(val []byte) error {
+		value = val
+		return nil
+	})
+	avroProducer := &AvroProducer{producer: producerMock, schemaRegistryClient: registry}
+	defer avroProducer.Close()
+
+	producer, err := NewTypedProducer[testPurchase](avroProducer, "orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := producer.Send([]byte("1"), testPurchase{ID: 1, Customer: "ann", Amount: 2.5}); err != nil {
+		t.Fatal(err)
+	}
+	if len(value) < 5 || int(binary.BigEndian.Uint32(value[1:5])) != producer.SchemaId() {
+		t.Fatalf("Expected the wire format with schema %d, got %v", producer.SchemaId(), value)
+	}
+	codec, err := registry.GetSchema(producer.SchemaId())
+	if err != nil {
+		t.Fatal(err)
+	}
+	native, _, err := codec.NativeFromBinary(value[5:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(native) != "map[amount:2.5 customer:ann id:1]" {
+		t.Errorf("Unexpected decoded value %v", native)
+	}
+	if subjects, _ := registry.GetSubjects(); len(subjects) != 1 || subjects[0] != "orders-value" {
+		t.Errorf("Expected the schema to be registered to orders-value, got %v", subjects)
+	}
+}
+
+func TestNewTypedProducer_InvalidType(t *testing.T) {
+	if _, err := NewTypedProducer[int](&AvroProducer{schemaRegistryClient: newMemorySchemaRegistry()}, "orders"); err == nil {
+		t.Errorf("Expected an error for a non struct type")
+	}
+}
@@ -0,0 +1,95 @@
+package kafka
+
+import (
+	"fmt"
+
+	"github.com/Shopify/sarama"
+)
+
+// UnframedError is returned for a message that is not in the wire format of its topic,
+// e.g. written by a plain producer without a schema registry
+type UnframedError struct {
+	Topic     string
+	Partition int32
+	Offset    int64
+	Reason    string
+}
+
+func (e *UnframedError) Error() string {
+	return fmt.Sprintf("message %s/%d@%d is not in the avro wire format: %s", e.Topic, e.Partition, e.Offset, e.Reason)
+}
+
+// IsUnframedError reports whether the error means that a message is not in the wire format of its topic
+func IsUnframedError(err error) bool {
+	_, ok := err.(*UnframedError)
+	return ok
+}
+
+func newUnframedError(m *sarama.ConsumerMessage, format string, args ...interface{}) *UnframedError {
+	return &UnframedError{Topic: m.Topic, Partition: m.Partition, Offset: m.Offset, Reason: fmt.Sprintf(format, args...)}
+}
+
+// UnframedAction defines what the consumer does with the messages that are not in the wire format of their topic
+type UnframedAction int
+
+const (
+	// UnframedFail handles them like the other decode errors, they are dead-lettered when the consumer has
+	// a dead-letter queue, otherwise passed to OnError
+	UnframedFail UnframedAction = iota
+	// UnframedSkip commits them without calling the callbacks
+	UnframedSkip
+	// UnframedDeadLetter sends them to the dead-letter queue without calling the callbacks,
+	// they fail like the other decode errors when the consumer has no dead-letter queue
+	UnframedDeadLetter
+	// UnframedRaw passes them to the Handler of the fallback
+	UnframedRaw
+)
+
+// UnframedFallback configures how a consumer handles the messages that are not in the wire format of their topic
+type UnframedFallback struct {
+	Action UnframedAction
+	// Handler receives the raw messages with UnframedRaw, a failed message is retried or dead-lettered
+	// like a failed OnProcess
+	Handler func(m *sarama.ConsumerMessage) error
+}
+
+// SetUnframedFallback sets how the consumer handles the messages that are not in the wire format of their topic,
+// defaults to UnframedFail
+func (ac *AvroConsumer) SetUnframedFallback(fallback UnframedFallback) {
+	ac.unframed = fallback
+}
+
+// handleUnframed applies the fallback to an unframed message, it returns false when the message
+// must be handled like the other decode errors
+func (ac *AvroConsumer) handleUnframed(session sarama.ConsumerGroupSession, m *sarama.ConsumerMessage, cause error) bool {
+	switch ac.unframed.Action {
+	case UnframedSkip:
+		orNop(ac.logger).Debug("skipping unframed message", "topic", m.Topic, "partition", m.Partition,
+			"offset", m.Offset, "error", cause)
+		ac.markHandled(session, m)
+		return true
+	case UnframedDeadLetter:
+		if ac.deadLetter == nil {
+			return false
+		}
+		if err := ac.deadLetterMsg(m, cause); err != nil {
+			ac.haltWith(err)
+			return true
+		}
+		ac.markHandled(session, m)
+		return true
+	case UnframedRaw:
+		if ac.unframed.Handler == nil {
+			return false
+		}
+		if err := ac.unframed.Handler(m); err != nil {
+			if err := ac.processFailed(m, err); err != nil {
+				ac.haltWith(err)
+				return true
+			}
+		}
+		ac.markHandled(session, m)
+		return true
+	}
+	return false
+}
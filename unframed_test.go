@@ -0,0 +1,62 @@
+package kafka
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Shopify/sarama"
+)
+
+func TestConfluentWireFormat_Unframed(t *testing.T) {
+	for _, value := range [][]byte{nil, {0, 0, 1}, []byte("plain json")} {
+		_, _, err := ConfluentWireFormat{}.Decode(&sarama.ConsumerMessage{Topic: "test", Value: value})
+		if !IsUnframedError(err) {
+			t.Errorf("Expected an UnframedError for %v, got %v", value, err)
+		}
+	}
+}
+
+func TestAvroConsumer_UnframedFallback(t *testing.T) {
+	var errs []error
+	received := 0
+	newConsumer := func(fallback UnframedFallback) *AvroConsumer {
+		consumer := &AvroConsumer{callbacks: ConsumerCallbacks{
+			OnDataReceived: func(msg Message) { received++ },
+			OnError:        func(err error) { errs = append(errs, err) },
+		}}
+		consumer.SetUnframedFallback(fallback)
+		return consumer
+	}
+	unframed := &sarama.ConsumerMessage{Topic: "orders", Offset: 3, Value: []byte("{}")}
+
+	session := newTestSession(nil)
+	newConsumer(UnframedFallback{Action: UnframedSkip}).handle(session, unframed)
+	if len(errs) != 0 || received != 0 || session.offsets[0] != 4 {
+		t.Errorf("Expected the message to be skipped and committed, got %v, %d, %v", errs, received, session.offsets)
+	}
+
+	var raw []*sarama.ConsumerMessage
+	session = newTestSession(nil)
+	consumer := newConsumer(UnframedFallback{Action: UnframedRaw, Handler: func(m *sarama.ConsumerMessage) error {
+		raw = append(raw, m)
+		return errors.New("rejected")
+	}})
+	consumer.handle(session, unframed)
+	if len(raw) != 1 || len(errs) != 1 || received != 0 || session.offsets[0] != 4 {
+		t.Errorf("Expected the raw handler to be called and its error reported, got %d, %v, %d", len(raw), errs, received)
+	}
+
+	producer := &testSyncProducer{}
+	consumer = newConsumer(UnframedFallback{Action: UnframedDeadLetter})
+	consumer.SetDeadLetterQueue(DeadLetterConfig{Producer: producer})
+	consumer.handle(newTestSession(nil), unframed)
+	if len(producer.sent) != 1 || received != 0 {
+		t.Errorf("Expected the message to be dead-lettered, got %d", len(producer.sent))
+	}
+
+	errs = nil
+	newConsumer(UnframedFallback{Action: UnframedDeadLetter}).handle(newTestSession(nil), unframed)
+	if len(errs) != 1 || !IsUnframedError(errs[0]) {
+		t.Errorf("Expected the decode error without a dead-letter queue, got %v", errs)
+	}
+}
@@ -0,0 +1,76 @@
+package kafka
+
+import (
+	"encoding/binary"
+
+	"github.com/Shopify/sarama"
+)
+
+// ApicurioValueHeader is the header in which Apicurio serializers carry the id of the value schema
+const ApicurioValueHeader = "apicurio.value.globalId"
+
+// WireFormat frames the encoded values of a topic with the id of their schema
+type WireFormat interface {
+	// Encode returns the value and the headers of a message with the schema id and the encoded payload
+	Encode(schemaId int, payload []byte) (sarama.Encoder, []sarama.RecordHeader)
+	// Decode returns the schema id and the encoded payload of a consumed message
+	Decode(m *sarama.ConsumerMessage) (int, []byte, error)
+}
+
+// ConfluentWireFormat prefixes the values with the magic byte 0 and the 4 byte big-endian schema id, it is the default
+type ConfluentWireFormat struct{}
+
+// Encode implements WireFormat
+func (ConfluentWireFormat) Encode(schemaId int, payload []byte) (sarama.Encoder, []sarama.RecordHeader) {
+	return &AvroEncoder{SchemaID: schemaId, Content: payload}, nil
+}
+
+// Decode implements WireFormat
+func (ConfluentWireFormat) Decode(m *sarama.ConsumerMessage) (int, []byte, error) {
+	if len(m.Value) < 5 {
+		return 0, nil, newUnframedError(m, "%d bytes is shorter than the 5 bytes header", len(m.Value))
+	}
+	if m.Value[0] != 0 {
+		return 0, nil, newUnframedError(m, "magic byte is %d, expected 0", m.Value[0])
+	}
+	return int(binary.BigEndian.Uint32(m.Value[1:5])), m.Value[5:], nil
+}
+
+// HeaderWireFormat carries the schema id in the Header record header as an 8 byte big-endian number and the bare
+// payload in the value, like the Apicurio serializers with ApicurioValueHeader. Consumers also accept 4 byte ids.
+// Record headers require kafka 0.11 or later.
+type HeaderWireFormat struct {
+	Header string
+}
+
+// Encode implements WireFormat
+func (f HeaderWireFormat) Encode(schemaId int, payload []byte) (sarama.Encoder, []sarama.RecordHeader) {
+	id := make([]byte, 8)
+	binary.BigEndian.PutUint64(id, uint64(schemaId))
+	return sarama.ByteEncoder(payload), []sarama.RecordHeader{{Key: []byte(f.Header), Value: id}}
+}
+
+// Decode implements WireFormat
+func (f HeaderWireFormat) Decode(m *sarama.ConsumerMessage) (int, []byte, error) {
+	for _, header := range m.Headers {
+		if header == nil || string(header.Key) != f.Header {
+			continue
+		}
+		switch len(header.Value) {
+		case 8:
+			return int(binary.BigEndian.Uint64(header.Value)), m.Value, nil
+		case 4:
+			return int(binary.BigEndian.Uint32(header.Value)), m.Value, nil
+		}
+		return 0, nil, newUnframedError(m, "the %s header has %d bytes, expected 4 or 8", f.Header, len(header.Value))
+	}
+	return 0, nil, newUnframedError(m, "no %s header", f.Header)
+}
+
+// wireFormat returns the wire format of the topic, ConfluentWireFormat when it is not set
+func (t TopicConfig) wireFormat() WireFormat {
+	if t.WireFormat == nil {
+		return ConfluentWireFormat{}
+	}
+	return t.WireFormat
+}
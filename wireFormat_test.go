@@ -0,0 +1,64 @@
+package kafka
+
+import (
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/mocks"
+)
+
+func TestHeaderWireFormat(t *testing.T) {
+	registry := newMemorySchemaRegistry()
+	format := HeaderWireFormat{Header: ApicurioValueHeader}
+	config := Config{Defaults: TopicConfig{WireFormat: format}}
+	producerMock := mocks.NewSyncProducer(t, nil)
+	var sent []byte
+	producerMock.ExpectSendMessageWithCheckerFunctionAndSucceed(func(val []byte) error {
+		sent = val
+		return nil
+	})
+	producer := &AvroProducer{producer: producerMock, schemaRegistryClient: registry, config: &config}
+	defer producer.Close()
+	schema := `{"type": "record", "name": "r", "fields": [{"name": "n", "type": "int"}]}`
+	if err := producer.Add("test", schema, []byte("key"), []byte(`{"n": 5}`)); err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 1 || sent[0] != 10 {
+		t.Fatalf("Expected the bare avro payload, got %v", sent)
+	}
+
+	value, headers := format.Encode(3, sent)
+	payload, _ := value.Encode()
+	m := &sarama.ConsumerMessage{Topic: "test", Value: payload, Headers: []*sarama.RecordHeader{&headers[0]}}
+	schemaId, decoded, err := format.Decode(m)
+	if err != nil || schemaId != 3 || string(decoded) != string(sent) {
+		t.Errorf("Expected schema 3 and the payload, got %d, %v, %v", schemaId, decoded, err)
+	}
+	m.Headers = []*sarama.RecordHeader{{Key: []byte(ApicurioValueHeader), Value: []byte{0, 0, 0, 4}}}
+	if schemaId, _, err := format.Decode(m); err != nil || schemaId != 4 {
+		t.Errorf("Expected a 4 byte id to be accepted, got %d, %v", schemaId, err)
+	}
+	m.Headers = nil
+	if _, _, err := format.Decode(m); err == nil {
+		t.Errorf("Expected an error without the header")
+	}
+}
+
+func TestAvroConsumer_HeaderWireFormat(t *testing.T) {
+	schemaRegistryTestObject := createSchemaRegistryTestObject(t, "test", 1)
+	format := HeaderWireFormat{Header: "schema-id"}
+	consumer := &AvroConsumer{
+		SchemaRegistryClient: NewCachedSchemaRegistryClient([]string{schemaRegistryTestObject.MockServer.URL}),
+		config:               &Config{Topics: map[string]TopicConfig{"test": {WireFormat: format}}},
+	}
+	framed := getTestAvroMsg(t, schemaRegistryTestObject.Codec)
+	_, headers := format.Encode(1, nil)
+	msg, err := consumer.ProcessAvroMsg(&sarama.ConsumerMessage{Topic: "test", Value: framed[5:],
+		Headers: []*sarama.RecordHeader{&headers[0]}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.SchemaId != 1 || msg.Value != testData {
+		t.Errorf("Unexpected message %+v", msg)
+	}
+}